@@ -0,0 +1,724 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"sql-golang-playground/internal/service"
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// app holds the repositories and services the subcommands run against.
+type app struct {
+	accountRepo           repository.AccountRepository
+	transactionRepo       repository.TransactionRepository
+	reconciliationRuns    repository.ReconciliationRunRepository
+	txService             service.TransactionService
+	scheduledService      service.ScheduledTransferService
+	snapshotService       service.BalanceSnapshotService
+	fixtureService        service.FixtureService
+	reconciliationService service.ReconciliationService
+	interestService       service.InterestService
+	logger                util.Logger
+}
+
+// action is what a subcommand does once its flags are parsed. validate, if set, runs before
+// the database is opened, so usage mistakes are reported without a connection.
+type action struct {
+	validate func() error
+	run      func(a *app) error
+}
+
+// command is one subcommand of the CLI. setup registers its flags on fs.
+type command struct {
+	name    string
+	summary string
+	setup   func(fs *flag.FlagSet) action
+}
+
+// usageError marks an error caused by bad arguments; it exits with exitUsage.
+type usageError struct {
+	msg string
+}
+
+// Error implements error.
+func (e usageError) Error() string {
+	return e.msg
+}
+
+// usagef returns a usageError with a formatted message.
+func usagef(format string, args ...interface{}) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// commands lists the subcommands in the order usage shows them.
+var commands = []command{
+	{name: "transfer", summary: "move funds between two accounts", setup: transferCommand},
+	{name: "schedule-transfer", summary: "book a transfer to run at a future time", setup: scheduleTransferCommand},
+	{name: "process-scheduled", summary: "execute the scheduled transfers that are due", setup: processScheduledCommand},
+	{name: "record", summary: "record a transaction with optional notes, without moving funds", setup: recordCommand},
+	{name: "transactions", summary: "list an account's transactions with their categories", setup: transactionsCommand},
+	{name: "export", summary: "stream every transaction to a CSV or JSON file", setup: exportCommand},
+	{name: "export-fixtures", summary: "snapshot all accounts and transactions to a JSON fixtures file", setup: exportFixturesCommand},
+	{name: "import-fixtures", summary: "load a JSON fixtures file into the database", setup: importFixturesCommand},
+	{name: "total-balance", summary: "print the total balance of all active accounts", setup: totalBalanceCommand},
+	{name: "close-account", summary: "soft-delete an account", setup: closeAccountCommand},
+	{name: "reopen-account", summary: "undo close-account", setup: reopenAccountCommand},
+	{name: "close-inactive", summary: "close every active account without activity since a time", setup: closeInactiveCommand},
+	{name: "reconcile", summary: "reconcile the database against external CSV files", setup: reconcileCommand},
+	{name: "reconcile-schedule", summary: "reconcile CSV files periodically and store each run's summary", setup: reconcileScheduleCommand},
+	{name: "last-reconciliation", summary: "show the most recent stored reconciliation run", setup: lastReconciliationCommand},
+	{name: "verify-ledger", summary: "list accounts whose balance disagrees with their transactions", setup: verifyLedgerCommand},
+	{name: "snapshot-balances", summary: "record every open account's balance as of a time", setup: snapshotBalancesCommand},
+	{name: "balance-as-of", summary: "print an account's balance as of a past time", setup: balanceAsOfCommand},
+	{name: "accrue-interest", summary: "credit one period's interest to every account with a positive balance", setup: accrueInterestCommand},
+}
+
+// findCommand returns the subcommand called name.
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// printUsage lists the subcommands on w.
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: app <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'app <command> -h' for a command's flags.")
+}
+
+// requireID rejects a missing (non-positive) account or transaction ID flag.
+func requireID(name string, id int64) error {
+	if id <= 0 {
+		return usagef("--%s is required and must be positive", name)
+	}
+	return nil
+}
+
+// nullString converts an optional flag value to a sql.NullString.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullID converts an optional account ID flag (0 meaning unset) to a sql.NullInt64.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id > 0}
+}
+
+// transferCommand moves funds with TransactionService.TransferFunds.
+func transferCommand(fs *flag.FlagSet) action {
+	from := fs.Int64("from", 0, "sending account ID")
+	to := fs.Int64("to", 0, "receiving account ID")
+	amount := fs.Float64("amount", 0, "amount to transfer, in the sender's currency")
+	description := fs.String("description", "", "transaction description")
+	notes := fs.String("notes", "", "free-form notes")
+	return action{
+		validate: func() error {
+			if err := requireID("from", *from); err != nil {
+				return err
+			}
+			if err := requireID("to", *to); err != nil {
+				return err
+			}
+			if *amount <= 0 {
+				return usagef("--amount is required and must be positive")
+			}
+			return nil
+		},
+		run: func(a *app) error {
+			if err := a.txService.TransferFunds(*from, *to, *amount, *description, *notes); err != nil {
+				return fmt.Errorf("%s: %w", transferFailureMessage(err), err)
+			}
+			fmt.Printf("Transferred %.2f from account %d to account %d\n", *amount, *from, *to)
+			return nil
+		},
+	}
+}
+
+// transferFailureMessage explains a failed transfer in user terms.
+func transferFailureMessage(err error) string {
+	switch {
+	case errors.Is(err, service.ErrInsufficientFunds):
+		return "the transfer could not be completed due to insufficient funds"
+	case errors.Is(err, service.ErrAccountNotFound), errors.Is(err, service.ErrAccountInactive):
+		return "one of the accounts involved is not valid or active"
+	case errors.Is(err, service.ErrAccountFrozen):
+		return "one of the accounts involved is frozen"
+	case errors.Is(err, service.ErrSameAccountTransfer):
+		return "cannot transfer funds to the same account"
+	case errors.Is(err, service.ErrInvalidTransferAmount):
+		return "invalid transfer amount"
+	case errors.Is(err, service.ErrRequiresReview):
+		return "the transfer is held for review"
+	}
+	return "fund transfer failed"
+}
+
+// scheduleTransferCommand books a transfer with ScheduledTransferService.ScheduleTransfer.
+func scheduleTransferCommand(fs *flag.FlagSet) action {
+	from := fs.Int64("from", 0, "sending account ID")
+	to := fs.Int64("to", 0, "receiving account ID")
+	amount := fs.Float64("amount", 0, "amount to transfer, in the sender's currency")
+	at := fs.String("at", "", "execution time, RFC 3339, e.g. 2025-07-01T09:00:00Z")
+	description := fs.String("description", "", "transaction description")
+	notes := fs.String("notes", "", "free-form notes")
+	var executeAt time.Time
+	return action{
+		validate: func() error {
+			if err := requireID("from", *from); err != nil {
+				return err
+			}
+			if err := requireID("to", *to); err != nil {
+				return err
+			}
+			if *amount <= 0 {
+				return usagef("--amount is required and must be positive")
+			}
+			var err error
+			if executeAt, err = time.Parse(time.RFC3339, *at); err != nil {
+				return usagef("--at must be an RFC 3339 time: %v", err)
+			}
+			return nil
+		},
+		run: func(a *app) error {
+			id, err := a.scheduledService.ScheduleTransfer(*from, *to, *amount, executeAt, *description, *notes)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Scheduled transfer %d: %.2f from account %d to account %d at %s\n", id, *amount, *from, *to, executeAt.Format(time.RFC3339))
+			return nil
+		},
+	}
+}
+
+// processScheduledCommand runs ScheduledTransferService.ProcessDueTransfers once.
+func processScheduledCommand(fs *flag.FlagSet) action {
+	return action{
+		run: func(a *app) error {
+			processed, err := a.scheduledService.ProcessDueTransfers(time.Now())
+			for _, st := range processed {
+				fmt.Printf("Scheduled transfer %d: %s\n", st.ScheduledTransferID, st.Status)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Processed %d due scheduled transfers\n", len(processed))
+			return nil
+		},
+	}
+}
+
+// recordCommand inserts a transaction row with TransactionRepository.CreateTransactionWithNotes.
+func recordCommand(fs *flag.FlagSet) action {
+	from := fs.Int64("from", 0, "sending account ID; omit for money coming from outside")
+	to := fs.Int64("to", 0, "receiving account ID; omit for money leaving the system")
+	txType := fs.String("type", "", "transaction type, e.g. DEPOSIT or WITHDRAWAL")
+	amount := fs.Float64("amount", 0, "positive amount; the type gives the direction")
+	description := fs.String("description", "", "transaction description")
+	notes := fs.String("notes", "", "free-form notes")
+	return action{
+		validate: func() error {
+			if *from <= 0 && *to <= 0 {
+				return usagef("at least one of --from and --to is required")
+			}
+			if *txType == "" {
+				return usagef("--type is required")
+			}
+			if *amount <= 0 {
+				return usagef("--amount is required and must be positive")
+			}
+			return nil
+		},
+		run: func(a *app) error {
+			id, err := a.transactionRepo.CreateTransactionWithNotes(nullID(*from), nullID(*to), strings.ToUpper(*txType), *amount, nullString(*description), nullString(*notes))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Recorded transaction %d\n", id)
+			return nil
+		},
+	}
+}
+
+// transactionsCommand lists an account's transactions with GetTransactionsWithCategory.
+func transactionsCommand(fs *flag.FlagSet) action {
+	account := fs.Int64("account", 0, "account ID")
+	byAmount := fs.Bool("by-amount", false, "order by amount, largest first, instead of newest first")
+	includeDeleted := fs.Bool("include-deleted", false, "include soft-deleted transactions")
+	includePending := fs.Bool("include-pending", false, "include transfers pending review, which have moved no funds")
+	category := fs.Int64("category", 0, "only transactions in this category ID")
+	uncategorized := fs.Bool("uncategorized", false, "only transactions without a category")
+	return action{
+		validate: func() error {
+			if *category != 0 && *uncategorized {
+				return usagef("--category and --uncategorized are mutually exclusive")
+			}
+			return requireID("account", *account)
+		},
+		run: func(a *app) error {
+			acc, err := a.accountRepo.GetAccountByIDIncludingDeleted(*account)
+			if err != nil {
+				return err
+			}
+			transactions, err := a.transactionRepo.GetTransactionsWithCategory(*account, repository.CategoryListOptions{
+				OrderByAmount:  *byAmount,
+				IncludeDeleted: *includeDeleted,
+				IncludePending: *includePending,
+				CategoryID:     nullID(*category),
+				Uncategorized:  *uncategorized,
+			})
+			if err != nil {
+				return err
+			}
+			for _, tx := range transactions {
+				fmt.Printf("ID: %d, Type: %s, Amount: %s, Desc: %s",
+					tx.TransactionID, tx.TransactionType, util.FormatMoney(statementAmount(tx, acc), acc.Currency), tx.Description.String)
+				if tx.CategoryName.Valid {
+					fmt.Printf(", Category: %s", tx.CategoryName.String)
+				}
+				if tx.Status != models.TransactionStatusCompleted {
+					fmt.Printf(", Status: %s", tx.Status)
+				}
+				fmt.Println()
+			}
+			return nil
+		},
+	}
+}
+
+// statementAmount returns the amount of tx in acc's currency: a cross-currency transfer is
+// credited its converted amount.
+func statementAmount(tx models.TransactionWithCategory, acc models.Account) float64 {
+	if tx.CreditedAmount.Valid && tx.ToAccountID.Valid && tx.ToAccountID.Int64 == acc.AccountID {
+		return tx.CreditedAmount.Float64
+	}
+	return tx.Amount
+}
+
+// exportCommand streams every transaction that is not soft-deleted to a file in ID order,
+// without loading the whole table into memory.
+func exportCommand(fs *flag.FlagSet) action {
+	format := fs.String("format", "csv", "output format: csv or json")
+	out := fs.String("out", "", "output file path")
+	return action{
+		validate: func() error {
+			if *format != "csv" && *format != "json" {
+				return usagef("--format must be csv or json, got %q", *format)
+			}
+			if *out == "" {
+				return usagef("--out is required")
+			}
+			return nil
+		},
+		run: func(a *app) error {
+			count := 0
+			err := writeReport(*out, func(w io.Writer) error {
+				var writer interface {
+					Write(tx models.Transaction) error
+					Close() error
+				}
+				if *format == "json" {
+					writer = util.NewTransactionJSONWriter(w)
+				} else {
+					writer = util.NewTransactionCSVWriter(w)
+				}
+				if err := a.transactionRepo.ForEachTransactionForReconciliation(func(tx models.Transaction) error {
+					count++
+					return writer.Write(tx)
+				}); err != nil {
+					return err
+				}
+				return writer.Close()
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Exported %d transactions to %s\n", count, *out)
+			return nil
+		},
+	}
+}
+
+// totalBalanceCommand prints CalculateTotalBalanceOfActiveAccounts.
+func totalBalanceCommand(fs *flag.FlagSet) action {
+	return action{
+		run: func(a *app) error {
+			total, err := a.accountRepo.CalculateTotalBalanceOfActiveAccounts()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Total balance of all active accounts: %.2f\n", total)
+			return nil
+		},
+	}
+}
+
+// exportFixturesCommand runs FixtureService.ExportFixtures.
+func exportFixturesCommand(fs *flag.FlagSet) action {
+	out := fs.String("out", "", "output file path")
+	return action{
+		validate: func() error {
+			if *out == "" {
+				return usagef("--out is required")
+			}
+			return nil
+		},
+		run: func(a *app) error {
+			if err := writeReport(*out, a.fixtureService.ExportFixtures); err != nil {
+				return err
+			}
+			fmt.Printf("Exported fixtures to %s\n", *out)
+			return nil
+		},
+	}
+}
+
+// importFixturesCommand runs FixtureService.ImportFixtures.
+func importFixturesCommand(fs *flag.FlagSet) action {
+	in := fs.String("in", "", "fixtures file written by export-fixtures")
+	return action{
+		validate: func() error {
+			if *in == "" {
+				return usagef("--in is required")
+			}
+			return nil
+		},
+		run: func(a *app) error {
+			f, err := os.Open(*in)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if err := a.fixtureService.ImportFixtures(f); err != nil {
+				return err
+			}
+			fmt.Printf("Imported fixtures from %s\n", *in)
+			return nil
+		},
+	}
+}
+
+// closeAccountCommand soft-deletes an account.
+func closeAccountCommand(fs *flag.FlagSet) action {
+	id := fs.Int64("id", 0, "account ID")
+	return action{
+		validate: func() error { return requireID("id", *id) },
+		run: func(a *app) error {
+			if _, err := a.accountRepo.SoftDeleteAccount(*id); err != nil {
+				return err
+			}
+			fmt.Printf("Closed account %d\n", *id)
+			return nil
+		},
+	}
+}
+
+// closeInactiveCommand runs SoftDeleteInactiveAccounts, or lists the accounts it would close.
+func closeInactiveCommand(fs *flag.FlagSet) action {
+	sinceFlag := fs.String("since", "", "close accounts without transactions since this time, RFC 3339 (required)")
+	zeroBalance := fs.Bool("zero-balance", false, "only close accounts with a zero balance")
+	dryRun := fs.Bool("dry-run", false, "list the accounts that would be closed without closing them")
+	var since time.Time
+	return action{
+		validate: func() error {
+			if *sinceFlag == "" {
+				return usagef("--since is required")
+			}
+			var err error
+			since, err = time.Parse(time.RFC3339, *sinceFlag)
+			if err != nil {
+				return usagef("--since must be an RFC 3339 time: %v", err)
+			}
+			return nil
+		},
+		run: func(a *app) error {
+			opts := repository.InactiveAccountOptions{ZeroBalanceOnly: *zeroBalance}
+			if *dryRun {
+				accounts, err := a.accountRepo.FindInactiveAccounts(since, opts)
+				if err != nil {
+					return err
+				}
+				for _, acc := range accounts {
+					fmt.Printf("%d\t%s\t%s\t%s\n", acc.AccountID, acc.AccountNumber, acc.AccountHolder, util.FormatMoney(acc.Balance, acc.Currency))
+				}
+				fmt.Printf("Would close %d accounts\n", len(accounts))
+				return nil
+			}
+			softDelete := a.accountRepo.SoftDeleteInactiveAccounts
+			if *zeroBalance {
+				softDelete = a.accountRepo.SoftDeleteInactiveZeroBalanceAccounts
+			}
+			closed, err := softDelete(since)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Closed %d accounts\n", closed)
+			return nil
+		},
+	}
+}
+
+// reopenAccountCommand undoes closeAccountCommand.
+func reopenAccountCommand(fs *flag.FlagSet) action {
+	id := fs.Int64("id", 0, "account ID")
+	return action{
+		validate: func() error { return requireID("id", *id) },
+		run: func(a *app) error {
+			if _, err := a.accountRepo.UndeleteAccount(*id); err != nil {
+				return err
+			}
+			acc, err := a.accountRepo.GetAccountByIDIncludingDeleted(*id)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Reopened account %d as %s\n", *id, acc.Status)
+			return nil
+		},
+	}
+}
+
+// reconcileCommand runs a reconciliation and optionally saves the report.
+func reconcileCommand(fs *flag.FlagSet) action {
+	file := fs.String("file", "", "CSV file to reconcile")
+	dir := fs.String("dir", "", "directory or glob of CSV files to reconcile together")
+	account := fs.Int64("account", 0, "reconcile --file as the statement of this account")
+	csvOut := fs.String("csv-out", "", "also write the report as CSV to this path")
+	jsonOut := fs.String("json-out", "", "also write the report as JSON to this path")
+	failOnDiff := fs.Bool("fail-on-diff", false, "exit non-zero if anything did not match")
+	return action{
+		validate: func() error {
+			if (*file == "") == (*dir == "") {
+				return usagef("exactly one of --file and --dir is required")
+			}
+			if *account != 0 && *file == "" {
+				return usagef("--account needs --file")
+			}
+			return nil
+		},
+		run: func(a *app) error {
+			// Ctrl-C stops a long run cleanly instead of killing it mid-query.
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			reconciler := a.reconciliationService.WithContext(ctx)
+
+			var report *service.ReconciliationReport
+			var err error
+			switch {
+			case *dir != "":
+				report, err = reconciler.ReconcileDirectory(*dir)
+			case *account != 0:
+				report, err = reconciler.ReconcileAccountStatement(*account, *file)
+			default:
+				report, err = reconciler.ReconcileTransactions(*file)
+			}
+			if err != nil {
+				return err
+			}
+			if err := writeReport(*csvOut, report.WriteCSV); err != nil {
+				return err
+			}
+			if err := writeReport(*jsonOut, report.WriteJSON); err != nil {
+				return err
+			}
+			if *failOnDiff && hasDifferences(report) {
+				return fmt.Errorf("reconciliation found differences")
+			}
+			return nil
+		},
+	}
+}
+
+// writeReport writes a report to path with write; an empty path writes nothing.
+func writeReport(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// hasDifferences reports whether a reconciliation left anything unmatched or mismatched.
+func hasDifferences(r *service.ReconciliationReport) bool {
+	return len(r.AmountMismatches)+len(r.PartialSplitMatches)+len(r.AmbiguousSplits)+
+		len(r.OnlyInDB)+len(r.OnlyInCSV)+len(r.FileErrors)+len(r.UnreadableDBRows) > 0
+}
+
+// verifyLedgerCommand lists ledger discrepancies and fails if there are any.
+func verifyLedgerCommand(fs *flag.FlagSet) action {
+	return action{
+		run: func(a *app) error {
+			discrepancies, err := a.transactionRepo.VerifyLedgerIntegrity()
+			if err != nil {
+				return err
+			}
+			for _, d := range discrepancies {
+				fmt.Printf("Account %d (%s): stored %.2f, ledger %.2f, difference %.2f\n",
+					d.AccountID, d.AccountNumber, d.StoredBalance, d.LedgerBalance, d.Difference)
+			}
+			if len(discrepancies) > 0 {
+				return fmt.Errorf("%d account(s) disagree with their ledger", len(discrepancies))
+			}
+			fmt.Println("All account balances match their ledgers")
+			return nil
+		},
+	}
+}
+
+// reconcileScheduleCommand runs a ReconciliationRunner until interrupted.
+func reconcileScheduleCommand(fs *flag.FlagSet) action {
+	dir := fs.String("dir", "", "directory or glob of CSV files to reconcile")
+	every := fs.Duration("every", time.Hour, "time between runs")
+	return action{
+		validate: func() error {
+			if *dir == "" {
+				return usagef("--dir is required")
+			}
+			if *every <= 0 {
+				return usagef("--every must be positive")
+			}
+			return nil
+		},
+		run: func(a *app) error {
+			runner, err := service.NewReconciliationRunner(a.reconciliationService, a.reconciliationRuns, *dir, *every, a.logger)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			runner.Start(ctx)
+			<-ctx.Done()
+			return nil
+		},
+	}
+}
+
+// lastReconciliationCommand prints GetLastReconciliationRun.
+func lastReconciliationCommand(fs *flag.FlagSet) action {
+	input := fs.String("input", "", "only runs of this directory or glob; default any")
+	return action{
+		run: func(a *app) error {
+			run, err := a.reconciliationRuns.GetLastReconciliationRun(*input)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Run %d of %s, %s to %s\n", run.RunID, run.Input, run.StartedAt.Format(time.RFC3339), run.FinishedAt.Format(time.RFC3339))
+			if run.Error.Valid {
+				fmt.Printf("Failed: %s\n", run.Error.String)
+				return nil
+			}
+			fmt.Printf("Matched: %d, Split: %d, Partial split: %d, Ambiguous split: %d, Amount mismatch: %d, Only in DB: %d, Only in CSV: %d, File errors: %d\n",
+				run.Matched, run.SplitMatches, run.PartialSplitMatches, run.AmbiguousSplits, run.AmountMismatches, run.OnlyInDB, run.OnlyInCSV, run.FileErrors)
+			fmt.Printf("Match rate: %.1f%%\n", run.MatchRate()*100)
+			return nil
+		},
+	}
+}
+
+// parseAsOf parses an optional --as-of flag value; empty means now.
+func parseAsOf(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, usagef("--as-of must be an RFC 3339 time: %v", err)
+	}
+	return t, nil
+}
+
+// snapshotBalancesCommand runs BalanceSnapshotService.SnapshotBalances.
+func snapshotBalancesCommand(fs *flag.FlagSet) action {
+	asOfFlag := fs.String("as-of", "", "snapshot time, RFC 3339; default now")
+	var asOf time.Time
+	return action{
+		validate: func() error {
+			var err error
+			asOf, err = parseAsOf(*asOfFlag)
+			return err
+		},
+		run: func(a *app) error {
+			count, err := a.snapshotService.SnapshotBalances(asOf)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Snapshotted %d account balances as of %s\n", count, asOf.UTC().Format(time.RFC3339))
+			return nil
+		},
+	}
+}
+
+// balanceAsOfCommand prints BalanceSnapshotService.GetBalanceAsOf.
+func balanceAsOfCommand(fs *flag.FlagSet) action {
+	account := fs.Int64("account", 0, "account ID")
+	asOfFlag := fs.String("as-of", "", "time, RFC 3339; default now")
+	var asOf time.Time
+	return action{
+		validate: func() error {
+			if err := requireID("account", *account); err != nil {
+				return err
+			}
+			var err error
+			asOf, err = parseAsOf(*asOfFlag)
+			return err
+		},
+		run: func(a *app) error {
+			balance, err := a.snapshotService.GetBalanceAsOf(*account, asOf)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Balance of account %d as of %s: %.2f\n", *account, asOf.UTC().Format(time.RFC3339), balance)
+			return nil
+		},
+	}
+}
+
+// accrueInterestCommand runs InterestService.AccrueInterest once.
+func accrueInterestCommand(fs *flag.FlagSet) action {
+	rate := fs.Float64("rate", 0, "interest rate for the period, e.g. 0.001 for 0.1%")
+	asOfFlag := fs.String("as-of", "", "time identifying the period, RFC 3339; default now")
+	var asOf time.Time
+	return action{
+		validate: func() error {
+			if *rate <= 0 {
+				return usagef("--rate must be positive")
+			}
+			var err error
+			asOf, err = parseAsOf(*asOfFlag)
+			return err
+		},
+		run: func(a *app) error {
+			if err := a.interestService.AccrueInterest(*rate, asOf); err != nil {
+				return err
+			}
+			fmt.Printf("Accrued interest at rate %v for %s\n", *rate, asOf.UTC().Format("2006-01-02"))
+			return nil
+		},
+	}
+}
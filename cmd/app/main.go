@@ -1,175 +1,173 @@
 package main
 
 import (
-	"database/sql"
-	"fmt"
-	"log"
 	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/joho/godotenv"
 
 	"sql-golang-playground/internal/db"
-	"sql-golang-playground/repository"
 	"sql-golang-playground/internal/service"
 	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// transferMaxRetries is how many times a transfer is retried after a deadlock or lock wait timeout.
+const transferMaxRetries = 3
+
+// Repository query timeouts. The reconciliation scan reads the whole table, so it gets longer.
+const (
+	queryTimeout               = 30 * time.Second
+	reconciliationQueryTimeout = 5 * time.Minute
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFailure = 1 // the command ran and failed
+	exitUsage   = 2 // bad command line
 )
 
-func softDeleteDemo(accountRepo repository.AccountRepository) {
-    // Assume accID1 was created earlier
-    accID1 := int64(1) // Replace with an actual ID from your DB
-
-    // Soft Delete Account
-    fmt.Printf("\nAttempting to soft delete account ID %d...\n", accID1)
-    affected, err := accountRepo.SoftDeleteAccount(accID1)
-    if err != nil {
-        log.Printf("Error soft deleting account: %v\n", err)
-    } else if affected > 0 {
-        fmt.Printf("Soft deleted account ID: %d\n", accID1)
-    }
-
-    // Try to get it with the normal function (should fail or return not found)
-    _, err = accountRepo.GetAccountByID(accID1)
-    if err != nil {
-        fmt.Printf("Attempt to get active account ID %d after soft delete: %v (this is expected)\n", accID1, err)
-    }
-
-	// Undelete Account
-    fmt.Printf("\nAttempting to undelete account ID %d...\n", accID1)
-    affected, err = accountRepo.UndeleteAccount(accID1)
-    if err != nil {
-        log.Printf("Error undeleting account: %v", err)
-    } else if affected > 0 {
-        fmt.Printf("Undeleted account ID: %d\n", accID1)
-    }
+func main() {
+	os.Exit(run(os.Args[1:]))
 }
 
-func totalBalanceDemo(accountRepo repository.AccountRepository) {
-	// Get total balance of active accounts
-	totalBal, err := accountRepo.CalculateTotalBalanceOfActiveAccounts()
-	if err != nil {
-		log.Printf("Error calculating total balance: %v", err)
-	} else {
-		fmt.Printf("Total balance of all active accounts: %.2f\n", totalBal)
+// run executes the subcommand named by args[0] and returns the process exit code.
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		printUsage(os.Stderr)
+		return exitUsage
 	}
-}
 
-func transactionsWithCategoryDemo(transactionRepo repository.TransactionRepository) {
-	// Get transactions with categories
-	aliceTransactionsWithCat, err := transactionRepo.GetTransactionsWithCategory(1)
-	if err != nil {
-		log.Printf("Error getting transactions with categories: %v", err)
-	} else {
-		fmt.Println("\nAlice's Transactions with Categories:")
-		for _, tx := range aliceTransactionsWithCat {
-			fmt.Printf("  ID: %d, Type: %s, Amount: %.2f, Desc: %s",
-				tx.Transaction.TransactionID, tx.Transaction.TransactionType, tx.Transaction.Amount, tx.Transaction.Description.String)
-			if tx.CategoryName.Valid {
-				fmt.Printf(", Category: %s", tx.CategoryName.String)
-			}
-			fmt.Println()
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	act := cmd.setup(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
 		}
+		return exitUsage // the flag package has already printed the problem and the flags
 	}
-}
-
-func transactionWithNotesDemo(transactionRepo repository.TransactionRepository) {
-	// Transaction WITH notes
-	desc1 := sql.NullString{String: "Salary payment", Valid: true}
-	notes1 := sql.NullString{String: "Monthly salary for June", Valid: true}
-	toAccount1 := sql.NullInt64{Int64: 1, Valid: true} // Deposit to Alice
-	fromAccount1 := sql.NullInt64{Valid: false}      // From external source
-
-	txID1, err := transactionRepo.CreateTransactionWithNotes(fromAccount1, toAccount1, "DEPOSIT", 3000.00, desc1, notes1)
-	if err != nil {
-		log.Printf("Error creating transaction with notes: %v", err)
-	} else {
-		fmt.Printf("Created transaction with notes, ID: %d\n", txID1)
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "%s: unexpected arguments %v\n", cmd.name, fs.Args())
+		return exitUsage
+	}
+	if act.validate != nil {
+		if err := act.validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+			fs.Usage()
+			return exitUsage
+		}
 	}
 
-	// Transaction WITHOUT notes (notes will be NULL in DB)
-	desc2 := sql.NullString{String: "Coffee purchase", Valid: true}
-	notes2 := sql.NullString{Valid: false} // No notes, so Valid is false
-	fromAccount2 := sql.NullInt64{Int64: 1, Valid: true} // Withdrawal from Alice
-	toAccount2 := sql.NullInt64{Valid: false}        // To external vendor
-
-	txID2, err := transactionRepo.CreateTransactionWithNotes(fromAccount2, toAccount2, "WITHDRAWAL", -4.50, desc2, notes2) // Amount is negative for withdrawal
-	if err != nil {
-		log.Printf("Error creating transaction without notes: %v", err)
-	} else {
-		fmt.Printf("Created transaction without notes, ID: %d\n", txID2)
+	if err := withApp(act.run); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			return exitUsage
+		}
+		return exitFailure
 	}
+	return exitOK
 }
 
-func fundTransferDemo(txService service.TransactionService) {
-    // --- Example: Fund Transfer (demonstrating new error handling) ---
-    // Make sure accounts 1 and 2 exist and account 1 has funds
-    // E.g., CreateAccount(db, "Sender One", 1000.00) -> ID 1
-    //       CreateAccount(db, "Receiver Two", 200.00) -> ID 2
-    err := txService.TransferFunds(1, 2, 50.75, "Payment for services", "Invoice #123")
-    if err != nil {
-        log.Printf("ERROR: Fund transfer failed: %v", err)
-        if errors.Is(err, service.ErrInsufficientFunds) {
-            fmt.Println("User Message: The transfer could not be completed due to insufficient funds.")
-        } else if errors.Is(err, service.ErrAccountNotFound) || errors.Is(err, service.ErrAccountInactive) {
-            fmt.Println("User Message: One of the accounts involved is not valid or active.")
-        } else if errors.Is(err, service.ErrSameAccountTransfer) {
-            fmt.Println("User Message: Cannot transfer funds to the same account.")
-        } else if errors.Is(err, service.ErrInvalidTransferAmount) {
-            fmt.Println("User Message: Invalid transfer amount.")
-        }
-        // etc.
-    } else {
-        fmt.Println("Fund transfer initiated successfully (check logs for details).")
-    }
+// defaultCurrency returns the currency for amounts not tied to an account, from the
+// DEFAULT_CURRENCY environment variable, falling back to models.DefaultCurrency.
+func defaultCurrency() (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(os.Getenv("DEFAULT_CURRENCY")))
+	if code == "" {
+		return models.DefaultCurrency, nil
+	}
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("DEFAULT_CURRENCY must be a three-letter ISO 4217 code, got %q", code)
+	}
+	return code, nil
 }
 
-func reconciliationDemo(reconciliationService service.ReconciliationService) {
-    reconciliationService.ReconcileTransactions("data/external_transactions.csv")
+// csvFormat returns the format of partner CSV files from the CSV_DELIMITER environment
+// variable: a single character, or "tab". Unset means comma-separated.
+func csvFormat() (util.CSVFormat, error) {
+	delimiter := os.Getenv("CSV_DELIMITER")
+	switch {
+	case delimiter == "":
+		return util.CSVFormat{}, nil
+	case strings.EqualFold(delimiter, "tab"):
+		return util.CSVFormat{Comma: '\t'}, nil
+	case utf8.RuneCountInString(delimiter) == 1:
+		r, _ := utf8.DecodeRuneInString(delimiter)
+		return util.CSVFormat{Comma: r}, nil
+	}
+	return util.CSVFormat{}, fmt.Errorf("CSV_DELIMITER must be a single character or \"tab\", got %q", delimiter)
 }
 
-func main() {
-	dbConn := db.Connect() // Use the new db.Connect function
-	defer dbConn.Close()   // Ensure database connection is closed
-
-    // Initialize repositories
-    accountRepo := repository.NewMySQLAccountRepository(dbConn)
-    transactionRepo := repository.NewMySQLTransactionRepository(dbConn)
-
-    // Initialize services
-    txService := service.NewTransactionService(accountRepo, transactionRepo)
-    dataLoader := util.NewCSVDataLoader()
-    reconciliationService := service.NewReconciliationService(transactionRepo, dataLoader)
-
-    demos := map[string]func(){ // Change signature to no parameters
-        "soft_delete": func() { softDeleteDemo(accountRepo) },
-        "total_balance": func() { totalBalanceDemo(accountRepo) },
-        "transactions_with_category": func() { transactionsWithCategoryDemo(transactionRepo) },
-        "transaction_with_notes": func() { transactionWithNotesDemo(transactionRepo) },
-        "fund_transfer": func() { fundTransferDemo(txService) }, // Pass txService
-        "reconciliation": func() { reconciliationDemo(reconciliationService) }, // Pass reconciliationService
-    }
-
-    fmt.Println("Available Demos:")
-    for name := range demos {
-        fmt.Printf("  - %s\n", name)
-    }
-
-    fmt.Print("Enter the name of the demo to run (or 'all' to run all, 'exit' to quit): ")
-    var choice string
-    fmt.Scanln(&choice)
-
-    if choice == "all" {
-        for name, demoFunc := range demos {
-            fmt.Printf("\n--- Running Demo: %s ---\n", name)
-            demoFunc() // Call without parameters
-            fmt.Printf("--- Finished Demo: %s ---\n", name)
-        }
-    } else if choice == "exit" {
-        fmt.Println("Exiting demo program.")
-    } else {
-        if demoFunc, ok := demos[choice]; ok {
-            fmt.Printf("\n--- Running Demo: %s ---\n", choice)
-            demoFunc() // Call without parameters
-            fmt.Printf("--- Finished Demo: %s ---\n", choice)
-        } else {
-            fmt.Printf("Unknown demo: %s\n", choice)
-        }
-    }
+// withApp connects to the database, wires the repositories and services, and runs fn.
+// Settings come from the environment, after loading .env from the working directory if present.
+func withApp(fn func(a *app) error) error {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to load .env file: %w", err)
+	}
+	dbConn, err := db.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbConn.Close() // Ensure database connection is closed
+
+	if err := repository.CheckSchema(dbConn); err != nil {
+		return fmt.Errorf("database schema check failed: %w", err)
+	}
+	currency, err := defaultCurrency()
+	if err != nil {
+		return err
+	}
+	format, err := csvFormat()
+	if err != nil {
+		return err
+	}
+
+	// Initialize repositories
+	repoOpts := []repository.RepositoryOption{
+		repository.WithPreparedStatements(),
+		repository.WithQueryTimeout(queryTimeout),
+		repository.WithMethodTimeout("GetAllTransactionsForReconciliation", reconciliationQueryTimeout),
+		repository.WithMethodTimeout("GetAllTransactionsForReconciliationSkippingBadRows", reconciliationQueryTimeout),
+		repository.WithMethodTimeout("ForEachTransactionForReconciliation", reconciliationQueryTimeout),
+	}
+	accountRepo := repository.NewMySQLAccountRepository(dbConn, repoOpts...)
+	defer accountRepo.Close()
+	transactionRepo := repository.NewMySQLTransactionRepository(dbConn, repoOpts...)
+	defer transactionRepo.Close()
+
+	// Initialize services
+	logger := util.NewStdLogger(false)
+	dataLoader := util.NewCSVDataLoader(logger, util.WithCSVFormat(format))
+	txService := service.NewTransactionService(repository.NewSQLTransactor(dbConn), accountRepo, transactionRepo, transferMaxRetries, logger)
+	scheduledRepo := repository.NewMySQLScheduledTransferRepository(dbConn, repoOpts...)
+	return fn(&app{
+		accountRepo:           accountRepo,
+		transactionRepo:       transactionRepo,
+		reconciliationRuns:    repository.NewMySQLReconciliationRunRepository(dbConn, repoOpts...),
+		txService:             txService,
+		scheduledService:      service.NewScheduledTransferService(accountRepo, scheduledRepo, txService, logger),
+		snapshotService:       service.NewBalanceSnapshotService(accountRepo, repository.NewMySQLBalanceSnapshotRepository(dbConn, repoOpts...), logger),
+		fixtureService:        service.NewFixtureService(dbConn, logger),
+		reconciliationService: service.NewReconciliationService(transactionRepo, dataLoader, logger, service.WithRunLock(repository.NewMySQLLocker(dbConn)), service.WithSkipBadDBRows(), service.WithAmountFormatter(util.NewMoneyFormatter(currency))),
+		interestService:       service.NewInterestService(repository.NewSQLTransactor(dbConn), accountRepo, transactionRepo, 0, logger),
+		logger:                logger,
+	})
 }
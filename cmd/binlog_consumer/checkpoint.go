@@ -0,0 +1,102 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/go-mysql-org/go-mysql/mysql"
+    "github.com/go-mysql-org/go-mysql/replication"
+)
+
+// checkpointer saves where to resume after every committed transaction: the executed GTID set
+// in GTID mode, or the binlog file and position in position mode. It is safe for concurrent use,
+// so a shutdown can flush it while the event loop is still winding down.
+type checkpointer struct {
+    mu      sync.Mutex
+    mode    string
+    file    string // current binlog file, tracked from rotate events in position mode
+    pending string // latest checkpoint not yet written, if any
+}
+
+// observe updates the checkpoint from ev. It must be called after the event has been handled,
+// so a saved checkpoint never points past changes that were not yet emitted.
+func (c *checkpointer) observe(ev *replication.BinlogEvent) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    switch e := ev.Event.(type) {
+    case *replication.RotateEvent:
+        c.file = string(e.NextLogName)
+    case *replication.XIDEvent:
+        if c.mode == modeGTID {
+            if e.GSet == nil {
+                return nil
+            }
+            c.pending = e.GSet.String()
+        } else {
+            if c.file == "" {
+                return nil
+            }
+            c.pending = formatPosition(mysql.Position{Name: c.file, Pos: ev.Header.LogPos})
+        }
+        return c.flushLocked()
+    }
+    return nil
+}
+
+// flush writes the latest checkpoint if an earlier write of it failed.
+func (c *checkpointer) flush() error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.flushLocked()
+}
+
+// flushLocked writes the pending checkpoint; the caller must hold c.mu.
+func (c *checkpointer) flushLocked() error {
+    if c.pending == "" {
+        return nil
+    }
+    path := gtidCheckpointFile
+    if c.mode == modePosition {
+        path = positionCheckpointFile
+    }
+    if err := writeCheckpoint(path, c.pending); err != nil {
+        return err
+    }
+    c.pending = ""
+    return nil
+}
+
+// writeCheckpoint replaces path with value, via a rename so a crash never leaves a partial file.
+func writeCheckpoint(path, value string) error {
+    tmp := path + ".tmp"
+    if err := os.WriteFile(tmp, []byte(value), 0o644); err != nil {
+        return fmt.Errorf("writeCheckpoint: %w", err)
+    }
+    if err := os.Rename(tmp, path); err != nil {
+        return fmt.Errorf("writeCheckpoint: %w", err)
+    }
+    return nil
+}
+
+// formatPosition renders a binlog position as "file:pos".
+func formatPosition(pos mysql.Position) string {
+    return fmt.Sprintf("%s:%d", pos.Name, pos.Pos)
+}
+
+// parsePosition parses a "file:pos" binlog position.
+func parsePosition(s string) (mysql.Position, error) {
+    s = strings.TrimSpace(s)
+    i := strings.LastIndex(s, ":")
+    if i <= 0 {
+        return mysql.Position{}, fmt.Errorf("parsePosition: want file:pos, got %q", s)
+    }
+    pos, err := strconv.ParseUint(s[i+1:], 10, 32)
+    if err != nil {
+        return mysql.Position{}, fmt.Errorf("parsePosition: invalid position in %q: %w", s, err)
+    }
+    return mysql.Position{Name: s[:i], Pos: uint32(pos)}, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/go-mysql-org/go-mysql/replication"
+
+    "sql-golang-playground/internal/binlog"
+)
+
+// stopTimeout bounds how long a shutdown waits for the in-flight event to finish.
+const stopTimeout = 10 * time.Second
+
+// consumer runs the event loop in the background: each event is handed to the transaction
+// buffer and then to the checkpointer. Stop shuts it down without losing or replaying the
+// last committed transaction.
+type consumer struct {
+    syncer     *replication.BinlogSyncer
+    streamer   *replication.BinlogStreamer
+    buffer     *binlog.TransactionBuffer
+    checkpoint *checkpointer
+
+    cancel context.CancelFunc
+    done   chan struct{}
+    err    error // why the loop stopped; set before done is closed
+}
+
+// newConsumer creates a consumer over an already started streamer.
+func newConsumer(syncer *replication.BinlogSyncer, streamer *replication.BinlogStreamer, buffer *binlog.TransactionBuffer, checkpoint *checkpointer) *consumer {
+    return &consumer{
+        syncer:     syncer,
+        streamer:   streamer,
+        buffer:     buffer,
+        checkpoint: checkpoint,
+        done:       make(chan struct{}),
+    }
+}
+
+// Start launches the event loop.
+func (c *consumer) Start() {
+    ctx, cancel := context.WithCancel(context.Background())
+    c.cancel = cancel
+    go c.run(ctx)
+}
+
+// Done is closed when the event loop exits, whether through Stop or a failure.
+func (c *consumer) Done() <-chan struct{} {
+    return c.done
+}
+
+// run processes events until ctx is cancelled or an event fails. Cancellation is only observed
+// between events, so an event that has started is always handled and checkpointed.
+func (c *consumer) run(ctx context.Context) {
+    defer close(c.done)
+    for {
+        ev, err := c.streamer.GetEvent(ctx)
+        if err != nil {
+            if !errors.Is(err, context.Canceled) {
+                c.err = fmt.Errorf("fetching event: %w", err)
+            }
+            return
+        }
+        if err := c.buffer.HandleEvent(ev); err != nil {
+            c.err = fmt.Errorf("handling event: %w", err)
+            return
+        }
+        if err := c.checkpoint.observe(ev); err != nil {
+            c.err = fmt.Errorf("saving checkpoint: %w", err)
+            return
+        }
+    }
+}
+
+// Stop signals the event loop, waits up to timeout for the in-flight event to complete, saves
+// the final checkpoint and closes the syncer. The returned error is nil only if shutdown was
+// clean: the loop exited in time without failing and the checkpoint was written.
+func (c *consumer) Stop(timeout time.Duration) error {
+    c.cancel()
+
+    var errs []error
+    select {
+    case <-c.done:
+        if c.err != nil {
+            errs = append(errs, c.err)
+        }
+    case <-time.After(timeout):
+        errs = append(errs, fmt.Errorf("event loop did not finish within %v", timeout))
+    }
+    if err := c.checkpoint.flush(); err != nil {
+        errs = append(errs, fmt.Errorf("flushing checkpoint: %w", err))
+    }
+    c.syncer.Close()
+    return errors.Join(errs...)
+}
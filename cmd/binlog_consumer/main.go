@@ -7,12 +7,28 @@ import (
     "log"
     "os"
     "os/signal"
+    "strings"
     "syscall"
+    "time"
 
     _ "github.com/go-sql-driver/mysql"
     "github.com/go-mysql-org/go-mysql/mysql"
     "github.com/go-mysql-org/go-mysql/replication"
     "github.com/joho/godotenv"
+
+    "sql-golang-playground/internal/binlog"
+)
+
+// Checkpoint files recording where to resume, one per sync mode.
+const (
+    gtidCheckpointFile     = "last_gtid.txt"
+    positionCheckpointFile = "last_position.txt"
+)
+
+// Sync modes selected with BINLOG_MODE.
+const (
+    modeGTID     = "gtid"
+    modePosition = "position"
 )
 
 func main() {
@@ -24,8 +40,12 @@ func main() {
     if pwd == "" {
         log.Fatal("MYSQL_REPLICATOR_PASSWORD not set")
     }
+    mode := strings.ToLower(strings.TrimSpace(os.Getenv("BINLOG_MODE")))
+    if mode == "" {
+        mode = modeGTID
+    }
 
-    // 2. Build syncer config (same as before)
+    // 2. Build syncer config, shared by both modes
     cfg := replication.BinlogSyncerConfig{
         ServerID: 101,
         Flavor:   "mysql",
@@ -36,57 +56,142 @@ func main() {
     }
     syncer := replication.NewBinlogSyncer(cfg)
 
-    // 3. Retrieve last GTID set from your checkpoint store
-    //    Here we cheat by reading it from a file; you can replace with DB or KV.
-    lastGtid, err := os.ReadFile("last_gtid.txt")
-    if err != nil {
-        log.Printf("No saved GTID found, starting from current master position")
-        // fallback: fetch current executed GTID_SET from MySQL
-        lastGtid, err = fetchMasterGTID(pwd)
+    // 3. Resume from the saved checkpoint for the selected mode, falling back to the
+    //    master's current position when there is none.
+    var streamer *replication.BinlogStreamer
+    checkpoint := &checkpointer{mode: mode}
+    switch mode {
+    case modeGTID:
+        lastGtid, err := os.ReadFile(gtidCheckpointFile)
         if err != nil {
-            log.Fatalf("Failed to get master GTID: %v", err)
+            log.Printf("No saved GTID found, starting from current master position")
+            // fallback: fetch current executed GTID_SET from MySQL
+            lastGtid, err = fetchMasterGTID(pwd, defaultMasterQueryOptions)
+            if err != nil {
+                log.Fatalf("Failed to get master GTID: %v", err)
+            }
         }
+        gtidSet, err := mysql.ParseGTIDSet("mysql", strings.TrimSpace(string(lastGtid)))
+        if err != nil {
+            log.Fatalf("Invalid GTID format: %v", err)
+        }
+        log.Printf("Resuming replication at GTID set: %s", gtidSet.String())
+        streamer, err = syncer.StartSyncGTID(gtidSet)
+        if err != nil {
+            log.Fatalf("Failed to start GTID sync: %v", err)
+        }
+    case modePosition:
+        lastPos, err := os.ReadFile(positionCheckpointFile)
+        if err != nil {
+            log.Printf("No saved binlog position found, starting from current master position")
+            lastPos, err = fetchMasterPosition(pwd, defaultMasterQueryOptions)
+            if err != nil {
+                log.Fatalf("Failed to get master position: %v", err)
+            }
+        }
+        pos, err := parsePosition(string(lastPos))
+        if err != nil {
+            log.Fatalf("Invalid binlog position: %v", err)
+        }
+        log.Printf("Resuming replication at binlog position: %s", pos)
+        checkpoint.file = pos.Name
+        streamer, err = syncer.StartSync(pos)
+        if err != nil {
+            log.Fatalf("Failed to start position sync: %v", err)
+        }
+    default:
+        log.Fatalf("Unknown BINLOG_MODE %q (want %q or %q)", mode, modeGTID, modePosition)
     }
-    gtidSet, err := mysql.ParseGTIDSet("mysql", string(lastGtid))
-    if err != nil {
-        log.Fatalf("Invalid GTID format: %v", err)
-    }
-    log.Printf("Resuming replication at GTID set: %s", gtidSet.String())
+    log.Printf("Binlog streamer started in %s mode...", mode)
 
-    // 4. Start GTID sync
-    streamer, err := syncer.StartSyncGTID(gtidSet)
-    if err != nil {
-        log.Fatalf("Failed to start GTID sync: %v", err)
-    }
-    log.Println("GTID-based binlog streamer started...")
+    // 4. Event loop: row changes are buffered per transaction and only emitted on commit,
+    //    after which the checkpoint is advanced.
+    c := newConsumer(syncer, streamer, binlog.NewTransactionBuffer(printChanges), checkpoint)
+    c.Start()
 
-    // 5. Graceful shutdown setup
-    ctx, cancel := context.WithCancel(context.Background())
+    // 5. Graceful shutdown: let the in-flight event finish and flush the checkpoint before exiting.
     sigCh := make(chan os.Signal, 1)
     signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-    go func() {
-        <-sigCh
+    select {
+    case <-sigCh:
         log.Println("Shutdown signal received")
-        syncer.Close()
-        cancel()
-    }()
+        if err := c.Stop(stopTimeout); err != nil {
+            log.Fatalf("Unclean shutdown: %v", err)
+        }
+        log.Println("Shutdown complete")
+    case <-c.Done():
+        log.Fatalf("Event loop stopped: %v", c.Stop(stopTimeout))
+    }
+}
 
-    // 6. Event loop
-    for {
-        ev, err := streamer.GetEvent(ctx)
+// printChanges writes the row changes of a committed transaction to stdout. Account and
+// transaction rows are printed with their decoded balances and amounts when the binlog
+// carries column names; other rows are printed as they are.
+func printChanges(changes []binlog.RowChange) error {
+    fmt.Printf("=== Committed transaction (%d row event(s)) ===\n", len(changes))
+    for _, change := range changes {
+        fmt.Printf("%s %s.%s\n", change.Action, change.Schema, change.Table)
+        decoded, err := binlog.Decode(change)
         if err != nil {
-            if err == context.Canceled {
-                return
-            }
-            log.Fatalf("Error fetching event: %v", err)
+            return err
+        }
+        for _, c := range decoded {
+            printChange(c)
+        }
+    }
+    return nil
+}
+
+// printChange writes one decoded row change to stdout.
+func printChange(c binlog.Change) {
+    switch c := c.(type) {
+    case binlog.AccountChange:
+        switch {
+        case c.Before != nil && c.After != nil:
+            fmt.Printf("  account %d: balance %.2f -> %.2f %s, status %s -> %s\n", c.After.AccountID, c.Before.Balance, c.After.Balance, c.After.Currency, c.Before.Status, c.After.Status)
+        case c.After != nil:
+            fmt.Printf("  account %d: opened with balance %.2f %s\n", c.After.AccountID, c.After.Balance, c.After.Currency)
+        case c.Before != nil:
+            fmt.Printf("  account %d: removed with balance %.2f %s\n", c.Before.AccountID, c.Before.Balance, c.Before.Currency)
+        }
+    case binlog.TransactionChange:
+        tx := c.After
+        if tx == nil {
+            tx = c.Before
+        }
+        fmt.Printf("  transaction %d: %s %.2f from %s to %s at %s (%s)\n", tx.TransactionID, tx.TransactionType, tx.Amount,
+            accountLabel(tx.FromAccountID), accountLabel(tx.ToAccountID), tx.TransactionTs.Format(time.RFC3339), tx.Status)
+    case binlog.GenericChange:
+        if c.Before != nil {
+            fmt.Printf("  before: %v\n", c.Before)
+        }
+        if c.After != nil {
+            fmt.Printf("  after:  %v\n", c.After)
         }
-        ev.Dump(os.Stdout)
     }
 }
 
-// fetchMasterGTID connects to MySQL and reads @@global.gtid_executed
-func fetchMasterGTID(password string) ([]byte, error) {
-    // Use "repl" user and "127.0.0.1" host for fetching GTID, consistent with binlog syncer config
+// accountLabel renders a nullable account reference for printChange.
+func accountLabel(id sql.NullInt64) string {
+    if !id.Valid {
+        return "external"
+    }
+    return fmt.Sprintf("account %d", id.Int64)
+}
+
+// masterQueryOptions controls how the startup queries against the master are retried.
+type masterQueryOptions struct {
+    Attempts int           // total tries, at least 1
+    Timeout  time.Duration // per-attempt timeout
+    Backoff  time.Duration // wait before the first retry; doubles after each failure
+}
+
+// defaultMasterQueryOptions rides out a brief DB hiccup at startup without hanging forever.
+var defaultMasterQueryOptions = masterQueryOptions{Attempts: 3, Timeout: 5 * time.Second, Backoff: time.Second}
+
+// queryMaster opens a connection as the replication user and runs query, retrying per opts.
+func queryMaster(password string, opts masterQueryOptions, query func(ctx context.Context, db *sql.DB) ([]byte, error)) ([]byte, error) {
+    // Use "repl" user and "localhost" host, consistent with binlog syncer config
     dsn := fmt.Sprintf("repl:%s@tcp(localhost:3306)/", password)
     db, err := sql.Open("mysql", dsn)
     if err != nil {
@@ -94,11 +199,85 @@ func fetchMasterGTID(password string) ([]byte, error) {
     }
     defer db.Close()
 
-    var gtid string
-    // @@global.gtid_executed shows all GTIDs the master has executed
-    err = db.QueryRow("SELECT @@global.gtid_executed").Scan(&gtid)
+    return retryMasterQuery(opts, func(ctx context.Context) ([]byte, error) {
+        return query(ctx, db)
+    })
+}
+
+// fetchMasterGTID reads @@global.gtid_executed from the master.
+func fetchMasterGTID(password string, opts masterQueryOptions) ([]byte, error) {
+    gtid, err := queryMaster(password, opts, func(ctx context.Context, db *sql.DB) ([]byte, error) {
+        var gtid string
+        // @@global.gtid_executed shows all GTIDs the master has executed
+        if err := db.QueryRowContext(ctx, "SELECT @@global.gtid_executed").Scan(&gtid); err != nil {
+            return nil, err
+        }
+        return []byte(gtid), nil
+    })
     if err != nil {
-        return nil, err
+        return nil, fmt.Errorf("fetchMasterGTID: %w", err)
     }
-    return []byte(gtid), nil
-}
\ No newline at end of file
+    return gtid, nil
+}
+
+// fetchMasterPosition reads the master's current binlog file and position, formatted as
+// in the position checkpoint file.
+func fetchMasterPosition(password string, opts masterQueryOptions) ([]byte, error) {
+    pos, err := queryMaster(password, opts, func(ctx context.Context, db *sql.DB) ([]byte, error) {
+        rows, err := db.QueryContext(ctx, "SHOW MASTER STATUS")
+        if err != nil {
+            return nil, err
+        }
+        defer rows.Close()
+        if !rows.Next() {
+            if err := rows.Err(); err != nil {
+                return nil, err
+            }
+            return nil, fmt.Errorf("SHOW MASTER STATUS returned no rows; is binary logging enabled?")
+        }
+        // The column count varies by server version; only File and Position are needed.
+        cols, err := rows.Columns()
+        if err != nil {
+            return nil, err
+        }
+        var file string
+        var position uint32
+        dest := make([]interface{}, len(cols))
+        dest[0], dest[1] = &file, &position
+        for i := 2; i < len(dest); i++ {
+            dest[i] = new(sql.RawBytes)
+        }
+        if err := rows.Scan(dest...); err != nil {
+            return nil, err
+        }
+        return []byte(formatPosition(mysql.Position{Name: file, Pos: position})), nil
+    })
+    if err != nil {
+        return nil, fmt.Errorf("fetchMasterPosition: %w", err)
+    }
+    return pos, nil
+}
+
+// retryMasterQuery runs fetch until it succeeds or opts.Attempts is exhausted, giving each
+// attempt its own timeout.
+func retryMasterQuery(opts masterQueryOptions, fetch func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+    if opts.Attempts < 1 {
+        opts.Attempts = 1
+    }
+    var err error
+    for i := 0; i < opts.Attempts; i++ {
+        if i > 0 {
+            backoff := opts.Backoff << (i - 1)
+            log.Printf("Query against master failed (attempt %d of %d), retrying in %v: %v", i, opts.Attempts, backoff, err)
+            time.Sleep(backoff)
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+        var out []byte
+        out, err = fetch(ctx)
+        cancel()
+        if err == nil {
+            return out, nil
+        }
+    }
+    return nil, fmt.Errorf("giving up after %d attempts: %w", opts.Attempts, err)
+}
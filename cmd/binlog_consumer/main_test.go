@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryMasterQuery(t *testing.T) {
+	hiccup := errors.New("connection refused")
+	opts := masterQueryOptions{Attempts: 3, Timeout: time.Second, Backoff: time.Millisecond}
+
+	t.Run("fails once then succeeds", func(t *testing.T) {
+		calls := 0
+		got, err := retryMasterQuery(opts, func(ctx context.Context) ([]byte, error) {
+			calls++
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("attempt has no deadline")
+			}
+			if calls == 1 {
+				return nil, hiccup
+			}
+			return []byte("uuid:1-42"), nil
+		})
+		if err != nil || string(got) != "uuid:1-42" {
+			t.Fatalf("retryMasterQuery = %q, %v; want uuid:1-42, nil", got, err)
+		}
+		if calls != 2 {
+			t.Errorf("fetch called %d times, want 2", calls)
+		}
+	})
+
+	t.Run("gives up after every attempt fails", func(t *testing.T) {
+		calls := 0
+		_, err := retryMasterQuery(opts, func(context.Context) ([]byte, error) {
+			calls++
+			return nil, hiccup
+		})
+		if !errors.Is(err, hiccup) || !strings.Contains(err.Error(), "after 3 attempts") {
+			t.Errorf("retryMasterQuery error = %v, want %v after 3 attempts", err, hiccup)
+		}
+		if calls != 3 {
+			t.Errorf("fetch called %d times, want 3", calls)
+		}
+	})
+
+	t.Run("times out each attempt", func(t *testing.T) {
+		calls := 0
+		start := time.Now()
+		_, err := retryMasterQuery(masterQueryOptions{Attempts: 2, Timeout: 10 * time.Millisecond}, func(ctx context.Context) ([]byte, error) {
+			calls++
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		if !errors.Is(err, context.DeadlineExceeded) || calls != 2 {
+			t.Errorf("retryMasterQuery = %d calls, %v; want 2 calls ending in %v", calls, err, context.DeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("two hung attempts took %v, want about twice the 10ms timeout", elapsed)
+		}
+	})
+
+	t.Run("at least one attempt", func(t *testing.T) {
+		calls := 0
+		_, err := retryMasterQuery(masterQueryOptions{Timeout: time.Second}, func(context.Context) ([]byte, error) {
+			calls++
+			return nil, hiccup
+		})
+		if !errors.Is(err, hiccup) || calls != 1 {
+			t.Errorf("retryMasterQuery with no attempts = %d calls, %v; want 1 call", calls, err)
+		}
+	})
+}
@@ -1,3 +1,6 @@
+//go:build ignore
+
+// Run with: go run cmd/binlog_consumer/test.go
 package main
 
 import (
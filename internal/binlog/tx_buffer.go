@@ -0,0 +1,110 @@
+package binlog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// RowChange is a single rows event captured from the binlog. Columns names the row values
+// when the server logs full row metadata (binlog_row_metadata=FULL) and is nil otherwise; in
+// that case ENUM values are also given as strings rather than their indexes. See Decode.
+type RowChange struct {
+	Schema  string
+	Table   string
+	Action  string          // insert, update or delete
+	Columns []string        // column names, in row order
+	Rows    [][]interface{} // for updates, rows alternate before/after images
+}
+
+// TransactionBuffer groups row changes by transaction and hands them to a flush
+// callback only once the transaction commits, so downstream never sees changes
+// from a transaction that was rolled back.
+type TransactionBuffer struct {
+	pending []RowChange
+	flush   func([]RowChange) error
+}
+
+// NewTransactionBuffer creates a buffer that calls flush with the changes of each committed transaction.
+func NewTransactionBuffer(flush func([]RowChange) error) *TransactionBuffer {
+	return &TransactionBuffer{flush: flush}
+}
+
+// Begin starts a new transaction, discarding anything left over from an unterminated one.
+func (b *TransactionBuffer) Begin() {
+	b.pending = b.pending[:0]
+}
+
+// Add buffers a row change for the current transaction.
+func (b *TransactionBuffer) Add(change RowChange) {
+	b.pending = append(b.pending, change)
+}
+
+// Commit flushes the buffered changes and starts a fresh buffer.
+// Transactions without row changes are not flushed.
+func (b *TransactionBuffer) Commit() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	changes := make([]RowChange, len(b.pending))
+	copy(changes, b.pending)
+	b.pending = b.pending[:0]
+	if err := b.flush(changes); err != nil {
+		return fmt.Errorf("TransactionBuffer: flush failed: %w", err)
+	}
+	return nil
+}
+
+// Rollback discards the buffered changes.
+func (b *TransactionBuffer) Rollback() {
+	b.pending = b.pending[:0]
+}
+
+// Pending returns the number of row changes buffered for the current transaction.
+func (b *TransactionBuffer) Pending() int {
+	return len(b.pending)
+}
+
+// HandleEvent feeds a binlog event into the buffer. BEGIN starts a transaction,
+// rows events are buffered, XID and COMMIT flush, and ROLLBACK discards.
+// Other events are ignored.
+func (b *TransactionBuffer) HandleEvent(ev *replication.BinlogEvent) error {
+	switch e := ev.Event.(type) {
+	case *replication.QueryEvent:
+		switch strings.ToUpper(strings.TrimSpace(string(e.Query))) {
+		case "BEGIN":
+			b.Begin()
+		case "COMMIT":
+			return b.Commit()
+		case "ROLLBACK":
+			b.Rollback()
+		}
+	case *replication.RowsEvent:
+		change := RowChange{Action: e.Type().String(), Rows: e.Rows}
+		if e.Table != nil {
+			change.Schema = string(e.Table.Schema)
+			change.Table = string(e.Table.Table)
+			change.Columns = e.Table.ColumnNameString()
+			resolveEnums(e.Table, e.Rows)
+		}
+		b.Add(change)
+	case *replication.XIDEvent:
+		return b.Commit()
+	}
+	return nil
+}
+
+// resolveEnums replaces ENUM indexes in rows with their values, when the table map carries them.
+func resolveEnums(table *replication.TableMapEvent, rows [][]interface{}) {
+	for column, values := range table.EnumStrValueMap() {
+		for _, row := range rows {
+			if column >= len(row) {
+				continue
+			}
+			if index, ok := row[column].(int64); ok && index >= 1 && int(index) <= len(values) {
+				row[column] = values[index-1]
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package binlog
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// recorder collects the transactions a TransactionBuffer flushes, as lists of table names.
+type recorder struct {
+	flushed [][]string
+	err     error // returned by flush
+}
+
+func (r *recorder) flush(changes []RowChange) error {
+	var tables []string
+	for _, c := range changes {
+		tables = append(tables, c.Table)
+	}
+	r.flushed = append(r.flushed, tables)
+	return r.err
+}
+
+func query(q string) *replication.BinlogEvent {
+	return &replication.BinlogEvent{Event: &replication.QueryEvent{Query: []byte(q)}}
+}
+
+func rows(table string) *replication.BinlogEvent {
+	return &replication.BinlogEvent{Event: &replication.RowsEvent{
+		Table: &replication.TableMapEvent{Schema: []byte("bank"), Table: []byte(table)},
+		Rows:  [][]interface{}{{int64(1)}},
+	}}
+}
+
+func xid() *replication.BinlogEvent {
+	return &replication.BinlogEvent{Event: &replication.XIDEvent{}}
+}
+
+func TestTransactionBufferFlushesOnlyCommitted(t *testing.T) {
+	rec := &recorder{}
+	buf := NewTransactionBuffer(rec.flush)
+	events := []*replication.BinlogEvent{
+		// Committed with an XID event, as InnoDB transactions are.
+		query("BEGIN"), rows("accounts"), rows("transactions"), xid(),
+		// Rolled back: nothing may reach downstream.
+		query("BEGIN"), rows("accounts"), query("ROLLBACK"),
+		// Committed with a COMMIT query, as non-transactional engines are.
+		query("begin"), rows("outbox_events"), query(" COMMIT "),
+		// A transaction with no row changes is not flushed.
+		query("BEGIN"), xid(),
+		// Left unterminated; the next BEGIN discards it.
+		query("BEGIN"), rows("accounts"),
+		query("BEGIN"), rows("transactions"), xid(),
+	}
+	for i, ev := range events {
+		if err := buf.HandleEvent(ev); err != nil {
+			t.Fatalf("event %d: HandleEvent: %v", i, err)
+		}
+	}
+
+	want := [][]string{{"accounts", "transactions"}, {"outbox_events"}, {"transactions"}}
+	if !reflect.DeepEqual(rec.flushed, want) {
+		t.Errorf("flushed %v, want %v", rec.flushed, want)
+	}
+	if n := buf.Pending(); n != 0 {
+		t.Errorf("Pending = %d after the last commit, want 0", n)
+	}
+}
+
+func TestTransactionBufferFlushError(t *testing.T) {
+	rec := &recorder{err: errors.New("sink unavailable")}
+	buf := NewTransactionBuffer(rec.flush)
+	buf.Begin()
+	buf.Add(RowChange{Table: "accounts"})
+	if err := buf.Commit(); !errors.Is(err, rec.err) {
+		t.Fatalf("Commit error = %v, want %v", err, rec.err)
+	}
+	// The failed transaction is not flushed again with the next one.
+	buf.Begin()
+	buf.Add(RowChange{Table: "transactions"})
+	rec.err = nil
+	if err := buf.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got := rec.flushed[len(rec.flushed)-1]; len(got) != 1 || got[0] != "transactions" {
+		t.Errorf("second flush = %v, want only transactions", got)
+	}
+}
+
+func TestTransactionBufferFlushesCopies(t *testing.T) {
+	var flushed []RowChange
+	buf := NewTransactionBuffer(func(changes []RowChange) error {
+		flushed = changes
+		return nil
+	})
+	buf.Begin()
+	buf.Add(RowChange{Table: "accounts"})
+	if err := buf.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	// Reusing the buffer must not overwrite changes already handed downstream.
+	buf.Begin()
+	buf.Add(RowChange{Table: "transactions"})
+	if flushed[0].Table != "accounts" {
+		t.Errorf("flushed change became %q after the buffer was reused", flushed[0].Table)
+	}
+}
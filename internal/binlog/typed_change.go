@@ -0,0 +1,314 @@
+package binlog
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-golang-playground/models"
+)
+
+// Change is one decoded row change: an AccountChange, a TransactionChange or a GenericChange.
+type Change interface {
+	change()
+}
+
+// AccountChange is a row change to the accounts table. Before is nil for inserts and After is
+// nil for deletes. Columns missing from the row image are left at their zero values.
+type AccountChange struct {
+	Action string
+	Before *models.Account
+	After  *models.Account
+}
+
+// TransactionChange is a row change to the transactions table, shaped like AccountChange.
+type TransactionChange struct {
+	Action string
+	Before *models.Transaction
+	After  *models.Transaction
+}
+
+// GenericChange is a row change to any other table, or to accounts or transactions when the
+// binlog carries no column names. Rows are keyed by column name, or by "@1", "@2", ... as in
+// mysqlbinlog output when names are unavailable.
+type GenericChange struct {
+	Schema string
+	Table  string
+	Action string
+	Before map[string]interface{}
+	After  map[string]interface{}
+}
+
+func (AccountChange) change()     {}
+func (TransactionChange) change() {}
+func (GenericChange) change()     {}
+
+// Decode turns a row change into one Change per affected row. Rows of the accounts and
+// transactions tables become AccountChange and TransactionChange values with parsed amounts and
+// timestamps; that needs column names, which the binlog only carries when the server runs with
+// binlog_row_metadata=FULL. Everything else becomes a GenericChange.
+func Decode(rc RowChange) ([]Change, error) {
+	var changes []Change
+	err := eachImage(rc, func(before, after []interface{}) error {
+		c, err := decodeRow(rc, before, after)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Decode: %s.%s: %w", rc.Schema, rc.Table, err)
+	}
+	return changes, nil
+}
+
+// eachImage calls fn with the before and after image of each row; updates pair consecutive
+// rows, inserts have no before image and deletes no after image.
+func eachImage(rc RowChange, fn func(before, after []interface{}) error) error {
+	switch rc.Action {
+	case "update":
+		if len(rc.Rows)%2 != 0 {
+			return fmt.Errorf("update event has an odd number of row images (%d)", len(rc.Rows))
+		}
+		for i := 0; i < len(rc.Rows); i += 2 {
+			if err := fn(rc.Rows[i], rc.Rows[i+1]); err != nil {
+				return err
+			}
+		}
+	case "delete":
+		for _, row := range rc.Rows {
+			if err := fn(row, nil); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, row := range rc.Rows {
+			if err := fn(nil, row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeRow decodes one row's images according to the table they belong to.
+func decodeRow(rc RowChange, before, after []interface{}) (Change, error) {
+	beforeMap, afterMap := rc.named(before), rc.named(after)
+	if len(rc.Columns) > 0 {
+		switch rc.Table {
+		case "accounts":
+			c := AccountChange{Action: rc.Action}
+			var err error
+			if c.Before, err = decodeAccount(beforeMap); err != nil {
+				return nil, err
+			}
+			if c.After, err = decodeAccount(afterMap); err != nil {
+				return nil, err
+			}
+			return c, nil
+		case "transactions":
+			c := TransactionChange{Action: rc.Action}
+			var err error
+			if c.Before, err = decodeTransaction(beforeMap); err != nil {
+				return nil, err
+			}
+			if c.After, err = decodeTransaction(afterMap); err != nil {
+				return nil, err
+			}
+			return c, nil
+		}
+	}
+	return GenericChange{Schema: rc.Schema, Table: rc.Table, Action: rc.Action, Before: beforeMap, After: afterMap}, nil
+}
+
+// named keys row by column name. A nil row gives a nil map.
+func (rc RowChange) named(row []interface{}) map[string]interface{} {
+	if row == nil {
+		return nil
+	}
+	m := make(map[string]interface{}, len(row))
+	for i, v := range row {
+		if i < len(rc.Columns) {
+			m[rc.Columns[i]] = v
+		} else {
+			m["@"+strconv.Itoa(i+1)] = v
+		}
+	}
+	return m
+}
+
+// decodeAccount builds an account from a row image keyed by column name; nil gives nil.
+func decodeAccount(row map[string]interface{}) (*models.Account, error) {
+	if row == nil {
+		return nil, nil
+	}
+	d := rowDecoder{row: row}
+	acc := &models.Account{
+		AccountID:      d.int64("account_id"),
+		AccountNumber:  d.string("account_number"),
+		AccountHolder:  d.string("account_holder"),
+		Balance:        d.float64("balance"),
+		Currency:       d.string("currency"),
+		MinBalance:     d.nullFloat64("min_balance"),
+		MaxBalance:     d.nullFloat64("max_balance"),
+		OverdraftLimit: d.float64("overdraft_limit"),
+		LastUpdated:    d.time("last_updated"),
+		Status:         d.string("status"),
+	}
+	if v, ok := row["metadata"]; ok && d.err == nil {
+		if err := acc.Metadata.Scan(v); err != nil {
+			d.err = fmt.Errorf("column metadata: %w", err)
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("account row: %w", d.err)
+	}
+	return acc, nil
+}
+
+// decodeTransaction builds a transaction from a row image keyed by column name; nil gives nil.
+func decodeTransaction(row map[string]interface{}) (*models.Transaction, error) {
+	if row == nil {
+		return nil, nil
+	}
+	d := rowDecoder{row: row}
+	tx := &models.Transaction{
+		TransactionID:   d.int64("transaction_id"),
+		FromAccountID:   d.nullInt64("from_account_id"),
+		ToAccountID:     d.nullInt64("to_account_id"),
+		TransactionType: d.string("transaction_type"),
+		Amount:          d.float64("amount"),
+		CreditedAmount:  d.nullFloat64("credited_amount"),
+		ExchangeRate:    d.nullFloat64("exchange_rate"),
+		TransactionTs:   d.time("transaction_ts"),
+		Description:     d.nullString("description"),
+		Notes:           d.nullString("notes"),
+		ReversalOf:      d.nullInt64("reversal_of"),
+		Status:          d.string("status"),
+		IsDeleted:       d.int64("is_deleted") != 0,
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("transaction row: %w", d.err)
+	}
+	return tx, nil
+}
+
+// binlogDatetimeLayout is how go-mysql renders DATETIME and TIMESTAMP values without ParseTime.
+const binlogDatetimeLayout = "2006-01-02 15:04:05.999999"
+
+// rowDecoder reads typed columns from a row image, keeping the first error. Missing columns
+// and NULLs decode to zero values.
+type rowDecoder struct {
+	row map[string]interface{}
+	err error
+}
+
+// text returns column as a string and whether it was present and not NULL.
+func (d *rowDecoder) text(column string) (string, bool) {
+	switch v := d.row[column].(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case fmt.Stringer: // decimal.Decimal when the syncer uses UseDecimal
+		return v.String(), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// fail records err for column unless an earlier error was recorded.
+func (d *rowDecoder) fail(column string, err error) {
+	if d.err == nil {
+		d.err = fmt.Errorf("column %s: %w", column, err)
+	}
+}
+
+// string reads a text column.
+func (d *rowDecoder) string(column string) string {
+	s, _ := d.text(column)
+	return s
+}
+
+// nullString reads a nullable text column.
+func (d *rowDecoder) nullString(column string) sql.NullString {
+	s, ok := d.text(column)
+	return sql.NullString{String: s, Valid: ok}
+}
+
+// int64 reads an integer column.
+func (d *rowDecoder) int64(column string) int64 {
+	return d.nullInt64(column).Int64
+}
+
+// nullInt64 reads a nullable integer column of any width.
+func (d *rowDecoder) nullInt64(column string) sql.NullInt64 {
+	switch v := d.row[column].(type) {
+	case nil:
+		return sql.NullInt64{}
+	case int8:
+		return sql.NullInt64{Int64: int64(v), Valid: true}
+	case int16:
+		return sql.NullInt64{Int64: int64(v), Valid: true}
+	case int32:
+		return sql.NullInt64{Int64: int64(v), Valid: true}
+	case int64:
+		return sql.NullInt64{Int64: v, Valid: true}
+	case uint64:
+		return sql.NullInt64{Int64: int64(v), Valid: true}
+	}
+	s, _ := d.text(column)
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		d.fail(column, err)
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: n, Valid: true}
+}
+
+// float64 reads a numeric column.
+func (d *rowDecoder) float64(column string) float64 {
+	return d.nullFloat64(column).Float64
+}
+
+// nullFloat64 parses DECIMAL columns, which go-mysql hands over as strings, as well as
+// FLOAT and DOUBLE.
+func (d *rowDecoder) nullFloat64(column string) sql.NullFloat64 {
+	switch v := d.row[column].(type) {
+	case nil:
+		return sql.NullFloat64{}
+	case float64:
+		return sql.NullFloat64{Float64: v, Valid: true}
+	case float32:
+		return sql.NullFloat64{Float64: float64(v), Valid: true}
+	}
+	s, _ := d.text(column)
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		d.fail(column, err)
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: f, Valid: true}
+}
+
+// time parses DATETIME and TIMESTAMP columns as UTC, matching the repositories' UTC sessions.
+func (d *rowDecoder) time(column string) time.Time {
+	switch v := d.row[column].(type) {
+	case nil:
+		return time.Time{}
+	case time.Time:
+		return v.UTC()
+	}
+	s, _ := d.text(column)
+	t, err := time.ParseInLocation(binlogDatetimeLayout, s, time.UTC)
+	if err != nil {
+		d.fail(column, err)
+		return time.Time{}
+	}
+	return t
+}
@@ -1,36 +1,176 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
-// Connect establishes a connection to the database using the DSN from environment variables.
-func Connect() *sql.DB {
-	err := godotenv.Load()
+// connMaxLifetime bounds how long a pooled connection is reused so stale connections
+// (e.g. ones closed by MySQL's wait_timeout or a proxy) are recycled.
+const connMaxLifetime = 3 * time.Minute
+
+// defaultCollation is applied when the DSN does not choose a collation.
+const defaultCollation = "utf8mb4_unicode_ci"
+
+// Connection retry defaults, overridable with DATABASE_CONNECT_ATTEMPTS and
+// DATABASE_CONNECT_MAX_WAIT. The first retry waits connectRetryBaseDelay and each further one
+// twice as long.
+const (
+	defaultConnectAttempts = 5
+	defaultConnectMaxWait  = 30 * time.Second
+	connectRetryBaseDelay  = 500 * time.Millisecond
+)
+
+// Config describes how Open reaches the database.
+type Config struct {
+	// DSN is a go-sql-driver/mysql DSN; buildDSN's defaults are added to it.
+	DSN string
+	// TLS optionally enables TLS when the DSN has no tls parameter: "true", "skip-verify",
+	// "preferred" or a registered config name.
+	TLS string
+	// ConnectAttempts is the total number of pings before giving up; values below 1 mean
+	// defaultConnectAttempts.
+	ConnectAttempts int
+	// ConnectMaxWait bounds the total time spent waiting between pings.
+	ConnectMaxWait time.Duration
+}
+
+// ConfigFromEnv reads a Config from the environment: DATABASE_DSN (required), DATABASE_TLS,
+// DATABASE_CONNECT_ATTEMPTS (the total number of pings; 1 disables retrying) and
+// DATABASE_CONNECT_MAX_WAIT (a time.ParseDuration string such as "45s"). It does not read .env
+// files; callers that want them load them first, e.g. with godotenv.Load.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		DSN:             os.Getenv("DATABASE_DSN"),
+		TLS:             os.Getenv("DATABASE_TLS"),
+		ConnectAttempts: defaultConnectAttempts,
+		ConnectMaxWait:  defaultConnectMaxWait,
+	}
+	if cfg.DSN == "" {
+		return cfg, fmt.Errorf("DATABASE_DSN environment variable not set")
+	}
+	if v := os.Getenv("DATABASE_CONNECT_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid DATABASE_CONNECT_ATTEMPTS %q: must be a positive integer", v)
+		}
+		cfg.ConnectAttempts = n
+	}
+	if v := os.Getenv("DATABASE_CONNECT_MAX_WAIT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("invalid DATABASE_CONNECT_MAX_WAIT %q: must be a non-negative duration", v)
+		}
+		cfg.ConnectMaxWait = d
+	}
+	return cfg, nil
+}
+
+// pingWithRetry pings until it succeeds, attempts pings have failed, or the next wait would
+// take the total past maxWait, backing off exponentially from connectRetryBaseDelay. The last
+// wait is shortened to fit within maxWait. It returns the last ping error.
+func pingWithRetry(pinger Pinger, attempts int, maxWait time.Duration) error {
+	var waited time.Duration
+	for i := 0; ; i++ {
+		err := pinger.PingContext(context.Background())
+		if err == nil {
+			return nil
+		}
+		if i+1 >= attempts || waited >= maxWait {
+			return fmt.Errorf("after %d attempt(s): %w", i+1, err)
+		}
+		delay := connectRetryBaseDelay << i
+		if waited+delay > maxWait {
+			delay = maxWait - waited
+		}
+		log.Printf("DB: database not reachable (attempt %d of %d), retrying in %v: %v", i+1, attempts, delay, err)
+		time.Sleep(delay)
+		waited += delay
+	}
+}
+
+// buildDSN applies the connection defaults the repositories rely on to dsn, unless the DSN
+// sets them itself: parseTime=true (so DATETIME/TIMESTAMP columns scan into time.Time),
+// loc=UTC, time_zone='+00:00' (so NOW() is stored in UTC) and defaultCollation. A non-empty tlsMode ("true", "skip-verify", "preferred" or a
+// registered config name) turns on TLS when the DSN has no tls parameter.
+func buildDSN(dsn, tlsMode string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
 	if err != nil {
-		log.Fatalf("DB: Error loading .env file: %v", err)
+		return "", fmt.Errorf("invalid DATABASE_DSN: %w", err)
 	}
+	var params url.Values
+	if i := strings.IndexByte(dsn, '?'); i >= 0 {
+		if params, err = url.ParseQuery(dsn[i+1:]); err != nil {
+			return "", fmt.Errorf("invalid DATABASE_DSN parameters: %w", err)
+		}
+	}
+
+	if !params.Has("parseTime") {
+		cfg.ParseTime = true
+	}
+	if !params.Has("loc") {
+		cfg.Loc = time.UTC
+	}
+	if !params.Has("time_zone") {
+		if cfg.Params == nil {
+			cfg.Params = make(map[string]string)
+		}
+		cfg.Params["time_zone"] = "'+00:00'"
+	}
+	if cfg.Collation == "" {
+		cfg.Collation = defaultCollation
+	}
+	if tlsMode != "" && cfg.TLSConfig == "" {
+		cfg.TLSConfig = tlsMode
+	}
+	return cfg.FormatDSN(), nil
+}
 
-	dsn := os.Getenv("DATABASE_DSN")
-	if dsn == "" {
-		log.Fatal("DB: DATABASE_DSN environment variable not set in .env file or environment.")
+// Connect opens the database configured by the environment, as read by ConfigFromEnv. It is
+// a convenience for Open(ConfigFromEnv()); code that already has a *sql.DB, such as tests or
+// callers with their own pool settings, passes it to the repositories directly instead.
+func Connect() (*sql.DB, error) {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("DB: %w", err)
+	}
+	return Open(cfg)
+}
+
+// Open opens a connection pool for cfg and checks that the database is reachable. A database
+// that is not accepting connections yet, e.g. a MySQL container still starting, is retried with
+// exponential backoff, up to cfg.ConnectAttempts pings within cfg.ConnectMaxWait.
+func Open(cfg Config) (*sql.DB, error) {
+	dsn, err := buildDSN(cfg.DSN, cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("DB: %w", err)
+	}
+	attempts := cfg.ConnectAttempts
+	if attempts < 1 {
+		attempts = defaultConnectAttempts
 	}
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		log.Fatalf("DB: Error opening database: %v", err)
+		return nil, fmt.Errorf("DB: error opening database: %w", err)
 	}
+	db.SetConnMaxLifetime(connMaxLifetime)
 
-	err = db.Ping()
+	err = pingWithRetry(db, attempts, cfg.ConnectMaxWait)
 	if err != nil {
-		log.Fatalf("DB: Error connecting to database: %v", err)
+		db.Close()
+		return nil, fmt.Errorf("DB: error connecting to database: %w", err)
 	}
 	log.Println("DB: Successfully connected to database!")
 
-	return db
+	return db, nil
 }
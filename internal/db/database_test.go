@@ -0,0 +1,36 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestBuildDSNDefaultsToUTC(t *testing.T) {
+	dsn, err := buildDSN("app:secret@tcp(db:3306)/bank", "")
+	if err != nil {
+		t.Fatalf("buildDSN: %v", err)
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("ParseDSN(%q): %v", dsn, err)
+	}
+	if !cfg.ParseTime || cfg.Loc != time.UTC || cfg.Params["time_zone"] != "'+00:00'" || cfg.Collation != defaultCollation {
+		t.Errorf("buildDSN = %q, want parseTime, loc=UTC, time_zone='+00:00' and collation %s", dsn, defaultCollation)
+	}
+}
+
+func TestBuildDSNKeepsExplicitSettings(t *testing.T) {
+	dsn, err := buildDSN("app:secret@tcp(db:3306)/bank?parseTime=false&loc=Local&time_zone=%27Europe%2FParis%27&collation=utf8mb4_bin&tls=false", "true")
+	if err != nil {
+		t.Fatalf("buildDSN: %v", err)
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("ParseDSN(%q): %v", dsn, err)
+	}
+	if cfg.ParseTime || cfg.Loc != time.Local || cfg.Params["time_zone"] != "'Europe/Paris'" || cfg.Collation != "utf8mb4_bin" || cfg.TLSConfig != "false" {
+		t.Errorf("buildDSN = %q, want the DSN's own settings kept", dsn)
+	}
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"sql-golang-playground/internal/util"
+)
+
+// Pinger is the part of *sql.DB the health monitor depends on.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// HealthMonitor periodically pings the database so dead pooled connections are
+// detected and replaced before the application's next query needs them.
+type HealthMonitor struct {
+	pinger   Pinger
+	interval time.Duration
+	timeout  time.Duration
+	logger   util.Logger
+
+	mu                  sync.RWMutex
+	healthy             bool
+	consecutiveFailures int
+	lastErr             error
+}
+
+// NewHealthMonitor creates a monitor that pings every interval. Each ping is bounded
+// by the interval so a hung connection cannot stall the monitor. interval must be positive.
+// A nil logger defaults to the standard library logger.
+func NewHealthMonitor(pinger Pinger, interval time.Duration, logger util.Logger) (*HealthMonitor, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("NewHealthMonitor: interval must be positive, got %v", interval)
+	}
+	return &HealthMonitor{
+		pinger:   pinger,
+		interval: interval,
+		timeout:  interval,
+		logger:   util.LoggerOrDefault(logger),
+		healthy:  true,
+	}, nil
+}
+
+// Start runs the monitor in a background goroutine until ctx is canceled.
+func (m *HealthMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Check pings the database once and records the result. database/sql discards the
+// broken connection on failure, so the pool reconnects on the next successful ping.
+func (m *HealthMonitor) Check(ctx context.Context) error {
+	pingCtx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	err := m.pinger.PingContext(pingCtx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.consecutiveFailures++
+		m.lastErr = err
+		if m.healthy {
+			m.logger.Error("DB health check failed, database marked unhealthy: %v", err)
+		} else {
+			m.logger.Warn("DB health check still failing (%d consecutive failures): %v", m.consecutiveFailures, err)
+		}
+		m.healthy = false
+		return err
+	}
+	if !m.healthy {
+		m.logger.Info("DB connection recovered after %d failed health check(s)", m.consecutiveFailures)
+	}
+	m.healthy = true
+	m.consecutiveFailures = 0
+	m.lastErr = nil
+	return nil
+}
+
+// Healthy reports whether the most recent health check succeeded.
+func (m *HealthMonitor) Healthy() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.healthy
+}
+
+// LastError returns the error from the most recent failed check, or nil when healthy.
+func (m *HealthMonitor) LastError() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastErr
+}
+
+// ConsecutiveFailures returns how many checks in a row have failed.
+func (m *HealthMonitor) ConsecutiveFailures() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.consecutiveFailures
+}
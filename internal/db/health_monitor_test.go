@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePinger returns the queued errors from successive pings, then nil. A ping whose queued
+// error is errHang blocks until its context is done instead.
+type fakePinger struct {
+	mu     sync.Mutex
+	errs   []error
+	pinged chan struct{}
+}
+
+var errHang = errors.New("hang")
+
+func (p *fakePinger) PingContext(ctx context.Context) error {
+	p.mu.Lock()
+	var err error
+	if len(p.errs) > 0 {
+		err, p.errs = p.errs[0], p.errs[1:]
+	}
+	p.mu.Unlock()
+	if p.pinged != nil {
+		defer func() { p.pinged <- struct{}{} }()
+	}
+	if err == errHang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return err
+}
+
+// newHealthMonitor returns a monitor with the default logger, failing the test on error.
+func newHealthMonitor(t *testing.T, pinger Pinger, interval time.Duration) *HealthMonitor {
+	t.Helper()
+	m, err := NewHealthMonitor(pinger, interval, nil)
+	if err != nil {
+		t.Fatalf("NewHealthMonitor(%v): %v", interval, err)
+	}
+	return m
+}
+
+func TestNewHealthMonitorRejectsNonPositiveInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if m, err := NewHealthMonitor(&fakePinger{}, interval, nil); err == nil {
+			t.Errorf("NewHealthMonitor(%v) = %v, want an error", interval, m)
+		}
+	}
+}
+
+func TestHealthMonitorCheck(t *testing.T) {
+	down := errors.New("connection refused")
+	pinger := &fakePinger{errs: []error{down, down}}
+	m := newHealthMonitor(t, pinger, time.Second)
+	ctx := context.Background()
+
+	if !m.Healthy() {
+		t.Fatal("a new monitor reports unhealthy")
+	}
+	for want := 1; want <= 2; want++ {
+		if err := m.Check(ctx); !errors.Is(err, down) {
+			t.Fatalf("Check error = %v, want %v", err, down)
+		}
+		if m.Healthy() || m.ConsecutiveFailures() != want || !errors.Is(m.LastError(), down) {
+			t.Fatalf("after %d failed checks: healthy %v, failures %d, last error %v", want, m.Healthy(), m.ConsecutiveFailures(), m.LastError())
+		}
+	}
+
+	if err := m.Check(ctx); err != nil {
+		t.Fatalf("Check after recovery: %v", err)
+	}
+	if !m.Healthy() || m.ConsecutiveFailures() != 0 || m.LastError() != nil {
+		t.Errorf("after recovery: healthy %v, failures %d, last error %v", m.Healthy(), m.ConsecutiveFailures(), m.LastError())
+	}
+}
+
+func TestHealthMonitorCheckTimesOut(t *testing.T) {
+	m := newHealthMonitor(t, &fakePinger{errs: []error{errHang}}, 20*time.Millisecond)
+
+	start := time.Now()
+	if err := m.Check(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Check error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Check of a hung connection returned after %v, want about the 20ms interval", elapsed)
+	}
+	if m.Healthy() {
+		t.Error("a hung connection left the monitor healthy")
+	}
+}
+
+func TestHealthMonitorStart(t *testing.T) {
+	pinger := &fakePinger{errs: []error{errors.New("connection reset")}, pinged: make(chan struct{}, 10)}
+	m := newHealthMonitor(t, pinger, 5*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.Start(ctx)
+
+	waitForPing := func() {
+		t.Helper()
+		select {
+		case <-pinger.pinged:
+		case <-time.After(5 * time.Second):
+			t.Fatal("monitor did not ping")
+		}
+	}
+	waitForPing()
+	waitForPing()
+	cancel()
+
+	// The first ping failed and the second succeeded; once a check has recorded the second,
+	// the monitor is healthy again.
+	deadline := time.Now().Add(5 * time.Second)
+	for !m.Healthy() || m.ConsecutiveFailures() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("monitor did not recover: healthy %v, failures %d", m.Healthy(), m.ConsecutiveFailures())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// AccountHistoryService defines the interface for building account audit trails.
+type AccountHistoryService interface {
+	GetAccountHistory(accountID int64, from, to time.Time) ([]models.HistoryEvent, error)
+}
+
+// accountHistoryServiceImpl implements AccountHistoryService.
+type accountHistoryServiceImpl struct {
+	accountRepo     repository.AccountRepository
+	transactionRepo repository.TransactionRepository
+	logger          util.Logger
+}
+
+// NewAccountHistoryService creates a new account history service.
+// A nil logger defaults to the standard library logger.
+func NewAccountHistoryService(accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository, logger util.Logger) AccountHistoryService {
+	return &accountHistoryServiceImpl{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		logger:          util.LoggerOrDefault(logger),
+	}
+}
+
+// GetAccountHistory returns the account's events between from and to, inclusive, oldest first.
+// Every transaction touching the account becomes a TRANSACTION event with its signed balance
+// delta. Accounts have no recorded creation time, so the account is considered opened at its
+// earliest transaction.
+func (s *accountHistoryServiceImpl) GetAccountHistory(accountID int64, from, to time.Time) ([]models.HistoryEvent, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("GetAccountHistory: range end %s is before start %s", to.Format(time.RFC3339), from.Format(time.RFC3339))
+	}
+	account, err := s.accountRepo.GetAccountByID(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("GetAccountHistory: failed to get account %d: %w", accountID, err)
+	}
+
+	transactions, err := s.transactionRepo.GetTransactionsForAccount(accountID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("GetAccountHistory: %w", err)
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		if !transactions[i].TransactionTs.Equal(transactions[j].TransactionTs) {
+			return transactions[i].TransactionTs.Before(transactions[j].TransactionTs)
+		}
+		return transactions[i].TransactionID < transactions[j].TransactionID
+	})
+
+	inRange := func(ts time.Time) bool {
+		return !ts.Before(from) && !ts.After(to)
+	}
+
+	var events []models.HistoryEvent
+	if len(transactions) > 0 && inRange(transactions[0].TransactionTs) {
+		events = append(events, models.HistoryEvent{
+			Timestamp:   transactions[0].TransactionTs,
+			Kind:        models.HistoryEventAccountOpened,
+			Description: fmt.Sprintf("Account %s opened for %s", account.AccountNumber, account.AccountHolder),
+		})
+	}
+	for _, tx := range transactions {
+		if !inRange(tx.TransactionTs) {
+			continue
+		}
+		events = append(events, models.HistoryEvent{
+			Timestamp:       tx.TransactionTs,
+			Kind:            models.HistoryEventTransaction,
+			TransactionID:   sql.NullInt64{Int64: tx.TransactionID, Valid: true},
+			TransactionType: tx.TransactionType,
+			Amount:          tx.Amount,
+			BalanceDelta:    tx.SignedAmount,
+			Description:     tx.Description.String,
+		})
+	}
+	s.logger.Debug("GetAccountHistory: %d events for account %d", len(events), accountID)
+	return events, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// BalanceObserver is told about account balance changes once the database transaction that
+// made them has committed. Changes rolled back are never reported.
+type BalanceObserver interface {
+	BalanceChanged(change models.BalanceChange) error
+}
+
+// BalanceObserverFunc adapts an ordinary function to a BalanceObserver.
+type BalanceObserverFunc func(change models.BalanceChange) error
+
+// BalanceChanged calls f(change).
+func (f BalanceObserverFunc) BalanceChanged(change models.BalanceChange) error {
+	return f(change)
+}
+
+// balanceChanges collects the balance changes made inside one database transaction.
+// A nil *balanceChanges records nothing, so services without an observer skip the extra reads.
+type balanceChanges struct {
+	changes []models.BalanceChange
+}
+
+// newBalanceChanges returns a collector when observer is set and nil otherwise.
+func newBalanceChanges(observer BalanceObserver) *balanceChanges {
+	if observer == nil {
+		return nil
+	}
+	return &balanceChanges{}
+}
+
+// adjust applies AdjustAccountBalance and, when collecting, reads the balance back. The
+// adjusted row is locked by the transaction by then, so the old and new balances are exact
+// even if other transfers changed the account since it was first read.
+func (c *balanceChanges) adjust(accountRepo repository.AccountRepository, accountID int64, change float64) error {
+	if _, err := accountRepo.AdjustAccountBalance(accountID, change); err != nil {
+		return err
+	}
+	if c == nil {
+		return nil
+	}
+	account, err := accountRepo.GetAccountByID(accountID)
+	if err != nil {
+		return fmt.Errorf("failed to read adjusted balance: %w", err)
+	}
+	c.changes = append(c.changes, models.BalanceChange{
+		AccountID:  accountID,
+		OldBalance: math.Round((account.Balance-change)*100) / 100,
+		NewBalance: account.Balance,
+	})
+	return nil
+}
+
+// setTransaction attributes every change collected so far without a transaction to transactionID.
+func (c *balanceChanges) setTransaction(transactionID int64) {
+	if c == nil {
+		return
+	}
+	for i := range c.changes {
+		if c.changes[i].TransactionID == 0 {
+			c.changes[i].TransactionID = transactionID
+		}
+	}
+}
+
+// notify delivers the collected changes to observer in the background, so a slow or failing
+// observer neither delays nor fails the operation that committed them. Errors and panics are
+// logged. Deliveries for different operations may arrive out of order.
+func (c *balanceChanges) notify(observer BalanceObserver, logger util.Logger) {
+	if c == nil || observer == nil || len(c.changes) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	changes := make([]models.BalanceChange, len(c.changes))
+	for i, change := range c.changes {
+		change.ChangedAt = now
+		changes[i] = change
+	}
+	go func() {
+		for _, change := range changes {
+			deliverBalanceChange(observer, logger, change)
+		}
+	}()
+}
+
+// deliverBalanceChange calls observer for one change, logging an error or panic.
+func deliverBalanceChange(observer BalanceObserver, logger util.Logger, change models.BalanceChange) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Balance observer panicked for account %d (transaction %d): %v", change.AccountID, change.TransactionID, r)
+		}
+	}()
+	if err := observer.BalanceChanged(change); err != nil {
+		logger.Error("Balance observer failed for account %d (transaction %d): %v", change.AccountID, change.TransactionID, err)
+	}
+}
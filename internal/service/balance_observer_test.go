@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// errorLogger sends every Error message on errs and drops the rest.
+type errorLogger struct {
+	errs chan string
+}
+
+func (l errorLogger) Debug(string, ...interface{}) {}
+func (l errorLogger) Info(string, ...interface{})  {}
+func (l errorLogger) Warn(string, ...interface{})  {}
+func (l errorLogger) Error(format string, args ...interface{}) {
+	l.errs <- fmt.Sprintf(format, args...)
+}
+
+// receiveChange waits for the next balance change on changes.
+func receiveChange(t *testing.T, changes <-chan models.BalanceChange) models.BalanceChange {
+	t.Helper()
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("observer was not notified")
+		return models.BalanceChange{}
+	}
+}
+
+func TestBalanceObserverReportsCommittedTransfer(t *testing.T) {
+	changes := make(chan models.BalanceChange, 10)
+	observer := BalanceObserverFunc(func(change models.BalanceChange) error {
+		changes <- change
+		return nil
+	})
+	svc, store, accounts := newMemoryTransactionService(t, WithBalanceObserver(observer))
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+
+	// Rejected and rolled-back transfers are never reported.
+	if err := svc.TransferFunds(alice, bob, 500, "", ""); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("TransferFunds over the balance: error = %v, want %v", err, ErrInsufficientFunds)
+	}
+	if err := svc.TransferFunds(alice, 999, 10, "", ""); !errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("TransferFunds to a missing account: error = %v, want %v", err, ErrAccountNotFound)
+	}
+
+	if err := svc.TransferFunds(alice, bob, 30, "", ""); err != nil {
+		t.Fatalf("TransferFunds: %v", err)
+	}
+	transactionID := lastTransactionID(t, store, alice)
+
+	got := map[int64]models.BalanceChange{}
+	for i := 0; i < 2; i++ {
+		change := receiveChange(t, changes)
+		if change.ChangedAt.IsZero() {
+			t.Errorf("change for account %d has no ChangedAt", change.AccountID)
+		}
+		change.ChangedAt = time.Time{}
+		got[change.AccountID] = change
+	}
+	want := map[int64]models.BalanceChange{
+		alice: {AccountID: alice, OldBalance: 100, NewBalance: 70, TransactionID: transactionID},
+		bob:   {AccountID: bob, OldBalance: 20, NewBalance: 50, TransactionID: transactionID},
+	}
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("change for account %d = %+v, want %+v", id, got[id], w)
+		}
+	}
+
+	select {
+	case change := <-changes:
+		t.Errorf("unexpected extra change %+v", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBalanceObserverFailureDoesNotAffectTransfer(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		notify func() error
+	}{
+		{"error", func() error { return errors.New("observer down") }},
+		{"panic", func() error { panic("observer bug") }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := errorLogger{errs: make(chan string, 10)}
+			observer := BalanceObserverFunc(func(models.BalanceChange) error { return tc.notify() })
+			store := repository.NewMemoryStore()
+			accounts := store.Accounts()
+			svc := NewTransactionService(store, accounts, store.Transactions(), 0, logger, WithBalanceObserver(observer))
+			alice := createAccount(t, accounts, "Alice", 100)
+			bob := createAccount(t, accounts, "Bob", 20)
+
+			if err := svc.TransferFunds(alice, bob, 30, "", ""); err != nil {
+				t.Fatalf("TransferFunds with a failing observer: %v", err)
+			}
+			assertBalance(t, accounts, alice, 70)
+			assertBalance(t, accounts, bob, 50)
+			for i := 0; i < 2; i++ {
+				select {
+				case <-logger.errs:
+				case <-time.After(5 * time.Second):
+					t.Fatal("observer failure was not logged")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"sql-golang-playground/models"
+)
+
+// defaultWebhookTimeout is used when NewWebhookBalanceObserver is given a non-positive timeout.
+const defaultWebhookTimeout = 5 * time.Second
+
+// webhookBalanceObserver posts each balance change as JSON to a URL.
+type webhookBalanceObserver struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookBalanceObserver creates a BalanceObserver that POSTs each change to url as a JSON
+// models.BalanceChange. A request that takes longer than timeout, fails, or gets a non-2xx
+// response is reported as an error; the change is not retried.
+func NewWebhookBalanceObserver(url string, timeout time.Duration) BalanceObserver {
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &webhookBalanceObserver{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// BalanceChanged posts change to the webhook URL.
+func (w *webhookBalanceObserver) BalanceChanged(change models.BalanceChange) error {
+	body, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("webhook: failed to encode balance change: %w", err)
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // drain so the connection can be reused
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: %s returned %s", w.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sql-golang-playground/models"
+)
+
+func TestWebhookBalanceObserver(t *testing.T) {
+	change := models.BalanceChange{AccountID: 7, OldBalance: 100, NewBalance: 70, TransactionID: 42, ChangedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+
+	t.Run("posts the change as JSON", func(t *testing.T) {
+		received := make(chan models.BalanceChange, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("request %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+			}
+			var got models.BalanceChange
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Errorf("decode body: %v", err)
+			}
+			received <- got
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		if err := NewWebhookBalanceObserver(server.URL, time.Second).BalanceChanged(change); err != nil {
+			t.Fatalf("BalanceChanged: %v", err)
+		}
+		if got := <-received; !got.ChangedAt.Equal(change.ChangedAt) || got.AccountID != change.AccountID ||
+			got.OldBalance != change.OldBalance || got.NewBalance != change.NewBalance || got.TransactionID != change.TransactionID {
+			t.Errorf("webhook received %+v, want %+v", got, change)
+		}
+	})
+
+	t.Run("non-2xx response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		if err := NewWebhookBalanceObserver(server.URL, time.Second).BalanceChanged(change); err == nil {
+			t.Fatal("BalanceChanged succeeded on a 503 response, want an error")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		start := time.Now()
+		if err := NewWebhookBalanceObserver(server.URL, 20*time.Millisecond).BalanceChanged(change); err == nil {
+			t.Fatal("BalanceChanged succeeded against a hung server, want an error")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("BalanceChanged returned after %v, want about the 20ms timeout", elapsed)
+		}
+	})
+}
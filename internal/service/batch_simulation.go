@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+)
+
+// Transfer is a single payment in a batch sent from one source account.
+type Transfer struct {
+	ToAccountID int64
+	Amount      float64
+}
+
+// SimulateBatchTransfer projects the ending balance of every account involved in a batch of
+// transfers from fromAccountID, without writing anything. The projection is returned even
+// when the source would end up beyond its overdraft limit; in that case the error wraps
+// ErrProjectedOverdraft and names the source account.
+func (s *transactionServiceImpl) SimulateBatchTransfer(fromAccountID int64, transfers []Transfer) (map[int64]float64, error) {
+	ids := []int64{fromAccountID}
+	seen := map[int64]bool{fromAccountID: true}
+	for i, t := range transfers {
+		if t.ToAccountID == fromAccountID {
+			return nil, fmt.Errorf("SimulateBatchTransfer: transfer %d: %w", i, ErrSameAccountTransfer)
+		}
+		if t.Amount <= 0 {
+			return nil, fmt.Errorf("SimulateBatchTransfer: transfer %d: %w (%.2f)", i, ErrInvalidTransferAmount, t.Amount)
+		}
+		if !seen[t.ToAccountID] {
+			seen[t.ToAccountID] = true
+			ids = append(ids, t.ToAccountID)
+		}
+	}
+
+	current, err := s.accountRepo.GetBalancesByIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("SimulateBatchTransfer: %w", err)
+	}
+	for _, id := range ids {
+		if _, ok := current[id]; !ok {
+			return nil, fmt.Errorf("SimulateBatchTransfer: %w (ID: %d)", ErrAccountNotFound, id)
+		}
+	}
+
+	projected := make(map[int64]float64, len(current))
+	for id, balance := range current {
+		projected[id] = balance
+	}
+	for _, t := range transfers {
+		projected[fromAccountID] -= t.Amount
+		projected[t.ToAccountID] += t.Amount
+	}
+
+	// Only the source is debited, so it is the only account that can cross its overdraft limit.
+	source, err := s.accountRepo.GetAccountByID(fromAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("SimulateBatchTransfer: %w", err)
+	}
+	if projected[fromAccountID] < -source.OverdraftLimit {
+		return projected, fmt.Errorf("SimulateBatchTransfer: %w: %v", ErrProjectedOverdraft, []int64{fromAccountID})
+	}
+	return projected, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSimulateBatchTransfer(t *testing.T) {
+	svc, _, accounts := newMemoryTransactionService(t)
+	payroll := createAccount(t, accounts, "Payroll", 1000)
+	alice := createAccount(t, accounts, "Alice", 10)
+	bob := createAccount(t, accounts, "Bob", 0)
+
+	batch := []Transfer{{alice, 300}, {bob, 250}, {alice, 50}}
+	projected, err := svc.SimulateBatchTransfer(payroll, batch)
+	if err != nil {
+		t.Fatalf("SimulateBatchTransfer: %v", err)
+	}
+	want := map[int64]float64{payroll: 400, alice: 360, bob: 250}
+	if !reflect.DeepEqual(projected, want) {
+		t.Errorf("SimulateBatchTransfer = %v, want %v", projected, want)
+	}
+	// Nothing is written.
+	assertBalance(t, accounts, payroll, 1000)
+	assertBalance(t, accounts, alice, 10)
+	assertBalance(t, accounts, bob, 0)
+}
+
+func TestSimulateBatchTransferOverdraws(t *testing.T) {
+	svc, _, accounts := newMemoryTransactionService(t)
+	payroll := createAccount(t, accounts, "Payroll", 500)
+	alice := createAccount(t, accounts, "Alice", 0)
+	bob := createAccount(t, accounts, "Bob", 0)
+
+	// Each transfer fits on its own; together they overdraw the source.
+	projected, err := svc.SimulateBatchTransfer(payroll, []Transfer{{alice, 300}, {bob, 300}})
+	if !errors.Is(err, ErrProjectedOverdraft) {
+		t.Fatalf("SimulateBatchTransfer error = %v, want %v", err, ErrProjectedOverdraft)
+	}
+	want := map[int64]float64{payroll: -100, alice: 300, bob: 300}
+	if !reflect.DeepEqual(projected, want) {
+		t.Errorf("projection with an overdraft = %v, want %v", projected, want)
+	}
+	assertBalance(t, accounts, payroll, 500)
+}
+
+func TestSimulateBatchTransferErrors(t *testing.T) {
+	svc, _, accounts := newMemoryTransactionService(t)
+	payroll := createAccount(t, accounts, "Payroll", 500)
+	alice := createAccount(t, accounts, "Alice", 0)
+
+	tests := []struct {
+		name    string
+		from    int64
+		batch   []Transfer
+		wantErr error
+	}{
+		{"pays the source", payroll, []Transfer{{alice, 10}, {payroll, 10}}, ErrSameAccountTransfer},
+		{"zero amount", payroll, []Transfer{{alice, 0}}, ErrInvalidTransferAmount},
+		{"negative amount", payroll, []Transfer{{alice, -5}}, ErrInvalidTransferAmount},
+		{"missing payee", payroll, []Transfer{{alice, 10}, {999, 10}}, ErrAccountNotFound},
+		{"missing source", 999, []Transfer{{alice, 10}}, ErrAccountNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projected, err := svc.SimulateBatchTransfer(tt.from, tt.batch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SimulateBatchTransfer error = %v, want %v", err, tt.wantErr)
+			}
+			if projected != nil {
+				t.Errorf("SimulateBatchTransfer = %v, want no projection", projected)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// FixtureService snapshots accounts and transactions to JSON and loads such a snapshot into
+// another database, for seeding demo and staging environments.
+type FixtureService interface {
+	ExportFixtures(w io.Writer) error
+	ImportFixtures(r io.Reader) error
+}
+
+// fixtureServiceImpl implements FixtureService.
+type fixtureServiceImpl struct {
+	db     *sql.DB
+	logger util.Logger
+}
+
+// NewFixtureService creates a new fixture service.
+// A nil logger defaults to the standard library logger.
+func NewFixtureService(db *sql.DB, logger util.Logger) FixtureService {
+	return &fixtureServiceImpl{db: db, logger: util.LoggerOrDefault(logger)}
+}
+
+// ExportFixtures writes every account and transaction to w as an indented JSON
+// models.Fixtures. Both are read in one database transaction, so every transaction's accounts
+// are in the snapshot.
+func (s *fixtureServiceImpl) ExportFixtures(w io.Writer) error {
+	var fixtures models.Fixtures
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		fixtureRepo := repository.NewMySQLFixtureRepository(tx)
+		var err error
+		if fixtures.Accounts, err = fixtureRepo.GetAllAccountsForFixtures(); err != nil {
+			return err
+		}
+		fixtures.Transactions, err = fixtureRepo.GetAllTransactionsForFixtures()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ExportFixtures: %w", err)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(fixtures); err != nil {
+		return fmt.Errorf("ExportFixtures: %w", err)
+	}
+	s.logger.Info("Exported %d accounts and %d transactions", len(fixtures.Accounts), len(fixtures.Transactions))
+	return nil
+}
+
+// ImportFixtures loads a snapshot written by ExportFixtures. Accounts are inserted before
+// transactions, and transactions in ID order so reversals follow what they reverse. IDs are kept
+// where they are free; rows that had to take a new ID are remapped, and the references to them
+// rewritten. Everything runs in one database transaction: a conflict, such as an account number
+// that already exists, or a transaction referring to an account missing from the snapshot, fails
+// the import and leaves the database unchanged.
+func (s *fixtureServiceImpl) ImportFixtures(r io.Reader) error {
+	var fixtures models.Fixtures
+	if err := json.NewDecoder(r).Decode(&fixtures); err != nil {
+		return fmt.Errorf("ImportFixtures: invalid fixtures: %w", err)
+	}
+
+	var remapped int
+	err := repository.WithTransaction(s.db, func(tx *sql.Tx) error {
+		fixtureRepo := repository.NewMySQLFixtureRepository(tx)
+
+		accountIDs := make(map[int64]int64, len(fixtures.Accounts))
+		for _, acc := range fixtures.Accounts {
+			id, err := fixtureRepo.InsertAccountFixture(acc)
+			if err != nil {
+				return err
+			}
+			if id != acc.AccountID {
+				remapped++
+			}
+			accountIDs[acc.AccountID] = id
+		}
+
+		transactionIDs := make(map[int64]int64, len(fixtures.Transactions))
+		for _, t := range sortedByID(fixtures.Transactions) {
+			original := t.TransactionID
+			var err error
+			if t.FromAccountID, err = remapID(accountIDs, t.FromAccountID, "account", original); err != nil {
+				return err
+			}
+			if t.ToAccountID, err = remapID(accountIDs, t.ToAccountID, "account", original); err != nil {
+				return err
+			}
+			if t.ReversalOf, err = remapID(transactionIDs, t.ReversalOf, "reversed transaction", original); err != nil {
+				return err
+			}
+			id, err := fixtureRepo.InsertTransactionFixture(t)
+			if err != nil {
+				return err
+			}
+			if id != original {
+				remapped++
+			}
+			transactionIDs[original] = id
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ImportFixtures: %w", err)
+	}
+	s.logger.Info("Imported %d accounts and %d transactions (%d with new IDs)", len(fixtures.Accounts), len(fixtures.Transactions), remapped)
+	return nil
+}
+
+// remapID rewrites a reference using ids, the new ID of every row imported so far. A reference
+// to a row missing from the snapshot is an error naming the kind of row and the transaction.
+func remapID(ids map[int64]int64, ref sql.NullInt64, kind string, transactionID int64) (sql.NullInt64, error) {
+	if !ref.Valid {
+		return ref, nil
+	}
+	id, ok := ids[ref.Int64]
+	if !ok {
+		return ref, fmt.Errorf("transaction %d refers to %s %d, which is not in the fixtures", transactionID, kind, ref.Int64)
+	}
+	return sql.NullInt64{Int64: id, Valid: true}, nil
+}
+
+// sortedByID returns a copy of transactions in ascending ID order.
+func sortedByID(transactions []models.Transaction) []models.Transaction {
+	sorted := append([]models.Transaction(nil), transactions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].TransactionID < sorted[j].TransactionID })
+	return sorted
+}
@@ -0,0 +1,222 @@
+//go:build integration
+
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"sql-golang-playground/internal/testdb"
+	"sql-golang-playground/repository"
+)
+
+func TestMain(m *testing.M) {
+	testdb.Main(m)
+}
+
+// newMySQLTransactionService returns a transaction service on a fresh migrated database, along
+// with its account and transaction repositories.
+func newMySQLTransactionService(t *testing.T, maxRetries int, opts ...TransactionServiceOption) (TransactionService, repository.AccountRepository, repository.TransactionRepository) {
+	t.Helper()
+	conn := testdb.New(t)
+	accounts := repository.NewMySQLAccountRepository(conn)
+	transactions := repository.NewMySQLTransactionRepository(conn)
+	svc := NewTransactionService(repository.NewSQLTransactor(conn), accounts, transactions, maxRetries, nil, opts...)
+	return svc, accounts, transactions
+}
+
+func TestIntegrationTransferFunds(t *testing.T) {
+	svc, accounts, transactions := newMySQLTransactionService(t, 0)
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+
+	if err := svc.TransferFunds(alice, bob, 30.25, "rent", ""); err != nil {
+		t.Fatalf("TransferFunds: %v", err)
+	}
+	assertBalance(t, accounts, alice, 69.75)
+	assertBalance(t, accounts, bob, 50.25)
+	history, err := transactions.GetTransactionsForAccount(alice, nil)
+	if err != nil || len(history) != 1 || history[0].Amount != 30.25 {
+		t.Fatalf("GetTransactionsForAccount = %+v, %v; want the 30.25 transfer", history, err)
+	}
+
+	// A failed transfer rolls back: no balance change and no transaction row.
+	if err := svc.TransferFunds(alice, bob, 1000, "", ""); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("TransferFunds error = %v, want %v", err, ErrInsufficientFunds)
+	}
+	assertBalance(t, accounts, alice, 69.75)
+	if history, _ := transactions.GetTransactionsForAccount(alice, nil); len(history) != 1 {
+		t.Errorf("failed transfer left %d transactions, want 1", len(history))
+	}
+
+	for _, id := range []int64{alice, bob} {
+		if drift, err := svc.VerifyBalance(id); err != nil || drift != 0 {
+			t.Errorf("VerifyBalance(%d) = %v, %v; want 0", id, drift, err)
+		}
+	}
+}
+
+func TestIntegrationConcurrentTransfersConserveFunds(t *testing.T) {
+	// Transfers in opposite directions lock the two accounts in opposite orders and can
+	// deadlock; the retries must absorb that without losing or creating money.
+	svc, accounts, _ := newMySQLTransactionService(t, 10)
+	alice := createAccount(t, accounts, "Alice", 1000)
+	bob := createAccount(t, accounts, "Bob", 1000)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- svc.TransferFunds(alice, bob, 10, "", "")
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- svc.TransferFunds(bob, alice, 5, "", "")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("TransferFunds: %v", err)
+		}
+	}
+	assertBalance(t, accounts, alice, 900)
+	assertBalance(t, accounts, bob, 1100)
+}
+
+func TestIntegrationTransferWithPreparedStatementsRollsBack(t *testing.T) {
+	conn := testdb.New(t)
+	opts := []repository.RepositoryOption{repository.WithPreparedStatements(), repository.WithQueryTimeout(5 * time.Second)}
+	accounts := repository.NewMySQLAccountRepository(conn, opts...)
+	defer accounts.Close()
+	transactions := repository.NewMySQLTransactionRepository(conn, opts...)
+	defer transactions.Close()
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+
+	// The revenue account does not exist, so the transfer fails after its prepared balance
+	// adjustments ran; they must have run inside the transfer's transaction to be rolled back.
+	svc := NewTransactionService(repository.NewSQLTransactor(conn), accounts, transactions, 0, nil, WithTransferFee(FlatFee(1), bob+1000))
+	if err := svc.TransferFunds(alice, bob, 30, "", ""); !errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("TransferFunds error = %v, want %v", err, ErrAccountNotFound)
+	}
+	assertBalance(t, accounts, alice, 100)
+	assertBalance(t, accounts, bob, 20)
+
+	svc = NewTransactionService(repository.NewSQLTransactor(conn), accounts, transactions, 0, nil)
+	if err := svc.TransferFunds(alice, bob, 30, "", ""); err != nil {
+		t.Fatalf("TransferFunds: %v", err)
+	}
+	assertBalance(t, accounts, alice, 70)
+	assertBalance(t, accounts, bob, 50)
+}
+
+// recordingObserver records the names of the queries it observes.
+type recordingObserver struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (o *recordingObserver) ObserveQuery(name string, _ time.Duration, _ error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.names = append(o.names, name)
+}
+
+// count returns how many observations were made under name.
+func (o *recordingObserver) count(name string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := 0
+	for _, got := range o.names {
+		if got == name {
+			n++
+		}
+	}
+	return n
+}
+
+func TestIntegrationTransferIsObserved(t *testing.T) {
+	conn := testdb.New(t)
+	observer := &recordingObserver{}
+	accounts := repository.NewMySQLAccountRepository(conn, repository.WithQueryObserver(observer))
+	transactions := repository.NewMySQLTransactionRepository(conn, repository.WithQueryObserver(observer))
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+
+	svc := NewTransactionService(repository.NewSQLTransactor(conn), accounts, transactions, 0, nil)
+	if err := svc.TransferFunds(alice, bob, 30, "", ""); err != nil {
+		t.Fatalf("TransferFunds: %v", err)
+	}
+	// One adjustment per side of the transfer, made inside its transaction.
+	if n := observer.count("AdjustAccountBalance"); n != 2 {
+		t.Errorf("observed AdjustAccountBalance %d times, want 2", n)
+	}
+	if n := observer.count("CreateTransactionWithNotes"); n != 1 {
+		t.Errorf("observed CreateTransactionWithNotes %d times, want 1", n)
+	}
+}
+
+func TestIntegrationTransferWritesOutboxEvent(t *testing.T) {
+	conn := testdb.New(t)
+	outbox := repository.NewMySQLOutboxRepository(conn)
+	accounts := repository.NewMySQLAccountRepository(conn)
+	svc := NewTransactionService(repository.NewSQLTransactor(conn), accounts, repository.NewMySQLTransactionRepository(conn), 0, nil, WithOutbox(outbox))
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+
+	if err := svc.TransferFunds(alice, bob, 30, "", ""); err != nil {
+		t.Fatalf("TransferFunds: %v", err)
+	}
+	if err := svc.TransferFunds(alice, bob, 500, "", ""); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("TransferFunds error = %v, want %v", err, ErrInsufficientFunds)
+	}
+	// The rolled-back transfer's event was never committed.
+	events, err := outbox.GetUnpublishedEvents(10)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("GetUnpublishedEvents = %d events, %v; want 1", len(events), err)
+	}
+
+	published, err := NewOutboxService(outbox, 10, nil).PublishOutbox(func([]byte) error { return nil })
+	if err != nil || published != 1 {
+		t.Fatalf("PublishOutbox = %d, %v; want 1, nil", published, err)
+	}
+	if events, err := outbox.GetUnpublishedEvents(10); err != nil || len(events) != 0 {
+		t.Errorf("GetUnpublishedEvents after publishing = %d events, %v; want none", len(events), err)
+	}
+}
+
+func TestIntegrationReconcileWhileRunLockHeld(t *testing.T) {
+	conn := testdb.New(t)
+	loader := &countingLoader{}
+	svc := NewReconciliationService(repository.NewMySQLTransactionRepository(conn), loader, nil, WithRunLock(repository.NewMySQLLocker(conn)))
+
+	// Another process's run holds the lock.
+	release, acquired, err := repository.NewMySQLLocker(conn).TryLock(reconciliationLockName)
+	if err != nil || !acquired {
+		t.Fatalf("TryLock = %v, %v; want the lock", acquired, err)
+	}
+	if _, err := svc.ReconcileTransactions("statement.csv"); !errors.Is(err, ErrReconciliationInProgress) {
+		t.Fatalf("ReconcileTransactions error = %v, want %v", err, ErrReconciliationInProgress)
+	}
+	if loader.loads != 0 {
+		t.Errorf("refused run loaded %d files, want none", loader.loads)
+	}
+
+	if err := release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, err := svc.ReconcileTransactions("statement.csv"); err != nil {
+		t.Errorf("ReconcileTransactions after the lock was released: %v", err)
+	}
+}
+
+func TestIntegrationGetProjectedBalance(t *testing.T) {
+	conn := testdb.New(t)
+	testGetProjectedBalance(t, repository.NewMySQLAccountRepository(conn), repository.NewMySQLScheduledTransferRepository(conn))
+}
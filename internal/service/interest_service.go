@@ -0,0 +1,163 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// defaultInterestBatchSize is used when NewInterestService is given a non-positive batch size.
+const defaultInterestBatchSize = 100
+
+// InterestService defines the interface for crediting interest to accounts.
+type InterestService interface {
+	AccrueInterest(rate float64, asOf time.Time) error
+}
+
+// interestServiceImpl implements InterestService.
+type interestServiceImpl struct {
+	transactor      repository.Transactor
+	accountRepo     repository.AccountRepository
+	transactionRepo repository.TransactionRepository
+	batchSize       int
+	observer        BalanceObserver // nil means balance changes are not reported
+	logger          util.Logger
+}
+
+// InterestServiceOption configures optional interest service behaviour.
+type InterestServiceOption func(*interestServiceImpl)
+
+// WithInterestObserver reports every interest credit to observer once its batch has committed.
+// The observer runs in the background; a slow or failing observer does not affect accrual.
+func WithInterestObserver(observer BalanceObserver) InterestServiceOption {
+	return func(s *interestServiceImpl) {
+		s.observer = observer
+	}
+}
+
+// NewInterestService creates a new interest service. Accounts are credited in units of work
+// of transactor of at most batchSize accounts each, with accountRepo and transactionRepo bound
+// to them through WithDBTX; use repository.NewSQLTransactor for MySQL.
+// A nil logger defaults to the standard library logger.
+func NewInterestService(transactor repository.Transactor, accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository, batchSize int, logger util.Logger, opts ...InterestServiceOption) InterestService {
+	if batchSize <= 0 {
+		batchSize = defaultInterestBatchSize
+	}
+	s := &interestServiceImpl{
+		transactor:      transactor,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		batchSize:       batchSize,
+		logger:          util.LoggerOrDefault(logger),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// interestPeriod identifies the accrual period for asOf; one credit per account per period.
+func interestPeriod(asOf time.Time) string {
+	return asOf.UTC().Format("2006-01-02")
+}
+
+// AccrueInterest credits balance*rate, rounded to cents, to every active account with a positive
+// balance and records an INTEREST transaction for each. Credits are keyed on the account and
+// the period derived from asOf, so re-running for the same period does not credit twice.
+// An account the credit would take above its max_balance, or that was closed meanwhile, is
+// skipped and logged rather than failing the run.
+func (s *interestServiceImpl) AccrueInterest(rate float64, asOf time.Time) error {
+	if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return fmt.Errorf("AccrueInterest: rate must be positive, got %v", rate)
+	}
+	period := interestPeriod(asOf)
+
+	credited := 0
+	for offset := 0; ; offset += s.batchSize {
+		accounts, _, err := s.accountRepo.GetAllAccounts(repository.AccountListOptions{Limit: s.batchSize, Offset: offset})
+		if err != nil {
+			return fmt.Errorf("AccrueInterest: failed to list accounts: %w", err)
+		}
+		if len(accounts) == 0 {
+			break
+		}
+
+		n, err := s.accrueBatch(accounts, rate, period)
+		if err != nil {
+			return fmt.Errorf("AccrueInterest: batch at offset %d: %w", offset, err)
+		}
+		credited += n
+
+		if len(accounts) < s.batchSize {
+			break
+		}
+	}
+
+	s.logger.Info("Accrued interest for period %s at rate %v on %d account(s)", period, rate, credited)
+	return nil
+}
+
+// accrueBatch credits interest to one batch of accounts inside a single unit of work and returns
+// how many accounts were newly credited.
+func (s *interestServiceImpl) accrueBatch(accounts []models.Account, rate float64, period string) (int, error) {
+	credited := 0
+	var changes *balanceChanges
+	err := s.transactor.WithTransaction(func(tx repository.DBTX) error {
+		accountRepo := s.accountRepo.WithDBTX(tx)
+		transactionRepo := s.transactionRepo.WithDBTX(tx)
+
+		credited = 0
+		changes = newBalanceChanges(s.observer)
+		for _, listed := range accounts {
+			if listed.Balance <= 0 {
+				continue
+			}
+			// Lock the account and work from its current balance, so the limit check below
+			// still holds when the credit is applied.
+			acc, err := accountRepo.GetAccountByIDForUpdate(listed.AccountID)
+			if errors.Is(err, ErrAccountNotFound) {
+				s.logger.Warn("AccrueInterest: skipping account %d: %v", listed.AccountID, err)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to lock account %d: %w", listed.AccountID, err)
+			}
+			interest := math.Round(acc.Balance*rate*100) / 100
+			if interest <= 0 {
+				continue
+			}
+			if acc.MaxBalance.Valid && acc.Balance+interest > acc.MaxBalance.Float64 {
+				s.logger.Warn("AccrueInterest: skipping account %d: interest %.2f would take balance %.2f above its maximum %.2f",
+					acc.AccountID, interest, acc.Balance, acc.MaxBalance.Float64)
+				continue
+			}
+
+			key := fmt.Sprintf("interest:%s:%d", period, acc.AccountID)
+			toID := sql.NullInt64{Int64: acc.AccountID, Valid: true}
+			description := sql.NullString{String: "Interest for period " + period, Valid: true}
+			transactionID, created, err := transactionRepo.CreateTransactionIdempotent(key, sql.NullInt64{}, toID, models.TransactionTypeInterest, interest, description, sql.NullString{})
+			if err != nil {
+				return fmt.Errorf("failed to record interest for account %d: %w", acc.AccountID, err)
+			}
+			if !created {
+				continue // Already credited for this period
+			}
+			if err := changes.adjust(accountRepo, acc.AccountID, interest); err != nil {
+				return fmt.Errorf("failed to credit interest to account %d: %w", acc.AccountID, err)
+			}
+			changes.setTransaction(transactionID)
+			credited++
+		}
+		return nil
+	})
+	if err == nil {
+		changes.notify(s.observer, s.logger)
+	}
+	return credited, err
+}
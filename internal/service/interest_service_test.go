@@ -0,0 +1,128 @@
+package service
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// countingTransactor counts the units of work run through the wrapped Transactor.
+type countingTransactor struct {
+	repository.Transactor
+	calls int
+}
+
+func (c *countingTransactor) WithTransaction(fn func(tx repository.DBTX) error) error {
+	c.calls++
+	return c.Transactor.WithTransaction(fn)
+}
+
+// newMemoryInterestService returns an interest service over a fresh memory store.
+func newMemoryInterestService(t *testing.T, batchSize int) (InterestService, *repository.MemoryStore, *countingTransactor) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	transactor := &countingTransactor{Transactor: store}
+	svc := NewInterestService(transactor, store.Accounts(), store.Transactions(), batchSize, util.LoggerOrDefault(nil))
+	return svc, store, transactor
+}
+
+// interestTransactions returns the INTEREST transactions recorded in store.
+func interestTransactions(t *testing.T, store *repository.MemoryStore) []models.Transaction {
+	t.Helper()
+	txs, _, err := store.Transactions().ListTransactions(repository.TransactionFilter{Types: []string{models.TransactionTypeInterest}})
+	if err != nil {
+		t.Fatalf("ListTransactions: %v", err)
+	}
+	return txs
+}
+
+func TestAccrueInterestIsIdempotentPerPeriod(t *testing.T) {
+	svc, store, _ := newMemoryInterestService(t, 0)
+	accounts := store.Accounts()
+	alice := createAccount(t, accounts, "Alice", 1000)
+	bob := createAccount(t, accounts, "Bob", 250.50)
+
+	asOf := time.Date(2024, 3, 31, 23, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err := svc.AccrueInterest(0.01, asOf); err != nil {
+			t.Fatalf("AccrueInterest run %d: %v", i+1, err)
+		}
+	}
+	assertBalance(t, accounts, alice, 1010)
+	assertBalance(t, accounts, bob, 253.01)
+	if got := len(interestTransactions(t, store)); got != 2 {
+		t.Fatalf("interest transactions after re-run = %d, want 2", got)
+	}
+
+	// A new period credits again, on the updated balance.
+	if err := svc.AccrueInterest(0.01, asOf.Add(24*time.Hour)); err != nil {
+		t.Fatalf("AccrueInterest next period: %v", err)
+	}
+	assertBalance(t, accounts, alice, 1020.10)
+	if got := len(interestTransactions(t, store)); got != 4 {
+		t.Fatalf("interest transactions after next period = %d, want 4", got)
+	}
+}
+
+func TestAccrueInterestBatchesAcrossPages(t *testing.T) {
+	svc, store, transactor := newMemoryInterestService(t, 2)
+	accounts := store.Accounts()
+	balances := []float64{100, 0, 200, -50, 300}
+	ids := make([]int64, len(balances))
+	for i, balance := range balances {
+		ids[i] = insertAccount(t, store, models.Account{AccountHolder: "Holder", Balance: balance})
+	}
+
+	if err := svc.AccrueInterest(0.1, time.Now()); err != nil {
+		t.Fatalf("AccrueInterest: %v", err)
+	}
+	want := []float64{110, 0, 220, -50, 330}
+	for i, id := range ids {
+		assertBalance(t, accounts, id, want[i])
+	}
+	if got := len(interestTransactions(t, store)); got != 3 {
+		t.Fatalf("interest transactions = %d, want 3 (non-positive balances earn nothing)", got)
+	}
+	if transactor.calls != 3 {
+		t.Fatalf("units of work = %d, want 3 for 5 accounts in batches of 2", transactor.calls)
+	}
+}
+
+func TestAccrueInterestSkipsAccountsRejectedByLimit(t *testing.T) {
+	svc, store, _ := newMemoryInterestService(t, 0)
+	accounts := store.Accounts()
+	alice := createAccount(t, accounts, "Alice", 100)
+	capped := insertAccount(t, store, models.Account{AccountHolder: "Capped", Balance: 1000, MaxBalance: sql.NullFloat64{Float64: 1000, Valid: true}})
+	bob := createAccount(t, accounts, "Bob", 50)
+
+	if err := svc.AccrueInterest(0.1, time.Now()); err != nil {
+		t.Fatalf("AccrueInterest: %v", err)
+	}
+	assertBalance(t, accounts, alice, 110)
+	assertBalance(t, accounts, capped, 1000)
+	assertBalance(t, accounts, bob, 55)
+	for _, tx := range interestTransactions(t, store) {
+		if tx.ToAccountID.Int64 == capped {
+			t.Fatalf("interest transaction %d recorded for the capped account", tx.TransactionID)
+		}
+	}
+}
+
+func TestAccrueInterestRejectsInvalidRate(t *testing.T) {
+	svc, store, transactor := newMemoryInterestService(t, 0)
+	alice := createAccount(t, store.Accounts(), "Alice", 100)
+
+	for _, rate := range []float64{0, -0.01} {
+		if err := svc.AccrueInterest(rate, time.Now()); err == nil {
+			t.Fatalf("AccrueInterest(%v) succeeded, want an error", rate)
+		}
+	}
+	assertBalance(t, store.Accounts(), alice, 100)
+	if transactor.calls != 0 {
+		t.Fatalf("units of work = %d, want 0", transactor.calls)
+	}
+}
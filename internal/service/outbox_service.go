@@ -0,0 +1,63 @@
+package service
+
+import (
+	"fmt"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/repository"
+)
+
+// defaultOutboxBatchSize is used when NewOutboxService is given a non-positive batch size.
+const defaultOutboxBatchSize = 100
+
+// OutboxService defines the interface for delivering outbox events to a message publisher.
+type OutboxService interface {
+	PublishOutbox(publisher func([]byte) error) (int, error)
+}
+
+// outboxServiceImpl implements OutboxService.
+type outboxServiceImpl struct {
+	outboxRepo repository.OutboxRepository
+	batchSize  int
+	logger     util.Logger
+}
+
+// NewOutboxService creates a new outbox service that publishes up to batchSize events per run.
+// A nil logger defaults to the standard library logger.
+func NewOutboxService(outboxRepo repository.OutboxRepository, batchSize int, logger util.Logger) OutboxService {
+	if batchSize <= 0 {
+		batchSize = defaultOutboxBatchSize
+	}
+	return &outboxServiceImpl{
+		outboxRepo: outboxRepo,
+		batchSize:  batchSize,
+		logger:     util.LoggerOrDefault(logger),
+	}
+}
+
+// PublishOutbox hands unpublished event payloads to publisher in insertion order and marks
+// each one as published once the publisher accepts it. It stops at the first publish failure
+// so the remaining events are retried on the next run, giving at-least-once delivery.
+// It returns the number of events published.
+func (s *outboxServiceImpl) PublishOutbox(publisher func([]byte) error) (int, error) {
+	events, err := s.outboxRepo.GetUnpublishedEvents(s.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("PublishOutbox: failed to fetch unpublished events: %w", err)
+	}
+
+	published := 0
+	for _, ev := range events {
+		if err := publisher(ev.Payload); err != nil {
+			return published, fmt.Errorf("PublishOutbox: failed to publish event %d: %w", ev.EventID, err)
+		}
+		// A failure here means the event will be published again; consumers must be idempotent.
+		if _, err := s.outboxRepo.MarkEventPublished(ev.EventID); err != nil {
+			return published, fmt.Errorf("PublishOutbox: failed to mark event %d as published: %w", ev.EventID, err)
+		}
+		published++
+	}
+	if published > 0 {
+		s.logger.Info("Published %d outbox event(s)", published)
+	}
+	return published, nil
+}
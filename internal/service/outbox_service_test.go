@@ -0,0 +1,112 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// failingOutbox is an OutboxRepository whose CreateEvent always fails.
+type failingOutbox struct {
+	repository.OutboxRepository
+}
+
+func (failingOutbox) CreateEvent(string, []byte) (int64, error) {
+	return 0, errors.New("outbox unavailable")
+}
+
+func (o failingOutbox) WithDBTX(repository.DBTX) repository.OutboxRepository { return o }
+
+func TestTransferFundsWritesOutboxEvent(t *testing.T) {
+	store := repository.NewMemoryStore()
+	accounts := store.Accounts()
+	svc := NewTransactionService(store, accounts, store.Transactions(), 0, nil, WithOutbox(store.Outbox()))
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+
+	if err := svc.TransferFunds(alice, bob, 30, "rent", "March"); err != nil {
+		t.Fatalf("TransferFunds: %v", err)
+	}
+	if err := svc.TransferFunds(alice, bob, 500, "", ""); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("TransferFunds error = %v, want %v", err, ErrInsufficientFunds)
+	}
+
+	// Only the committed transfer has an event.
+	events, err := store.Outbox().GetUnpublishedEvents(10)
+	if err != nil {
+		t.Fatalf("GetUnpublishedEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].EventType != models.OutboxEventTransferCompleted {
+		t.Fatalf("outbox = %+v, want one %s event", events, models.OutboxEventTransferCompleted)
+	}
+	var event models.TransferEvent
+	if err := json.Unmarshal(events[0].Payload, &event); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	transactionID := lastTransactionID(t, store, bob)
+	if event.TransactionID != transactionID || event.FromAccountID != alice || event.ToAccountID != bob ||
+		event.Amount != 30 || event.Description != "rent" || event.Notes != "March" || event.OccurredAt.IsZero() {
+		t.Errorf("event = %+v, want transaction %d of 30.00 from %d to %d", event, transactionID, alice, bob)
+	}
+}
+
+func TestTransferFundsRollsBackWhenOutboxWriteFails(t *testing.T) {
+	store := repository.NewMemoryStore()
+	accounts := store.Accounts()
+	svc := NewTransactionService(store, accounts, store.Transactions(), 0, nil, WithOutbox(failingOutbox{store.Outbox()}))
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+
+	// The event is written in the transfer's unit of work, so the transfer cannot commit
+	// without it.
+	if err := svc.TransferFunds(alice, bob, 30, "", ""); err == nil {
+		t.Fatal("TransferFunds succeeded without writing its outbox event")
+	}
+	assertBalance(t, accounts, alice, 100)
+	assertBalance(t, accounts, bob, 20)
+	if history, _ := store.Transactions().GetTransactionsForAccount(alice, nil); len(history) != 0 {
+		t.Errorf("failed transfer left %d transactions, want none", len(history))
+	}
+}
+
+func TestPublishOutbox(t *testing.T) {
+	store := repository.NewMemoryStore()
+	outbox := store.Outbox()
+	for _, payload := range []string{"a", "b", "c"} {
+		if _, err := outbox.CreateEvent(models.OutboxEventTransferCompleted, []byte(payload)); err != nil {
+			t.Fatalf("CreateEvent: %v", err)
+		}
+	}
+	svc := NewOutboxService(outbox, 2, nil)
+
+	var got []string
+	down := errors.New("broker down")
+	failOn := "b"
+	publish := func(payload []byte) error {
+		if string(payload) == failOn {
+			return down
+		}
+		got = append(got, string(payload))
+		return nil
+	}
+
+	// A failure stops the run; the events from it on stay unpublished.
+	if n, err := svc.PublishOutbox(publish); !errors.Is(err, down) || n != 1 {
+		t.Fatalf("PublishOutbox = %d, %v; want 1, %v", n, err, down)
+	}
+	failOn = ""
+	// The next run starts at the failed event and publishes at most the batch size.
+	if n, err := svc.PublishOutbox(publish); err != nil || n != 2 {
+		t.Fatalf("PublishOutbox = %d, %v; want 2, nil", n, err)
+	}
+	if n, err := svc.PublishOutbox(publish); err != nil || n != 0 {
+		t.Fatalf("PublishOutbox with nothing pending = %d, %v; want 0, nil", n, err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+}
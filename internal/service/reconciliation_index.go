@@ -0,0 +1,153 @@
+package service
+
+import (
+	"math"
+
+	"sql-golang-playground/models"
+)
+
+// amountKey buckets transactions by type and amount in cents. Comparing cents means the sum of
+// a split group's CSV rows is not thrown off by floating-point rounding.
+type amountKey struct {
+	txType string
+	cents  int64
+}
+
+// newAmountKey returns the bucket of an amount of type txType.
+func newAmountKey(txType string, amount float64) amountKey {
+	return amountKey{txType: txType, cents: int64(math.Round(amount * 100))}
+}
+
+// csvIndex finds unprocessed CSV rows by type and amount, or by type alone, without scanning
+// every row. Buckets hold row positions in ascending order, so lookups return the first
+// unprocessed row just as a scan in file order would. Processed rows are dropped from the front
+// of a bucket as they are met, keeping the total work linear in the number of rows.
+type csvIndex struct {
+	rows         []models.ExternalTransaction
+	processed    map[int]bool
+	byTypeAmount map[amountKey][]int
+	byType       map[string][]int
+}
+
+// newCSVIndex indexes rows; processed is shared with the caller and consulted on every lookup.
+func newCSVIndex(rows []models.ExternalTransaction, processed map[int]bool) *csvIndex {
+	ix := &csvIndex{
+		rows:         rows,
+		processed:    processed,
+		byTypeAmount: make(map[amountKey][]int),
+		byType:       make(map[string][]int),
+	}
+	for i, row := range rows {
+		key := newAmountKey(row.Type, row.Amount)
+		ix.byTypeAmount[key] = append(ix.byTypeAmount[key], i)
+		ix.byType[row.Type] = append(ix.byType[row.Type], i)
+	}
+	return ix
+}
+
+// firstExact returns the position of the first unprocessed row of type txType whose amount
+// equals amount exactly.
+func (ix *csvIndex) firstExact(txType string, amount float64) (int, bool) {
+	key := newAmountKey(txType, amount)
+	bucket := ix.trim(ix.byTypeAmount[key])
+	ix.byTypeAmount[key] = bucket
+	for _, i := range bucket {
+		if !ix.processed[i] && ix.rows[i].Amount == amount {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// firstOfType returns the position of the first unprocessed row of type txType.
+func (ix *csvIndex) firstOfType(txType string) (int, bool) {
+	bucket := ix.trim(ix.byType[txType])
+	ix.byType[txType] = bucket
+	if len(bucket) == 0 {
+		return 0, false
+	}
+	return bucket[0], true
+}
+
+// trim drops the processed positions at the front of bucket.
+func (ix *csvIndex) trim(bucket []int) []int {
+	for len(bucket) > 0 && ix.processed[bucket[0]] {
+		bucket = bucket[1:]
+	}
+	return bucket
+}
+
+// dbIndex finds unprocessed DB transactions by type and amount to the cent, and counts the
+// unprocessed transactions of each type. Buckets hold positions in ascending order and are
+// compacted as they are read, so each processed transaction is dropped at most once per bucket.
+type dbIndex struct {
+	txs          []ClassifiedTransaction
+	processed    map[int64]bool
+	byTypeAmount map[amountKey][]int
+	byType       map[string][]int
+	unprocessed  map[string]int // type -> number of unprocessed transactions
+}
+
+// newDBIndex indexes the transactions not yet in processed, which is shared with the caller.
+// Transactions must be marked processed through markProcessed so the counts stay right.
+func newDBIndex(txs []ClassifiedTransaction, processed map[int64]bool) *dbIndex {
+	ix := &dbIndex{
+		txs:          txs,
+		processed:    processed,
+		byTypeAmount: make(map[amountKey][]int),
+		byType:       make(map[string][]int),
+		unprocessed:  make(map[string]int),
+	}
+	for i, tx := range txs {
+		if processed[tx.TransactionID] {
+			continue
+		}
+		key := newAmountKey(tx.Type, tx.Amount)
+		ix.byTypeAmount[key] = append(ix.byTypeAmount[key], i)
+		ix.byType[tx.Type] = append(ix.byType[tx.Type], i)
+		ix.unprocessed[tx.Type]++
+	}
+	return ix
+}
+
+// withAmount returns the unprocessed transactions of type txType whose amount equals amount to
+// the cent, in their original order.
+func (ix *dbIndex) withAmount(txType string, amount float64) []models.Transaction {
+	key := newAmountKey(txType, amount)
+	bucket := ix.compact(ix.byTypeAmount[key])
+	ix.byTypeAmount[key] = bucket
+	txs := make([]models.Transaction, len(bucket))
+	for j, i := range bucket {
+		txs[j] = ix.txs[i].Transaction
+	}
+	return txs
+}
+
+// onlyOfType returns the single unprocessed transaction of type txType, if there is exactly one.
+func (ix *dbIndex) onlyOfType(txType string) (models.Transaction, bool) {
+	if ix.unprocessed[txType] != 1 {
+		return models.Transaction{}, false
+	}
+	bucket := ix.compact(ix.byType[txType])
+	ix.byType[txType] = bucket
+	return ix.txs[bucket[0]].Transaction, true
+}
+
+// markProcessed records the transaction with transactionID, of type txType, as consumed.
+func (ix *dbIndex) markProcessed(transactionID int64, txType string) {
+	if !ix.processed[transactionID] {
+		ix.processed[transactionID] = true
+		ix.unprocessed[txType]--
+	}
+}
+
+// compact removes the processed positions from bucket, reusing its storage.
+func (ix *dbIndex) compact(bucket []int) []int {
+	kept := bucket[:0]
+	for _, i := range bucket {
+		if !ix.processed[ix.txs[i].TransactionID] {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
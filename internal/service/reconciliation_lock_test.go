@@ -0,0 +1,121 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// fakeLocker simulates a run lock: TryLock fails with err, reports the lock taken while held,
+// and otherwise takes it until the release function runs, which fails with releaseErr.
+type fakeLocker struct {
+	held       bool
+	err        error
+	releaseErr error
+	names      []string
+	releases   int
+}
+
+func (l *fakeLocker) TryLock(name string) (func() error, bool, error) {
+	l.names = append(l.names, name)
+	if l.err != nil {
+		return nil, false, l.err
+	}
+	if l.held {
+		return nil, false, nil
+	}
+	l.held = true
+	return func() error {
+		l.held = false
+		l.releases++
+		return l.releaseErr
+	}, true, nil
+}
+
+// countingLoader returns no external transactions and counts the files it was asked to load.
+type countingLoader struct {
+	util.DataLoader
+	loads int
+}
+
+func (l *countingLoader) LoadExternalTransactions(string) ([]models.ExternalTransaction, error) {
+	l.loads++
+	return nil, nil
+}
+
+// lockProbeLoader runs load while loading a file, i.e. while its run holds the run lock.
+type lockProbeLoader struct {
+	util.DataLoader
+	load func()
+}
+
+func (l *lockProbeLoader) LoadExternalTransactions(string) ([]models.ExternalTransaction, error) {
+	l.load()
+	return nil, nil
+}
+
+func TestReconcileWithRunLock(t *testing.T) {
+	lockDown := errors.New("lock server unreachable")
+	releaseDown := errors.New("connection lost")
+	for _, tc := range []struct {
+		name         string
+		locker       *fakeLocker
+		wantErr      error
+		wantLoads    int
+		wantReleases int
+	}{
+		{"lock free", &fakeLocker{}, nil, 1, 1},
+		{"lock held by another run", &fakeLocker{held: true}, ErrReconciliationInProgress, 0, 0},
+		{"lock unavailable", &fakeLocker{err: lockDown}, lockDown, 0, 0},
+		{"release fails", &fakeLocker{releaseErr: releaseDown}, releaseDown, 1, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			loader := &countingLoader{}
+			s := NewReconciliationService(repository.NewMemoryStore().Transactions(), loader, nil, WithRunLock(tc.locker))
+
+			report, err := s.ReconcileTransactions("statement.csv")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ReconcileTransactions error = %v, want %v", err, tc.wantErr)
+			}
+			if (report == nil) != (tc.wantErr != nil) {
+				t.Errorf("ReconcileTransactions report = %v with error %v", report, err)
+			}
+			if loader.loads != tc.wantLoads {
+				t.Errorf("loaded %d files, want %d", loader.loads, tc.wantLoads)
+			}
+			if tc.locker.releases != tc.wantReleases {
+				t.Errorf("released the lock %d times, want %d", tc.locker.releases, tc.wantReleases)
+			}
+			if len(tc.locker.names) != 1 || tc.locker.names[0] != reconciliationLockName {
+				t.Errorf("TryLock names = %q, want [%q]", tc.locker.names, reconciliationLockName)
+			}
+		})
+	}
+}
+
+func TestReconcileRunLockHeldDuringRun(t *testing.T) {
+	locker := &fakeLocker{}
+	loader := &countingLoader{}
+	s := NewReconciliationService(repository.NewMemoryStore().Transactions(), loader, nil, WithRunLock(locker))
+
+	// The outer run holds the lock while it loads its file, so a run started meanwhile finds it
+	// taken and fails before loading anything.
+	var nested error
+	probe := &lockProbeLoader{load: func() { _, nested = s.ReconcileTransactions("other.csv") }}
+	outer := NewReconciliationService(repository.NewMemoryStore().Transactions(), probe, nil, WithRunLock(locker))
+	if _, err := outer.ReconcileTransactions("statement.csv"); err != nil {
+		t.Fatalf("ReconcileTransactions: %v", err)
+	}
+	if !errors.Is(nested, ErrReconciliationInProgress) {
+		t.Errorf("concurrent ReconcileTransactions error = %v, want %v", nested, ErrReconciliationInProgress)
+	}
+	if loader.loads != 0 {
+		t.Errorf("concurrent run loaded %d files, want none", loader.loads)
+	}
+	if locker.held {
+		t.Error("lock still held after the run")
+	}
+}
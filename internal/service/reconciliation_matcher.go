@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+
+	"sql-golang-playground/models"
+)
+
+// ClassifiedTransaction is a DB transaction prepared for matching. Type is the label it was
+// classified as, in the vocabulary of the CSV rows' Type: a partner transaction type for
+// system-wide runs, CREDIT or DEBIT for account statements.
+type ClassifiedTransaction struct {
+	models.Transaction
+	Type string
+}
+
+// MatchResult is how a Matcher paired DB transactions with CSV rows. Every DB transaction and
+// every CSV row should appear in exactly one section.
+type MatchResult struct {
+	Matched             []ReconciliationMatch // same type and amount
+	AmountMismatches    []ReconciliationMatch // same type, different amount
+	SplitMatches        []SplitMatch          // CSV rows sharing a Reference sum to the DB amount
+	PartialSplitMatches []SplitMatch          // CSV rows sharing a Reference, only DB candidate by type, different total
+	AmbiguousSplits     []AmbiguousSplit
+	OnlyInDB            []models.Transaction
+	OnlyInCSV           []models.ExternalTransaction
+}
+
+// Matcher pairs classified DB transactions with external CSV rows. The reconciliation service
+// loads and classifies both sides, calls Match, and reports the result, so a different matching
+// strategy only needs a different Matcher; see WithMatcher. Match should check ctx periodically
+// and return ctx.Err() once it is canceled.
+type Matcher interface {
+	Match(ctx context.Context, dbTxs []ClassifiedTransaction, csvTxs []models.ExternalTransaction) (MatchResult, error)
+}
+
+// MatcherFunc adapts an ordinary function to a Matcher.
+type MatcherFunc func(ctx context.Context, dbTxs []ClassifiedTransaction, csvTxs []models.ExternalTransaction) (MatchResult, error)
+
+// Match calls f(ctx, dbTxs, csvTxs).
+func (f MatcherFunc) Match(ctx context.Context, dbTxs []ClassifiedTransaction, csvTxs []models.ExternalTransaction) (MatchResult, error) {
+	return f(ctx, dbTxs, csvTxs)
+}
+
+// DefaultMatcher is the matching strategy used unless WithMatcher sets another. It matches in
+// three passes: exact one-to-one matches on type and amount, then split matches of several CSV
+// rows sharing a Reference against one DB transaction (see matchSplits), then one-to-one pairs
+// with the same type but a different amount. Each DB transaction pairs with the first suitable
+// CSV row in file order. Candidates are found through indexes keyed by type and amount, so a
+// run takes time roughly linear in the number of rows on both sides. ctx is checked once per
+// DB transaction in each pass.
+type DefaultMatcher struct{}
+
+// Match implements Matcher.
+func (DefaultMatcher) Match(ctx context.Context, databaseTransactions []ClassifiedTransaction, csvTransactions []models.ExternalTransaction) (MatchResult, error) {
+	var result MatchResult
+
+	// Track processed items to avoid double-counting. CSV rows are tracked by position, so a
+	// row can only ever be consumed once even if ExternalIDs repeat.
+	processedDBTx := make(map[int64]bool)
+	processedCSVTx := make(map[int]bool)
+	csvIndex := newCSVIndex(csvTransactions, processedCSVTx)
+
+	// Pass 1: Type and Amount match
+	for _, dbTx := range databaseTransactions {
+		if err := ctx.Err(); err != nil {
+			return MatchResult{}, err
+		}
+		if i, ok := csvIndex.firstExact(dbTx.Type, dbTx.Amount); ok {
+			result.Matched = append(result.Matched, ReconciliationMatch{DB: dbTx.Transaction, DBType: dbTx.Type, CSV: csvTransactions[i]})
+			processedDBTx[dbTx.TransactionID] = true
+			processedCSVTx[i] = true
+		}
+	}
+
+	// Pass 2: several CSV rows for one DB transaction
+	if err := matchSplits(ctx, &result, databaseTransactions, csvTransactions, processedDBTx, processedCSVTx); err != nil {
+		return MatchResult{}, err
+	}
+
+	// Pass 3: type match with different amount
+	for _, dbTx := range databaseTransactions {
+		if processedDBTx[dbTx.TransactionID] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return MatchResult{}, err
+		}
+		// Any unprocessed row of the same type has a different amount; an equal one would
+		// have been matched in pass 1.
+		if i, ok := csvIndex.firstOfType(dbTx.Type); ok {
+			result.AmountMismatches = append(result.AmountMismatches, ReconciliationMatch{DB: dbTx.Transaction, DBType: dbTx.Type, CSV: csvTransactions[i]})
+			processedDBTx[dbTx.TransactionID] = true // Mark as processed even if mismatched, to avoid being "only in DB"
+			processedCSVTx[i] = true                 // Mark CSV as processed to avoid being "only in CSV"
+			// Note: This simple logic might misclassify if multiple CSV entries have the same type.
+			// A more robust system would use more unique identifiers or a tolerance for amounts.
+		}
+	}
+
+	for _, dbTx := range databaseTransactions {
+		if !processedDBTx[dbTx.TransactionID] {
+			result.OnlyInDB = append(result.OnlyInDB, dbTx.Transaction)
+		}
+	}
+	for i, csvTx := range csvTransactions {
+		if !processedCSVTx[i] {
+			result.OnlyInCSV = append(result.OnlyInCSV, csvTx)
+		}
+	}
+	return result, nil
+}
+
+// classifyTransactions labels each DB transaction with classify for matching.
+func classifyTransactions(transactions []models.Transaction, classify func(models.Transaction) string) []ClassifiedTransaction {
+	classified := make([]ClassifiedTransaction, len(transactions))
+	for i, tx := range transactions {
+		classified[i] = ClassifiedTransaction{Transaction: tx, Type: classify(tx)}
+	}
+	return classified
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"sql-golang-playground/models"
+)
+
+// nestedLoopMatch is DefaultMatcher as it was before matching was indexed: each pass scans the
+// CSV rows for every DB transaction. It is kept as the reference DefaultMatcher must agree with
+// and as the baseline of BenchmarkNestedLoopMatcher.
+func nestedLoopMatch(ctx context.Context, databaseTransactions []ClassifiedTransaction, csvTransactions []models.ExternalTransaction) (MatchResult, error) {
+	var result MatchResult
+	processedDBTx := make(map[int64]bool)
+	processedCSVTx := make(map[int]bool)
+
+	for _, dbTx := range databaseTransactions {
+		if err := ctx.Err(); err != nil {
+			return MatchResult{}, err
+		}
+		for i, csvTx := range csvTransactions {
+			if !processedCSVTx[i] && dbTx.Type == csvTx.Type && dbTx.Amount == csvTx.Amount {
+				result.Matched = append(result.Matched, ReconciliationMatch{DB: dbTx.Transaction, DBType: dbTx.Type, CSV: csvTx})
+				processedDBTx[dbTx.TransactionID] = true
+				processedCSVTx[i] = true
+				break
+			}
+		}
+	}
+
+	for _, g := range groupByReference(csvTransactions, processedCSVTx) {
+		if err := ctx.Err(); err != nil {
+			return MatchResult{}, err
+		}
+		txType := g.primaryType()
+		var sameType, sameTotal []models.Transaction
+		for _, dbTx := range databaseTransactions {
+			if processedDBTx[dbTx.TransactionID] || dbTx.Type != txType {
+				continue
+			}
+			sameType = append(sameType, dbTx.Transaction)
+			if math.Round(dbTx.Amount*100) == math.Round(g.total*100) {
+				sameTotal = append(sameTotal, dbTx.Transaction)
+			}
+		}
+		switch {
+		case len(sameTotal) == 1:
+			result.SplitMatches = append(result.SplitMatches, SplitMatch{DB: sameTotal[0], DBType: txType, CSV: g.rows, CSVTotal: g.total})
+			processedDBTx[sameTotal[0].TransactionID] = true
+		case len(sameTotal) > 1:
+			result.AmbiguousSplits = append(result.AmbiguousSplits, AmbiguousSplit{Reference: g.reference, CSV: g.rows, CSVTotal: g.total, Candidates: sameTotal})
+		case len(sameType) == 1:
+			result.PartialSplitMatches = append(result.PartialSplitMatches, SplitMatch{DB: sameType[0], DBType: txType, CSV: g.rows, CSVTotal: g.total})
+			processedDBTx[sameType[0].TransactionID] = true
+		default:
+			continue
+		}
+		for _, i := range g.indexes {
+			processedCSVTx[i] = true
+		}
+	}
+
+	for _, dbTx := range databaseTransactions {
+		if processedDBTx[dbTx.TransactionID] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return MatchResult{}, err
+		}
+		for i, csvTx := range csvTransactions {
+			if !processedCSVTx[i] && dbTx.Type == csvTx.Type {
+				result.AmountMismatches = append(result.AmountMismatches, ReconciliationMatch{DB: dbTx.Transaction, DBType: dbTx.Type, CSV: csvTx})
+				processedDBTx[dbTx.TransactionID] = true
+				processedCSVTx[i] = true
+				break
+			}
+		}
+	}
+
+	for _, dbTx := range databaseTransactions {
+		if !processedDBTx[dbTx.TransactionID] {
+			result.OnlyInDB = append(result.OnlyInDB, dbTx.Transaction)
+		}
+	}
+	for i, csvTx := range csvTransactions {
+		if !processedCSVTx[i] {
+			result.OnlyInCSV = append(result.OnlyInCSV, csvTx)
+		}
+	}
+	return result, nil
+}
+
+var matcherTestTypes = []string{"DEPOSIT", "WITHDRAWAL", "TRANSFER_OUT", "TRANSFER_IN"}
+
+// randomMatcherInput returns nDB DB transactions and about nCSV CSV rows drawn from amounts
+// distinct amounts and references distinct references, so small pools give many duplicate
+// amounts and shared references. About a third of the CSV rows copy a DB transaction, and
+// some DB transactions are split into a larger row and a fee row sharing a reference.
+func randomMatcherInput(rng *rand.Rand, nDB, nCSV, amounts, references int) ([]ClassifiedTransaction, []models.ExternalTransaction) {
+	amount := func() float64 { return float64(rng.Intn(amounts)+1) / 4 }
+	txType := func() string { return matcherTestTypes[rng.Intn(len(matcherTestTypes))] }
+
+	dbTxs := make([]ClassifiedTransaction, nDB)
+	for i := range dbTxs {
+		dbTxs[i] = ClassifiedTransaction{
+			Transaction: models.Transaction{TransactionID: int64(i + 1), Amount: amount()},
+			Type:        txType(),
+		}
+	}
+
+	csvTxs := make([]models.ExternalTransaction, 0, nCSV+1)
+	for len(csvTxs) < nCSV {
+		row := models.ExternalTransaction{ExternalID: fmt.Sprintf("ext-%d", len(csvTxs)), Amount: amount(), Type: txType()}
+		if rng.Intn(4) == 0 {
+			row.Reference = fmt.Sprintf("ref-%d", rng.Intn(references))
+		}
+		switch n := rng.Intn(6); {
+		case n < 2 && nDB > 0:
+			dbTx := dbTxs[rng.Intn(nDB)]
+			row.Amount, row.Type = dbTx.Amount, dbTx.Type
+		case n == 2 && nDB > 0:
+			dbTx := dbTxs[rng.Intn(nDB)]
+			fee := math.Min(0.25, dbTx.Amount/2)
+			row.Amount, row.Type, row.Reference = dbTx.Amount-fee, dbTx.Type, fmt.Sprintf("split-%d", len(csvTxs))
+			csvTxs = append(csvTxs, models.ExternalTransaction{ExternalID: row.ExternalID + "-fee", Amount: fee, Type: "FEE", Reference: row.Reference})
+		}
+		csvTxs = append(csvTxs, row)
+	}
+	return dbTxs, csvTxs
+}
+
+func TestDefaultMatcherMatchesNestedLoop(t *testing.T) {
+	ctx := context.Background()
+	for seed := int64(0); seed < 500; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		dbTxs, csvTxs := randomMatcherInput(rng, rng.Intn(40), rng.Intn(40), 1+rng.Intn(20), 1+rng.Intn(5))
+
+		want, err := nestedLoopMatch(ctx, dbTxs, csvTxs)
+		if err != nil {
+			t.Fatalf("seed %d: nestedLoopMatch: %v", seed, err)
+		}
+		got, err := DefaultMatcher{}.Match(ctx, dbTxs, csvTxs)
+		if err != nil {
+			t.Fatalf("seed %d: Match: %v", seed, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("seed %d: DefaultMatcher and the nested loops disagree\ngot:  %+v\nwant: %+v", seed, got, want)
+		}
+	}
+}
+
+func TestDefaultMatcherCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dbTxs, csvTxs := randomMatcherInput(rand.New(rand.NewSource(1)), 10, 10, 10, 2)
+	if _, err := (DefaultMatcher{}).Match(ctx, dbTxs, csvTxs); err != context.Canceled {
+		t.Errorf("Match error = %v, want %v", err, context.Canceled)
+	}
+}
+
+// benchmarkMatcher runs match on n DB transactions and n CSV rows of mostly distinct amounts.
+func benchmarkMatcher(b *testing.B, n int, match MatcherFunc) {
+	dbTxs, csvTxs := randomMatcherInput(rand.New(rand.NewSource(1)), n, n, 100*n, n)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := match(ctx, dbTxs, csvTxs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDefaultMatcher(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("%dx%d", n, n), func(b *testing.B) {
+			benchmarkMatcher(b, n, DefaultMatcher{}.Match)
+		})
+	}
+}
+
+// BenchmarkNestedLoopMatcher is the baseline for BenchmarkDefaultMatcher. It stops at 10k rows
+// a side: the scans are quadratic, so 100k x 100k takes minutes per iteration.
+func BenchmarkNestedLoopMatcher(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("%dx%d", n, n), func(b *testing.B) {
+			benchmarkMatcher(b, n, nestedLoopMatch)
+		})
+	}
+}
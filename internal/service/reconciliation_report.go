@@ -0,0 +1,231 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// Report sections, used as the Section column in CSV output and as JSON keys.
+const (
+	SectionMatched        = "MATCHED"
+	SectionAmountMismatch = "AMOUNT_MISMATCH"
+	SectionOnlyInDB       = "ONLY_IN_DB"
+	SectionOnlyInCSV      = "ONLY_IN_CSV"
+	SectionSplitMatch     = "SPLIT_MATCH"
+	SectionPartialSplit   = "PARTIAL_SPLIT_MATCH"
+	SectionAmbiguousSplit = "AMBIGUOUS_SPLIT"
+	SectionUnreadableDB   = "UNREADABLE_DB_ROW"
+)
+
+// reportCSVHeader is the CSV layout of a report. Finance loads these files into spreadsheets,
+// so columns may be appended but never renamed or reordered.
+var reportCSVHeader = []string{"Section", "DBTransactionID", "DBType", "DBAmount", "ExternalID", "CSVType", "CSVAmount", "Reference", "Description"}
+
+// ReconciliationMatch pairs a DB transaction with the CSV row it was matched to.
+// DBType is the label the DB transaction was classified as for matching.
+type ReconciliationMatch struct {
+	DB     models.Transaction
+	DBType string
+	CSV    models.ExternalTransaction
+}
+
+// SplitMatch pairs one DB transaction with several CSV rows sharing a Reference.
+// CSVTotal is the sum of the rows' amounts.
+type SplitMatch struct {
+	DB       models.Transaction
+	DBType   string
+	CSV      []models.ExternalTransaction
+	CSVTotal float64
+}
+
+// AmbiguousSplit is a group of CSV rows sharing a Reference whose total matches more than one
+// DB transaction. None of the candidates is consumed; they are reconciled on their own.
+type AmbiguousSplit struct {
+	Reference  string
+	CSV        []models.ExternalTransaction
+	CSVTotal   float64
+	Candidates []models.Transaction
+}
+
+// FileError records a CSV file that could not be loaded during a multi-file run.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+// ReconciliationReport is the outcome of a reconciliation run: the Matcher's result, a summary
+// of both sides, and the inputs that could not take part in matching.
+type ReconciliationReport struct {
+	MatchResult
+	Summary          ReconciliationSummary
+	FileErrors       []FileError
+	UnreadableDBRows []repository.RowError // DB rows skipped with WithSkipBadDBRows; not matched
+}
+
+// WriteCSV writes the report as one CSV table with a header row. Each row carries its section
+// in the first column; fields that do not apply to a section are left empty. The summary has
+// no place in that layout and is left out.
+func (r *ReconciliationReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(reportCSVHeader); err != nil {
+		return fmt.Errorf("WriteCSV: failed to write header: %w", err)
+	}
+	amount := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	var records [][]string
+	for _, section := range []struct {
+		name    string
+		matches []ReconciliationMatch
+	}{{SectionMatched, r.Matched}, {SectionAmountMismatch, r.AmountMismatches}} {
+		for _, m := range section.matches {
+			records = append(records, []string{section.name, strconv.FormatInt(m.DB.TransactionID, 10), m.DBType, amount(m.DB.Amount),
+				m.CSV.ExternalID, m.CSV.Type, amount(m.CSV.Amount), m.CSV.Reference, m.DB.Description.String})
+		}
+	}
+	for _, section := range []struct {
+		name    string
+		matches []SplitMatch
+	}{{SectionSplitMatch, r.SplitMatches}, {SectionPartialSplit, r.PartialSplitMatches}} {
+		for _, m := range section.matches {
+			for _, csvTx := range m.CSV {
+				records = append(records, []string{section.name, strconv.FormatInt(m.DB.TransactionID, 10), m.DBType, amount(m.DB.Amount),
+					csvTx.ExternalID, csvTx.Type, amount(csvTx.Amount), csvTx.Reference, m.DB.Description.String})
+			}
+		}
+	}
+	// An ambiguous group is written as its CSV rows followed by its DB candidates, tied
+	// together by the group's Reference.
+	for _, g := range r.AmbiguousSplits {
+		for _, csvTx := range g.CSV {
+			records = append(records, []string{SectionAmbiguousSplit, "", "", "",
+				csvTx.ExternalID, csvTx.Type, amount(csvTx.Amount), csvTx.Reference, ""})
+		}
+		for _, dbTx := range g.Candidates {
+			records = append(records, []string{SectionAmbiguousSplit, strconv.FormatInt(dbTx.TransactionID, 10), dbTx.TransactionType, amount(dbTx.Amount),
+				"", "", "", g.Reference, dbTx.Description.String})
+		}
+	}
+	for _, dbTx := range r.OnlyInDB {
+		records = append(records, []string{SectionOnlyInDB, strconv.FormatInt(dbTx.TransactionID, 10), dbTx.TransactionType, amount(dbTx.Amount),
+			"", "", "", "", dbTx.Description.String})
+	}
+	for _, csvTx := range r.OnlyInCSV {
+		records = append(records, []string{SectionOnlyInCSV, "", "", "",
+			csvTx.ExternalID, csvTx.Type, amount(csvTx.Amount), csvTx.Reference, ""})
+	}
+	// The Description column carries the scan error of an unreadable row.
+	for _, bad := range r.UnreadableDBRows {
+		records = append(records, []string{SectionUnreadableDB, strconv.FormatInt(bad.TransactionID, 10), "", "",
+			"", "", "", "", bad.Err.Error()})
+	}
+	if err := writer.WriteAll(records); err != nil {
+		return fmt.Errorf("WriteCSV: %w", err)
+	}
+	return nil
+}
+
+// reportJSONEntry is the JSON shape of one reconciled DB transaction and/or CSV row.
+type reportJSONEntry struct {
+	DBTransactionID *int64   `json:"db_transaction_id,omitempty"`
+	DBType          string   `json:"db_type,omitempty"`
+	DBAmount        *float64 `json:"db_amount,omitempty"`
+	ExternalID      string   `json:"external_id,omitempty"`
+	CSVType         string   `json:"csv_type,omitempty"`
+	CSVAmount       *float64 `json:"csv_amount,omitempty"`
+	Reference       string   `json:"reference,omitempty"`
+	Description     string   `json:"description,omitempty"`
+}
+
+// dbEntry fills the DB side of an entry.
+func (e *reportJSONEntry) dbEntry(tx models.Transaction, txType string) {
+	id, amount := tx.TransactionID, tx.Amount
+	e.DBTransactionID, e.DBType, e.DBAmount, e.Description = &id, txType, &amount, tx.Description.String
+}
+
+// csvEntry fills the CSV side of an entry.
+func (e *reportJSONEntry) csvEntry(tx models.ExternalTransaction) {
+	amount := tx.Amount
+	e.ExternalID, e.CSVType, e.CSVAmount, e.Reference = tx.ExternalID, tx.Type, &amount, tx.Reference
+}
+
+// WriteJSON writes the report as a JSON object keyed by section. Every section is present,
+// as an empty array when it has no entries, the summary is under "summary", file errors are
+// listed under "file_errors" and unreadable DB rows under "unreadable_db_rows".
+func (r *ReconciliationReport) WriteJSON(w io.Writer) error {
+	out := map[string][]reportJSONEntry{
+		SectionMatched:        {},
+		SectionAmountMismatch: {},
+		SectionOnlyInDB:       {},
+		SectionOnlyInCSV:      {},
+		SectionSplitMatch:     {},
+		SectionPartialSplit:   {},
+		SectionAmbiguousSplit: {},
+	}
+	for name, matches := range map[string][]ReconciliationMatch{SectionMatched: r.Matched, SectionAmountMismatch: r.AmountMismatches} {
+		for _, m := range matches {
+			var e reportJSONEntry
+			e.dbEntry(m.DB, m.DBType)
+			e.csvEntry(m.CSV)
+			out[name] = append(out[name], e)
+		}
+	}
+	for name, matches := range map[string][]SplitMatch{SectionSplitMatch: r.SplitMatches, SectionPartialSplit: r.PartialSplitMatches} {
+		for _, m := range matches {
+			for _, csvTx := range m.CSV {
+				var e reportJSONEntry
+				e.dbEntry(m.DB, m.DBType)
+				e.csvEntry(csvTx)
+				out[name] = append(out[name], e)
+			}
+		}
+	}
+	for _, g := range r.AmbiguousSplits {
+		for _, csvTx := range g.CSV {
+			var e reportJSONEntry
+			e.csvEntry(csvTx)
+			out[SectionAmbiguousSplit] = append(out[SectionAmbiguousSplit], e)
+		}
+		for _, dbTx := range g.Candidates {
+			e := reportJSONEntry{Reference: g.Reference}
+			e.dbEntry(dbTx, dbTx.TransactionType)
+			out[SectionAmbiguousSplit] = append(out[SectionAmbiguousSplit], e)
+		}
+	}
+	for _, dbTx := range r.OnlyInDB {
+		var e reportJSONEntry
+		e.dbEntry(dbTx, dbTx.TransactionType)
+		out[SectionOnlyInDB] = append(out[SectionOnlyInDB], e)
+	}
+	for _, csvTx := range r.OnlyInCSV {
+		var e reportJSONEntry
+		e.csvEntry(csvTx)
+		out[SectionOnlyInCSV] = append(out[SectionOnlyInCSV], e)
+	}
+
+	fileErrors := make([]map[string]string, 0, len(r.FileErrors))
+	for _, fe := range r.FileErrors {
+		fileErrors = append(fileErrors, map[string]string{"path": fe.Path, "error": fe.Err.Error()})
+	}
+
+	unreadable := make([]map[string]interface{}, 0, len(r.UnreadableDBRows))
+	for _, bad := range r.UnreadableDBRows {
+		unreadable = append(unreadable, map[string]interface{}{"db_transaction_id": bad.TransactionID, "error": bad.Err.Error()})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(struct {
+		Summary          ReconciliationSummary        `json:"summary"`
+		Sections         map[string][]reportJSONEntry `json:"sections"`
+		FileErrors       []map[string]string          `json:"file_errors"`
+		UnreadableDBRows []map[string]interface{}     `json:"unreadable_db_rows"`
+	}{r.Summary, out, fileErrors, unreadable}); err != nil {
+		return fmt.Errorf("WriteJSON: %w", err)
+	}
+	return nil
+}
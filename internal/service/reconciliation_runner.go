@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// ReconciliationRunner reconciles a fixed input location on a schedule and stores a summary of
+// every run in reconciliation_runs, so match rates can be tracked over time.
+type ReconciliationRunner struct {
+	reconciler ReconciliationService
+	runRepo    repository.ReconciliationRunRepository
+	input      string
+	interval   time.Duration
+	logger     util.Logger
+}
+
+// NewReconciliationRunner creates a runner that reconciles input, a directory or glob as
+// accepted by ReconcileDirectory, every interval. interval must be positive.
+// A nil logger defaults to the standard library logger.
+func NewReconciliationRunner(reconciler ReconciliationService, runRepo repository.ReconciliationRunRepository, input string, interval time.Duration, logger util.Logger) (*ReconciliationRunner, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("NewReconciliationRunner: interval must be positive, got %v", interval)
+	}
+	return &ReconciliationRunner{
+		reconciler: reconciler,
+		runRepo:    runRepo,
+		input:      input,
+		interval:   interval,
+		logger:     util.LoggerOrDefault(logger),
+	}, nil
+}
+
+// Start runs a reconciliation immediately and then every interval, in a background goroutine,
+// until ctx is canceled. Canceling ctx also stops a run in progress. Failures are logged; the
+// schedule carries on.
+func (r *ReconciliationRunner) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := r.runOnce(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Error("Scheduled reconciliation of %s failed: %v", r.input, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce reconciles the input and stores the run's summary, including failed runs with their
+// error. A run skipped because another reconciliation holds the run lock is not stored and
+// returns ErrReconciliationInProgress.
+func (r *ReconciliationRunner) RunOnce() (models.ReconciliationRun, error) {
+	return r.runOnce(context.Background())
+}
+
+// runOnce is RunOnce under ctx. A run stopped because ctx was canceled is not stored either.
+func (r *ReconciliationRunner) runOnce(ctx context.Context) (models.ReconciliationRun, error) {
+	run := models.ReconciliationRun{Input: r.input, StartedAt: time.Now().UTC()}
+	report, reconcileErr := r.reconciler.WithContext(ctx).ReconcileDirectory(r.input)
+	run.FinishedAt = time.Now().UTC()
+	if errors.Is(reconcileErr, ErrReconciliationInProgress) {
+		r.logger.Info("Skipping scheduled reconciliation of %s: %v", r.input, reconcileErr)
+		return run, reconcileErr
+	}
+	if reconcileErr != nil && ctx.Err() != nil {
+		r.logger.Info("Reconciliation of %s canceled: %v", r.input, reconcileErr)
+		return run, reconcileErr
+	}
+	if reconcileErr != nil {
+		run.Error = sql.NullString{String: reconcileErr.Error(), Valid: true}
+	} else {
+		summarizeReport(&run, report)
+	}
+
+	id, err := r.runRepo.CreateReconciliationRun(run)
+	if err != nil {
+		return run, errors.Join(reconcileErr, fmt.Errorf("RunOnce: failed to store run summary: %w", err))
+	}
+	run.RunID = id
+	if reconcileErr != nil {
+		return run, reconcileErr
+	}
+	r.logger.Info("Reconciliation run %d of %s: %d matched, %d split, %d mismatched, %d only in DB, %d only in CSV (match rate %.1f%%)",
+		id, r.input, run.Matched, run.SplitMatches, run.AmountMismatches, run.OnlyInDB, run.OnlyInCSV, run.MatchRate()*100)
+	return run, nil
+}
+
+// summarizeReport copies the size of each report section into run.
+func summarizeReport(run *models.ReconciliationRun, report *ReconciliationReport) {
+	run.Matched = len(report.Matched)
+	run.AmountMismatches = len(report.AmountMismatches)
+	run.SplitMatches = len(report.SplitMatches)
+	run.PartialSplitMatches = len(report.PartialSplitMatches)
+	run.AmbiguousSplits = len(report.AmbiguousSplits)
+	run.OnlyInDB = len(report.OnlyInDB)
+	run.OnlyInCSV = len(report.OnlyInCSV)
+	run.FileErrors = len(report.FileErrors)
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewReconciliationRunnerRejectsNonPositiveInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		if r, err := NewReconciliationRunner(nil, nil, "testdata", interval, nil); err == nil {
+			t.Errorf("NewReconciliationRunner(%v) = %v, want an error", interval, r)
+		}
+	}
+	if _, err := NewReconciliationRunner(nil, nil, "testdata", time.Minute, nil); err != nil {
+		t.Errorf("NewReconciliationRunner(1m): %v", err)
+	}
+}
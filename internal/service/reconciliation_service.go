@@ -1,32 +1,122 @@
 package service
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
-	"sql-golang-playground/repository"
 	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// ErrReconciliationInProgress is returned when another reconciliation run holds the run lock.
+var ErrReconciliationInProgress = errors.New("reconciliation already in progress")
+
+// reconciliationLockName is the advisory lock that serializes reconciliation runs.
+const reconciliationLockName = "sql_golang_playground.reconciliation"
+
+// Directions used by account-centric reconciliation.
+const (
+	directionCredit = "CREDIT"
+	directionDebit  = "DEBIT"
 )
 
 // ReconciliationService defines the interface for reconciliation business logic.
 type ReconciliationService interface {
-	ReconcileTransactions(csvFilePath string)
+	ReconcileTransactions(csvFilePath string) (*ReconciliationReport, error)
+	ReconcileAccountStatement(accountID int64, csvFilePath string) (*ReconciliationReport, error)
+	ReconcileDirectory(pattern string) (*ReconciliationReport, error)
+	ExportOnlyInDB(w io.Writer, report *ReconciliationReport) error
+	WithContext(ctx context.Context) ReconciliationService
 }
 
 // reconciliationServiceImpl implements ReconciliationService.
 type reconciliationServiceImpl struct {
 	transactionRepo repository.TransactionRepository
 	dataLoader      util.DataLoader
+	logger          util.Logger
+	formatAmount    util.AmountFormatter
+	locker          repository.Locker
+	skipBadDBRows   bool
+	matcher         Matcher
+	ctx             context.Context
+}
+
+// ReconciliationServiceOption configures optional reconciliation service behaviour.
+type ReconciliationServiceOption func(*reconciliationServiceImpl)
+
+// WithAmountFormatter sets how amounts are rendered in the reconciliation report.
+// The default renders two decimal places without a currency.
+func WithAmountFormatter(formatter util.AmountFormatter) ReconciliationServiceOption {
+	return func(s *reconciliationServiceImpl) {
+		s.formatAmount = formatter
+	}
+}
+
+// WithRunLock makes each reconciliation run hold a named lock so concurrent runs, including
+// ones in other processes, fail fast with ErrReconciliationInProgress.
+func WithRunLock(locker repository.Locker) ReconciliationServiceOption {
+	return func(s *reconciliationServiceImpl) {
+		s.locker = locker
+	}
+}
+
+// WithSkipBadDBRows makes runs against the whole transactions table skip DB rows that cannot be
+// scanned, logging each one and listing it in the report's UnreadableDBRows, instead of failing.
+func WithSkipBadDBRows() ReconciliationServiceOption {
+	return func(s *reconciliationServiceImpl) {
+		s.skipBadDBRows = true
+	}
+}
+
+// WithMatcher replaces DefaultMatcher as the strategy that pairs DB transactions with CSV rows,
+// e.g. with one that matches on references, tolerates small amount differences, or only pairs
+// transactions within a date window. A nil matcher keeps the default.
+func WithMatcher(matcher Matcher) ReconciliationServiceOption {
+	return func(s *reconciliationServiceImpl) {
+		s.matcher = matcher
+	}
 }
 
 // NewReconciliationService creates a new reconciliation service.
-func NewReconciliationService(transactionRepo repository.TransactionRepository, dataLoader util.DataLoader) ReconciliationService {
-	return &reconciliationServiceImpl{
+// A nil logger defaults to the standard library logger.
+func NewReconciliationService(transactionRepo repository.TransactionRepository, dataLoader util.DataLoader, logger util.Logger, opts ...ReconciliationServiceOption) ReconciliationService {
+	s := &reconciliationServiceImpl{
 		transactionRepo: transactionRepo,
 		dataLoader:      dataLoader,
+		logger:          util.LoggerOrDefault(logger),
+		formatAmount:    util.DefaultAmountFormatter,
+		ctx:             context.Background(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.formatAmount == nil {
+		s.formatAmount = util.DefaultAmountFormatter
 	}
+	if s.matcher == nil {
+		s.matcher = DefaultMatcher{}
+	}
+	return s
+}
+
+// WithContext returns a copy of the service whose runs can be canceled through ctx: the database
+// reads run under ctx, and matching and multi-file loading stop once ctx is done, failing the run
+// with an error that wraps ctx.Err(). A run that has finished is not affected.
+func (s *reconciliationServiceImpl) WithContext(ctx context.Context) ReconciliationService {
+	c := *s
+	c.ctx = ctx
+	c.transactionRepo = s.transactionRepo.WithContext(ctx)
+	return &c
 }
 
 // normalizeDBTransactionType standardizes DB transaction types for comparison.
@@ -49,117 +139,348 @@ func (s *reconciliationServiceImpl) normalizeDBTransactionType(dbType string, fr
     return dbType // Fallback
 }
 
-// ReconcileTransactions performs reconciliation between database and external CSV transactions.
-func (s *reconciliationServiceImpl) ReconcileTransactions(csvFilePath string) {
-    fmt.Println("\n--- Reconciliation Report ---")
+// classifyForReconciliation labels a DB transaction with the type used in partner CSV files.
+func (s *reconciliationServiceImpl) classifyForReconciliation(tx models.Transaction) string {
+    return s.normalizeDBTransactionType(tx.TransactionType, tx.FromAccountID, tx.ToAccountID)
+}
 
-    csvTransactions, err := s.dataLoader.LoadExternalTransactions(csvFilePath)
+// fetchDatabaseTransactions reads the whole transactions table for reconciliation. With
+// WithSkipBadDBRows, unreadable rows are logged and returned separately instead of failing.
+func (s *reconciliationServiceImpl) fetchDatabaseTransactions() ([]models.Transaction, []repository.RowError, error) {
+    if !s.skipBadDBRows {
+        transactions, err := s.transactionRepo.GetAllTransactionsForReconciliation()
+        return transactions, nil, err
+    }
+    transactions, badRows, err := s.transactionRepo.GetAllTransactionsForReconciliationSkippingBadRows()
     if err != nil {
-        log.Fatalf("ReconciliationService: Failed to load external transactions: %v", err)
+        return nil, nil, err
     }
-    log.Printf("ReconciliationService: Loaded %d transactions from CSV.\n", len(csvTransactions))
+    for _, bad := range badRows {
+        s.logger.Warn("ReconciliationService: Skipping unreadable database row (ID %d): %v", bad.TransactionID, bad.Err)
+    }
+    return transactions, badRows, nil
+}
+
+// ReconcileTransactions performs reconciliation between database and external CSV transactions.
+// The report is printed and returned.
+func (s *reconciliationServiceImpl) ReconcileTransactions(csvFilePath string) (*ReconciliationReport, error) {
+    var report *ReconciliationReport
+    err := s.withRunLock("ReconcileTransactions", func() error {
+        csvTransactions, err := s.dataLoader.LoadExternalTransactions(csvFilePath)
+        if err != nil {
+            s.logger.Error("ReconciliationService: Failed to load external transactions: %v", err)
+            return fmt.Errorf("ReconcileTransactions: failed to load external transactions: %w", err)
+        }
+        s.logger.Info("ReconciliationService: Loaded %d transactions from CSV.", len(csvTransactions))
+
+        databaseTransactions, badRows, err := s.fetchDatabaseTransactions()
+        if err != nil {
+            s.logger.Error("ReconciliationService: Failed to fetch database transactions: %v", err)
+            return fmt.Errorf("ReconcileTransactions: failed to fetch database transactions: %w", err)
+        }
+        s.logger.Info("ReconciliationService: Fetched %d transactions from Database.", len(databaseTransactions))
 
-    databaseTransactions, err := s.transactionRepo.GetAllTransactionsForReconciliation()
+        report, err = s.match(databaseTransactions, csvTransactions, s.classifyForReconciliation)
+        if err != nil {
+            return fmt.Errorf("ReconcileTransactions: %w", err)
+        }
+        report.UnreadableDBRows = badRows
+        s.printReport(report)
+        return nil
+    })
     if err != nil {
-        log.Fatalf("ReconciliationService: Failed to fetch database transactions: %v", err)
+        return nil, err
     }
-    log.Printf("ReconciliationService: Fetched %d transactions from Database.\n", len(databaseTransactions))
+    return report, nil
+}
 
-    // Using maps to track processed items to avoid double-counting in simple N*M comparison
-    processedDBTx := make(map[int64]bool)
-    processedCSVTx := make(map[string]bool)
+// ReconcileAccountStatement reconciles a single account's statement against the database.
+// Each DB transaction is classified as CREDIT or DEBIT from the account's point of view, so a
+// statement debit matches any outflow regardless of the stored transaction type.
+func (s *reconciliationServiceImpl) ReconcileAccountStatement(accountID int64, csvFilePath string) (*ReconciliationReport, error) {
+    var report *ReconciliationReport
+    err := s.withRunLock("ReconcileAccountStatement", func() error {
+        csvTransactions, err := s.dataLoader.LoadExternalTransactions(csvFilePath)
+        if err != nil {
+            s.logger.Error("ReconciliationService: Failed to load external transactions: %v", err)
+            return fmt.Errorf("ReconcileAccountStatement: failed to load external transactions: %w", err)
+        }
+        for i := range csvTransactions {
+            csvTransactions[i].Type = normalizeStatementDirection(csvTransactions[i].Type)
+            csvTransactions[i].Amount = math.Abs(csvTransactions[i].Amount)
+        }
+        s.logger.Info("ReconciliationService: Loaded %d statement lines from CSV for account %d.", len(csvTransactions), accountID)
 
-    var foundInBoth []string
-    var mismatchedAmounts []string // Records matched by type but amounts differ
+        databaseTransactions, err := s.transactionRepo.GetTransactionsForAccount(accountID, nil)
+        if err != nil {
+            s.logger.Error("ReconciliationService: Failed to fetch transactions for account %d: %v", accountID, err)
+            return fmt.Errorf("ReconcileAccountStatement: failed to fetch transactions for account %d: %w", accountID, err)
+        }
+        s.logger.Info("ReconciliationService: Fetched %d transactions for account %d from Database.", len(databaseTransactions), accountID)
 
-    // Attempt to match DB transactions against CSV transactions
-    for _, dbTx := range databaseTransactions {
-        if processedDBTx[dbTx.TransactionID] {
-            continue
+        transactions := make([]models.Transaction, len(databaseTransactions))
+        for i, tx := range databaseTransactions {
+            transactions[i] = tx.Transaction
         }
-        matchedThisDBTx := false
-        for _, csvTx := range csvTransactions {
-            if processedCSVTx[csvTx.ExternalID] {
-                continue
-            }
+        report, err = s.match(transactions, csvTransactions, func(tx models.Transaction) string {
+            return accountDirection(accountID, tx)
+        })
+        if err != nil {
+            return fmt.Errorf("ReconcileAccountStatement: %w", err)
+        }
+        s.printReport(report)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return report, nil
+}
 
-            // Normalize DB type for comparison (e.g. your DB 'TRANSFER' might map to CSV 'TRANSFER_OUT' or 'TRANSFER_IN')
-            normalizedDBType := s.normalizeDBTransactionType(dbTx.TransactionType, dbTx.FromAccountID, dbTx.ToAccountID)
-            
-            // Criteria 1: Type and Amount match
-            if normalizedDBType == csvTx.Type && dbTx.Amount == csvTx.Amount {
-                foundInBoth = append(foundInBoth, fmt.Sprintf("  MATCH: DB ID %d (%.2f %s) with CSV ID %s (%.2f %s, Ref: %s)",
-                    dbTx.TransactionID, dbTx.Amount, normalizedDBType,
-                    csvTx.ExternalID, csvTx.Amount, csvTx.Type, csvTx.Reference))
-                processedDBTx[dbTx.TransactionID] = true
-                processedCSVTx[csvTx.ExternalID] = true
-                matchedThisDBTx = true
-                break // Found a match for this DB transaction
-            }
+// accountDirection classifies a transaction as CREDIT or DEBIT relative to accountID.
+func accountDirection(accountID int64, tx models.Transaction) string {
+    if tx.ToAccountID.Valid && tx.ToAccountID.Int64 == accountID {
+        return directionCredit
+    }
+    if tx.FromAccountID.Valid && tx.FromAccountID.Int64 == accountID {
+        return directionDebit
+    }
+    return strings.ToUpper(tx.TransactionType) // Not touching the account; leave it unmatched
+}
+
+// normalizeStatementDirection maps the debit/credit spellings used by bank statements to DEBIT or CREDIT.
+func normalizeStatementDirection(csvType string) string {
+    switch strings.ToUpper(strings.TrimSpace(csvType)) {
+    case "DEBIT", "DR", "D":
+        return directionDebit
+    case "CREDIT", "CR", "C":
+        return directionCredit
+    }
+    return csvType
+}
+
+// withRunLock runs fn while holding the reconciliation run lock, when one is configured.
+func (s *reconciliationServiceImpl) withRunLock(op string, fn func() error) (err error) {
+    if s.locker != nil {
+        release, acquired, lockErr := s.locker.TryLock(reconciliationLockName)
+        if lockErr != nil {
+            return fmt.Errorf("%s: failed to acquire run lock: %w", op, lockErr)
         }
-        // If no exact match on amount and type, check for type match with different amount
-        if !matchedThisDBTx {
-             for _, csvTx := range csvTransactions {
-                if processedCSVTx[csvTx.ExternalID] { // Skip already fully matched CSV
-                    continue
-                }
-                normalizedDBType := s.normalizeDBTransactionType(dbTx.TransactionType, dbTx.FromAccountID, dbTx.ToAccountID)
-                if normalizedDBType == csvTx.Type { // Type matches, amount must differ (otherwise caught above)
-                    mismatchedAmounts = append(mismatchedAmounts, fmt.Sprintf("  MISMATCH_AMOUNT: DB ID %d (%.2f %s) vs CSV ID %s (%.2f %s, Ref: %s)",
-                        dbTx.TransactionID, dbTx.Amount, normalizedDBType,
-                        csvTx.ExternalID, csvTx.Amount, csvTx.Type, csvTx.Reference))
-                    processedDBTx[dbTx.TransactionID] = true // Mark as processed even if mismatched, to avoid being "only in DB"
-                    processedCSVTx[csvTx.ExternalID] = true // Mark CSV as processed to avoid being "only in CSV"
-                    // Note: This simple logic might misclassify if multiple CSV entries have the same type.
-                    // A more robust system would use more unique identifiers or a tolerance for amounts.
-                    break 
+        if !acquired {
+            return ErrReconciliationInProgress
+        }
+        defer func() {
+            if releaseErr := release(); releaseErr != nil {
+                s.logger.Error("ReconciliationService: Failed to release run lock: %v", releaseErr)
+                if err == nil {
+                    err = fmt.Errorf("%s: %w", op, releaseErr)
                 }
             }
-        }
+        }()
     }
+    return fn()
+}
 
-    var onlyInDB []string
-    for _, dbTx := range databaseTransactions {
-        if !processedDBTx[dbTx.TransactionID] {
-            onlyInDB = append(onlyInDB, fmt.Sprintf("  DB ID: %d, Type: %s, Amount: %.2f, Desc: %s",
-                dbTx.TransactionID, dbTx.TransactionType, dbTx.Amount, dbTx.Description.String))
-        }
+// match classifies the DB transactions with classify, pairs them with the CSV rows using the
+// configured Matcher, and summarizes the outcome. It fails only when the run's context is done.
+func (s *reconciliationServiceImpl) match(databaseTransactions []models.Transaction, csvTransactions []models.ExternalTransaction, classify func(models.Transaction) string) (*ReconciliationReport, error) {
+    classified := classifyTransactions(databaseTransactions, classify)
+    result, err := s.matcher.Match(s.ctx, classified, csvTransactions)
+    if err != nil {
+        s.logger.Warn("ReconciliationService: Matching stopped: %v", err)
+        return nil, fmt.Errorf("matching stopped: %w", err)
     }
+    return &ReconciliationReport{MatchResult: result, Summary: summarize(classified, csvTransactions, result)}, nil
+}
 
-    var onlyInCSV []string
-    for _, csvTx := range csvTransactions {
-        if !processedCSVTx[csvTx.ExternalID] {
-            onlyInCSV = append(onlyInCSV, fmt.Sprintf("  CSV ID: %s, Type: %s, Amount: %.2f, Ref: %s",
-                csvTx.ExternalID, csvTx.Type, csvTx.Amount, csvTx.Reference))
+// printReport prints a reconciliation report to stdout.
+func (s *reconciliationServiceImpl) printReport(report *ReconciliationReport) {
+    s.writeReport(os.Stdout, report)
+}
+
+// writeReport writes a reconciliation report in the printed layout to w.
+func (s *reconciliationServiceImpl) writeReport(w io.Writer, report *ReconciliationReport) {
+    fmt.Fprintln(w, "\n--- Reconciliation Report ---")
+
+    fmt.Fprintln(w, "\n[Transactions Found in Both Systems (Exact Match on Type & Amount)]")
+    if len(report.Matched) > 0 {
+        for _, m := range report.Matched {
+            fmt.Fprintf(w, "  MATCH: DB ID %d (%s %s) with CSV ID %s (%s %s, Ref: %s)\n",
+                m.DB.TransactionID, s.formatAmount(m.DB.Amount), m.DBType,
+                m.CSV.ExternalID, s.formatAmount(m.CSV.Amount), m.CSV.Type, m.CSV.Reference)
         }
+    } else {
+        fmt.Fprintln(w, "  None")
     }
 
-    fmt.Println("\n[Transactions Found in Both Systems (Exact Match on Type & Amount)]")
-    if len(foundInBoth) > 0 {
-        for _, item := range foundInBoth { fmt.Println(item) }
+    fmt.Fprintln(w, "\n[Potential Matches with Mismatched Amounts (Same Type)]")
+    if len(report.AmountMismatches) > 0 {
+        for _, m := range report.AmountMismatches {
+            fmt.Fprintf(w, "  MISMATCH_AMOUNT: DB ID %d (%s %s) vs CSV ID %s (%s %s, Ref: %s)\n",
+                m.DB.TransactionID, s.formatAmount(m.DB.Amount), m.DBType,
+                m.CSV.ExternalID, s.formatAmount(m.CSV.Amount), m.CSV.Type, m.CSV.Reference)
+        }
     } else {
-        fmt.Println("  None")
+        fmt.Fprintln(w, "  None")
     }
-    
-    fmt.Println("\n[Potential Matches with Mismatched Amounts (Same Type)]")
-    if len(mismatchedAmounts) > 0 {
-        for _, item := range mismatchedAmounts { fmt.Println(item) }
+
+    fmt.Fprintln(w, "\n[Split Matches (Several CSV Rows Sharing a Reference)]")
+    if len(report.SplitMatches)+len(report.PartialSplitMatches) > 0 {
+        for _, section := range []struct {
+            label   string
+            matches []SplitMatch
+        }{{"SPLIT_MATCH", report.SplitMatches}, {"PARTIAL_SPLIT", report.PartialSplitMatches}} {
+            for _, m := range section.matches {
+                fmt.Fprintf(w, "  %s: DB ID %d (%s %s) with %d CSV rows totalling %s (Ref: %s)\n",
+                    section.label, m.DB.TransactionID, s.formatAmount(m.DB.Amount), m.DBType,
+                    len(m.CSV), s.formatAmount(m.CSVTotal), m.CSV[0].Reference)
+            }
+        }
     } else {
-        fmt.Println("  None")
+        fmt.Fprintln(w, "  None")
     }
 
-    fmt.Println("\n[Transactions Only in Database]")
-    if len(onlyInDB) > 0 {
-        for _, item := range onlyInDB { fmt.Println(item) }
+    if len(report.AmbiguousSplits) > 0 {
+        fmt.Fprintln(w, "\n[Ambiguous Split Groups (Several DB Transactions Fit)]")
+        for _, g := range report.AmbiguousSplits {
+            ids := make([]string, 0, len(g.Candidates))
+            for _, c := range g.Candidates {
+                ids = append(ids, strconv.FormatInt(c.TransactionID, 10))
+            }
+            fmt.Fprintf(w, "  AMBIGUOUS: Ref %s, %d CSV rows totalling %s, candidate DB IDs %s\n",
+                g.Reference, len(g.CSV), s.formatAmount(g.CSVTotal), strings.Join(ids, ", "))
+        }
+    }
+
+    fmt.Fprintln(w, "\n[Transactions Only in Database]")
+    if len(report.OnlyInDB) > 0 {
+        for _, dbTx := range report.OnlyInDB {
+            fmt.Fprintf(w, "  DB ID: %d, Type: %s, Amount: %s, Desc: %s\n",
+                dbTx.TransactionID, dbTx.TransactionType, s.formatAmount(dbTx.Amount), dbTx.Description.String)
+        }
     } else {
-        fmt.Println("  None")
+        fmt.Fprintln(w, "  None")
     }
 
-    fmt.Println("\n[Transactions Only in CSV File]")
-    if len(onlyInCSV) > 0 {
-        for _, item := range onlyInCSV { fmt.Println(item) }
+    fmt.Fprintln(w, "\n[Transactions Only in CSV File]")
+    if len(report.OnlyInCSV) > 0 {
+        for _, csvTx := range report.OnlyInCSV {
+            fmt.Fprintf(w, "  CSV ID: %s, Type: %s, Amount: %s, Ref: %s\n",
+                csvTx.ExternalID, csvTx.Type, s.formatAmount(csvTx.Amount), csvTx.Reference)
+        }
     } else {
-        fmt.Println("  None")
+        fmt.Fprintln(w, "  None")
+    }
+
+    if len(report.FileErrors) > 0 {
+        fmt.Fprintln(w, "\n[Files That Could Not Be Loaded]")
+        for _, fe := range report.FileErrors {
+            fmt.Fprintf(w, "  %s: %v\n", fe.Path, fe.Err)
+        }
+    }
+
+    if len(report.UnreadableDBRows) > 0 {
+        fmt.Fprintln(w, "\n[Database Rows That Could Not Be Read (Not Reconciled)]")
+        for _, bad := range report.UnreadableDBRows {
+            fmt.Fprintf(w, "  DB ID %d: %v\n", bad.TransactionID, bad.Err)
+        }
     }
-    fmt.Println("\n--- End of Reconciliation Report ---")
-}
\ No newline at end of file
+
+    summary := report.Summary
+    fmt.Fprintln(w, "\n[Summary]")
+    fmt.Fprintf(w, "  Database: %d transactions, net %s (%d matched, %d unmatched)\n",
+        summary.DBCount, s.formatAmount(summary.DBTotal), summary.MatchedDB, summary.UnmatchedDB)
+    fmt.Fprintf(w, "  CSV:      %d transactions, net %s (%d matched, %d unmatched)\n",
+        summary.CSVCount, s.formatAmount(summary.CSVTotal), summary.MatchedCSV, summary.UnmatchedCSV)
+    fmt.Fprintf(w, "  Difference (DB - CSV): %s\n", s.formatAmount(summary.Difference))
+    fmt.Fprintln(w, "\n--- End of Reconciliation Report ---")
+}
+
+// ReconcileDirectory reconciles the database against every CSV file matched by pattern.
+// pattern is either a directory, meaning all *.csv files in it, or a glob. The files are merged
+// into one set keyed by ExternalID; the first occurrence of a duplicated ID wins and later ones
+// are logged and dropped. A file that fails to load is recorded in the report's FileErrors and
+// the run continues with the remaining files.
+func (s *reconciliationServiceImpl) ReconcileDirectory(pattern string) (*ReconciliationReport, error) {
+    var report *ReconciliationReport
+    err := s.withRunLock("ReconcileDirectory", func() error {
+        if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+            pattern = filepath.Join(pattern, "*.csv")
+        }
+        files, err := filepath.Glob(pattern)
+        if err != nil {
+            return fmt.Errorf("ReconcileDirectory: invalid pattern %q: %w", pattern, err)
+        }
+        sort.Strings(files)
+        s.logger.Info("ReconciliationService: Found %d CSV files matching %s.", len(files), pattern)
+
+        var csvTransactions []models.ExternalTransaction
+        var fileErrors []FileError
+        seenIn := make(map[string]string) // ExternalID -> file it was first seen in
+        for _, file := range files {
+            if err := s.ctx.Err(); err != nil {
+                return fmt.Errorf("ReconcileDirectory: loading stopped before %s: %w", file, err)
+            }
+            loaded, err := s.dataLoader.LoadExternalTransactions(file)
+            if err != nil {
+                s.logger.Error("ReconciliationService: Failed to load %s, skipping it: %v", file, err)
+                fileErrors = append(fileErrors, FileError{Path: file, Err: err})
+                continue
+            }
+            for _, csvTx := range loaded {
+                if firstFile, dup := seenIn[csvTx.ExternalID]; dup {
+                    s.logger.Warn("ReconciliationService: Duplicate external ID %s in %s (first seen in %s), ignoring it.", csvTx.ExternalID, file, firstFile)
+                    continue
+                }
+                seenIn[csvTx.ExternalID] = file
+                csvTransactions = append(csvTransactions, csvTx)
+            }
+        }
+        s.logger.Info("ReconciliationService: Loaded %d unique transactions from %d CSV files.", len(csvTransactions), len(files)-len(fileErrors))
+
+        databaseTransactions, badRows, err := s.fetchDatabaseTransactions()
+        if err != nil {
+            s.logger.Error("ReconciliationService: Failed to fetch database transactions: %v", err)
+            return fmt.Errorf("ReconcileDirectory: failed to fetch database transactions: %w", err)
+        }
+        s.logger.Info("ReconciliationService: Fetched %d transactions from Database.", len(databaseTransactions))
+
+        report, err = s.match(databaseTransactions, csvTransactions, s.classifyForReconciliation)
+        if err != nil {
+            return fmt.Errorf("ReconcileDirectory: %w", err)
+        }
+        report.FileErrors = fileErrors
+        report.UnreadableDBRows = badRows
+        s.printReport(report)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return report, nil
+}
+
+// ExportOnlyInDB writes the report's "only in DB" transactions as CSV in the external file layout,
+// so they can be re-submitted to the partner system.
+func (s *reconciliationServiceImpl) ExportOnlyInDB(w io.Writer, report *ReconciliationReport) error {
+    records := make([]models.ExternalTransaction, 0, len(report.OnlyInDB))
+    for _, dbTx := range report.OnlyInDB {
+        records = append(records, externalFromDB(dbTx, s.classifyForReconciliation(dbTx)))
+    }
+    if err := util.ExportExternalTransactionsCSV(w, records); err != nil {
+        return fmt.Errorf("ExportOnlyInDB: %w", err)
+    }
+    s.logger.Info("ReconciliationService: Exported %d DB-only transactions for re-submission.", len(records))
+    return nil
+}
+
+// externalFromDB converts a DB transaction into the external record shape.
+// The DB transaction ID becomes the external ID and the description the reference.
+func externalFromDB(tx models.Transaction, txType string) models.ExternalTransaction {
+    return models.ExternalTransaction{
+        ExternalID: strconv.FormatInt(tx.TransactionID, 10),
+        Amount:     math.Abs(tx.Amount),
+        Type:       txType,
+        Reference:  tx.Description.String,
+    }
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// reportFor matches dbTxs against csvTxs with s and returns the report as printed.
+func reportFor(t *testing.T, s *reconciliationServiceImpl, dbTxs []models.Transaction, csvTxs []models.ExternalTransaction) string {
+	t.Helper()
+	report, err := s.match(dbTxs, csvTxs, s.classifyForReconciliation)
+	if err != nil {
+		t.Fatalf("match: %v", err)
+	}
+	var out bytes.Buffer
+	s.writeReport(&out, report)
+	return out.String()
+}
+
+func TestSummarizeSignsByDirection(t *testing.T) {
+	dbTxs := []ClassifiedTransaction{
+		{Type: "DEPOSIT", Transaction: models.Transaction{Amount: 100}},
+		{Type: "INTEREST", Transaction: models.Transaction{Amount: 1.25}},
+		{Type: "WITHDRAWAL", Transaction: models.Transaction{Amount: 40}},
+		{Type: "FEE", Transaction: models.Transaction{Amount: 2}},
+		{Type: "TRANSFER", Transaction: models.Transaction{Amount: 500}},
+	}
+	csvTxs := []models.ExternalTransaction{
+		{ExternalID: "ext-1", Type: "deposit", Amount: 100},
+		{ExternalID: "ext-2", Type: "interest", Amount: 1.25},
+		{ExternalID: "ext-3", Type: "WITHDRAWAL", Amount: -40},
+		{ExternalID: "ext-4", Type: "INTEREST", Amount: 0.5},
+	}
+	got := summarize(dbTxs, csvTxs, MatchResult{})
+	if got.DBTotal != 61.25 || got.CSVTotal != 61.75 || got.Difference != -0.5 {
+		t.Errorf("summary totals db %v, csv %v, difference %v; want 61.25, 61.75, -0.5", got.DBTotal, got.CSVTotal, got.Difference)
+	}
+}
+
+func TestReportAmountFormatter(t *testing.T) {
+	account := sql.NullInt64{Int64: 1, Valid: true}
+	dbTxs := []models.Transaction{
+		{TransactionID: 1, ToAccountID: account, TransactionType: "DEPOSIT", Amount: 1500},
+		{TransactionID: 2, FromAccountID: account, TransactionType: "WITHDRAWAL", Amount: 250},
+		{TransactionID: 3, ToAccountID: account, TransactionType: "DEPOSIT", Amount: 1234567},
+	}
+	csvTxs := []models.ExternalTransaction{
+		{ExternalID: "ext-1", Type: "DEPOSIT", Amount: 1500},
+		{ExternalID: "ext-2", Type: "WITHDRAWAL", Amount: 300},
+		{ExternalID: "ext-3", Type: "FEE", Amount: 99},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		opts    []ReconciliationServiceOption
+		want    []string
+		notWant string
+	}{
+		{
+			name: "default",
+			want: []string{"(1500.00 DEPOSIT)", "(250.00 WITHDRAWAL) vs CSV ID ext-2 (300.00 WITHDRAWAL", "Amount: 1234567.00,", "Amount: 99.00,"},
+		},
+		{
+			name:    "JPY",
+			opts:    []ReconciliationServiceOption{WithAmountFormatter(util.NewMoneyFormatter("JPY"))},
+			want:    []string{"(¥1,500 DEPOSIT)", "(¥250 WITHDRAWAL) vs CSV ID ext-2 (¥300 WITHDRAWAL", "Amount: ¥1,234,567,", "Amount: ¥99,", "Difference (DB - CSV): ¥1,234,617"},
+			notWant: ".00",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewReconciliationService(nil, nil, nil, tc.opts...).(*reconciliationServiceImpl)
+			out := reportFor(t, s, dbTxs, csvTxs)
+			for _, want := range tc.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("report does not contain %q:\n%s", want, out)
+				}
+			}
+			if tc.notWant != "" && strings.Contains(out, tc.notWant) {
+				t.Errorf("report contains %q:\n%s", tc.notWant, out)
+			}
+		})
+	}
+}
+
+// statementLoader returns a copy of rows for any file, as the CSV loader would read them.
+type statementLoader struct {
+	util.DataLoader
+	rows []models.ExternalTransaction
+}
+
+func (l statementLoader) LoadExternalTransactions(string) ([]models.ExternalTransaction, error) {
+	return append([]models.ExternalTransaction(nil), l.rows...), nil
+}
+
+func TestReconcileAccountStatement(t *testing.T) {
+	store := repository.NewMemoryStore()
+	accounts, transactions := store.Accounts(), store.Transactions()
+	alice := createAccount(t, accounts, "Alice", 0)
+	bob := createAccount(t, accounts, "Bob", 0)
+	id := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+	create := func(from, to sql.NullInt64, txType string, amount float64) int64 {
+		t.Helper()
+		txID, err := transactions.CreateTransaction(from, to, txType, amount, sql.NullString{})
+		if err != nil {
+			t.Fatalf("CreateTransaction: %v", err)
+		}
+		return txID
+	}
+	deposit := create(sql.NullInt64{}, id(alice), "DEPOSIT", 100)
+	aliceToBob := create(id(alice), id(bob), "TRANSFER", 30)
+	bobToAlice := create(id(bob), id(alice), "TRANSFER", 12.5)
+	withdrawal := create(id(alice), sql.NullInt64{}, "WITHDRAWAL", 5)
+
+	for _, tc := range []struct {
+		name      string
+		accountID int64
+		statement []models.ExternalTransaction
+		want      map[int64]string // matched DB transaction ID to its direction
+		wantNet   float64
+	}{
+		{
+			// Alice pays the first transfer and receives the second, so the same stored type
+			// is a debit in one and a credit in the other.
+			name:      "focal account on each side",
+			accountID: alice,
+			statement: []models.ExternalTransaction{
+				{ExternalID: "a1", Type: "CR", Amount: 100},
+				{ExternalID: "a2", Type: "DEBIT", Amount: -30},
+				{ExternalID: "a3", Type: "credit", Amount: 12.5},
+				{ExternalID: "a4", Type: " d ", Amount: 5},
+			},
+			want:    map[int64]string{deposit: directionCredit, aliceToBob: directionDebit, bobToAlice: directionCredit, withdrawal: directionDebit},
+			wantNet: 77.5,
+		},
+		{
+			name:      "counterparty",
+			accountID: bob,
+			statement: []models.ExternalTransaction{
+				{ExternalID: "b1", Type: "CREDIT", Amount: 30},
+				{ExternalID: "b2", Type: "DR", Amount: 12.5},
+			},
+			want:    map[int64]string{aliceToBob: directionCredit, bobToAlice: directionDebit},
+			wantNet: 17.5,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewReconciliationService(transactions, statementLoader{rows: tc.statement}, nil)
+			report, err := s.ReconcileAccountStatement(tc.accountID, "statement.csv")
+			if err != nil {
+				t.Fatalf("ReconcileAccountStatement: %v", err)
+			}
+			got := make(map[int64]string)
+			for _, m := range report.Matched {
+				got[m.DB.TransactionID] = m.DBType
+				if m.CSV.Type != m.DBType || m.CSV.Amount < 0 {
+					t.Errorf("statement line %s matched as %s %v, want a positive %s", m.CSV.ExternalID, m.CSV.Type, m.CSV.Amount, m.DBType)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("matched = %v, want %v", got, tc.want)
+			}
+			if n := len(report.AmountMismatches) + len(report.OnlyInDB) + len(report.OnlyInCSV); n != 0 {
+				t.Errorf("report has %d unmatched entries, want none: %+v", n, report.MatchResult)
+			}
+			if report.Summary.DBTotal != tc.wantNet || report.Summary.Difference != 0 {
+				t.Errorf("summary net %v, difference %v; want %v, 0", report.Summary.DBTotal, report.Summary.Difference, tc.wantNet)
+			}
+		})
+	}
+
+	// A debit on Bob's statement cannot be Alice's payment to him, whatever its amount.
+	s := NewReconciliationService(transactions, statementLoader{rows: []models.ExternalTransaction{{ExternalID: "b1", Type: "DEBIT", Amount: 30}}}, nil)
+	report, err := s.ReconcileAccountStatement(bob, "statement.csv")
+	if err != nil {
+		t.Fatalf("ReconcileAccountStatement: %v", err)
+	}
+	for _, m := range report.Matched {
+		if m.DB.TransactionID == aliceToBob {
+			t.Errorf("Bob's statement debit matched the incoming transfer %d", aliceToBob)
+		}
+	}
+}
+
+func TestExportOnlyInDB(t *testing.T) {
+	account := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+	report := &ReconciliationReport{MatchResult: MatchResult{OnlyInDB: []models.Transaction{
+		{TransactionID: 7, ToAccountID: account(1), TransactionType: "deposit", Amount: 150, Description: sql.NullString{String: "payroll", Valid: true}},
+		{TransactionID: 8, FromAccountID: account(1), TransactionType: "WITHDRAWAL", Amount: -20.5},
+		{TransactionID: 9, FromAccountID: account(1), TransactionType: "TRANSFER", Amount: 30},
+		{TransactionID: 10, ToAccountID: account(2), TransactionType: "TRANSFER", Amount: 12.25},
+		{TransactionID: 11, FromAccountID: account(1), ToAccountID: account(2), TransactionType: "TRANSFER", Amount: 5},
+	}}}
+	want := []models.ExternalTransaction{
+		{ExternalID: "7", Amount: 150, Type: "DEPOSIT", Reference: "payroll"},
+		{ExternalID: "8", Amount: 20.5, Type: "WITHDRAWAL"},
+		{ExternalID: "9", Amount: 30, Type: "TRANSFER_OUT"},
+		{ExternalID: "10", Amount: 12.25, Type: "TRANSFER_IN"},
+		{ExternalID: "11", Amount: 5, Type: "INTERNAL_TRANSFER"},
+	}
+
+	s := NewReconciliationService(nil, nil, nil).(*reconciliationServiceImpl)
+	for i, tx := range report.OnlyInDB {
+		if got := externalFromDB(tx, s.classifyForReconciliation(tx)); got != want[i] {
+			t.Errorf("externalFromDB(%+v) = %+v, want %+v", tx, got, want[i])
+		}
+	}
+
+	// The export is in the layout the CSV loader reads, so it loads back as the same records.
+	var out bytes.Buffer
+	if err := s.ExportOnlyInDB(&out, report); err != nil {
+		t.Fatalf("ExportOnlyInDB: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "ExternalID,Amount,Type,Reference\n7,150.00,DEPOSIT,payroll\n") {
+		t.Errorf("ExportOnlyInDB wrote:\n%s", out.String())
+	}
+	loaded, err := util.NewCSVDataLoader(nil).LoadExternalTransactionsFromReader(&out)
+	if err != nil {
+		t.Fatalf("LoadExternalTransactionsFromReader: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("exported records load as %+v, want %+v", loaded, want)
+	}
+}
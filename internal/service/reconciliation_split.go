@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+
+	"sql-golang-playground/models"
+)
+
+// splitGroup is a set of unmatched CSV rows sharing a Reference, with their positions in the
+// CSV slice so they can be marked processed.
+type splitGroup struct {
+	reference string
+	indexes   []int
+	rows      []models.ExternalTransaction
+	total     float64
+}
+
+// primaryType returns the type of the group's largest row. A transfer with its fee split out
+// keeps the transfer's type on the larger row, so that is the type a DB transaction must have.
+func (g splitGroup) primaryType() string {
+	largest := g.rows[0]
+	for _, row := range g.rows[1:] {
+		if row.Amount > largest.Amount {
+			largest = row
+		}
+	}
+	return largest.Type
+}
+
+// groupByReference groups the unprocessed CSV rows by Reference, in order of first appearance.
+// Rows without a reference and references with a single row are not grouped.
+func groupByReference(csvTransactions []models.ExternalTransaction, processedCSVTx map[int]bool) []splitGroup {
+	var order []string
+	groups := make(map[string]*splitGroup)
+	for i, csvTx := range csvTransactions {
+		if processedCSVTx[i] || csvTx.Reference == "" {
+			continue
+		}
+		g, ok := groups[csvTx.Reference]
+		if !ok {
+			g = &splitGroup{reference: csvTx.Reference}
+			groups[csvTx.Reference] = g
+			order = append(order, csvTx.Reference)
+		}
+		g.indexes = append(g.indexes, i)
+		g.rows = append(g.rows, csvTx)
+		g.total += csvTx.Amount
+	}
+
+	var result []splitGroup
+	for _, ref := range order {
+		if g := groups[ref]; len(g.rows) > 1 {
+			result = append(result, *g)
+		}
+	}
+	return result
+}
+
+// matchSplits matches groups of CSV rows sharing a Reference against single DB transactions of
+// the group's primary type. A group whose total equals exactly one unprocessed DB transaction is
+// a split match. When several DB transactions have that amount the group is ambiguous and
+// reported as such without consuming any of them. A group with no amount match but exactly one
+// DB transaction of its type is a partial split. Other groups are left for one-to-one matching.
+// Every row in a grouped outcome is marked processed, so no CSV row lands in two sections.
+// ctx is checked once per group.
+func matchSplits(ctx context.Context, result *MatchResult, databaseTransactions []ClassifiedTransaction, csvTransactions []models.ExternalTransaction, processedDBTx map[int64]bool, processedCSVTx map[int]bool) error {
+	groups := groupByReference(csvTransactions, processedCSVTx)
+	if len(groups) == 0 {
+		return nil
+	}
+	dbIndex := newDBIndex(databaseTransactions, processedDBTx)
+	for _, g := range groups {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		txType := g.primaryType()
+		sameTotal := dbIndex.withAmount(txType, g.total)
+
+		if len(sameTotal) == 1 {
+			result.SplitMatches = append(result.SplitMatches, SplitMatch{DB: sameTotal[0], DBType: txType, CSV: g.rows, CSVTotal: g.total})
+			dbIndex.markProcessed(sameTotal[0].TransactionID, txType)
+		} else if len(sameTotal) > 1 {
+			result.AmbiguousSplits = append(result.AmbiguousSplits, AmbiguousSplit{Reference: g.reference, CSV: g.rows, CSVTotal: g.total, Candidates: sameTotal})
+		} else if only, ok := dbIndex.onlyOfType(txType); ok {
+			result.PartialSplitMatches = append(result.PartialSplitMatches, SplitMatch{DB: only, DBType: txType, CSV: g.rows, CSVTotal: g.total})
+			dbIndex.markProcessed(only.TransactionID, txType)
+		} else {
+			continue
+		}
+		for _, i := range g.indexes {
+			processedCSVTx[i] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"math"
+	"strings"
+
+	"sql-golang-playground/models"
+)
+
+// ReconciliationSummary gives the size and net value of both sides of a run, so a large
+// imbalance is visible without reading the individual sections.
+//
+// Totals are signed by direction: deposits, interest, incoming transfers and statement credits
+// count as positive, withdrawals, outgoing transfers and statement debits as negative. Types
+// without a direction, such as internal transfers and fees, count towards neither total. A DB transaction or CSV
+// row is matched when it is part of an exact or split match; everything else is unmatched.
+type ReconciliationSummary struct {
+	DBCount      int     `json:"db_count"`
+	CSVCount     int     `json:"csv_count"`
+	DBTotal      float64 `json:"db_total"`
+	CSVTotal     float64 `json:"csv_total"`
+	Difference   float64 `json:"difference"` // DBTotal - CSVTotal
+	MatchedDB    int     `json:"matched_db"`
+	UnmatchedDB  int     `json:"unmatched_db"`
+	MatchedCSV   int     `json:"matched_csv"`
+	UnmatchedCSV int     `json:"unmatched_csv"`
+}
+
+// summarize computes the summary of matching dbTxs against csvTxs with the given result.
+func summarize(dbTxs []ClassifiedTransaction, csvTxs []models.ExternalTransaction, result MatchResult) ReconciliationSummary {
+	var dbTotal, csvTotal float64
+	for _, tx := range dbTxs {
+		dbTotal += directionSign(tx.Type) * math.Abs(tx.Amount)
+	}
+	for _, tx := range csvTxs {
+		csvTotal += directionSign(tx.Type) * math.Abs(tx.Amount)
+	}
+	dbTotal, csvTotal = roundCents(dbTotal), roundCents(csvTotal)
+
+	matchedDB := len(result.Matched) + len(result.SplitMatches)
+	matchedCSV := len(result.Matched)
+	for _, m := range result.SplitMatches {
+		matchedCSV += len(m.CSV)
+	}
+	return ReconciliationSummary{
+		DBCount:      len(dbTxs),
+		CSVCount:     len(csvTxs),
+		DBTotal:      dbTotal,
+		CSVTotal:     csvTotal,
+		Difference:   roundCents(dbTotal - csvTotal),
+		MatchedDB:    matchedDB,
+		UnmatchedDB:  len(dbTxs) - matchedDB,
+		MatchedCSV:   matchedCSV,
+		UnmatchedCSV: len(csvTxs) - matchedCSV,
+	}
+}
+
+// directionSign returns +1 for types that bring money in, -1 for types that take it out and
+// 0 for types without a direction. INTEREST is credited to an account from outside it, so it
+// counts like a deposit. A FEE moves money from the payer to the fee revenue account, both held
+// here, so like a TRANSFER it has no direction; on an account statement it is classified as a
+// DEBIT or CREDIT and counted as such.
+func directionSign(txType string) float64 {
+	switch strings.ToUpper(txType) {
+	case "DEPOSIT", "INTEREST", "TRANSFER_IN", directionCredit:
+		return 1
+	case "WITHDRAWAL", "TRANSFER_OUT", directionDebit:
+		return -1
+	}
+	return 0
+}
+
+// roundCents rounds v to two decimal places, dropping float noise from summing amounts.
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// ErrExecuteAtNotInFuture is returned by ScheduleTransfer for an execution time that has passed.
+var ErrExecuteAtNotInFuture = errors.New("scheduled execution time must be in the future")
+
+// ScheduledTransferService defines the interface for future-dated transfers.
+type ScheduledTransferService interface {
+	ScheduleTransfer(fromAccountID, toAccountID int64, amount float64, executeAt time.Time, description, notes string) (int64, error)
+	ProcessDueTransfers(now time.Time) ([]models.ScheduledTransfer, error)
+	GetProjectedBalance(accountID int64, until time.Time) (float64, error)
+}
+
+// scheduledTransferServiceImpl implements ScheduledTransferService.
+type scheduledTransferServiceImpl struct {
+	accountRepo   repository.AccountRepository
+	scheduledRepo repository.ScheduledTransferRepository
+	transfers     TransactionService
+	logger        util.Logger
+}
+
+// NewScheduledTransferService creates a new scheduled transfer service. Due transfers are
+// executed with transfers.TransferFunds, and projected balances use transfers.QuoteTransfer;
+// with a nil transfers, projections move each scheduled amount unchanged.
+// A nil logger defaults to the standard library logger.
+func NewScheduledTransferService(accountRepo repository.AccountRepository, scheduledRepo repository.ScheduledTransferRepository, transfers TransactionService, logger util.Logger) ScheduledTransferService {
+	return &scheduledTransferServiceImpl{
+		accountRepo:   accountRepo,
+		scheduledRepo: scheduledRepo,
+		transfers:     transfers,
+		logger:        util.LoggerOrDefault(logger),
+	}
+}
+
+// ScheduleTransfer books a transfer to execute at executeAt and returns its scheduled transfer
+// ID. No funds move until ProcessDueTransfers executes it. The request is checked with the same
+// rules as TransferFunds, and both accounts must exist; balances are only checked on execution.
+func (s *scheduledTransferServiceImpl) ScheduleTransfer(fromAccountID, toAccountID int64, amount float64, executeAt time.Time, description, notes string) (int64, error) {
+	if errs := ValidateTransferRequest(TransferRequest{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+		Description:   description,
+		Notes:         notes,
+	}); errs != nil {
+		return 0, errs
+	}
+	if !executeAt.After(time.Now()) {
+		return 0, fmt.Errorf("ScheduleTransfer: %w: %s", ErrExecuteAtNotInFuture, executeAt.Format(time.RFC3339))
+	}
+	for _, id := range []int64{fromAccountID, toAccountID} {
+		if _, err := s.accountRepo.GetAccountByID(id); err != nil {
+			return 0, fmt.Errorf("ScheduleTransfer: failed to get account %d: %w", id, err)
+		}
+	}
+
+	id, err := s.scheduledRepo.CreateScheduledTransfer(models.ScheduledTransfer{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+		ExecuteAt:     executeAt,
+		Description:   sql.NullString{String: description, Valid: description != ""},
+		Notes:         sql.NullString{String: notes, Valid: notes != ""},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ScheduleTransfer: %w", err)
+	}
+	s.logger.Info("Scheduled transfer %d: %.2f from account %d to account %d at %s",
+		id, amount, fromAccountID, toAccountID, executeAt.UTC().Format(time.RFC3339))
+	return id, nil
+}
+
+// ProcessDueTransfers executes every pending scheduled transfer due at or before now with
+// TransferFunds, in execution order, and returns them with their final status. A transfer that
+// TransferFunds rejects, e.g. for insufficient funds, is marked FAILED and not retried; one held
+// for review counts as EXECUTED, since the held transaction now exists.
+// Each transfer is claimed before it runs, so concurrent processors never execute it twice. If
+// the process dies between the claim and the final status, the transfer stays PROCESSING and
+// must be checked by hand rather than risk a second execution.
+func (s *scheduledTransferServiceImpl) ProcessDueTransfers(now time.Time) ([]models.ScheduledTransfer, error) {
+	due, err := s.scheduledRepo.GetDueScheduledTransfers(now)
+	if err != nil {
+		return nil, fmt.Errorf("ProcessDueTransfers: %w", err)
+	}
+
+	var processed []models.ScheduledTransfer
+	for _, st := range due {
+		claimed, err := s.scheduledRepo.ClaimScheduledTransfer(st.ScheduledTransferID)
+		if err != nil {
+			return processed, fmt.Errorf("ProcessDueTransfers: %w", err)
+		}
+		if !claimed {
+			s.logger.Debug("ProcessDueTransfers: scheduled transfer %d was claimed by another processor", st.ScheduledTransferID)
+			continue
+		}
+
+		st.Status = models.ScheduledTransferExecuted
+		transferErr := s.transfers.TransferFunds(st.FromAccountID, st.ToAccountID, st.Amount, st.Description.String, st.Notes.String)
+		switch {
+		case errors.Is(transferErr, ErrRequiresReview):
+			s.logger.Warn("Scheduled transfer %d is held for review: %v", st.ScheduledTransferID, transferErr)
+		case transferErr != nil:
+			st.Status = models.ScheduledTransferFailed
+			s.logger.Error("Scheduled transfer %d failed: %v", st.ScheduledTransferID, transferErr)
+		default:
+			s.logger.Info("Executed scheduled transfer %d: %.2f from account %d to account %d",
+				st.ScheduledTransferID, st.Amount, st.FromAccountID, st.ToAccountID)
+		}
+
+		if _, err := s.scheduledRepo.SetScheduledTransferStatus(st.ScheduledTransferID, st.Status); err != nil {
+			return processed, fmt.Errorf("ProcessDueTransfers: scheduled transfer %d is %s but stays %s: %w",
+				st.ScheduledTransferID, st.Status, models.ScheduledTransferProcessing, err)
+		}
+		processed = append(processed, st)
+	}
+	return processed, nil
+}
+
+// GetProjectedBalance returns the account's current balance with every pending scheduled transfer
+// due at or before until applied: outgoing ones subtracted with their fee and incoming ones added
+// in the account's currency, as quoted by the transaction service's current fees and rates.
+// Transfers that are overdue but not yet processed are included. Fees the account would collect
+// as the revenue account are not.
+func (s *scheduledTransferServiceImpl) GetProjectedBalance(accountID int64, until time.Time) (float64, error) {
+	account, err := s.accountRepo.GetAccountByID(accountID)
+	if err != nil {
+		return 0, fmt.Errorf("GetProjectedBalance: failed to get account %d: %w", accountID, err)
+	}
+
+	transfers, err := s.scheduledRepo.GetPendingScheduledTransfersForAccount(accountID, until)
+	if err != nil {
+		return 0, fmt.Errorf("GetProjectedBalance: %w", err)
+	}
+
+	projected := account.Balance
+	for _, st := range transfers {
+		quote := TransferQuote{Debited: st.Amount, Credited: st.Amount}
+		if s.transfers != nil {
+			quote, err = s.transfers.QuoteTransfer(st.FromAccountID, st.ToAccountID, st.Amount)
+			if err != nil {
+				return 0, fmt.Errorf("GetProjectedBalance: scheduled transfer %d: %w", st.ScheduledTransferID, err)
+			}
+		}
+		if st.FromAccountID == accountID {
+			projected -= quote.Debited
+		}
+		if st.ToAccountID == accountID {
+			projected += quote.Credited
+		}
+	}
+	s.logger.Debug("GetProjectedBalance: account %d balance %.2f, projected %.2f until %s across %d scheduled transfers",
+		accountID, account.Balance, projected, until.Format(time.RFC3339), len(transfers))
+	return projected, nil
+}
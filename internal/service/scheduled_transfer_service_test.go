@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// memoryScheduledTransfers is an in-memory ScheduledTransferRepository.
+type memoryScheduledTransfers struct {
+	transfers []models.ScheduledTransfer
+}
+
+func (m *memoryScheduledTransfers) CreateScheduledTransfer(st models.ScheduledTransfer) (int64, error) {
+	st.ScheduledTransferID = int64(len(m.transfers) + 1)
+	st.Status = models.ScheduledTransferPending
+	m.transfers = append(m.transfers, st)
+	return st.ScheduledTransferID, nil
+}
+
+func (m *memoryScheduledTransfers) GetPendingScheduledTransfersForAccount(accountID int64, until time.Time) ([]models.ScheduledTransfer, error) {
+	var pending []models.ScheduledTransfer
+	for _, st := range m.transfers {
+		if st.Status == models.ScheduledTransferPending && !st.ExecuteAt.After(until) && (st.FromAccountID == accountID || st.ToAccountID == accountID) {
+			pending = append(pending, st)
+		}
+	}
+	return pending, nil
+}
+
+func (m *memoryScheduledTransfers) GetDueScheduledTransfers(now time.Time) ([]models.ScheduledTransfer, error) {
+	var due []models.ScheduledTransfer
+	for _, st := range m.transfers {
+		if st.Status == models.ScheduledTransferPending && !st.ExecuteAt.After(now) {
+			due = append(due, st)
+		}
+	}
+	return due, nil
+}
+
+func (m *memoryScheduledTransfers) ClaimScheduledTransfer(id int64) (bool, error) {
+	if m.transfers[id-1].Status != models.ScheduledTransferPending {
+		return false, nil
+	}
+	m.transfers[id-1].Status = models.ScheduledTransferProcessing
+	return true, nil
+}
+
+func (m *memoryScheduledTransfers) SetScheduledTransferStatus(id int64, status string) (int64, error) {
+	m.transfers[id-1].Status = status
+	return 1, nil
+}
+
+// testGetProjectedBalance checks GetProjectedBalance over a mix of overdue, due, future and
+// already executed scheduled transfers stored in scheduled.
+func testGetProjectedBalance(t *testing.T, accounts repository.AccountRepository, scheduled repository.ScheduledTransferRepository) {
+	t.Helper()
+	svc := NewScheduledTransferService(accounts, scheduled, nil, nil)
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 50)
+	carol := createAccount(t, accounts, "Carol", 0)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	schedule := func(from, to int64, amount float64, executeAt time.Time) int64 {
+		t.Helper()
+		id, err := scheduled.CreateScheduledTransfer(models.ScheduledTransfer{FromAccountID: from, ToAccountID: to, Amount: amount, ExecuteAt: executeAt})
+		if err != nil {
+			t.Fatalf("CreateScheduledTransfer: %v", err)
+		}
+		return id
+	}
+	schedule(alice, bob, 10, now.Add(-time.Hour))                 // overdue, not yet processed
+	schedule(alice, carol, 25.5, now.Add(time.Hour))              // due within a day
+	schedule(bob, alice, 40, now.Add(2*time.Hour))                // incoming within a day
+	schedule(alice, bob, 60, now.Add(48*time.Hour))               // only within a week
+	schedule(bob, carol, 5, now.Add(time.Hour))                   // does not involve Alice
+	executed := schedule(alice, carol, 30, now.Add(-2*time.Hour)) // already executed
+	if _, err := scheduled.SetScheduledTransferStatus(executed, models.ScheduledTransferExecuted); err != nil {
+		t.Fatalf("SetScheduledTransferStatus: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		accountID int64
+		until     time.Time
+		want      float64
+	}{
+		{"before anything is due", alice, now.Add(-2 * time.Hour), 100},
+		{"overdue only", alice, now, 90},
+		{"within a day", alice, now.Add(24 * time.Hour), 104.5},
+		{"within a week", alice, now.Add(7 * 24 * time.Hour), 44.5},
+		{"at the execution time", alice, now.Add(time.Hour), 64.5},
+		{"counterparty", bob, now.Add(24 * time.Hour), 50 + 10 - 40 - 5},
+		{"receiver only", carol, now.Add(24 * time.Hour), 30.5},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.GetProjectedBalance(tt.accountID, tt.until)
+			if err != nil {
+				t.Fatalf("GetProjectedBalance: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetProjectedBalance(%d) = %.2f, want %.2f", tt.accountID, got, tt.want)
+			}
+		})
+	}
+
+	// Projecting changes nothing.
+	assertBalance(t, accounts, alice, 100)
+	if _, err := svc.GetProjectedBalance(carol+1000, now); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("GetProjectedBalance of a missing account error = %v, want %v", err, ErrAccountNotFound)
+	}
+}
+
+func TestGetProjectedBalance(t *testing.T) {
+	testGetProjectedBalance(t, repository.NewMemoryStore().Accounts(), &memoryScheduledTransfers{})
+}
+
+func TestGetProjectedBalanceIncludesFeesAndConversion(t *testing.T) {
+	store := repository.NewMemoryStore()
+	accounts := store.Accounts()
+	revenue := createAccount(t, accounts, "Bank", 0)
+	transfers := NewTransactionService(store, accounts, store.Transactions(), 0, nil,
+		WithTransferFee(FlatFee(2), revenue), WithExchangeRates(fixedRate{rate: 150}))
+	scheduled := &memoryScheduledTransfers{}
+	svc := NewScheduledTransferService(accounts, scheduled, transfers, nil)
+
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 50)
+	yen := insertAccount(t, store, models.Account{AccountHolder: "Yen", Balance: 1000, Currency: "JPY"})
+	now := time.Now().UTC()
+	for _, st := range []models.ScheduledTransfer{
+		{FromAccountID: alice, ToAccountID: bob, Amount: 10, ExecuteAt: now},
+		{FromAccountID: alice, ToAccountID: yen, Amount: 20, ExecuteAt: now},
+		{FromAccountID: bob, ToAccountID: alice, Amount: 5, ExecuteAt: now},
+	} {
+		if _, err := scheduled.CreateScheduledTransfer(st); err != nil {
+			t.Fatalf("CreateScheduledTransfer: %v", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name      string
+		accountID int64
+		want      float64
+	}{
+		{"sender pays each fee", alice, 100 - 12 - 22 + 5},
+		{"receiver and sender", bob, 50 + 10 - 7},
+		{"credited in the receiver's currency", yen, 1000 + 20*150},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.GetProjectedBalance(tt.accountID, now)
+			if err != nil {
+				t.Fatalf("GetProjectedBalance: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetProjectedBalance(%d) = %.2f, want %.2f", tt.accountID, got, tt.want)
+			}
+		})
+	}
+}
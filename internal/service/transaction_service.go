@@ -2,115 +2,633 @@ package service
 
 import (
 	"database/sql"
-	"fmt"
-	"log"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
 	"sql-golang-playground/repository"
 )
 
-// Define custom errors for the service layer
+// Define custom errors for the service layer.
+// The shared ones alias internal/util and repository so repository and service errors match
+// with errors.Is.
 var (
-    ErrInsufficientFunds   = errors.New("insufficient funds")
-    ErrAccountNotFound     = errors.New("account not found")
-    ErrAccountInactive     = errors.New("account is inactive")
-    ErrSameAccountTransfer = errors.New("cannot transfer funds to the same account")
-    ErrInvalidTransferAmount = errors.New("invalid transfer amount")
+	ErrInsufficientFunds     = util.ErrInsufficientFunds
+	ErrAccountNotFound       = util.ErrAccountNotFound
+	ErrAccountInactive       = util.ErrAccountInactive
+	ErrAccountFrozen         = util.ErrAccountFrozen
+	ErrSameAccountTransfer   = util.ErrSameAccountTransfer
+	ErrInvalidTransferAmount = util.ErrInvalidTransferAmount
+	ErrBelowMinimumBalance   = util.ErrBelowMinimumBalance
+	ErrAboveMaximumBalance   = util.ErrAboveMaximumBalance
+	ErrInvalidAccountHolder  = util.ErrInvalidAccountHolder
+	ErrTransactionNotFound   = repository.ErrTransactionNotFound
+	ErrNotReversible         = errors.New("transaction cannot be reversed")
+	ErrAlreadyReversed       = errors.New("transaction has already been reversed")
+	ErrProjectedOverdraft    = errors.New("batch would overdraw one or more accounts")
+	ErrRequiresReview        = errors.New("transfer exceeds the review threshold and is held for approval")
+	ErrNotPendingReview      = errors.New("transaction is not pending review")
+	ErrExchangeRateRequired  = errors.New("transfer between currencies requires an exchange rate")
 )
 
+// ReviewRequiredError is returned by TransferFunds when a transfer is held for review.
+// It matches ErrRequiresReview with errors.Is; TransactionID is the held transaction to approve.
+type ReviewRequiredError struct {
+	TransactionID int64
+}
+
+// Error implements error.
+func (e *ReviewRequiredError) Error() string {
+	return fmt.Sprintf("%s (transaction %d)", ErrRequiresReview, e.TransactionID)
+}
+
+// Is reports whether target is ErrRequiresReview.
+func (e *ReviewRequiredError) Is(target error) bool {
+	return target == ErrRequiresReview
+}
+
+// ExchangeRateProvider supplies the rate that converts an amount in currency from into currency
+// to, so that amount * rate is the converted amount.
+type ExchangeRateProvider interface {
+	ExchangeRate(from, to string) (float64, error)
+}
+
+// MySQL error numbers that indicate the transaction was aborted and can safely be retried.
+const (
+	mysqlErrLockWaitTimeout uint16 = 1205
+	mysqlErrDeadlock        uint16 = 1213
+)
+
+// retryBaseDelay is the backoff before the first retry; it doubles on each further attempt.
+const retryBaseDelay = 50 * time.Millisecond
+
 // TransactionService defines the interface for transaction-related business logic.
 type TransactionService interface {
 	TransferFunds(fromAccountID int64, toAccountID int64, amount float64, description string, notes string) error
+	ReverseTransaction(transactionID int64, reason string) error
+	RecomputeBalance(accountID int64) (float64, error)
+	VerifyBalance(accountID int64) (float64, error)
+	SimulateBatchTransfer(fromAccountID int64, transfers []Transfer) (map[int64]float64, error)
+	ApproveTransfer(transactionID int64) error
+	QuoteTransfer(fromAccountID, toAccountID int64, amount float64) (TransferQuote, error)
 }
 
 // transactionServiceImpl implements TransactionService.
 type transactionServiceImpl struct {
+	transactor      repository.Transactor
 	accountRepo     repository.AccountRepository
 	transactionRepo repository.TransactionRepository
+	maxRetries      int
+	outboxRepo      repository.OutboxRepository // nil means no outbox events are written
+	reviewThreshold float64              // transfers above it are held for review; 0 disables review
+	rates           ExchangeRateProvider // nil means cross-currency transfers are rejected
+	observer        BalanceObserver      // nil means balance changes are not reported
+	fees            FeeCalculator        // nil means transfers are free
+	feeAccountID    int64                // revenue account credited with fees
+	logger          util.Logger
+}
+
+// TransactionServiceOption configures optional transaction service behaviour.
+type TransactionServiceOption func(*transactionServiceImpl)
+
+// WithOutbox makes TransferFunds write a TRANSFER_COMPLETED event to outboxRepo in the same
+// database transaction as the transfer.
+func WithOutbox(outboxRepo repository.OutboxRepository) TransactionServiceOption {
+	return func(s *transactionServiceImpl) {
+		s.outboxRepo = outboxRepo
+	}
+}
+
+// WithReviewThreshold makes TransferFunds hold transfers of more than threshold for review
+// instead of executing them. Held transfers are executed with ApproveTransfer.
+func WithReviewThreshold(threshold float64) TransactionServiceOption {
+	return func(s *transactionServiceImpl) {
+		s.reviewThreshold = threshold
+	}
+}
+
+// WithExchangeRates lets TransferFunds move funds between accounts in different currencies,
+// converting the amount with rates. Without it such transfers fail with ErrExchangeRateRequired.
+func WithExchangeRates(rates ExchangeRateProvider) TransactionServiceOption {
+	return func(s *transactionServiceImpl) {
+		s.rates = rates
+	}
+}
+
+// WithBalanceObserver reports every balance change made by TransferFunds, ApproveTransfer and
+// ReverseTransaction to observer once the change has committed. The observer runs in the
+// background; a slow or failing observer does not affect the transfer.
+func WithBalanceObserver(observer BalanceObserver) TransactionServiceOption {
+	return func(s *transactionServiceImpl) {
+		s.observer = observer
+	}
+}
+
+// WithTransferFee charges the sender of every transfer the fee given by fees, in the same
+// database transaction as the transfer, and credits it to the revenue account revenueAccountID
+// with a separate FEE transaction. The sender must cover the amount and the fee together. Held
+// transfers are charged when approved. Transfers sent from the revenue account are free.
+func WithTransferFee(fees FeeCalculator, revenueAccountID int64) TransactionServiceOption {
+	return func(s *transactionServiceImpl) {
+		s.fees = fees
+		s.feeAccountID = revenueAccountID
+	}
 }
 
 // NewTransactionService creates a new transaction service.
-func NewTransactionService(accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository) TransactionService {
-	return &transactionServiceImpl{
+// Each transfer runs as one unit of work of transactor, with accountRepo and transactionRepo
+// bound to it through WithDBTX; use repository.NewSQLTransactor for MySQL.
+// maxRetries is how many times a transfer is retried after a MySQL deadlock or lock wait timeout.
+// A nil logger defaults to the standard library logger.
+func NewTransactionService(transactor repository.Transactor, accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository, maxRetries int, logger util.Logger, opts ...TransactionServiceOption) TransactionService {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	s := &transactionServiceImpl{
+		transactor:      transactor,
 		accountRepo:     accountRepo,
 		transactionRepo: transactionRepo,
+		maxRetries:      maxRetries,
+		logger:          util.LoggerOrDefault(logger),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// isRetryableMySQLError reports whether err is a MySQL deadlock or lock wait timeout.
+func isRetryableMySQLError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
 	}
+	return mysqlErr.Number == mysqlErrDeadlock || mysqlErr.Number == mysqlErrLockWaitTimeout
 }
 
 // TransferFunds handles the atomic transfer of funds between two accounts.
 // It logs the transaction and ensures proper error handling and rollback.
+// amount is in the sender's currency. When the receiver's currency differs, the amount is
+// converted with the WithExchangeRates provider and the credited amount and rate are recorded.
+// Attempts aborted by a deadlock or lock wait timeout are retried with exponential backoff.
+// Invalid input is rejected up front with ValidationErrors listing every problem.
+// With a review threshold set, larger transfers that pass the account, balance and currency
+// checks are recorded as PENDING_REVIEW without moving funds and a *ReviewRequiredError
+// (matching ErrRequiresReview) is returned.
 func (s *transactionServiceImpl) TransferFunds(fromAccountID int64, toAccountID int64, amount float64, description string, notes string) error {
-    if fromAccountID == toAccountID {
-        return ErrSameAccountTransfer
+    if errs := ValidateTransferRequest(TransferRequest{
+        FromAccountID: fromAccountID,
+        ToAccountID:   toAccountID,
+        Amount:        amount,
+        Description:   description,
+        Notes:         notes,
+    }); errs != nil {
+        return errs
     }
-    if amount <= 0 {
-        return ErrInvalidTransferAmount
+    if s.reviewThreshold > 0 && amount > s.reviewThreshold {
+        return s.holdForReview(fromAccountID, toAccountID, amount, description, notes)
     }
 
-    // In a real application, you'd likely pass a transaction context (e.g., *sql.Tx)
-    // down to the repository methods, or have a UnitOfWork pattern.
-    // For simplicity here, we'll assume repository methods handle their own transactions
-    // or that this service method is called within a larger transaction.
-    // However, for atomicity, this entire operation should be a single DB transaction.
-    // Since DBTX is passed to repos, we can pass a *sql.Tx to them.
+    return s.withRetry("TransferFunds", func() error {
+        return s.transferFundsOnce(fromAccountID, toAccountID, amount, description, notes)
+    })
+}
 
-    // This part needs to be refactored to use a transaction passed to the repositories.
-    // For now, I'll keep the direct DB interaction as it was, but this is a key area for improvement.
-    // The current DBTX interface allows passing *sql.DB or *sql.Tx.
-    // To make this truly atomic, the service would start a transaction and pass it to the repos.
+// withRetry runs attempt, retrying with exponential backoff while it fails with a
+// MySQL deadlock or lock wait timeout, up to s.maxRetries times.
+func (s *transactionServiceImpl) withRetry(operation string, attempt func() error) error {
+	var err error
+	for i := 0; ; i++ {
+		err = attempt()
+		if err == nil || !isRetryableMySQLError(err) || i >= s.maxRetries {
+			break
+		}
+		backoff := retryBaseDelay << i
+		s.logger.Warn("%s attempt %d of %d hit a retryable MySQL error, retrying in %v: %v", operation, i+1, s.maxRetries+1, backoff, err)
+		time.Sleep(backoff)
+	}
+	return err
+}
 
-    // For now, I'll simulate the transaction logic here, assuming the repos can work with a transaction.
-    // This requires the repository methods to accept a DBTX.
+// transferFundsOnce runs a single transfer attempt inside its own database transaction.
+func (s *transactionServiceImpl) transferFundsOnce(fromAccountID int64, toAccountID int64, amount float64, description string, notes string) error {
+    var changes *balanceChanges
+    err := s.transactor.WithTransaction(func(tx repository.DBTX) error {
+        accountRepo := s.accountRepo.WithDBTX(tx)
+        transactionRepo := s.transactionRepo.WithDBTX(tx)
 
+        changes = newBalanceChanges(s.observer)
+        fee, err := s.transferFee("TransferFunds", fromAccountID, toAccountID, amount)
+        if err != nil {
+            return err
+        }
+        fx, err := moveFunds(accountRepo, "TransferFunds", fromAccountID, toAccountID, amount, fee, s.rates, changes)
+        if err != nil {
+            return err
+        }
+
+        // Log the transaction
+        sqlFromID := sql.NullInt64{Int64: fromAccountID, Valid: true}
+        sqlToID := sql.NullInt64{Int64: toAccountID, Valid: true}
+        sqlDescription := sql.NullString{String: description, Valid: description != ""}
+        sqlNotes := sql.NullString{String: notes, Valid: notes != ""}
+
+        transactionID, err := transactionRepo.CreateTransactionWithNotes(sqlFromID, sqlToID, "TRANSFER", amount, sqlDescription, sqlNotes)
+        if err != nil {
+            return fmt.Errorf("TransferFunds: failed to log transaction: %w", err)
+        }
+        changes.setTransaction(transactionID)
+        if err := fx.record(transactionRepo, "TransferFunds", transactionID); err != nil {
+            return err
+        }
+        if err := s.chargeFee(accountRepo, transactionRepo, "TransferFunds", transactionID, fromAccountID, fee, changes); err != nil {
+            return err
+        }
+        return s.writeTransferEvent(tx, "TransferFunds", transactionID, fromAccountID, toAccountID, amount, description, notes)
+    })
+    if err != nil {
+        return err
+    }
+
+    s.logger.Info("Successfully transferred %.2f from account %d to account %d", amount, fromAccountID, toAccountID)
+    changes.notify(s.observer, s.logger)
+    return nil
+}
+
+// exchange is the currency conversion applied by moveFunds.
+type exchange struct {
+	crossCurrency bool
+	credited      float64 // amount credited to the receiver, in its currency
+	rate          float64
+}
+
+// record stores a cross-currency conversion on the transfer's transaction row.
+func (fx exchange) record(transactionRepo repository.TransactionRepository, op string, transactionID int64) error {
+	if !fx.crossCurrency {
+		return nil
+	}
+	if _, err := transactionRepo.SetTransactionExchange(transactionID, fx.credited, fx.rate); err != nil {
+		return fmt.Errorf("%s: failed to record exchange rate: %w", op, err)
+	}
+	return nil
+}
+
+// convert works out how much of amount, in from's currency, to credit to to. Accounts in the
+// same currency need no rate; otherwise rates must be set and return a positive rate. The
+// credited amount is rounded to to's minor unit, at most cents since balances are stored with
+// two decimals.
+func convert(op string, from, to models.Account, amount float64, rates ExchangeRateProvider) (exchange, error) {
+	if from.Currency == to.Currency {
+		return exchange{credited: amount}, nil
+	}
+	if rates == nil {
+		return exchange{}, fmt.Errorf("%s: %w (%s to %s)", op, ErrExchangeRateRequired, from.Currency, to.Currency)
+	}
+	rate, err := rates.ExchangeRate(from.Currency, to.Currency)
+	if err != nil {
+		return exchange{}, fmt.Errorf("%s: failed to get %s/%s exchange rate: %w", op, from.Currency, to.Currency, err)
+	}
+	if rate <= 0 {
+		return exchange{}, fmt.Errorf("%s: %w: invalid %s/%s rate %v", op, ErrExchangeRateRequired, from.Currency, to.Currency, rate)
+	}
+	unit := math.Pow10(min(util.CurrencyDecimals(to.Currency), 2))
+	credited := math.Round(amount*rate*unit) / unit
+	return exchange{crossCurrency: true, credited: credited, rate: rate}, nil
+}
+
+// checkTransfer runs the read-only checks of a transfer: both accounts exist and are active, the
+// sender can cover amount plus fee, and amount can be converted to the receiver's currency. It
+// returns the conversion to apply; op prefixes errors.
+func checkTransfer(accountRepo repository.AccountRepository, op string, fromAccountID, toAccountID int64, amount, fee float64, rates ExchangeRateProvider) (exchange, error) {
     // Check sender's account status and balance
-    fromAccount, err := s.accountRepo.GetAccountByID(fromAccountID)
+    fromAccount, err := accountRepo.GetAccountByID(fromAccountID)
     if err != nil {
-        if errors.Is(err, sql.ErrNoRows) { // Check for specific error from repository
-            return fmt.Errorf("TransferFunds: sender %w (ID: %d)", ErrAccountNotFound, fromAccountID)
+        if errors.Is(err, ErrAccountNotFound) {
+            return exchange{}, fmt.Errorf("%s: sender %w (ID: %d)", op, ErrAccountNotFound, fromAccountID)
         }
-        return fmt.Errorf("TransferFunds: failed to get sender account (ID: %d): %w", fromAccountID, err)
+        return exchange{}, fmt.Errorf("%s: failed to get sender account (ID: %d): %w", op, fromAccountID, err)
     }
-    if fromAccount.IsDeleted {
-        return fmt.Errorf("TransferFunds: sender %w (ID: %d)", ErrAccountInactive, fromAccountID)
+    if err := checkTransferable(op, "sender", fromAccount); err != nil {
+        return exchange{}, err
     }
-    if fromAccount.Balance < amount {
-        return fmt.Errorf("TransferFunds: sender %w (ID: %d, Balance: %.2f, Amount: %.2f)", ErrInsufficientFunds, fromAccountID, fromAccount.Balance, amount)
+    if fromAccount.AvailableFunds() < amount+fee {
+        return exchange{}, fmt.Errorf("%s: sender %w (ID: %d, Balance: %.2f, Overdraft: %.2f, Amount: %.2f, Fee: %.2f)", op, ErrInsufficientFunds, fromAccountID, fromAccount.Balance, fromAccount.OverdraftLimit, amount, fee)
     }
 
     // Check receiver's account status
-    toAccount, err := s.accountRepo.GetAccountByID(toAccountID)
+    toAccount, err := accountRepo.GetAccountByID(toAccountID)
     if err != nil {
-        if errors.Is(err, sql.ErrNoRows) {
-            return fmt.Errorf("TransferFunds: receiver %w (ID: %d)", ErrAccountNotFound, toAccountID)
+        if errors.Is(err, ErrAccountNotFound) {
+            return exchange{}, fmt.Errorf("%s: receiver %w (ID: %d)", op, ErrAccountNotFound, toAccountID)
         }
-        return fmt.Errorf("TransferFunds: failed to get receiver account (ID: %d): %w", toAccountID, err)
+        return exchange{}, fmt.Errorf("%s: failed to get receiver account (ID: %d): %w", op, toAccountID, err)
     }
-    if toAccount.IsDeleted {
-        return fmt.Errorf("TransferFunds: receiver %w (ID: %d)", ErrAccountInactive, toAccountID)
+    if err := checkTransferable(op, "receiver", toAccount); err != nil {
+        return exchange{}, err
     }
 
-    // Perform balance adjustments
-    _, err = s.accountRepo.AdjustAccountBalance(fromAccountID, -amount)
+    return convert(op, fromAccount, toAccount, amount, rates)
+}
+
+// moveFunds checks the transfer with checkTransfer, then moves amount from the sender to the
+// receiver, converted to the receiver's currency if needed. The fee itself is not moved; see
+// chargeFee. accountRepo must be bound to the caller's transaction; op prefixes errors. The
+// adjustments are recorded in changes.
+func moveFunds(accountRepo repository.AccountRepository, op string, fromAccountID, toAccountID int64, amount, fee float64, rates ExchangeRateProvider, changes *balanceChanges) (exchange, error) {
+    fx, err := checkTransfer(accountRepo, op, fromAccountID, toAccountID, amount, fee, rates)
     if err != nil {
-        return fmt.Errorf("TransferFunds: failed to decrement sender's balance (ID: %d): %w", fromAccountID, err)
+        return exchange{}, err
     }
 
-    _, err = s.accountRepo.AdjustAccountBalance(toAccountID, amount)
-    if err != nil {
-        return fmt.Errorf("TransferFunds: failed to increment receiver's balance (ID: %d): %w", toAccountID, err)
+    // Perform balance adjustments
+    if err := changes.adjust(accountRepo, fromAccountID, -amount); err != nil {
+        return exchange{}, fmt.Errorf("%s: failed to decrement sender's balance (ID: %d): %w", op, fromAccountID, err)
+    }
+    if err := changes.adjust(accountRepo, toAccountID, fx.credited); err != nil {
+        return exchange{}, fmt.Errorf("%s: failed to increment receiver's balance (ID: %d): %w", op, toAccountID, err)
     }
+    return fx, nil
+}
 
-    // Log the transaction
-    sqlFromID := sql.NullInt64{Int64: fromAccountID, Valid: true}
-    sqlToID := sql.NullInt64{Int64: toAccountID, Valid: true}
-    sqlDescription := sql.NullString{String: description, Valid: description != ""}
-    sqlNotes := sql.NullString{String: notes, Valid: notes != ""}
+// checkTransferable rejects a CLOSED account with ErrAccountInactive and a FROZEN one with
+// ErrAccountFrozen; role names the account's side in error messages.
+func checkTransferable(op, role string, account models.Account) error {
+	switch account.Status {
+	case models.AccountStatusClosed:
+		return fmt.Errorf("%s: %s %w (ID: %d)", op, role, ErrAccountInactive, account.AccountID)
+	case models.AccountStatusFrozen:
+		return fmt.Errorf("%s: %s %w (ID: %d)", op, role, ErrAccountFrozen, account.AccountID)
+	}
+	return nil
+}
 
-    _, err = s.transactionRepo.CreateTransactionWithNotes(sqlFromID, sqlToID, "TRANSFER", amount, sqlDescription, sqlNotes)
-    if err != nil {
-        return fmt.Errorf("TransferFunds: failed to log transaction: %w", err)
-    }
+// writeTransferEvent records a TRANSFER_COMPLETED outbox event in tx when the outbox is enabled.
+func (s *transactionServiceImpl) writeTransferEvent(tx repository.DBTX, op string, transactionID, fromAccountID, toAccountID int64, amount float64, description, notes string) error {
+	if s.outboxRepo == nil {
+		return nil
+	}
+	payload, err := json.Marshal(models.TransferEvent{
+		TransactionID: transactionID,
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+		Description:   description,
+		Notes:         notes,
+		OccurredAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: failed to encode outbox event: %w", op, err)
+	}
+	if _, err := s.outboxRepo.WithDBTX(tx).CreateEvent(models.OutboxEventTransferCompleted, payload); err != nil {
+		return fmt.Errorf("%s: failed to write outbox event: %w", op, err)
+	}
+	return nil
+}
 
-    log.Printf("INFO: Successfully transferred %.2f from account %d to account %d", amount, fromAccountID, toAccountID)
-    return nil
+// holdForReview records a transfer above the review threshold as PENDING_REVIEW without
+// moving funds, and returns a *ReviewRequiredError carrying its ID. A transfer that could not be
+// approved as things stand, e.g. from a frozen account or for more than the sender has, is
+// rejected with the error TransferFunds would return instead of being held.
+func (s *transactionServiceImpl) holdForReview(fromAccountID int64, toAccountID int64, amount float64, description string, notes string) error {
+	fee, err := s.transferFee("TransferFunds", fromAccountID, toAccountID, amount)
+	if err != nil {
+		return err
+	}
+	if _, err := checkTransfer(s.accountRepo, "TransferFunds", fromAccountID, toAccountID, amount, fee, s.rates); err != nil {
+		return err
+	}
+
+	sqlFromID := sql.NullInt64{Int64: fromAccountID, Valid: true}
+	sqlToID := sql.NullInt64{Int64: toAccountID, Valid: true}
+	sqlDescription := sql.NullString{String: description, Valid: description != ""}
+	sqlNotes := sql.NullString{String: notes, Valid: notes != ""}
+
+	transactionID, err := s.transactionRepo.CreatePendingReviewTransaction(sqlFromID, sqlToID, "TRANSFER", amount, sqlDescription, sqlNotes)
+	if err != nil {
+		return fmt.Errorf("TransferFunds: failed to record transfer for review: %w", err)
+	}
+	s.logger.Warn("Transfer of %.2f from account %d to account %d exceeds review threshold %.2f; held as transaction %d", amount, fromAccountID, toAccountID, s.reviewThreshold, transactionID)
+	return &ReviewRequiredError{TransactionID: transactionID}
+}
+
+// ApproveTransfer executes a transfer held for review: the funds move and the transaction
+// becomes COMPLETED in one database transaction. Approving a transaction that is not pending
+// review returns ErrNotPendingReview.
+func (s *transactionServiceImpl) ApproveTransfer(transactionID int64) error {
+	return s.withRetry("ApproveTransfer", func() error {
+		return s.approveTransferOnce(transactionID)
+	})
+}
+
+// approveTransferOnce runs a single approval attempt inside its own database transaction.
+func (s *transactionServiceImpl) approveTransferOnce(transactionID int64) error {
+	var held models.Transaction
+	var changes *balanceChanges
+	err := s.transactor.WithTransaction(func(tx repository.DBTX) error {
+		accountRepo := s.accountRepo.WithDBTX(tx)
+		transactionRepo := s.transactionRepo.WithDBTX(tx)
+
+		changes = newBalanceChanges(s.observer)
+
+		var err error
+		held, err = transactionRepo.GetTransactionByID(transactionID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("ApproveTransfer: %w (ID: %d)", ErrTransactionNotFound, transactionID)
+			}
+			return fmt.Errorf("ApproveTransfer: failed to get transaction (ID: %d): %w", transactionID, err)
+		}
+		if held.Status != models.TransactionStatusPendingReview || !held.FromAccountID.Valid || !held.ToAccountID.Valid {
+			return fmt.Errorf("ApproveTransfer: %w (ID: %d, Status: %s)", ErrNotPendingReview, transactionID, held.Status)
+		}
+
+		// Claiming the row first serializes concurrent approvals of the same transfer.
+		claimed, err := transactionRepo.MarkTransactionCompleted(transactionID)
+		if err != nil {
+			return fmt.Errorf("ApproveTransfer: %w", err)
+		}
+		if claimed == 0 {
+			return fmt.Errorf("ApproveTransfer: %w (ID: %d)", ErrNotPendingReview, transactionID)
+		}
+
+		fromAccountID, toAccountID := held.FromAccountID.Int64, held.ToAccountID.Int64
+		fee, err := s.transferFee("ApproveTransfer", fromAccountID, toAccountID, held.Amount)
+		if err != nil {
+			return err
+		}
+		fx, err := moveFunds(accountRepo, "ApproveTransfer", fromAccountID, toAccountID, held.Amount, fee, s.rates, changes)
+		if err != nil {
+			return err
+		}
+		changes.setTransaction(transactionID)
+		if err := fx.record(transactionRepo, "ApproveTransfer", transactionID); err != nil {
+			return err
+		}
+		if err := s.chargeFee(accountRepo, transactionRepo, "ApproveTransfer", transactionID, fromAccountID, fee, changes); err != nil {
+			return err
+		}
+		return s.writeTransferEvent(tx, "ApproveTransfer", transactionID, fromAccountID, toAccountID, held.Amount, held.Description.String, held.Notes.String)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Approved transfer %d: moved %.2f from account %d to account %d", transactionID, held.Amount, held.FromAccountID.Int64, held.ToAccountID.Int64)
+	changes.notify(s.observer, s.logger)
+	return nil
+}
+
+// ReverseTransaction undoes a TRANSFER by moving its amount back from the receiver to the
+// sender and recording a new TRANSFER linked to the original, all in one database transaction.
+// A transaction can be reversed only once; later attempts return ErrAlreadyReversed.
+func (s *transactionServiceImpl) ReverseTransaction(transactionID int64, reason string) error {
+	return s.withRetry("ReverseTransaction", func() error {
+		return s.reverseTransactionOnce(transactionID, reason)
+	})
+}
+
+// reverseTransactionOnce runs a single reversal attempt inside its own database transaction.
+func (s *transactionServiceImpl) reverseTransactionOnce(transactionID int64, reason string) error {
+	var original models.Transaction
+	var reversalID int64
+	var changes *balanceChanges
+	err := s.transactor.WithTransaction(func(tx repository.DBTX) error {
+		accountRepo := s.accountRepo.WithDBTX(tx)
+		transactionRepo := s.transactionRepo.WithDBTX(tx)
+
+		changes = newBalanceChanges(s.observer)
+
+		// Locking the original makes a concurrent reversal of it wait, then see this one's
+		// reversal row in IsTransactionReversed below instead of reversing it twice.
+		var err error
+		original, err = transactionRepo.GetTransactionByIDForUpdate(transactionID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("ReverseTransaction: %w (ID: %d)", ErrTransactionNotFound, transactionID)
+			}
+			return fmt.Errorf("ReverseTransaction: failed to get transaction (ID: %d): %w", transactionID, err)
+		}
+		if original.TransactionType != "TRANSFER" || !original.FromAccountID.Valid || !original.ToAccountID.Valid {
+			return fmt.Errorf("ReverseTransaction: %w: only transfers between two accounts can be reversed (ID: %d, Type: %s)", ErrNotReversible, transactionID, original.TransactionType)
+		}
+		if original.Status != models.TransactionStatusCompleted {
+			return fmt.Errorf("ReverseTransaction: %w: transaction %d has status %s and never moved funds", ErrNotReversible, transactionID, original.Status)
+		}
+		if original.ReversalOf.Valid {
+			return fmt.Errorf("ReverseTransaction: %w: transaction %d is itself a reversal of %d", ErrNotReversible, transactionID, original.ReversalOf.Int64)
+		}
+		if original.ExchangeRate.Valid {
+			return fmt.Errorf("ReverseTransaction: %w: transaction %d is a cross-currency transfer", ErrNotReversible, transactionID)
+		}
+
+		reversed, err := transactionRepo.IsTransactionReversed(transactionID)
+		if err != nil {
+			return fmt.Errorf("ReverseTransaction: %w", err)
+		}
+		if reversed {
+			return fmt.Errorf("ReverseTransaction: %w (ID: %d)", ErrAlreadyReversed, transactionID)
+		}
+
+		// The original receiver now pays the amount back to the original sender.
+		payerID, payeeID := original.ToAccountID.Int64, original.FromAccountID.Int64
+		payer, err := accountRepo.GetAccountByID(payerID)
+		if err != nil {
+			return fmt.Errorf("ReverseTransaction: failed to get original receiver (ID: %d): %w", payerID, err)
+		}
+		if err := checkTransferable("ReverseTransaction", "original receiver", payer); err != nil {
+			return err
+		}
+		if payer.AvailableFunds() < original.Amount {
+			return fmt.Errorf("ReverseTransaction: original receiver %w (ID: %d, Balance: %.2f, Overdraft: %.2f, Amount: %.2f)", ErrInsufficientFunds, payerID, payer.Balance, payer.OverdraftLimit, original.Amount)
+		}
+		payee, err := accountRepo.GetAccountByID(payeeID)
+		if err != nil {
+			return fmt.Errorf("ReverseTransaction: failed to get original sender (ID: %d): %w", payeeID, err)
+		}
+		if err := checkTransferable("ReverseTransaction", "original sender", payee); err != nil {
+			return err
+		}
+
+		if err := changes.adjust(accountRepo, payerID, -original.Amount); err != nil {
+			return fmt.Errorf("ReverseTransaction: failed to decrement original receiver's balance (ID: %d): %w", payerID, err)
+		}
+		if err := changes.adjust(accountRepo, payeeID, original.Amount); err != nil {
+			return fmt.Errorf("ReverseTransaction: failed to increment original sender's balance (ID: %d): %w", payeeID, err)
+		}
+
+		description := sql.NullString{String: fmt.Sprintf("Reversal of transaction %d", transactionID), Valid: true}
+		sqlReason := sql.NullString{String: reason, Valid: reason != ""}
+		reversalID, err = transactionRepo.CreateReversalTransaction(original, description, sqlReason)
+		if errors.Is(err, repository.ErrDuplicateEntry) {
+			// A concurrent reversal won the race on the unique reversal_of index.
+			return fmt.Errorf("ReverseTransaction: %w (ID: %d)", ErrAlreadyReversed, transactionID)
+		}
+		if err != nil {
+			return fmt.Errorf("ReverseTransaction: failed to log reversal: %w", err)
+		}
+		changes.setTransaction(reversalID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Reversed transaction %d with transaction %d (%.2f from account %d to account %d)", transactionID, reversalID, original.Amount, original.ToAccountID.Int64, original.FromAccountID.Int64)
+	changes.notify(s.observer, s.logger)
+	return nil
+}
+
+// RecomputeBalance resets an account's stored balance to the signed sum of its transactions
+// and returns the corrected balance. Opening balances are only included if they were recorded
+// as transactions. The account row is locked first so concurrent transfers cannot slip in
+// between summing the ledger and writing the result.
+func (s *transactionServiceImpl) RecomputeBalance(accountID int64) (float64, error) {
+	var previous, corrected float64
+	err := s.transactor.WithTransaction(func(tx repository.DBTX) error {
+		accountRepo := s.accountRepo.WithDBTX(tx)
+		transactionRepo := s.transactionRepo.WithDBTX(tx)
+
+		account, err := accountRepo.GetAccountByIDForUpdate(accountID)
+		if err != nil {
+			return fmt.Errorf("RecomputeBalance: %w", err)
+		}
+		previous = account.Balance
+
+		corrected, err = transactionRepo.GetLedgerBalance(accountID)
+		if err != nil {
+			return fmt.Errorf("RecomputeBalance: %w", err)
+		}
+		if _, err := accountRepo.SetAccountBalance(accountID, corrected); err != nil {
+			return fmt.Errorf("RecomputeBalance: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if previous != corrected {
+		s.logger.Warn("Recomputed balance of account %d from %.2f to %.2f", accountID, previous, corrected)
+	}
+	return corrected, nil
+}
+
+// VerifyBalance returns the stored balance minus the balance implied by the ledger without
+// changing anything; zero means the account is consistent.
+func (s *transactionServiceImpl) VerifyBalance(accountID int64) (float64, error) {
+	account, err := s.accountRepo.GetAccountByID(accountID)
+	if err != nil {
+		return 0, fmt.Errorf("VerifyBalance: %w", err)
+	}
+	ledger, err := s.transactionRepo.GetLedgerBalance(accountID)
+	if err != nil {
+		return 0, fmt.Errorf("VerifyBalance: %w", err)
+	}
+	return account.Balance - ledger, nil
 }
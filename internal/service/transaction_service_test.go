@@ -0,0 +1,490 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+func TestIsRetryableMySQLError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"deadlock", &mysql.MySQLError{Number: mysqlErrDeadlock}, true},
+		{"lock wait timeout", &mysql.MySQLError{Number: mysqlErrLockWaitTimeout}, true},
+		{"wrapped deadlock", fmt.Errorf("TransferFunds: %w", &mysql.MySQLError{Number: mysqlErrDeadlock}), true},
+		{"duplicate entry", &mysql.MySQLError{Number: 1062}, false},
+		{"insufficient funds", ErrInsufficientFunds, false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableMySQLError(tt.err); got != tt.want {
+				t.Errorf("isRetryableMySQLError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	deadlock := fmt.Errorf("TransferFunds: %w", &mysql.MySQLError{Number: mysqlErrDeadlock, Message: "Deadlock found"})
+	tests := []struct {
+		name       string
+		maxRetries int
+		errs       []error // returned by successive attempts; nil after the list runs out
+		wantCalls  int
+		wantErr    error
+	}{
+		{"succeeds first time", 3, nil, 1, nil},
+		{"retries deadlocks until success", 3, []error{deadlock, deadlock}, 3, nil},
+		{"gives up after max retries", 1, []error{deadlock, deadlock, deadlock}, 2, deadlock},
+		{"no retries configured", 0, []error{deadlock}, 1, deadlock},
+		{"does not retry business errors", 3, []error{fmt.Errorf("TransferFunds: %w", util.ErrInsufficientFunds)}, 1, util.ErrInsufficientFunds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &transactionServiceImpl{maxRetries: tt.maxRetries, logger: util.LoggerOrDefault(nil)}
+			calls := 0
+			err := s.withRetry("TransferFunds", func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("attempts = %d, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// newMemoryTransactionService returns a transaction service backed by a fresh MemoryStore,
+// along with the store's account repository for setting up and checking balances.
+func newMemoryTransactionService(t *testing.T, opts ...TransactionServiceOption) (TransactionService, *repository.MemoryStore, repository.AccountRepository) {
+	t.Helper()
+	store := repository.NewMemoryStore()
+	accounts := store.Accounts()
+	svc := NewTransactionService(store, accounts, store.Transactions(), 0, util.LoggerOrDefault(nil), opts...)
+	return svc, store, accounts
+}
+
+// createAccount opens an account with balance and returns its ID.
+func createAccount(t *testing.T, accounts repository.AccountRepository, holder string, balance float64) int64 {
+	t.Helper()
+	id, _, err := accounts.CreateAccount(holder, balance)
+	if err != nil {
+		t.Fatalf("CreateAccount(%q): %v", holder, err)
+	}
+	return id
+}
+
+// assertBalance fails the test unless the account's stored balance is want.
+func assertBalance(t *testing.T, accounts repository.AccountRepository, accountID int64, want float64) {
+	t.Helper()
+	acc, err := accounts.GetAccountByIDIncludingDeleted(accountID)
+	if err != nil {
+		t.Fatalf("GetAccountByIDIncludingDeleted(%d): %v", accountID, err)
+	}
+	if acc.Balance != want {
+		t.Errorf("account %d balance = %.2f, want %.2f", accountID, acc.Balance, want)
+	}
+}
+
+// insertAccount seeds acc, e.g. with a currency or balance limits CreateAccount does not set,
+// and returns its ID. The account is ACTIVE in USD unless acc says otherwise.
+func insertAccount(t *testing.T, store *repository.MemoryStore, acc models.Account) int64 {
+	t.Helper()
+	fixtures := store.Fixtures()
+	if acc.AccountNumber == "" {
+		existing, err := fixtures.GetAllAccountsForFixtures()
+		if err != nil {
+			t.Fatalf("GetAllAccountsForFixtures: %v", err)
+		}
+		acc.AccountNumber = fmt.Sprintf("SEED%06d", len(existing)+1)
+	}
+	if acc.Currency == "" {
+		acc.Currency = models.DefaultCurrency
+	}
+	if acc.Status == "" {
+		acc.Status = models.AccountStatusActive
+	}
+	id, err := fixtures.InsertAccountFixture(acc)
+	if err != nil {
+		t.Fatalf("InsertAccountFixture(%q): %v", acc.AccountHolder, err)
+	}
+	return id
+}
+
+func TestTransferFunds(t *testing.T) {
+	svc, store, accounts := newMemoryTransactionService(t)
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+
+	if err := svc.TransferFunds(alice, bob, 30, "rent", "March"); err != nil {
+		t.Fatalf("TransferFunds: %v", err)
+	}
+	assertBalance(t, accounts, alice, 70)
+	assertBalance(t, accounts, bob, 50)
+
+	history, err := store.Transactions().GetTransactionsForAccount(bob, nil)
+	if err != nil {
+		t.Fatalf("GetTransactionsForAccount: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("got %d transactions for the receiver, want 1", len(history))
+	}
+	if got := history[0]; got.TransactionType != "TRANSFER" || got.Amount != 30 || got.Description.String != "rent" || got.Notes.String != "March" {
+		t.Errorf("recorded transaction = %+v, want a TRANSFER of 30.00 described as rent with notes March", got)
+	}
+}
+
+func TestTransferFundsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, accounts repository.AccountRepository, from, to int64)
+		from    func(from, to int64) int64
+		to      func(from, to int64) int64
+		amount  float64
+		wantErr error
+	}{
+		{"insufficient funds", nil, nil, nil, 500, ErrInsufficientFunds},
+		{"same account", nil, nil, func(from, _ int64) int64 { return from }, 10, ErrSameAccountTransfer},
+		{"zero amount", nil, nil, nil, 0, ErrInvalidTransferAmount},
+		{"missing sender", nil, func(_, _ int64) int64 { return 999 }, nil, 10, ErrAccountNotFound},
+		{"missing receiver", nil, nil, func(_, _ int64) int64 { return 999 }, 10, ErrAccountNotFound},
+		{"closed receiver", func(t *testing.T, accounts repository.AccountRepository, _, to int64) {
+			if _, err := accounts.SoftDeleteAccount(to); err != nil {
+				t.Fatalf("SoftDeleteAccount: %v", err)
+			}
+		}, nil, nil, 10, ErrAccountNotFound},
+		{"frozen sender", func(t *testing.T, accounts repository.AccountRepository, from, _ int64) {
+			if _, err := accounts.FreezeAccount(from); err != nil {
+				t.Fatalf("FreezeAccount: %v", err)
+			}
+		}, nil, nil, 10, ErrAccountFrozen},
+		{"frozen receiver", func(t *testing.T, accounts repository.AccountRepository, _, to int64) {
+			if _, err := accounts.FreezeAccount(to); err != nil {
+				t.Fatalf("FreezeAccount: %v", err)
+			}
+		}, nil, nil, 10, ErrAccountFrozen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, accounts := newMemoryTransactionService(t)
+			from := createAccount(t, accounts, "Alice", 100)
+			to := createAccount(t, accounts, "Bob", 20)
+			if tt.setup != nil {
+				tt.setup(t, accounts, from, to)
+			}
+			fromID, toID := from, to
+			if tt.from != nil {
+				fromID = tt.from(from, to)
+			}
+			if tt.to != nil {
+				toID = tt.to(from, to)
+			}
+
+			err := svc.TransferFunds(fromID, toID, tt.amount, "", "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TransferFunds error = %v, want %v", err, tt.wantErr)
+			}
+			assertBalance(t, accounts, from, 100)
+			assertBalance(t, accounts, to, 20)
+			if history, _ := store.Transactions().GetTransactionsForAccount(from, nil); len(history) != 0 {
+				t.Errorf("failed transfer left %d transactions", len(history))
+			}
+		})
+	}
+}
+
+func TestTransferFundsRollsBackOnLaterFailure(t *testing.T) {
+	// The revenue account does not exist, so charging the fee fails after moveFunds has
+	// already adjusted both balances.
+	svc, store, accounts := newMemoryTransactionService(t, WithTransferFee(FlatFee(1), 999))
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+
+	if err := svc.TransferFunds(alice, bob, 30, "", ""); !errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("TransferFunds error = %v, want %v", err, ErrAccountNotFound)
+	}
+	assertBalance(t, accounts, alice, 100)
+	assertBalance(t, accounts, bob, 20)
+	if history, _ := store.Transactions().GetTransactionsForAccount(alice, nil); len(history) != 0 {
+		t.Errorf("rolled back transfer left %d transactions", len(history))
+	}
+}
+
+func TestTransferFundsWithFee(t *testing.T) {
+	store := repository.NewMemoryStore()
+	accounts := store.Accounts()
+	revenue := createAccount(t, accounts, "Bank", 0)
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+	svc := NewTransactionService(store, accounts, store.Transactions(), 0, nil, WithTransferFee(FlatFee(2.5), revenue))
+
+	if err := svc.TransferFunds(alice, bob, 30, "", ""); err != nil {
+		t.Fatalf("TransferFunds: %v", err)
+	}
+	assertBalance(t, accounts, alice, 67.5)
+	assertBalance(t, accounts, bob, 50)
+	assertBalance(t, accounts, revenue, 2.5)
+
+	// The sender must cover the amount and the fee together.
+	if err := svc.TransferFunds(alice, bob, 66, "", ""); !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("TransferFunds error = %v, want %v", err, ErrInsufficientFunds)
+	}
+	assertBalance(t, accounts, alice, 67.5)
+}
+
+// lastTransactionID returns the ID of the newest transaction touching accountID.
+func lastTransactionID(t *testing.T, store *repository.MemoryStore, accountID int64) int64 {
+	t.Helper()
+	history, err := store.Transactions().GetTransactionsForAccount(accountID, nil)
+	if err != nil || len(history) == 0 {
+		t.Fatalf("GetTransactionsForAccount(%d) = %d transactions, %v", accountID, len(history), err)
+	}
+	return history[0].TransactionID
+}
+
+func TestReverseTransaction(t *testing.T) {
+	svc, store, accounts := newMemoryTransactionService(t)
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+	if err := svc.TransferFunds(alice, bob, 30, "", ""); err != nil {
+		t.Fatalf("TransferFunds: %v", err)
+	}
+	transferID := lastTransactionID(t, store, alice)
+
+	if err := svc.ReverseTransaction(transferID, "sent by mistake"); err != nil {
+		t.Fatalf("ReverseTransaction: %v", err)
+	}
+	assertBalance(t, accounts, alice, 100)
+	assertBalance(t, accounts, bob, 20)
+	reversed, err := store.Transactions().IsTransactionReversed(transferID)
+	if err != nil || !reversed {
+		t.Errorf("IsTransactionReversed = %v, %v; want true, nil", reversed, err)
+	}
+
+	// Reversing again must neither move funds nor record a second reversal.
+	if err := svc.ReverseTransaction(transferID, ""); !errors.Is(err, ErrAlreadyReversed) {
+		t.Fatalf("second ReverseTransaction error = %v, want %v", err, ErrAlreadyReversed)
+	}
+	assertBalance(t, accounts, alice, 100)
+	assertBalance(t, accounts, bob, 20)
+
+	// A reversal cannot itself be reversed.
+	reversalID := lastTransactionID(t, store, alice)
+	if err := svc.ReverseTransaction(reversalID, ""); !errors.Is(err, ErrNotReversible) {
+		t.Errorf("ReverseTransaction of a reversal error = %v, want %v", err, ErrNotReversible)
+	}
+}
+
+func TestReverseTransactionErrors(t *testing.T) {
+	svc, store, accounts := newMemoryTransactionService(t)
+	alice := createAccount(t, accounts, "Alice", 100)
+	depositID, err := store.Transactions().CreateTransaction(sql.NullInt64{}, sql.NullInt64{Int64: alice, Valid: true}, "DEPOSIT", 50, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+
+	if err := svc.ReverseTransaction(depositID, ""); !errors.Is(err, ErrNotReversible) {
+		t.Errorf("ReverseTransaction of a deposit error = %v, want %v", err, ErrNotReversible)
+	}
+	if err := svc.ReverseTransaction(999, ""); !errors.Is(err, ErrTransactionNotFound) || !errors.Is(err, repository.ErrTransactionNotFound) {
+		t.Errorf("ReverseTransaction of a missing transaction error = %v, want %v", err, ErrTransactionNotFound)
+	}
+	assertBalance(t, accounts, alice, 100)
+}
+
+func TestHeldTransferExcludedUntilApproved(t *testing.T) {
+	svc, store, accounts := newMemoryTransactionService(t, WithReviewThreshold(50))
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 20)
+	transactions := store.Transactions()
+
+	err := svc.TransferFunds(alice, bob, 80, "", "")
+	var review *ReviewRequiredError
+	if !errors.As(err, &review) || !errors.Is(err, ErrRequiresReview) {
+		t.Fatalf("TransferFunds error = %v, want a *ReviewRequiredError", err)
+	}
+	assertBalance(t, accounts, alice, 100)
+	assertBalance(t, accounts, bob, 20)
+
+	// Until approved, the held transfer has moved no funds and must not show up as history or
+	// as something to reconcile.
+	if history, _ := transactions.GetTransactionsForAccount(alice, nil); len(history) != 0 {
+		t.Errorf("GetTransactionsForAccount returned %d transactions, want the held transfer excluded", len(history))
+	}
+	if all, _ := transactions.GetAllTransactionsForReconciliation(); len(all) != 0 {
+		t.Errorf("GetAllTransactionsForReconciliation returned %d transactions, want the held transfer excluded", len(all))
+	}
+	if _, total, _ := transactions.ListTransactions(repository.TransactionFilter{}); total != 0 {
+		t.Errorf("ListTransactions total = %d, want the held transfer excluded", total)
+	}
+	if _, total, _ := transactions.ListTransactions(repository.TransactionFilter{IncludePending: true}); total != 1 {
+		t.Errorf("ListTransactions with IncludePending total = %d, want 1", total)
+	}
+
+	if err := svc.ApproveTransfer(review.TransactionID); err != nil {
+		t.Fatalf("ApproveTransfer: %v", err)
+	}
+	assertBalance(t, accounts, alice, 20)
+	assertBalance(t, accounts, bob, 100)
+	if history, _ := transactions.GetTransactionsForAccount(alice, nil); len(history) != 1 || history[0].TransactionID != review.TransactionID {
+		t.Errorf("GetTransactionsForAccount after approval = %+v, want the approved transfer", history)
+	}
+	if err := svc.ApproveTransfer(review.TransactionID); !errors.Is(err, ErrNotPendingReview) {
+		t.Errorf("second ApproveTransfer error = %v, want %v", err, ErrNotPendingReview)
+	}
+}
+
+func TestHoldForReviewRejectsUnapprovableTransfers(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, store *repository.MemoryStore, from, to int64) (int64, int64)
+		amount  float64
+		wantErr error
+	}{
+		{"missing sender", func(_ *testing.T, _ *repository.MemoryStore, _, to int64) (int64, int64) { return 999, to }, 80, ErrAccountNotFound},
+		{"missing receiver", func(_ *testing.T, _ *repository.MemoryStore, from, _ int64) (int64, int64) { return from, 999 }, 80, ErrAccountNotFound},
+		{"closed receiver", func(t *testing.T, store *repository.MemoryStore, from, to int64) (int64, int64) {
+			if _, err := store.Accounts().SoftDeleteAccount(to); err != nil {
+				t.Fatalf("SoftDeleteAccount: %v", err)
+			}
+			return from, to
+		}, 80, ErrAccountNotFound},
+		{"frozen sender", func(t *testing.T, store *repository.MemoryStore, from, to int64) (int64, int64) {
+			if _, err := store.Accounts().FreezeAccount(from); err != nil {
+				t.Fatalf("FreezeAccount: %v", err)
+			}
+			return from, to
+		}, 80, ErrAccountFrozen},
+		{"frozen receiver", func(t *testing.T, store *repository.MemoryStore, from, to int64) (int64, int64) {
+			if _, err := store.Accounts().FreezeAccount(to); err != nil {
+				t.Fatalf("FreezeAccount: %v", err)
+			}
+			return from, to
+		}, 80, ErrAccountFrozen},
+		// The sender has 100, which covers 99 but not 99 plus the fee of 2.
+		{"short of the fee", nil, 99, ErrInsufficientFunds},
+		{"short balance", nil, 150, ErrInsufficientFunds},
+		{"cross-currency without rates", func(t *testing.T, store *repository.MemoryStore, from, _ int64) (int64, int64) {
+			return from, insertAccount(t, store, models.Account{AccountHolder: "Hiro", Currency: "JPY"})
+		}, 80, ErrExchangeRateRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := repository.NewMemoryStore()
+			accounts := store.Accounts()
+			revenue := createAccount(t, accounts, "Bank", 0)
+			from := createAccount(t, accounts, "Alice", 100)
+			to := createAccount(t, accounts, "Bob", 20)
+			svc := NewTransactionService(store, accounts, store.Transactions(), 0, nil, WithReviewThreshold(50), WithTransferFee(FlatFee(2), revenue))
+			fromID, toID := from, to
+			if tt.setup != nil {
+				fromID, toID = tt.setup(t, store, from, to)
+			}
+
+			err := svc.TransferFunds(fromID, toID, tt.amount, "", "")
+			if !errors.Is(err, tt.wantErr) || errors.Is(err, ErrRequiresReview) {
+				t.Fatalf("TransferFunds error = %v, want %v and no hold", err, tt.wantErr)
+			}
+			if _, total, _ := store.Transactions().ListTransactions(repository.TransactionFilter{IncludePending: true}); total != 0 {
+				t.Errorf("rejected transfer was held: %d transactions recorded", total)
+			}
+		})
+	}
+}
+
+// fixedRate is an ExchangeRateProvider returning rate, or err, for every currency pair.
+type fixedRate struct {
+	rate float64
+	err  error
+}
+
+func (r fixedRate) ExchangeRate(from, to string) (float64, error) {
+	return r.rate, r.err
+}
+
+func TestTransferFundsAcrossCurrencies(t *testing.T) {
+	errRates := errors.New("rates service unavailable")
+	tests := []struct {
+		name         string
+		rates        ExchangeRateProvider // nil means no WithExchangeRates
+		toCurrency   string
+		wantErr      error // nil means the transfer succeeds
+		wantCredited float64
+	}{
+		{"no provider", nil, "EUR", ErrExchangeRateRequired, 0},
+		{"provider error", fixedRate{err: errRates}, "EUR", errRates, 0},
+		{"zero rate", fixedRate{rate: 0}, "EUR", ErrExchangeRateRequired, 0},
+		{"negative rate", fixedRate{rate: -0.9}, "EUR", ErrExchangeRateRequired, 0},
+		{"rounded to cents", fixedRate{rate: 0.91237}, "EUR", nil, 30.38},
+		{"rounded to whole yen", fixedRate{rate: 151.237}, "JPY", nil, 5036},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []TransactionServiceOption
+			if tt.rates != nil {
+				opts = append(opts, WithExchangeRates(tt.rates))
+			}
+			svc, store, accounts := newMemoryTransactionService(t, opts...)
+			alice := createAccount(t, accounts, "Alice", 100)
+			bob := insertAccount(t, store, models.Account{AccountHolder: "Bob", Balance: 10, Currency: tt.toCurrency})
+
+			err := svc.TransferFunds(alice, bob, 33.3, "", "")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("TransferFunds error = %v, want %v", err, tt.wantErr)
+				}
+				assertBalance(t, accounts, alice, 100)
+				assertBalance(t, accounts, bob, 10)
+				return
+			}
+			if err != nil {
+				t.Fatalf("TransferFunds: %v", err)
+			}
+			assertBalance(t, accounts, alice, 66.7)
+			assertBalance(t, accounts, bob, 10+tt.wantCredited)
+
+			tx, err := store.Transactions().GetTransactionByID(lastTransactionID(t, store, alice))
+			if err != nil {
+				t.Fatalf("GetTransactionByID: %v", err)
+			}
+			rate := tt.rates.(fixedRate).rate
+			if tx.Amount != 33.3 || tx.CreditedAmount != (sql.NullFloat64{Float64: tt.wantCredited, Valid: true}) ||
+				tx.ExchangeRate != (sql.NullFloat64{Float64: rate, Valid: true}) {
+				t.Errorf("transaction amount %v, credited %v, rate %v; want 33.3, %v, %v", tx.Amount, tx.CreditedAmount, tx.ExchangeRate, tt.wantCredited, rate)
+			}
+		})
+	}
+
+	// Transfers within one currency record no conversion.
+	svc, store, accounts := newMemoryTransactionService(t, WithExchangeRates(fixedRate{rate: 2}))
+	alice := createAccount(t, accounts, "Alice", 100)
+	bob := createAccount(t, accounts, "Bob", 0)
+	if err := svc.TransferFunds(alice, bob, 25, "", ""); err != nil {
+		t.Fatalf("TransferFunds: %v", err)
+	}
+	assertBalance(t, accounts, bob, 25)
+	tx, err := store.Transactions().GetTransactionByID(lastTransactionID(t, store, alice))
+	if err != nil {
+		t.Fatalf("GetTransactionByID: %v", err)
+	}
+	if tx.CreditedAmount.Valid || tx.ExchangeRate.Valid {
+		t.Errorf("same-currency transfer recorded credited %v, rate %v; want both NULL", tx.CreditedAmount, tx.ExchangeRate)
+	}
+}
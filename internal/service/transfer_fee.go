@@ -0,0 +1,125 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"sql-golang-playground/models"
+	"sql-golang-playground/repository"
+)
+
+// FeeCalculator decides the fee charged to the sender of a transfer, in the sender's currency.
+type FeeCalculator interface {
+	TransferFee(fromAccountID, toAccountID int64, amount float64) (float64, error)
+}
+
+// FeeCalculatorFunc adapts an ordinary function to a FeeCalculator.
+type FeeCalculatorFunc func(fromAccountID, toAccountID int64, amount float64) (float64, error)
+
+// TransferFee calls f(fromAccountID, toAccountID, amount).
+func (f FeeCalculatorFunc) TransferFee(fromAccountID, toAccountID int64, amount float64) (float64, error) {
+	return f(fromAccountID, toAccountID, amount)
+}
+
+// FlatFee charges fee on every transfer.
+func FlatFee(fee float64) FeeCalculator {
+	return FeeCalculatorFunc(func(int64, int64, float64) (float64, error) {
+		return fee, nil
+	})
+}
+
+// PercentageFee charges percent of the transferred amount, e.g. 1.5 for 1.5%, rounded to cents.
+func PercentageFee(percent float64) FeeCalculator {
+	return FeeCalculatorFunc(func(_, _ int64, amount float64) (float64, error) {
+		return math.Round(amount*percent) / 100, nil
+	})
+}
+
+// transferFee returns the fee for a transfer, rounded to cents: zero without a fee policy or
+// when the sender is the revenue account itself.
+func (s *transactionServiceImpl) transferFee(op string, fromAccountID, toAccountID int64, amount float64) (float64, error) {
+	if s.fees == nil || fromAccountID == s.feeAccountID {
+		return 0, nil
+	}
+	fee, err := s.fees.TransferFee(fromAccountID, toAccountID, amount)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to calculate transfer fee: %w", op, err)
+	}
+	if math.IsNaN(fee) || math.IsInf(fee, 0) || fee < 0 {
+		return 0, fmt.Errorf("%s: invalid transfer fee %v", op, fee)
+	}
+	return math.Round(fee*100) / 100, nil
+}
+
+// TransferQuote is what a transfer would move under the current fee policy and exchange rates.
+type TransferQuote struct {
+	Fee      float64 // charged to the sender, in its currency
+	Debited  float64 // taken from the sender in total: the amount plus Fee
+	Credited float64 // given to the receiver, in its currency
+}
+
+// QuoteTransfer works out the fee and converted amount of a transfer without checking balances or
+// account status and without writing anything. Both accounts must exist.
+func (s *transactionServiceImpl) QuoteTransfer(fromAccountID, toAccountID int64, amount float64) (TransferQuote, error) {
+	const op = "QuoteTransfer"
+	from, err := s.accountRepo.GetAccountByIDIncludingDeleted(fromAccountID)
+	if err != nil {
+		return TransferQuote{}, fmt.Errorf("%s: failed to get sender account (ID: %d): %w", op, fromAccountID, err)
+	}
+	to, err := s.accountRepo.GetAccountByIDIncludingDeleted(toAccountID)
+	if err != nil {
+		return TransferQuote{}, fmt.Errorf("%s: failed to get receiver account (ID: %d): %w", op, toAccountID, err)
+	}
+	fee, err := s.transferFee(op, fromAccountID, toAccountID, amount)
+	if err != nil {
+		return TransferQuote{}, err
+	}
+	fx, err := convert(op, from, to, amount, s.rates)
+	if err != nil {
+		return TransferQuote{}, err
+	}
+	return TransferQuote{Fee: fee, Debited: amount + fee, Credited: fx.credited}, nil
+}
+
+// chargeFee moves fee from the sender to the revenue account and records it as a FEE
+// transaction referring to the transfer. Both repositories must be bound to the transfer's
+// transaction, after moveFunds checked that the sender can cover the transfer and the fee
+// together. A zero fee does nothing.
+func (s *transactionServiceImpl) chargeFee(accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository, op string, transferID, fromAccountID int64, fee float64, changes *balanceChanges) error {
+	if fee == 0 {
+		return nil
+	}
+	sender, err := accountRepo.GetAccountByID(fromAccountID)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get sender account (ID: %d): %w", op, fromAccountID, err)
+	}
+	revenue, err := accountRepo.GetAccountByID(s.feeAccountID)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get fee revenue account (ID: %d): %w", op, s.feeAccountID, err)
+	}
+	if err := checkTransferable(op, "fee revenue", revenue); err != nil {
+		return err
+	}
+	if revenue.Currency != sender.Currency {
+		return fmt.Errorf("%s: %w: fee in %s cannot be credited to revenue account %d in %s",
+			op, ErrExchangeRateRequired, sender.Currency, s.feeAccountID, revenue.Currency)
+	}
+
+	if err := changes.adjust(accountRepo, fromAccountID, -fee); err != nil {
+		return fmt.Errorf("%s: failed to charge fee to sender (ID: %d): %w", op, fromAccountID, err)
+	}
+	if err := changes.adjust(accountRepo, s.feeAccountID, fee); err != nil {
+		return fmt.Errorf("%s: failed to credit fee to revenue account (ID: %d): %w", op, s.feeAccountID, err)
+	}
+	feeID, err := transactionRepo.CreateTransaction(
+		sql.NullInt64{Int64: fromAccountID, Valid: true},
+		sql.NullInt64{Int64: s.feeAccountID, Valid: true},
+		models.TransactionTypeFee, fee,
+		sql.NullString{String: fmt.Sprintf("Fee for transfer %d", transferID), Valid: true})
+	if err != nil {
+		return fmt.Errorf("%s: failed to log fee transaction: %w", op, err)
+	}
+	changes.setTransaction(feeID)
+	return nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TransferRequest is the input to a funds transfer, as collected from a form or API call.
+type TransferRequest struct {
+	FromAccountID int64
+	ToAccountID   int64
+	Amount        float64
+	Description   string
+	Notes         string
+}
+
+// FieldError is a single validation problem with one field of a request.
+// Err is the matching sentinel error, if there is one, so callers can still use errors.Is.
+type FieldError struct {
+	Field   string
+	Message string
+	Err     error
+}
+
+// ValidationErrors collects every validation problem found in a request.
+type ValidationErrors []FieldError
+
+// Error joins all problems into one message.
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, fe := range v {
+		msgs[i] = fmt.Sprintf("%s: %s", fe.Field, fe.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap exposes the sentinel errors behind each problem to errors.Is and errors.As.
+func (v ValidationErrors) Unwrap() []error {
+	var errs []error
+	for _, fe := range v {
+		if fe.Err != nil {
+			errs = append(errs, fe.Err)
+		}
+	}
+	return errs
+}
+
+// add records a problem with field.
+func (v *ValidationErrors) add(field, message string, err error) {
+	*v = append(*v, FieldError{Field: field, Message: message, Err: err})
+}
+
+// ValidateTransferRequest checks every transfer rule that does not need the database and
+// reports all violations at once. It returns nil when the request is valid.
+func ValidateTransferRequest(req TransferRequest) ValidationErrors {
+	var errs ValidationErrors
+	if req.FromAccountID <= 0 {
+		errs.add("FromAccountID", "must be a positive account ID", nil)
+	}
+	if req.ToAccountID <= 0 {
+		errs.add("ToAccountID", "must be a positive account ID", nil)
+	}
+	if req.FromAccountID == req.ToAccountID {
+		errs.add("ToAccountID", "must differ from FromAccountID", ErrSameAccountTransfer)
+	}
+	if math.IsNaN(req.Amount) || math.IsInf(req.Amount, 0) {
+		errs.add("Amount", "must be a finite number", ErrInvalidTransferAmount)
+	} else if req.Amount <= 0 {
+		errs.add("Amount", "must be positive", ErrInvalidTransferAmount)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateTransferRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  TransferRequest
+		want ValidationErrors
+	}{
+		{"valid", TransferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 10}, nil},
+		{
+			"same account and negative amount",
+			TransferRequest{FromAccountID: 3, ToAccountID: 3, Amount: -5},
+			ValidationErrors{
+				{Field: "ToAccountID", Message: "must differ from FromAccountID", Err: ErrSameAccountTransfer},
+				{Field: "Amount", Message: "must be positive", Err: ErrInvalidTransferAmount},
+			},
+		},
+		{
+			"every rule broken",
+			TransferRequest{FromAccountID: 0, ToAccountID: 0, Amount: math.NaN()},
+			ValidationErrors{
+				{Field: "FromAccountID", Message: "must be a positive account ID"},
+				{Field: "ToAccountID", Message: "must be a positive account ID"},
+				{Field: "ToAccountID", Message: "must differ from FromAccountID", Err: ErrSameAccountTransfer},
+				{Field: "Amount", Message: "must be a finite number", Err: ErrInvalidTransferAmount},
+			},
+		},
+		{
+			"bad IDs and infinite amount",
+			TransferRequest{FromAccountID: -1, ToAccountID: 4, Amount: math.Inf(1)},
+			ValidationErrors{
+				{Field: "FromAccountID", Message: "must be a positive account ID"},
+				{Field: "Amount", Message: "must be a finite number", Err: ErrInvalidTransferAmount},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateTransferRequest(tt.req); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateTransferRequest(%+v) = %+v, want %+v", tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	errs := ValidateTransferRequest(TransferRequest{FromAccountID: 0, ToAccountID: 0, Amount: 0})
+	if len(errs) != 4 {
+		t.Fatalf("ValidateTransferRequest = %+v, want 4 problems", errs)
+	}
+	want := "validation failed: FromAccountID: must be a positive account ID; ToAccountID: must be a positive account ID; " +
+		"ToAccountID: must differ from FromAccountID; Amount: must be positive"
+	if got := errs.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	var err error = errs
+	for _, sentinel := range []error{ErrSameAccountTransfer, ErrInvalidTransferAmount} {
+		if !errors.Is(err, sentinel) {
+			t.Errorf("errors.Is(%v, %v) = false, want true", err, sentinel)
+		}
+	}
+	if errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("errors.Is(%v, %v) = true, want false", err, ErrInsufficientFunds)
+	}
+	var got ValidationErrors
+	if !errors.As(err, &got) || len(got) != 4 {
+		t.Errorf("errors.As did not recover the ValidationErrors from %v", err)
+	}
+}
+
+func TestTransferFundsReportsEveryValidationProblem(t *testing.T) {
+	svc, _, accounts := newMemoryTransactionService(t)
+	alice := createAccount(t, accounts, "Alice", 100)
+
+	err := svc.TransferFunds(alice, alice, -10, "", "")
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("TransferFunds error = %v, want ValidationErrors", err)
+	}
+	fields := make([]string, len(errs))
+	for i, fe := range errs {
+		fields[i] = fe.Field
+	}
+	if got := strings.Join(fields, ","); got != "ToAccountID,Amount" {
+		t.Errorf("TransferFunds reported problems with %s, want ToAccountID,Amount", got)
+	}
+	if !errors.Is(err, ErrSameAccountTransfer) || !errors.Is(err, ErrInvalidTransferAmount) {
+		t.Errorf("TransferFunds error = %v, want it to match both sentinels", err)
+	}
+	// Nothing ran, so the balance is untouched.
+	assertBalance(t, accounts, alice, 100)
+}
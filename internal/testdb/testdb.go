@@ -0,0 +1,195 @@
+//go:build integration
+
+package testdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"sql-golang-playground/internal/db"
+)
+
+// Integration tests run against the MySQL server named by MYSQL_TEST_DSN, a DSN without a
+// database name for a user that may create and drop databases, e.g.
+// "root:test@tcp(127.0.0.1:3306)/". Without it, Main starts a throwaway mysqlImage container
+// with docker and removes it when the tests finish.
+const (
+	dsnEnv         = "MYSQL_TEST_DSN"
+	mysqlImage     = "mysql:8.0"
+	rootPassword   = "test"
+	startupTimeout = 2 * time.Minute
+)
+
+var (
+	admin      *sql.DB // connection to the server, without a database selected
+	serverCfg  *mysql.Config
+	setupErr   error
+	databaseID atomic.Int64
+)
+
+// Main runs a package's integration tests with a MySQL server available to New, then exits.
+// Call it from TestMain:
+//
+//	func TestMain(m *testing.M) { testdb.Main(m) }
+//
+// When no server can be reached or started, the tests that call New are skipped.
+func Main(m *testing.M) {
+	teardown, err := start()
+	if err != nil {
+		setupErr = err
+		fmt.Fprintf(os.Stderr, "testdb: integration tests will be skipped: %v\n", err)
+	}
+	code := m.Run()
+	if teardown != nil {
+		teardown()
+	}
+	os.Exit(code)
+}
+
+// start connects to the server named by MYSQL_TEST_DSN, or starts a container when it is
+// unset, and returns the function that releases it.
+func start() (func(), error) {
+	dsn := os.Getenv(dsnEnv)
+	removeContainer := func() {}
+	if dsn == "" {
+		id, addr, err := startContainer()
+		if err != nil {
+			return nil, fmt.Errorf("%s is not set and no MySQL container could be started: %w", dsnEnv, err)
+		}
+		removeContainer = func() { exec.Command("docker", "rm", "-f", id).Run() }
+		dsn = fmt.Sprintf("root:%s@tcp(%s)/", rootPassword, addr)
+	}
+
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		removeContainer()
+		return nil, fmt.Errorf("invalid %s: %w", dsnEnv, err)
+	}
+	// A fresh container takes a while to initialize before it accepts connections.
+	conn, err := db.Open(db.Config{DSN: dsn, ConnectAttempts: 20, ConnectMaxWait: startupTimeout})
+	if err != nil {
+		removeContainer()
+		return nil, err
+	}
+	admin, serverCfg = conn, cfg
+	return func() {
+		admin.Close()
+		removeContainer()
+	}, nil
+}
+
+// startContainer runs mysqlImage on a random local port and returns the container ID and the
+// address the server listens on.
+func startContainer() (id, addr string, err error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", "", err
+	}
+	out, err := exec.Command("docker", "run", "-d", "--rm", "-e", "MYSQL_ROOT_PASSWORD="+rootPassword, "-p", "127.0.0.1::3306", mysqlImage).Output()
+	if err != nil {
+		return "", "", fmt.Errorf("docker run: %w", commandError(err))
+	}
+	id = strings.TrimSpace(string(out))
+	out, err = exec.Command("docker", "port", id, "3306/tcp").Output()
+	if err != nil {
+		exec.Command("docker", "rm", "-f", id).Run()
+		return "", "", fmt.Errorf("docker port: %w", commandError(err))
+	}
+	// One line per published address, e.g. "127.0.0.1:49153".
+	addr = strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	return id, addr, nil
+}
+
+// commandError adds a failed command's stderr to err.
+func commandError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
+
+// New creates an empty database with every migration in migrations/ applied and returns a
+// connection to it, opened with the same defaults as the application's. The database is
+// dropped when t finishes, so tests do not see each other's rows. t is skipped when Main
+// found no server.
+func New(t testing.TB) *sql.DB {
+	t.Helper()
+	if setupErr != nil {
+		t.Skipf("testdb: no MySQL server: %v", setupErr)
+	}
+	if admin == nil {
+		t.Fatal("testdb: New called without testdb.Main in TestMain")
+	}
+
+	name := fmt.Sprintf("integration_%d_%d", os.Getpid(), databaseID.Add(1))
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("testdb: create database: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP DATABASE " + name); err != nil {
+			t.Errorf("testdb: drop database %s: %v", name, err)
+		}
+	})
+
+	cfg := serverCfg.Clone()
+	cfg.DBName = name
+	if err := migrate(cfg); err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	conn, err := db.Open(db.Config{DSN: cfg.FormatDSN(), ConnectAttempts: 1})
+	if err != nil {
+		t.Fatalf("testdb: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// migrate applies the migration files to the database of cfg in file name order. Each file is
+// sent whole, so the connection allows several statements per call.
+func migrate(cfg *mysql.Config) error {
+	cfg = cfg.Clone()
+	cfg.MultiStatements = true
+	conn, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return fmt.Errorf("open for migrations: %w", err)
+	}
+	defer conn.Close()
+
+	files, err := filepath.Glob(filepath.Join(migrationsDir(), "*.sql"))
+	if err != nil {
+		return fmt.Errorf("list migrations: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migrations found in %s", migrationsDir())
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		script, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("read migration: %w", err)
+		}
+		if _, err := conn.Exec(string(script)); err != nil {
+			return fmt.Errorf("apply %s: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+// migrationsDir returns the repository's migrations directory, found relative to this file so
+// tests in any package can use it.
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations")
+}
@@ -0,0 +1,238 @@
+package util
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"sql-golang-playground/models"
+)
+
+// transactionCSVHeader starts with the ExternalTransaction layout so exported files can be
+// read back by LoadExternalTransactions; the trailing columns are ignored on import.
+var transactionCSVHeader = []string{"ExternalID", "Amount", "Type", "Reference", "FromAccountID", "ToAccountID", "Timestamp"}
+
+// exportedTransaction is the JSON shape of an exported transaction; NULL columns become null.
+type exportedTransaction struct {
+	TransactionID   int64     `json:"transaction_id"`
+	FromAccountID   *int64    `json:"from_account_id"`
+	ToAccountID     *int64    `json:"to_account_id"`
+	TransactionType string    `json:"transaction_type"`
+	Amount          float64   `json:"amount"`
+	TransactionTs   time.Time `json:"transaction_ts"`
+	Description     *string   `json:"description"`
+	Notes           *string   `json:"notes"`
+}
+
+// ExportTransactionsCSV writes transactions as CSV with a header row.
+// NULL account IDs and descriptions are written as empty fields.
+func ExportTransactionsCSV(w io.Writer, transactions []models.Transaction) error {
+	writer := NewTransactionCSVWriter(w)
+	for _, tx := range transactions {
+		if err := writer.Write(tx); err != nil {
+			return fmt.Errorf("ExportTransactionsCSV: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("ExportTransactionsCSV: %w", err)
+	}
+	return nil
+}
+
+// TransactionCSVWriter writes transactions one at a time in the ExportTransactionsCSV format,
+// for exports too large to collect in a slice first. Close must be called to finish the output.
+type TransactionCSVWriter struct {
+	writer      *csv.Writer
+	wroteHeader bool
+}
+
+// NewTransactionCSVWriter returns a TransactionCSVWriter writing to w.
+func NewTransactionCSVWriter(w io.Writer) *TransactionCSVWriter {
+	return &TransactionCSVWriter{writer: csv.NewWriter(w)}
+}
+
+// writeHeader writes the header row once.
+func (t *TransactionCSVWriter) writeHeader() error {
+	if t.wroteHeader {
+		return nil
+	}
+	t.wroteHeader = true
+	if err := t.writer.Write(transactionCSVHeader); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	return nil
+}
+
+// Write writes one transaction, preceded by the header row on the first call.
+func (t *TransactionCSVWriter) Write(tx models.Transaction) error {
+	if err := t.writeHeader(); err != nil {
+		return err
+	}
+	record := []string{
+		strconv.FormatInt(tx.TransactionID, 10),
+		strconv.FormatFloat(tx.Amount, 'f', 2, 64),
+		tx.TransactionType,
+		tx.Description.String, // empty when NULL
+		nullInt64String(tx.FromAccountID),
+		nullInt64String(tx.ToAccountID),
+		tx.TransactionTs.Format(time.RFC3339),
+	}
+	if err := t.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write transaction %d: %w", tx.TransactionID, err)
+	}
+	return nil
+}
+
+// Close writes the header if no transaction was written and flushes buffered output.
+// It does not close the underlying writer.
+func (t *TransactionCSVWriter) Close() error {
+	if err := t.writeHeader(); err != nil {
+		return err
+	}
+	t.writer.Flush()
+	return t.writer.Error()
+}
+
+// ExportExternalTransactionsCSV writes external transactions in the layout LoadExternalTransactions
+// reads: ExternalID, Amount, Type, Reference.
+func ExportExternalTransactionsCSV(w io.Writer, transactions []models.ExternalTransaction) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(transactionCSVHeader[:4]); err != nil {
+		return fmt.Errorf("ExportExternalTransactionsCSV: failed to write header: %w", err)
+	}
+	for _, tx := range transactions {
+		record := []string{
+			tx.ExternalID,
+			strconv.FormatFloat(tx.Amount, 'f', 2, 64),
+			tx.Type,
+			tx.Reference,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("ExportExternalTransactionsCSV: failed to write transaction %s: %w", tx.ExternalID, err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("ExportExternalTransactionsCSV: %w", err)
+	}
+	return nil
+}
+
+// WriteRejectsCSV writes the rows rejected by LoadExternalTransactionsWithRejects under the
+// source file's header, so they can be corrected and loaded again. Short rows are padded to the
+// header's width and every row gets two trailing columns, RejectReason and SourceLine, which
+// the loader ignores on re-import.
+func WriteRejectsCSV(w io.Writer, result *LoadResult) error {
+	writer := csv.NewWriter(w)
+	header := append(append([]string{}, result.Header...), "RejectReason", "SourceLine")
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("WriteRejectsCSV: failed to write header: %w", err)
+	}
+	for _, rej := range result.Rejects {
+		record := append([]string{}, rej.Record...)
+		for len(record) < len(result.Header) {
+			record = append(record, "")
+		}
+		record = append(record, rej.Reason, strconv.Itoa(rej.Line))
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("WriteRejectsCSV: failed to write line %d: %w", rej.Line, err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("WriteRejectsCSV: %w", err)
+	}
+	return nil
+}
+
+// ExportTransactionsJSON writes transactions as a JSON array.
+// NULL account IDs, descriptions and notes are written as null.
+func ExportTransactionsJSON(w io.Writer, transactions []models.Transaction) error {
+	writer := NewTransactionJSONWriter(w)
+	for _, tx := range transactions {
+		if err := writer.Write(tx); err != nil {
+			return fmt.Errorf("ExportTransactionsJSON: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("ExportTransactionsJSON: %w", err)
+	}
+	return nil
+}
+
+// TransactionJSONWriter writes transactions one at a time as the JSON array produced by
+// ExportTransactionsJSON, for exports too large to collect in a slice first. Close must be
+// called to terminate the array.
+type TransactionJSONWriter struct {
+	w     io.Writer
+	count int
+}
+
+// NewTransactionJSONWriter returns a TransactionJSONWriter writing to w.
+func NewTransactionJSONWriter(w io.Writer) *TransactionJSONWriter {
+	return &TransactionJSONWriter{w: w}
+}
+
+// Write appends one transaction to the array.
+func (t *TransactionJSONWriter) Write(tx models.Transaction) error {
+	data, err := json.MarshalIndent(exportedTransaction{
+		TransactionID:   tx.TransactionID,
+		FromAccountID:   nullInt64Ptr(tx.FromAccountID),
+		ToAccountID:     nullInt64Ptr(tx.ToAccountID),
+		TransactionType: tx.TransactionType,
+		Amount:          tx.Amount,
+		TransactionTs:   tx.TransactionTs,
+		Description:     nullStringPtr(tx.Description),
+		Notes:           nullStringPtr(tx.Notes),
+	}, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction %d: %w", tx.TransactionID, err)
+	}
+	sep := ",\n  "
+	if t.count == 0 {
+		sep = "[\n  "
+	}
+	if _, err := io.WriteString(t.w, sep+string(data)); err != nil {
+		return fmt.Errorf("failed to write transaction %d: %w", tx.TransactionID, err)
+	}
+	t.count++
+	return nil
+}
+
+// Close terminates the array; with no transactions written it writes an empty one.
+// It does not close the underlying writer.
+func (t *TransactionJSONWriter) Close() error {
+	end := "\n]\n"
+	if t.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := io.WriteString(t.w, end); err != nil {
+		return fmt.Errorf("failed to finish JSON array: %w", err)
+	}
+	return nil
+}
+
+func nullInt64String(v sql.NullInt64) string {
+	if !v.Valid {
+		return ""
+	}
+	return strconv.FormatInt(v.Int64, 10)
+}
+
+func nullInt64Ptr(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
+
+func nullStringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
@@ -1,10 +1,11 @@
 package util
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"strconv"
 	"strings"
@@ -14,34 +15,274 @@ import (
 // DataLoader defines the interface for loading external data.
 type DataLoader interface {
 	LoadExternalTransactions(filePath string) ([]models.ExternalTransaction, error)
+	LoadExternalTransactionsWithRejects(filePath string) (*LoadResult, error)
+	LoadExternalTransactionsFromReader(r io.Reader) ([]models.ExternalTransaction, error)
 }
 
+// RejectedRecord is a CSV row the loader could not use. Line is its line number in the file and
+// Record its raw fields, so the row can be corrected and imported again.
+type RejectedRecord struct {
+	Line   int
+	Record []string
+	Reason string
+}
+
+// LoadResult is what LoadExternalTransactionsWithRejects read from a file: the usable
+// transactions, the file's header row, and every row that was skipped, with the reason.
+type LoadResult struct {
+	Transactions []models.ExternalTransaction
+	Header       []string
+	Rejects      []RejectedRecord
+}
+
+// CSVColumnMapping names the header columns that hold each ExternalTransaction field.
+// Reference may be left empty when the partner file has no reference column.
+type CSVColumnMapping struct {
+	ExternalID string
+	Amount     string
+	Type       string
+	Reference  string
+}
+
+// csvColumnIndexes holds the resolved position of each field within a CSV record.
+type csvColumnIndexes struct {
+	externalID int
+	amount     int
+	txType     int
+	reference  int // -1 when the file has no reference column
+}
+
+// defaultColumnIndexes is the legacy positional layout: ExternalID, Amount, Type, Reference.
+var defaultColumnIndexes = csvColumnIndexes{externalID: 0, amount: 1, txType: 2, reference: 3}
+
 // csvDataLoader implements DataLoader for CSV files.
-type csvDataLoader struct{}
+type csvDataLoader struct {
+	columns      *CSVColumnMapping // nil means use the positional layout
+	logger       Logger
+	genericTypes map[string]bool // types whose direction comes from the amount's sign
+	strictIDs    bool            // fail on blank or duplicate ExternalIDs instead of skipping them
+	format       CSVFormat
+}
+
+// CSVFormat holds the csv.Reader settings for partner files that are not plain comma-separated
+// CSV. The zero value is standard CSV.
+type CSVFormat struct {
+	Comma      rune // field delimiter, e.g. ';' or '\t'; 0 means ','
+	Comment    rune // lines starting with it are ignored; 0 disables comments
+	LazyQuotes bool // accept quotes inside unquoted fields and unescaped quotes in quoted ones
+	// FieldsPerRecord, when positive, makes a record with any other number of fields fail the
+	// whole file. Otherwise field counts may vary and rows too short for the mapped columns are
+	// rejected individually.
+	FieldsPerRecord int
+}
+
+// utf8BOM is the byte order mark some spreadsheet exports put at the start of a file.
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// CSVLoaderOption configures optional CSV loader behaviour.
+type CSVLoaderOption func(*csvDataLoader)
+
+// WithSignedAmounts handles files that encode direction only in the amount's sign. Rows whose
+// type is one of genericTypes (default "TRANSACTION") become WITHDRAWAL when the amount is
+// negative and DEPOSIT when it is positive, and the amount is stored as its absolute value.
+func WithSignedAmounts(genericTypes ...string) CSVLoaderOption {
+	if len(genericTypes) == 0 {
+		genericTypes = []string{"TRANSACTION"}
+	}
+	return func(l *csvDataLoader) {
+		l.genericTypes = make(map[string]bool, len(genericTypes))
+		for _, t := range genericTypes {
+			l.genericTypes[strings.ToUpper(strings.TrimSpace(t))] = true
+		}
+	}
+}
+
+// WithStrictExternalIDs makes LoadExternalTransactions fail with ErrMissingExternalID or
+// ErrDuplicateExternalID instead of skipping blank and repeated IDs with a warning.
+// Reconciliation keys on ExternalID, so either problem means the file cannot be trusted.
+func WithStrictExternalIDs() CSVLoaderOption {
+	return func(l *csvDataLoader) {
+		l.strictIDs = true
+	}
+}
+
+// WithCSVFormat reads files in format instead of standard CSV, e.g.
+// CSVFormat{Comma: ';'} for semicolon-delimited files or CSVFormat{Comma: '\t'} for TSV.
+func WithCSVFormat(format CSVFormat) CSVLoaderOption {
+	return func(l *csvDataLoader) {
+		l.format = format
+	}
+}
+
+// newCSVReader returns a csv.Reader over r configured with l.format. A UTF-8 byte order mark at
+// the start of r is dropped so it does not end up in the first field.
+func (l *csvDataLoader) newCSVReader(r io.Reader) *csv.Reader {
+	buffered := bufio.NewReader(r)
+	if prefix, err := buffered.Peek(len(utf8BOM)); err == nil && bytes.Equal(prefix, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+	reader := csv.NewReader(buffered)
+	if l.format.Comma != 0 {
+		reader.Comma = l.format.Comma
+	}
+	reader.Comment = l.format.Comment
+	reader.LazyQuotes = l.format.LazyQuotes
+	reader.FieldsPerRecord = -1 // short rows are rejected in load rather than failing the file
+	if l.format.FieldsPerRecord > 0 {
+		reader.FieldsPerRecord = l.format.FieldsPerRecord
+	}
+	return reader
+}
 
-// NewCSVDataLoader creates a new CSV data loader.
-func NewCSVDataLoader() DataLoader {
-	return &csvDataLoader{}
+// newCSVDataLoader applies opts to a loader.
+func newCSVDataLoader(columns *CSVColumnMapping, logger Logger, opts []CSVLoaderOption) *csvDataLoader {
+	l := &csvDataLoader{columns: columns, logger: LoggerOrDefault(logger)}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// NewCSVDataLoader creates a new CSV data loader using the positional column layout.
+// A nil logger defaults to the standard library logger.
+func NewCSVDataLoader(logger Logger, opts ...CSVLoaderOption) DataLoader {
+	return newCSVDataLoader(nil, logger, opts)
+}
+
+// NewCSVDataLoaderWithColumns creates a CSV data loader that locates fields by header name.
+// A nil logger defaults to the standard library logger.
+func NewCSVDataLoaderWithColumns(columns CSVColumnMapping, logger Logger, opts ...CSVLoaderOption) DataLoader {
+	return newCSVDataLoader(&columns, logger, opts)
+}
+
+// applyAmountSign derives the type of a generic row from its amount's sign and returns the
+// canonical type and absolute amount. Other rows, and zero amounts, are returned unchanged.
+func (l *csvDataLoader) applyAmountSign(txType string, amount float64) (string, float64) {
+	if !l.genericTypes[txType] {
+		return txType, amount
+	}
+	switch {
+	case amount < 0:
+		return "WITHDRAWAL", -amount
+	case amount > 0:
+		return "DEPOSIT", amount
+	}
+	return txType, amount
+}
+
+// resolveColumns builds the column indexes for a file from its header row.
+func (l *csvDataLoader) resolveColumns(header []string) (csvColumnIndexes, error) {
+	if l.columns == nil {
+		return defaultColumnIndexes, nil
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	lookup := func(field, name string, required bool) (int, error) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			if required {
+				return -1, fmt.Errorf("no header name configured for %s", field)
+			}
+			return -1, nil
+		}
+		idx, ok := positions[name]
+		if !ok {
+			return -1, fmt.Errorf("%w: %q (for %s) not found in header %v", ErrMissingCSVColumn, name, field, header)
+		}
+		return idx, nil
+	}
+
+	var idx csvColumnIndexes
+	var err error
+	if idx.externalID, err = lookup("ExternalID", l.columns.ExternalID, true); err != nil {
+		return idx, err
+	}
+	if idx.amount, err = lookup("Amount", l.columns.Amount, true); err != nil {
+		return idx, err
+	}
+	if idx.txType, err = lookup("Type", l.columns.Type, true); err != nil {
+		return idx, err
+	}
+	if idx.reference, err = lookup("Reference", l.columns.Reference, false); err != nil {
+		return idx, err
+	}
+	return idx, nil
+}
+
+// minFields returns the number of fields a record needs to contain every mapped column.
+func (idx csvColumnIndexes) minFields() int {
+	max := idx.externalID
+	for _, i := range []int{idx.amount, idx.txType, idx.reference} {
+		if i > max {
+			max = i
+		}
+	}
+	return max + 1
 }
 
 // LoadExternalTransactions reads transactions from a CSV file.
+// Rows with a blank ExternalID are skipped, and for a repeated ExternalID only the first row is
+// kept, each with a warning; WithStrictExternalIDs turns both into errors.
+// Malformed rows are skipped with a warning; use LoadExternalTransactionsWithRejects to get them.
 func (l *csvDataLoader) LoadExternalTransactions(filePath string) ([]models.ExternalTransaction, error) {
+    result, err := l.LoadExternalTransactionsWithRejects(filePath)
+    if err != nil {
+        return nil, err
+    }
+    return result.Transactions, nil
+}
+
+// LoadExternalTransactionsWithRejects reads transactions from a CSV file like
+// LoadExternalTransactions, and also returns every row it skipped so operators can correct and
+// reprocess them; WriteRejectsCSV saves them to a file.
+func (l *csvDataLoader) LoadExternalTransactionsWithRejects(filePath string) (*LoadResult, error) {
     file, err := os.Open(filePath)
     if err != nil {
         return nil, fmt.Errorf("LoadExternalTransactions: failed to open file %s: %w", filePath, err)
     }
     defer file.Close()
 
-    reader := csv.NewReader(file)
-    _, err = reader.Read() // Skip header row
+    return l.load(file, filePath)
+}
+
+// LoadExternalTransactionsFromReader reads transactions in the same CSV format, with the same
+// rules, as LoadExternalTransactions, from r instead of a file, e.g. an HTTP upload. It does not
+// close r.
+func (l *csvDataLoader) LoadExternalTransactionsFromReader(r io.Reader) ([]models.ExternalTransaction, error) {
+    result, err := l.load(r, "input")
+    if err != nil {
+        return nil, err
+    }
+    return result.Transactions, nil
+}
+
+// load parses CSV transactions from r. source names the input in error messages.
+func (l *csvDataLoader) load(r io.Reader, source string) (*LoadResult, error) {
+    reader := l.newCSVReader(r)
+    header, err := reader.Read()
     if err != nil {
         if err == io.EOF {
-            return []models.ExternalTransaction{}, nil // Empty file after header
+            return &LoadResult{Transactions: []models.ExternalTransaction{}}, nil // Empty file after header
         }
         return nil, fmt.Errorf("LoadExternalTransactions: failed to read header: %w", err)
     }
+    result := &LoadResult{Header: header}
+    reject := func(record []string, reason string) {
+        line, _ := reader.FieldPos(0)
+        result.Rejects = append(result.Rejects, RejectedRecord{Line: line, Record: record, Reason: reason})
+    }
+
+    columns, err := l.resolveColumns(header)
+    if err != nil {
+        return nil, fmt.Errorf("LoadExternalTransactions: %s: %w", source, err)
+    }
+    minFields := columns.minFields()
 
-    var transactions []models.ExternalTransaction
+    seenIDs := make(map[string]bool)
     for {
         record, err := reader.Read()
         if err != nil {
@@ -50,23 +291,51 @@ func (l *csvDataLoader) LoadExternalTransactions(filePath string) ([]models.Exte
             }
             return nil, fmt.Errorf("LoadExternalTransactions: error reading record: %w", err)
         }
-        if len(record) < 4 {
-             log.Printf("WARN: Skipping malformed CSV record: %v", record)
+        if len(record) < minFields {
+             l.logger.Warn("Skipping malformed CSV record: %v", record)
+             reject(record, fmt.Sprintf("expected at least %d fields, got %d", minFields, len(record)))
              continue
         }
 
-        amount, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+        amount, err := strconv.ParseFloat(strings.TrimSpace(record[columns.amount]), 64)
         if err != nil {
-            log.Printf("WARN: Skipping record with invalid amount %s: %v", record[1], err)
+            l.logger.Warn("Skipping record with invalid amount %s: %v", record[columns.amount], err)
+            reject(record, fmt.Sprintf("invalid amount %q", record[columns.amount]))
+            continue
+        }
+
+        var reference string
+        if columns.reference >= 0 {
+            reference = strings.TrimSpace(record[columns.reference])
+        }
+
+        txType, amount := l.applyAmountSign(strings.TrimSpace(strings.ToUpper(record[columns.txType])), amount)
+
+        externalID := strings.TrimSpace(record[columns.externalID])
+        if externalID == "" {
+            if l.strictIDs {
+                return nil, fmt.Errorf("LoadExternalTransactions: %s: %w in record %v", source, ErrMissingExternalID, record)
+            }
+            l.logger.Warn("Skipping record with empty external ID: %v", record)
+            reject(record, ErrMissingExternalID.Error())
+            continue
+        }
+        if seenIDs[externalID] {
+            if l.strictIDs {
+                return nil, fmt.Errorf("LoadExternalTransactions: %s: %w: %s", source, ErrDuplicateExternalID, externalID)
+            }
+            l.logger.Warn("Skipping record with duplicate external ID %s; keeping the first occurrence", externalID)
+            reject(record, fmt.Sprintf("%s: %s", ErrDuplicateExternalID, externalID))
             continue
         }
+        seenIDs[externalID] = true
 
-        transactions = append(transactions, models.ExternalTransaction{
-            ExternalID: strings.TrimSpace(record[0]),
+        result.Transactions = append(result.Transactions, models.ExternalTransaction{
+            ExternalID: externalID,
             Amount:     amount,
-            Type:       strings.TrimSpace(strings.ToUpper(record[2])),
-            Reference:  strings.TrimSpace(record[3]),
+            Type:       txType,
+            Reference:  reference,
         })
     }
-    return transactions, nil
+    return result, nil
 }
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sql-golang-playground/models"
+)
+
+func TestWithSignedAmounts(t *testing.T) {
+	const input = `id,amount,type,reference
+e1,-25.50,TRANSACTION,
+e2,40,transaction,r2
+e3,-10,DEPOSIT,
+e4,0,TRANSACTION,
+e5,-7,MOVEMENT,
+`
+	tests := []struct {
+		name string
+		opts []CSVLoaderOption
+		want []models.ExternalTransaction
+	}{
+		{
+			name: "default generic type",
+			opts: []CSVLoaderOption{WithSignedAmounts()},
+			want: []models.ExternalTransaction{
+				{ExternalID: "e1", Amount: 25.50, Type: "WITHDRAWAL"},
+				{ExternalID: "e2", Amount: 40, Type: "DEPOSIT", Reference: "r2"},
+				{ExternalID: "e3", Amount: -10, Type: "DEPOSIT"},   // not generic: left alone
+				{ExternalID: "e4", Amount: 0, Type: "TRANSACTION"}, // no sign to go by
+				{ExternalID: "e5", Amount: -7, Type: "MOVEMENT"},
+			},
+		},
+		{
+			name: "custom generic types",
+			opts: []CSVLoaderOption{WithSignedAmounts(" movement ", "TRANSACTION")},
+			want: []models.ExternalTransaction{
+				{ExternalID: "e1", Amount: 25.50, Type: "WITHDRAWAL"},
+				{ExternalID: "e2", Amount: 40, Type: "DEPOSIT", Reference: "r2"},
+				{ExternalID: "e3", Amount: -10, Type: "DEPOSIT"},
+				{ExternalID: "e4", Amount: 0, Type: "TRANSACTION"},
+				{ExternalID: "e5", Amount: 7, Type: "WITHDRAWAL"},
+			},
+		},
+		{
+			name: "without the option",
+			want: []models.ExternalTransaction{
+				{ExternalID: "e1", Amount: -25.50, Type: "TRANSACTION"},
+				{ExternalID: "e2", Amount: 40, Type: "TRANSACTION", Reference: "r2"},
+				{ExternalID: "e3", Amount: -10, Type: "DEPOSIT"},
+				{ExternalID: "e4", Amount: 0, Type: "TRANSACTION"},
+				{ExternalID: "e5", Amount: -7, Type: "MOVEMENT"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewCSVDataLoader(nil, tt.opts...).LoadExternalTransactionsFromReader(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("LoadExternalTransactionsFromReader: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loaded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
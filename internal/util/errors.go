@@ -5,5 +5,13 @@ import "errors"
 var ErrInsufficientFunds = errors.New("insufficient funds for transfer")
 var ErrAccountNotFound = errors.New("account not found")
 var ErrAccountInactive = errors.New("account is inactive")
+var ErrAccountFrozen = errors.New("account is frozen")
 var ErrSameAccountTransfer = errors.New("cannot transfer funds to the same account")
 var ErrInvalidTransferAmount = errors.New("transfer amount must be positive")
+var ErrMissingCSVColumn = errors.New("required CSV column missing")
+var ErrDuplicateExternalID = errors.New("duplicate external transaction ID")
+var ErrMissingExternalID = errors.New("external transaction ID is empty")
+var ErrInvalidTransactionType = errors.New("unknown transaction type")
+var ErrBelowMinimumBalance = errors.New("balance would fall below the account minimum")
+var ErrAboveMaximumBalance = errors.New("balance would exceed the account maximum")
+var ErrInvalidAccountHolder = errors.New("invalid account holder name")
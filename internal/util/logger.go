@@ -0,0 +1,58 @@
+package util
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger is the leveled logging interface accepted by the services and loaders.
+// Implementations can route messages to slog, zap or any other backend.
+type Logger interface {
+	Debug(format string, args ...interface{})
+	Info(format string, args ...interface{})
+	Warn(format string, args ...interface{})
+	Error(format string, args ...interface{})
+}
+
+// stdLogger implements Logger on top of a standard library *log.Logger.
+type stdLogger struct {
+	logger *log.Logger
+	debug  bool
+}
+
+// NewStdLogger creates a Logger that writes "LEVEL: message" lines through the standard log package.
+// Debug messages are dropped unless debug is true.
+func NewStdLogger(debug bool) Logger {
+	return &stdLogger{logger: log.Default(), debug: debug}
+}
+
+// LoggerOrDefault returns logger, or an info-level standard library logger when logger is nil.
+func LoggerOrDefault(logger Logger) Logger {
+	if logger == nil {
+		return NewStdLogger(false)
+	}
+	return logger
+}
+
+func (l *stdLogger) Debug(format string, args ...interface{}) {
+	if l.debug {
+		l.output("DEBUG", format, args...)
+	}
+}
+
+func (l *stdLogger) Info(format string, args ...interface{}) {
+	l.output("INFO", format, args...)
+}
+
+func (l *stdLogger) Warn(format string, args ...interface{}) {
+	l.output("WARN", format, args...)
+}
+
+func (l *stdLogger) Error(format string, args ...interface{}) {
+	l.output("ERROR", format, args...)
+}
+
+// output writes a single prefixed line; calldepth 3 attributes it to the caller of the level method.
+func (l *stdLogger) output(level, format string, args ...interface{}) {
+	l.logger.Output(3, level+": "+fmt.Sprintf(format, args...))
+}
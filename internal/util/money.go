@@ -0,0 +1,115 @@
+package util
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// currencyDecimals lists the ISO 4217 minor units for currencies that do not use two decimal places.
+var currencyDecimals = map[string]int{
+	"BHD": 3,
+	"CLP": 0,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"VND": 0,
+}
+
+// currencySymbols lists the symbols FormatMoney puts in front of an amount. Symbols shared by
+// several currencies, such as "$" for CAD or AUD, are left out so those stay unambiguous.
+var currencySymbols = map[string]string{
+	"EUR": "€",
+	"GBP": "£",
+	"INR": "₹",
+	"JPY": "¥",
+	"KRW": "₩",
+	"USD": "$",
+}
+
+// CurrencyDecimals returns the number of decimal places used by the ISO 4217 currency code.
+// Unknown currencies default to two.
+func CurrencyDecimals(currency string) int {
+	if d, ok := currencyDecimals[strings.ToUpper(currency)]; ok {
+		return d
+	}
+	return 2
+}
+
+// AmountFormatter renders a monetary amount for reports.
+type AmountFormatter func(amount float64) string
+
+// DefaultAmountFormatter renders amounts with two decimal places and no currency.
+func DefaultAmountFormatter(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// NewCurrencyFormatter returns an AmountFormatter that rounds to the currency's
+// decimal places and appends its code, e.g. "1500 JPY" or "12.50 USD".
+func NewCurrencyFormatter(currency string) AmountFormatter {
+	code := strings.ToUpper(currency)
+	decimals := CurrencyDecimals(code)
+	return func(amount float64) string {
+		return strconv.FormatFloat(amount, 'f', decimals, 64) + " " + code
+	}
+}
+
+// NewMoneyFormatter returns an AmountFormatter that renders amounts with FormatMoney.
+func NewMoneyFormatter(currency string) AmountFormatter {
+	return func(amount float64) string {
+		return FormatMoney(amount, currency)
+	}
+}
+
+// FormatMoney renders amount for display in the ISO 4217 currency: rounded to the currency's
+// decimal places, with thousands separators, and with its symbol in front when it has an
+// unambiguous one, e.g. "-$1,234.50", "¥1,500" or "12.345 KWD". Amounts that round to zero lose
+// their sign. An empty currency renders the number alone.
+func FormatMoney(amount float64, currency string) string {
+	code := strings.ToUpper(currency)
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return strings.TrimSpace(strconv.FormatFloat(amount, 'f', -1, 64) + " " + code)
+	}
+
+	digits := strconv.FormatFloat(math.Abs(amount), 'f', CurrencyDecimals(code), 64)
+	sign := ""
+	if amount < 0 && strings.Trim(digits, "0.") != "" {
+		sign = "-"
+	}
+	intPart, fracPart, hasFrac := strings.Cut(digits, ".")
+	number := groupThousands(intPart)
+	if hasFrac {
+		number += "." + fracPart
+	}
+
+	if symbol, ok := currencySymbols[code]; ok {
+		return sign + symbol + number
+	}
+	if code == "" {
+		return sign + number
+	}
+	return sign + number + " " + code
+}
+
+// groupThousands inserts a comma between every group of three digits of an unsigned integer.
+func groupThousands(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	lead := len(digits) % 3
+	if lead > 0 {
+		b.WriteString(digits[:lead])
+	}
+	for i := lead; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCurrencyDecimals(t *testing.T) {
+	for currency, want := range map[string]int{"JPY": 0, "jpy": 0, "KWD": 3, "USD": 2, "XYZ": 2, "": 2} {
+		if got := CurrencyDecimals(currency); got != want {
+			t.Errorf("CurrencyDecimals(%q) = %d, want %d", currency, got, want)
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	for _, tc := range []struct {
+		amount   float64
+		currency string
+		want     string
+	}{
+		{1500, "JPY", "¥1,500"},
+		{1499.6, "jpy", "¥1,500"},
+		{-1234.5, "USD", "-$1,234.50"},
+		{1234567.891, "EUR", "€1,234,567.89"},
+		{12.345, "KWD", "12.345 KWD"},
+		{999.999, "CAD", "1,000.00 CAD"},
+		{-0.004, "USD", "$0.00"},
+		{-0.4, "JPY", "¥0"},
+		{1234.5, "", "1,234.50"},
+		{math.Inf(1), "USD", "+Inf USD"},
+		{math.NaN(), "", "NaN"},
+	} {
+		if got := FormatMoney(tc.amount, tc.currency); got != tc.want {
+			t.Errorf("FormatMoney(%v, %q) = %q, want %q", tc.amount, tc.currency, got, tc.want)
+		}
+	}
+}
+
+func TestAmountFormatters(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format AmountFormatter
+		amount float64
+		want   string
+	}{
+		{"default", DefaultAmountFormatter, 1500, "1500.00"},
+		{"currency JPY", NewCurrencyFormatter("jpy"), 1500.4, "1500 JPY"},
+		{"currency USD", NewCurrencyFormatter("USD"), 12.5, "12.50 USD"},
+		{"money JPY", NewMoneyFormatter("JPY"), 1500, "¥1,500"},
+	} {
+		if got := tc.format(tc.amount); got != tc.want {
+			t.Errorf("%s(%v) = %q, want %q", tc.name, tc.amount, got, tc.want)
+		}
+	}
+}
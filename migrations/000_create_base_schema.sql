@@ -0,0 +1,33 @@
+-- Baseline accounts, transactions and categories tables as they stood before 001, so a fresh
+-- database can be brought up to date by applying every migration in order. Databases created
+-- before this file was added already have these tables and skip it.
+CREATE TABLE IF NOT EXISTS accounts (
+    account_id     BIGINT AUTO_INCREMENT PRIMARY KEY,
+    account_holder VARCHAR(255) NOT NULL,
+    balance        DECIMAL(15, 2) NOT NULL DEFAULT 0.00,
+    last_updated   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    is_deleted     BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS transaction_categories (
+    category_id   BIGINT AUTO_INCREMENT PRIMARY KEY,
+    category_name VARCHAR(100) NOT NULL
+);
+
+-- Direction comes from the account IDs: deposits have no sender, withdrawals no receiver.
+CREATE TABLE IF NOT EXISTS transactions (
+    transaction_id   BIGINT AUTO_INCREMENT PRIMARY KEY,
+    from_account_id  BIGINT NULL DEFAULT NULL,
+    to_account_id    BIGINT NULL DEFAULT NULL,
+    transaction_type VARCHAR(16) NOT NULL,
+    amount           DECIMAL(15, 2) NOT NULL,
+    transaction_ts   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    description      VARCHAR(255) NULL DEFAULT NULL,
+    notes            TEXT NULL DEFAULT NULL,
+    category_id      BIGINT NULL DEFAULT NULL,
+    CONSTRAINT fk_transactions_from_account FOREIGN KEY (from_account_id) REFERENCES accounts (account_id),
+    CONSTRAINT fk_transactions_to_account FOREIGN KEY (to_account_id) REFERENCES accounts (account_id),
+    CONSTRAINT fk_transactions_category FOREIGN KEY (category_id) REFERENCES transaction_categories (category_id),
+    INDEX idx_transactions_from_account (from_account_id, transaction_ts),
+    INDEX idx_transactions_to_account (to_account_id, transaction_ts)
+);
@@ -0,0 +1,10 @@
+-- Transactional outbox for transfer events.
+-- Rows are written in the same transaction as the transfer and published by a separate worker.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    event_id     BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_type   VARCHAR(64) NOT NULL,
+    payload      JSON NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP NULL DEFAULT NULL,
+    INDEX idx_outbox_unpublished (published_at, event_id)
+);
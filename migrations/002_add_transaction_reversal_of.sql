@@ -0,0 +1,6 @@
+-- Links a reversal transaction to the transaction it undoes.
+-- The unique index guarantees a transaction can be reversed at most once.
+ALTER TABLE transactions
+    ADD COLUMN reversal_of BIGINT NULL DEFAULT NULL,
+    ADD CONSTRAINT fk_transactions_reversal_of FOREIGN KEY (reversal_of) REFERENCES transactions (transaction_id),
+    ADD UNIQUE INDEX uq_transactions_reversal_of (reversal_of);
@@ -0,0 +1,5 @@
+-- Optional client-supplied key that makes transaction creation idempotent.
+-- NULL keys are not deduplicated; non-NULL keys may appear at most once.
+ALTER TABLE transactions
+    ADD COLUMN idempotency_key VARCHAR(64) NULL DEFAULT NULL,
+    ADD UNIQUE INDEX uq_transactions_idempotency_key (idempotency_key);
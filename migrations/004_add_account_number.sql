@@ -0,0 +1,7 @@
+-- Stable, human-facing account numbers. New accounts get a generated 10-digit number
+-- ending in a Luhn check digit; existing accounts are backfilled with their zero-padded ID.
+ALTER TABLE accounts ADD COLUMN account_number CHAR(10) NULL;
+UPDATE accounts SET account_number = LPAD(account_id, 10, '0') WHERE account_number IS NULL;
+ALTER TABLE accounts
+    MODIFY account_number CHAR(10) NOT NULL,
+    ADD UNIQUE INDEX uq_accounts_account_number (account_number);
@@ -0,0 +1,3 @@
+-- Amounts are always stored positive; direction comes from transaction_type and from/to IDs.
+-- Same as TransactionRepository.NormalizeNegativeAmounts.
+UPDATE transactions SET amount = ABS(amount) WHERE amount < 0;
@@ -0,0 +1,5 @@
+-- Optional per-account balance limits. NULL means no limit; AdjustAccountBalance enforces
+-- both in its UPDATE so concurrent debits and credits cannot race past them.
+ALTER TABLE accounts
+    ADD COLUMN min_balance DECIMAL(15, 2) NULL AFTER balance,
+    ADD COLUMN max_balance DECIMAL(15, 2) NULL AFTER min_balance;
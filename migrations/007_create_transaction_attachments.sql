@@ -0,0 +1,11 @@
+-- References to receipts and other files attached to a transaction.
+-- The files live in external storage; only their location is tracked here.
+CREATE TABLE IF NOT EXISTS transaction_attachments (
+    attachment_id  BIGINT AUTO_INCREMENT PRIMARY KEY,
+    transaction_id BIGINT NOT NULL,
+    url            VARCHAR(2048) NOT NULL,
+    filename       VARCHAR(255) NOT NULL,
+    uploaded_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_attachments_transaction FOREIGN KEY (transaction_id) REFERENCES transactions (transaction_id) ON DELETE CASCADE,
+    INDEX idx_attachments_transaction (transaction_id, attachment_id)
+);
@@ -0,0 +1,16 @@
+-- Transfers booked to execute at a future time. Rows stay PENDING until executed or failed;
+-- funds only move when the transfer is executed.
+CREATE TABLE IF NOT EXISTS scheduled_transfers (
+    scheduled_transfer_id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    from_account_id       BIGINT NOT NULL,
+    to_account_id         BIGINT NOT NULL,
+    amount                DECIMAL(15, 2) NOT NULL,
+    execute_at            TIMESTAMP NOT NULL,
+    description           VARCHAR(255) NULL DEFAULT NULL,
+    notes                 TEXT NULL DEFAULT NULL,
+    status                VARCHAR(16) NOT NULL DEFAULT 'PENDING',
+    created_at            TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_scheduled_from_account FOREIGN KEY (from_account_id) REFERENCES accounts (account_id),
+    CONSTRAINT fk_scheduled_to_account FOREIGN KEY (to_account_id) REFERENCES accounts (account_id),
+    INDEX idx_scheduled_status_execute_at (status, execute_at)
+);
@@ -0,0 +1,3 @@
+-- Free-form key/value metadata on accounts (branch, customer segment, risk flags, ...).
+-- Stored as a flat JSON object of string values; NULL means no metadata.
+ALTER TABLE accounts ADD COLUMN metadata JSON NULL DEFAULT NULL;
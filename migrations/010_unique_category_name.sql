@@ -0,0 +1,3 @@
+-- Category names are looked up and created on the fly during imports;
+-- the unique index makes concurrent creation of the same name resolve to one row.
+ALTER TABLE transaction_categories ADD UNIQUE INDEX uq_transaction_categories_name (category_name);
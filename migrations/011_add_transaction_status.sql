@@ -0,0 +1,5 @@
+-- Large transfers are held for compliance review before any funds move.
+-- Only COMPLETED rows have affected balances; PENDING_REVIEW rows are awaiting ApproveTransfer.
+ALTER TABLE transactions
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'COMPLETED',
+    ADD INDEX idx_transactions_status (status);
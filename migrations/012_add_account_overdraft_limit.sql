@@ -0,0 +1,4 @@
+-- Negotiated overdraft per account. A debit may take the balance down to -overdraft_limit;
+-- the default of 0 keeps existing accounts from going negative.
+ALTER TABLE accounts
+    ADD COLUMN overdraft_limit DECIMAL(15, 2) NOT NULL DEFAULT 0.00 AFTER max_balance;
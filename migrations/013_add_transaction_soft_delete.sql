@@ -0,0 +1,4 @@
+-- Soft-delete for transactions, so rows can be hidden from listings without losing audit history.
+-- Hidden rows still count toward GetLedgerBalance because their money movement still happened.
+ALTER TABLE transactions
+    ADD COLUMN is_deleted BOOLEAN NOT NULL DEFAULT FALSE;
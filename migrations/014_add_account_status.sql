@@ -0,0 +1,8 @@
+-- Account lifecycle status. FROZEN accounts stay visible but cannot send or receive transfers;
+-- CLOSED replaces the old is_deleted soft-delete flag.
+ALTER TABLE accounts
+    ADD COLUMN status ENUM('ACTIVE', 'FROZEN', 'CLOSED') NOT NULL DEFAULT 'ACTIVE' AFTER last_updated;
+
+UPDATE accounts SET status = 'CLOSED' WHERE is_deleted = TRUE;
+
+ALTER TABLE accounts DROP COLUMN is_deleted;
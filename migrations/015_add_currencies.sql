@@ -0,0 +1,10 @@
+-- ISO 4217 currency per account; existing accounts are in the previously implicit USD.
+ALTER TABLE accounts
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD' AFTER balance;
+
+-- Cross-currency transfers debit amount in the sender's currency and credit credited_amount
+-- in the receiver's; exchange_rate converts the first into the second. Both are NULL for
+-- same-currency transactions.
+ALTER TABLE transactions
+    ADD COLUMN credited_amount DECIMAL(15, 2) NULL AFTER amount,
+    ADD COLUMN exchange_rate DECIMAL(18, 8) NULL AFTER credited_amount;
@@ -0,0 +1,19 @@
+-- One row per scheduled reconciliation run with the size of each report section, so match
+-- rates can be tracked over time. input identifies what was reconciled (a directory or glob);
+-- error is set, and the counts left at zero, when the run failed.
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    run_id                BIGINT AUTO_INCREMENT PRIMARY KEY,
+    input                 VARCHAR(512) NOT NULL,
+    started_at            TIMESTAMP NOT NULL,
+    finished_at           TIMESTAMP NOT NULL,
+    matched               INT NOT NULL DEFAULT 0,
+    amount_mismatches     INT NOT NULL DEFAULT 0,
+    split_matches         INT NOT NULL DEFAULT 0,
+    partial_split_matches INT NOT NULL DEFAULT 0,
+    ambiguous_splits      INT NOT NULL DEFAULT 0,
+    only_in_db            INT NOT NULL DEFAULT 0,
+    only_in_csv           INT NOT NULL DEFAULT 0,
+    file_errors           INT NOT NULL DEFAULT 0,
+    error                 TEXT NULL DEFAULT NULL,
+    INDEX idx_reconciliation_runs_input_started (input, started_at)
+);
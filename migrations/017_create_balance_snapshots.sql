@@ -0,0 +1,11 @@
+-- Point-in-time account balances, so "balance as of" queries replay only the transactions
+-- after the nearest snapshot instead of the whole ledger. balance is the account's balance
+-- including every completed transaction with transaction_ts <= as_of.
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    account_id BIGINT NOT NULL,
+    as_of      TIMESTAMP NOT NULL,
+    balance    DECIMAL(15, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (account_id, as_of),
+    CONSTRAINT fk_balance_snapshot_account FOREIGN KEY (account_id) REFERENCES accounts (account_id)
+);
@@ -0,0 +1,4 @@
+-- Status an account had when it was closed, so reopening restores a FROZEN account as FROZEN.
+-- NULL for open accounts; accounts closed before this migration reopen as ACTIVE.
+ALTER TABLE accounts
+    ADD COLUMN previous_status ENUM('ACTIVE', 'FROZEN') NULL DEFAULT NULL AFTER status;
@@ -0,0 +1,6 @@
+-- Customer ID from the upstream system that provisioned the account, so retried provisioning
+-- finds the existing account instead of creating a duplicate. NULL for accounts created directly;
+-- non-NULL IDs may appear at most once.
+ALTER TABLE accounts
+    ADD COLUMN external_customer_id VARCHAR(64) NULL DEFAULT NULL,
+    ADD UNIQUE INDEX uq_accounts_external_customer_id (external_customer_id);
@@ -1,13 +1,52 @@
 package models
 
 import (
+	"database/sql"
 	"time"
 )
 
+// Account statuses stored in accounts.status.
+const (
+    AccountStatusActive = "ACTIVE"
+    AccountStatusFrozen = "FROZEN" // visible and queryable, but cannot send or receive transfers
+    AccountStatusClosed = "CLOSED" // soft-deleted
+)
+
+// DefaultCurrency is the currency of accounts created without one.
+const DefaultCurrency = "USD"
+
+// Account is a row of the accounts table. AccountNumber is the customer-facing identifier;
+// the schema enforces it with a unique index (uq_accounts_account_number).
+// MinBalance and MaxBalance are optional limits enforced by AdjustAccountBalance.
+// Currency is the ISO 4217 code Balance is held in.
+// OverdraftLimit is how far below zero a debit may take Balance; zero means no overdraft.
+// Status is one of the AccountStatus* values; CLOSED accounts are treated as deleted.
+// Metadata holds free-form key/value attributes such as branch or customer segment.
+// Accounts marshal to JSON with the tagged names; see MarshalJSON.
 type Account struct {
-    AccountID     int64
-    AccountHolder string
-    Balance       float64
-    LastUpdated   time.Time
-    IsDeleted     bool
+    AccountID      int64           `json:"account_id"`
+    AccountNumber  string          `json:"account_number"`
+    AccountHolder  string          `json:"account_holder"`
+    Balance        float64         `json:"balance"`
+    Currency       string          `json:"currency"`
+    MinBalance     sql.NullFloat64 `json:"min_balance"`
+    MaxBalance     sql.NullFloat64 `json:"max_balance"`
+    OverdraftLimit float64         `json:"overdraft_limit"`
+    LastUpdated    time.Time       `json:"last_updated"`
+    Status         string          `json:"status"`
+    Metadata       AccountMetadata `json:"metadata"`
+}
+
+// AccountWithActivity is an account with a summary of its transactions. TxCount is how many
+// transactions it sent or received; LastActivity is the time of the latest, NULL when there are none.
+type AccountWithActivity struct {
+    Account
+    TxCount      int64        `json:"tx_count"`
+    LastActivity sql.NullTime `json:"last_activity"`
+}
+
+// AvailableFunds returns the most that can be debited from the account: its balance plus any
+// overdraft limit.
+func (a Account) AvailableFunds() float64 {
+    return a.Balance + a.OverdraftLimit
 }
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// AccountMetadata is free-form key/value metadata stored in the accounts.metadata JSON column.
+// A NULL column scans as a nil map.
+type AccountMetadata map[string]string
+
+// MetadataSystemAccount marks an internal account, such as a fee revenue account, when set to
+// "true". System accounts are not offered as transfer targets.
+const MetadataSystemAccount = "system"
+
+// IsSystemAccount reports whether the metadata marks a system account.
+func (m AccountMetadata) IsSystemAccount() bool {
+	return m[MetadataSystemAccount] == "true"
+}
+
+// Scan implements sql.Scanner.
+func (m *AccountMetadata) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("AccountMetadata: cannot scan %T", src)
+	}
+	var out map[string]string
+	if err := json.Unmarshal(data, &out); err != nil {
+		return fmt.Errorf("AccountMetadata: %w", err)
+	}
+	*m = out
+	return nil
+}
+
+// Value implements driver.Valuer. A nil map is stored as NULL.
+func (m AccountMetadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]string(m))
+	if err != nil {
+		return nil, fmt.Errorf("AccountMetadata: %w", err)
+	}
+	return string(data), nil
+}
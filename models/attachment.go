@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Attachment references a file, such as a receipt, attached to a transaction.
+// The file itself is stored elsewhere; URL points to it.
+type Attachment struct {
+    AttachmentID  int64
+    TransactionID int64
+    URL           string
+    Filename      string
+    UploadedAt    time.Time
+}
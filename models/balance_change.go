@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// BalanceChange describes one committed change to an account's balance.
+// TransactionID is the transaction that caused it.
+type BalanceChange struct {
+    AccountID     int64     `json:"account_id"`
+    OldBalance    float64   `json:"old_balance"`
+    NewBalance    float64   `json:"new_balance"`
+    TransactionID int64     `json:"transaction_id"`
+    ChangedAt     time.Time `json:"changed_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// BalanceSnapshot is an account's balance as of AsOf: every completed transaction with
+// TransactionTs <= AsOf is included.
+type BalanceSnapshot struct {
+    AccountID int64
+    AsOf      time.Time
+    Balance   float64
+}
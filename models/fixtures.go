@@ -0,0 +1,8 @@
+package models
+
+// Fixtures is a snapshot of accounts and transactions used to seed demo and staging
+// environments. Transactions refer to accounts, and reversals to other transactions, by ID.
+type Fixtures struct {
+    Accounts     []Account     `json:"accounts"`
+    Transactions []Transaction `json:"transactions"`
+}
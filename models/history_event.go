@@ -0,0 +1,25 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Kinds of account history events.
+const (
+    HistoryEventAccountOpened = "ACCOUNT_OPENED"
+    HistoryEventTransaction   = "TRANSACTION"
+)
+
+// HistoryEvent is one entry of an account's audit trail.
+// TransactionID, TransactionType and Amount are only set for TRANSACTION events;
+// BalanceDelta is the signed effect of the event on the account's balance.
+type HistoryEvent struct {
+    Timestamp       time.Time
+    Kind            string
+    TransactionID   sql.NullInt64
+    TransactionType string
+    Amount          float64
+    BalanceDelta    float64
+    Description     string
+}
@@ -0,0 +1,266 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// The MarshalJSON methods below render the models with their struct tags, except that NULL-able
+// columns become the plain value or null rather than sql.Null* objects, and times are RFC 3339
+// in UTC. UnmarshalJSON reads the same format back. Types embedding Account or Transaction need
+// their own methods, since the embedded ones would otherwise be promoted and drop the outer fields.
+
+// account and transaction have the models' fields and tags but none of their methods, so the
+// JSON shapes below can embed them without recursing into MarshalJSON.
+type (
+	account     Account
+	transaction Transaction
+)
+
+// accountJSON is the JSON shape of an Account. Its fields shadow the embedded ones of the same name.
+type accountJSON struct {
+	account
+	MinBalance  *float64 `json:"min_balance"`
+	MaxBalance  *float64 `json:"max_balance"`
+	LastUpdated string   `json:"last_updated"`
+}
+
+// transactionJSON is the JSON shape of a Transaction.
+type transactionJSON struct {
+	transaction
+	FromAccountID  *int64   `json:"from_account_id"`
+	ToAccountID    *int64   `json:"to_account_id"`
+	CreditedAmount *float64 `json:"credited_amount"`
+	ExchangeRate   *float64 `json:"exchange_rate"`
+	TransactionTs  string   `json:"transaction_ts"`
+	Description    *string  `json:"description"`
+	Notes          *string  `json:"notes"`
+	ReversalOf     *int64   `json:"reversal_of"`
+}
+
+func (a Account) toJSON() accountJSON {
+	return accountJSON{
+		account:     account(a),
+		MinBalance:  jsonFloat(a.MinBalance),
+		MaxBalance:  jsonFloat(a.MaxBalance),
+		LastUpdated: jsonTime(a.LastUpdated),
+	}
+}
+
+func (t Transaction) toJSON() transactionJSON {
+	return transactionJSON{
+		transaction:    transaction(t),
+		FromAccountID:  jsonInt(t.FromAccountID),
+		ToAccountID:    jsonInt(t.ToAccountID),
+		CreditedAmount: jsonFloat(t.CreditedAmount),
+		ExchangeRate:   jsonFloat(t.ExchangeRate),
+		TransactionTs:  jsonTime(t.TransactionTs),
+		Description:    jsonString(t.Description),
+		Notes:          jsonString(t.Notes),
+		ReversalOf:     jsonInt(t.ReversalOf),
+	}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a Account) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.toJSON())
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a AccountWithActivity) MarshalJSON() ([]byte, error) {
+	var lastActivity *string
+	if a.LastActivity.Valid {
+		s := jsonTime(a.LastActivity.Time)
+		lastActivity = &s
+	}
+	return json.Marshal(struct {
+		accountJSON
+		TxCount      int64   `json:"tx_count"`
+		LastActivity *string `json:"last_activity"`
+	}{a.Account.toJSON(), a.TxCount, lastActivity})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.toJSON())
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t AccountTransaction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		transactionJSON
+		AccountID    int64   `json:"account_id"`
+		Direction    string  `json:"direction"`
+		SignedAmount float64 `json:"signed_amount"`
+	}{t.Transaction.toJSON(), t.AccountID, t.Direction, t.SignedAmount})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t TransactionWithCategory) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		transactionJSON
+		CategoryName *string `json:"category_name"`
+	}{t.Transaction.toJSON(), jsonString(t.CategoryName)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Account) UnmarshalJSON(data []byte) error {
+	var v accountJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	lastUpdated, err := parseJSONTime(v.LastUpdated)
+	if err != nil {
+		return fmt.Errorf("last_updated: %w", err)
+	}
+	*a = Account(v.account)
+	a.MinBalance = nullFloat(v.MinBalance)
+	a.MaxBalance = nullFloat(v.MaxBalance)
+	a.LastUpdated = lastUpdated
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *AccountWithActivity) UnmarshalJSON(data []byte) error {
+	var v struct {
+		TxCount      int64   `json:"tx_count"`
+		LastActivity *string `json:"last_activity"`
+	}
+	if err := json.Unmarshal(data, &a.Account); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	a.TxCount = v.TxCount
+	a.LastActivity = sql.NullTime{}
+	if v.LastActivity != nil {
+		t, err := parseJSONTime(*v.LastActivity)
+		if err != nil {
+			return fmt.Errorf("last_activity: %w", err)
+		}
+		a.LastActivity = sql.NullTime{Time: t, Valid: true}
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Transaction) UnmarshalJSON(data []byte) error {
+	var v transactionJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	ts, err := parseJSONTime(v.TransactionTs)
+	if err != nil {
+		return fmt.Errorf("transaction_ts: %w", err)
+	}
+	*t = Transaction(v.transaction)
+	t.FromAccountID = nullInt(v.FromAccountID)
+	t.ToAccountID = nullInt(v.ToAccountID)
+	t.CreditedAmount = nullFloat(v.CreditedAmount)
+	t.ExchangeRate = nullFloat(v.ExchangeRate)
+	t.TransactionTs = ts
+	t.Description = nullString(v.Description)
+	t.Notes = nullString(v.Notes)
+	t.ReversalOf = nullInt(v.ReversalOf)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *AccountTransaction) UnmarshalJSON(data []byte) error {
+	var v struct {
+		AccountID    int64   `json:"account_id"`
+		Direction    string  `json:"direction"`
+		SignedAmount float64 `json:"signed_amount"`
+	}
+	if err := json.Unmarshal(data, &t.Transaction); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	t.AccountID, t.Direction, t.SignedAmount = v.AccountID, v.Direction, v.SignedAmount
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *TransactionWithCategory) UnmarshalJSON(data []byte) error {
+	var v struct {
+		CategoryName *string `json:"category_name"`
+	}
+	if err := json.Unmarshal(data, &t.Transaction); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	t.CategoryName = nullString(v.CategoryName)
+	return nil
+}
+
+// jsonTime formats t as RFC 3339 in UTC.
+func jsonTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseJSONTime parses a time written by jsonTime. An empty string is the zero time.
+func parseJSONTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// jsonString returns a pointer to the value of s, or nil when it is NULL.
+func jsonString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+// jsonInt returns a pointer to the value of n, or nil when it is NULL.
+func jsonInt(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
+
+// jsonFloat returns a pointer to the value of f, or nil when it is NULL.
+func jsonFloat(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+
+// nullString is the inverse of jsonString.
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+// nullInt is the inverse of jsonInt.
+func nullInt(n *int64) sql.NullInt64 {
+	if n == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *n, Valid: true}
+}
+
+// nullFloat is the inverse of jsonFloat.
+func nullFloat(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
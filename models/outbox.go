@@ -0,0 +1,28 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// OutboxEventTransferCompleted is the event type written for every committed transfer.
+const OutboxEventTransferCompleted = "TRANSFER_COMPLETED"
+
+type OutboxEvent struct {
+    EventID     int64
+    EventType   string
+    Payload     []byte
+    CreatedAt   time.Time
+    PublishedAt sql.NullTime // NULL until the event has been published
+}
+
+// TransferEvent is the JSON payload of a TRANSFER_COMPLETED outbox event.
+type TransferEvent struct {
+    TransactionID int64     `json:"transaction_id"`
+    FromAccountID int64     `json:"from_account_id"`
+    ToAccountID   int64     `json:"to_account_id"`
+    Amount        float64   `json:"amount"`
+    Description   string    `json:"description,omitempty"`
+    Notes         string    `json:"notes,omitempty"`
+    OccurredAt    time.Time `json:"occurred_at"`
+}
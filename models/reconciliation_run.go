@@ -0,0 +1,35 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ReconciliationRun is the stored summary of one reconciliation run: how many entries landed in
+// each report section. Error is set when the run failed, in which case the counts are zero.
+type ReconciliationRun struct {
+    RunID               int64
+    Input               string
+    StartedAt           time.Time
+    FinishedAt          time.Time
+    Matched             int
+    AmountMismatches    int
+    SplitMatches        int
+    PartialSplitMatches int
+    AmbiguousSplits     int
+    OnlyInDB            int
+    OnlyInCSV           int
+    FileErrors          int
+    Error               sql.NullString
+}
+
+// MatchRate returns the fraction of DB transactions in the run that were matched exactly,
+// one-to-one or as a split, or 0 when there were none.
+func (r ReconciliationRun) MatchRate() float64 {
+    matched := r.Matched + r.SplitMatches
+    total := matched + r.AmountMismatches + r.PartialSplitMatches + r.OnlyInDB
+    if total == 0 {
+        return 0
+    }
+    return float64(matched) / float64(total)
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Statuses of a scheduled transfer.
+const (
+    ScheduledTransferPending    = "PENDING"
+    ScheduledTransferProcessing = "PROCESSING" // claimed by a processor; never picked up again
+    ScheduledTransferExecuted   = "EXECUTED"
+    ScheduledTransferFailed     = "FAILED"
+)
+
+// ScheduledTransfer is a transfer booked to execute at ExecuteAt.
+type ScheduledTransfer struct {
+    ScheduledTransferID int64
+    FromAccountID       int64
+    ToAccountID         int64
+    Amount              float64
+    ExecuteAt           time.Time
+    Description         sql.NullString
+    Notes               sql.NullString
+    Status              string
+    CreatedAt           time.Time
+}
@@ -5,20 +5,97 @@ import (
 	"time"
 )
 
+// Transaction types stored in transactions.transaction_type.
+const (
+    TransactionTypeDeposit    = "DEPOSIT"
+    TransactionTypeWithdrawal = "WITHDRAWAL"
+    TransactionTypeTransfer   = "TRANSFER"
+    TransactionTypeFee        = "FEE"
+    TransactionTypeInterest   = "INTEREST"
+)
+
+// ValidTransactionTypes is the set of transaction types the application writes.
+var ValidTransactionTypes = map[string]bool{
+    TransactionTypeDeposit:    true,
+    TransactionTypeWithdrawal: true,
+    TransactionTypeTransfer:   true,
+    TransactionTypeFee:        true,
+    TransactionTypeInterest:   true,
+}
+
+// Transaction statuses stored in transactions.status.
+const (
+    TransactionStatusCompleted     = "COMPLETED"
+    TransactionStatusPendingReview = "PENDING_REVIEW" // held for review; no funds have moved
+)
+
+// BalanceAffectingTransactionTypes are the types whose rows moved money between balances.
+// Deleting one of them would leave account balances out of step with the ledger.
+var BalanceAffectingTransactionTypes = []string{
+    TransactionTypeDeposit,
+    TransactionTypeWithdrawal,
+    TransactionTypeTransfer,
+    TransactionTypeFee,
+    TransactionTypeInterest,
+}
+
+// Transaction is a row of the transactions table. TransactionTs is always in UTC: the repositories
+// store timestamps in a UTC session and normalize them to UTC when scanning, so date ranges
+// such as "June" cover the same instants in every environment.
+// Transactions marshal to JSON with the tagged names; see MarshalJSON.
 type Transaction struct {
-    TransactionID   int64
-    FromAccountID   sql.NullInt64 // Nullable foreign key
-    ToAccountID     sql.NullInt64 // Nullable foreign key
-    TransactionType string
-    Amount          float64
-    TransactionTs   time.Time
-    Description     sql.NullString // Assuming description can be NULL
-    Notes           sql.NullString
+    TransactionID   int64           `json:"transaction_id"`
+    FromAccountID   sql.NullInt64   `json:"from_account_id"` // Nullable foreign key
+    ToAccountID     sql.NullInt64   `json:"to_account_id"`   // Nullable foreign key
+    TransactionType string          `json:"transaction_type"`
+    Amount          float64         `json:"amount"`
+    CreditedAmount  sql.NullFloat64 `json:"credited_amount"` // set for cross-currency transfers: amount in the receiver's currency
+    ExchangeRate    sql.NullFloat64 `json:"exchange_rate"`   // set for cross-currency transfers: CreditedAmount / Amount
+    TransactionTs   time.Time       `json:"transaction_ts"`
+    Description     sql.NullString  `json:"description"` // Assuming description can be NULL
+    Notes           sql.NullString  `json:"notes"`
+    ReversalOf      sql.NullInt64   `json:"reversal_of"` // ID of the transaction this one reverses, if any
+    Status          string          `json:"status"`
+    IsDeleted       bool            `json:"is_deleted"` // hidden from listings; still part of the ledger
+}
+
+// Directions of a transaction from one account's point of view.
+const (
+    DirectionIn  = "IN"  // the account received the money
+    DirectionOut = "OUT" // the account paid the money
+)
+
+// AccountTransaction is a transaction as seen by one of its accounts. SignedAmount is the
+// effect on that account's balance: positive for IN, negative for OUT. An incoming
+// cross-currency transfer counts its CreditedAmount, the amount in the receiver's currency.
+type AccountTransaction struct {
+    Transaction
+    AccountID    int64   `json:"account_id"`
+    Direction    string  `json:"direction"`
+    SignedAmount float64 `json:"signed_amount"`
+}
+
+// ForAccount returns t as seen by accountID. A transaction is IN when accountID is its
+// receiver, so a deposit (no sender) is IN and a withdrawal (no receiver) is OUT. A transaction
+// with accountID on both sides nets to zero and is reported as IN.
+func (t Transaction) ForAccount(accountID int64) AccountTransaction {
+    at := AccountTransaction{Transaction: t, AccountID: accountID, Direction: DirectionOut}
+    if t.ToAccountID.Valid && t.ToAccountID.Int64 == accountID {
+        at.Direction = DirectionIn
+        at.SignedAmount = t.Amount
+        if t.CreditedAmount.Valid {
+            at.SignedAmount = t.CreditedAmount.Float64
+        }
+    }
+    if t.FromAccountID.Valid && t.FromAccountID.Int64 == accountID {
+        at.SignedAmount -= t.Amount
+    }
+    return at
 }
 
 type TransactionWithCategory struct {
-    Transaction              // Embed the original Transaction struct
-    CategoryName sql.NullString // For category_name from the joined table
+    Transaction                 // Embed the original Transaction struct
+    CategoryName sql.NullString `json:"category_name"` // For category_name from the joined table
 }
 
 type ExternalTransaction struct {
@@ -27,3 +104,25 @@ type ExternalTransaction struct {
     Type       string // e.g., DEPOSIT, WITHDRAWAL, TRANSFER_OUT, TRANSFER_IN
     Reference  string
 }
+
+// TransactionAggregate is the number and summed amount of a group of transactions.
+type TransactionAggregate struct {
+    Count int64
+    Total float64
+}
+
+// IDGap is an inclusive range of missing transaction IDs.
+type IDGap struct {
+    Start int64
+    End   int64
+}
+
+// LedgerDiscrepancy is an account whose stored balance differs from the signed sum of its
+// completed transactions. Difference is StoredBalance - LedgerBalance.
+type LedgerDiscrepancy struct {
+    AccountID     int64
+    AccountNumber string
+    StoredBalance float64
+    LedgerBalance float64
+    Difference    float64
+}
@@ -1,78 +1,436 @@
 package repository
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"sql-golang-playground/internal/util"
 	"sql-golang-playground/models"
 )
 
+// Reasons returned by IsValidTransferTarget when an account cannot receive a transfer.
+const (
+	TransferTargetNotFound     = "account does not exist"
+	TransferTargetInactive     = "account is inactive"
+	TransferTargetFrozen       = "account is frozen"
+	TransferTargetSameAsSource = "account is the transfer source"
+	TransferTargetSystem       = "account is a system account"
+	// TransferTargetCurrencyMismatch is returned when the source exists and holds a different
+	// currency, since a transfer between them needs an exchange rate provider.
+	TransferTargetCurrencyMismatch = "account currency differs from the source"
+)
+
 // mysqlAccountRepository implements AccountRepository for MySQL.
 type mysqlAccountRepository struct {
-	db *sql.DB
+	db dbHandle
+}
+
+// NewMySQLAccountRepository creates a new MySQL account repository backed by a *sql.DB or *sql.Tx.
+func NewMySQLAccountRepository(db DBTX, opts ...RepositoryOption) AccountRepository {
+	h := newDBHandle(db, opts)
+	h.prepareStatements(getAccountByIDQuery, adjustBalanceQuery, adjustBalanceDebitQuery, adjustBalanceCreditQuery)
+	return &mysqlAccountRepository{db: h}
+}
+
+// WithContext returns a copy of the repository whose calls run under ctx. A deadline on ctx
+// overrides WithQueryTimeout and WithMethodTimeout for those calls. The copy shares the prepared
+// statements, so Close the original rather than the copy.
+func (r *mysqlAccountRepository) WithContext(ctx context.Context) AccountRepository {
+	return &mysqlAccountRepository{db: r.db.withContext(ctx)}
 }
 
-// NewMySQLAccountRepository creates a new MySQL account repository.
-func NewMySQLAccountRepository(db *sql.DB) AccountRepository {
-	return &mysqlAccountRepository{db: db}
+// WithDBTX returns a copy of the repository whose calls run on db, usually the *sql.Tx a
+// service is writing in, so its reads see that transaction's own changes and its locks. The copy
+// keeps the repository's options; Close the original rather than the copy.
+func (r *mysqlAccountRepository) WithDBTX(db DBTX) AccountRepository {
+	return &mysqlAccountRepository{db: r.db.withDB(db)}
+}
+
+// Close releases the statements prepared by WithPreparedStatements. It does not close the
+// underlying database.
+func (r *mysqlAccountRepository) Close() error {
+	return r.db.close()
+}
+
+// accountNumberAttempts bounds how many fresh account numbers CreateAccount tries after collisions.
+const accountNumberAttempts = 5
+
+// accountColumns is the column list scanned by scanAccount.
+const accountColumns = "account_id, account_number, account_holder, balance, currency, min_balance, max_balance, overdraft_limit, last_updated, status, metadata"
+
+// scanAccount scans a row selected with accountColumns.
+func scanAccount(row rowScanner, acc *models.Account) error {
+    return row.Scan(&acc.AccountID, &acc.AccountNumber, &acc.AccountHolder, (*decimal)(&acc.Balance), &acc.Currency, (*nullDecimal)(&acc.MinBalance), (*nullDecimal)(&acc.MaxBalance), (*decimal)(&acc.OverdraftLimit), (*utcTime)(&acc.LastUpdated), &acc.Status, &acc.Metadata)
 }
 
-// CreateAccount inserts a new account into the database and returns the new account's ID.
-func (r *mysqlAccountRepository) CreateAccount(holderName string, initialBalance float64) (int64, error) {
-    query := "INSERT INTO accounts (account_holder, balance) VALUES (?, ?)"
-    result, err := r.db.Exec(query, holderName, initialBalance)
+// generateAccountNumber returns a random 10-digit account number whose last digit is a Luhn check digit.
+func generateAccountNumber() (string, error) {
+    n, err := rand.Int(rand.Reader, big.NewInt(1_000_000_000))
     if err != nil {
-        return 0, fmt.Errorf("CreateAccount: %w", err)
+        return "", err
+    }
+    body := fmt.Sprintf("%09d", n.Int64())
+    return body + strconv.Itoa(luhnCheckDigit(body)), nil
+}
+
+// luhnCheckDigit computes the Luhn check digit for a string of decimal digits.
+func luhnCheckDigit(digits string) int {
+    sum := 0
+    double := true // the digit next to the check digit is doubled
+    for i := len(digits) - 1; i >= 0; i-- {
+        d := int(digits[i] - '0')
+        if double {
+            d *= 2
+            if d > 9 {
+                d -= 9
+            }
+        }
+        sum += d
+        double = !double
+    }
+    return (10 - sum%10) % 10
+}
+
+// CreateAccount inserts a new account into the database and returns the new account's ID and
+// generated account number. A number that collides with an existing one is regenerated.
+// holderName is trimmed; an empty, over-long or control-character name fails with
+// util.ErrInvalidAccountHolder.
+func (r *mysqlAccountRepository) CreateAccount(holderName string, initialBalance float64) (int64, string, error) {
+    holderName, err := normalizeHolderName(holderName)
+    if err != nil {
+        return 0, "", fmt.Errorf("CreateAccount: %w", err)
+    }
+    query := "INSERT INTO accounts (account_number, account_holder, balance) VALUES (?, ?, ?)"
+    for attempt := 1; ; attempt++ {
+        accountNumber, err := generateAccountNumber()
+        if err != nil {
+            return 0, "", fmt.Errorf("CreateAccount: failed to generate account number: %w", err)
+        }
+
+        result, err := r.db.op("CreateAccount").Exec(query, accountNumber, holderName, initialBalance)
+        if err != nil {
+            if isDuplicateEntryError(err) && attempt < accountNumberAttempts {
+                continue
+            }
+            return 0, "", fmt.Errorf("CreateAccount: %w", translateMySQLError(err))
+        }
+
+        id, err := result.LastInsertId()
+        if err != nil {
+            return 0, "", fmt.Errorf("CreateAccount: LastInsertId failed: %w", err)
+        }
+        return id, accountNumber, nil
     }
+}
+
+// maxExternalCustomerIDLength is the width of accounts.external_customer_id.
+const maxExternalCustomerIDLength = 64
+
+// validateExternalCustomerID rejects IDs that cannot be stored in accounts.external_customer_id.
+func validateExternalCustomerID(externalID string) error {
+	if externalID == "" {
+		return fmt.Errorf("external customer ID must not be empty")
+	}
+	if len(externalID) > maxExternalCustomerIDLength {
+		return fmt.Errorf("external customer ID is longer than %d bytes", maxExternalCustomerIDLength)
+	}
+	return nil
+}
 
-    id, err := result.LastInsertId()
+// CreateAccountIfNotExists creates an account for the upstream customer externalID and returns its
+// ID with created set to true. If an account with that external ID already exists, including a
+// CLOSED one, nothing is inserted and the existing ID is returned with created set to false, so
+// provisioning can be retried safely. The unique index on external_customer_id makes this race-safe.
+func (r *mysqlAccountRepository) CreateAccountIfNotExists(externalID, holderName string, initialBalance float64) (int64, bool, error) {
+    if err := validateExternalCustomerID(externalID); err != nil {
+        return 0, false, fmt.Errorf("CreateAccountIfNotExists: %w", err)
+    }
+    holderName, err := normalizeHolderName(holderName)
     if err != nil {
-        return 0, fmt.Errorf("CreateAccount: LastInsertId failed: %w", err)
+        return 0, false, fmt.Errorf("CreateAccountIfNotExists: %w", err)
+    }
+    query := "INSERT INTO accounts (external_customer_id, account_number, account_holder, balance) VALUES (?, ?, ?, ?)"
+    for attempt := 1; ; attempt++ {
+        accountNumber, err := generateAccountNumber()
+        if err != nil {
+            return 0, false, fmt.Errorf("CreateAccountIfNotExists: failed to generate account number: %w", err)
+        }
+
+        result, err := r.db.op("CreateAccountIfNotExists").Exec(query, externalID, accountNumber, holderName, initialBalance)
+        if err != nil {
+            if !isDuplicateEntryError(err) {
+                return 0, false, fmt.Errorf("CreateAccountIfNotExists: %w", translateMySQLError(err))
+            }
+            // Either the external ID exists or the account number collided; only the
+            // former leaves a row to find.
+            var existingID int64
+            lookupErr := r.db.op("CreateAccountIfNotExists").QueryRow("SELECT account_id FROM accounts WHERE external_customer_id = ?", externalID).Scan(&existingID)
+            if lookupErr == nil {
+                return existingID, false, nil
+            }
+            if lookupErr != sql.ErrNoRows {
+                return 0, false, fmt.Errorf("CreateAccountIfNotExists: failed to look up existing account for external ID %q: %w", externalID, lookupErr)
+            }
+            if attempt < accountNumberAttempts {
+                continue
+            }
+            return 0, false, fmt.Errorf("CreateAccountIfNotExists: %w", translateMySQLError(err))
+        }
+
+        id, err := result.LastInsertId()
+        if err != nil {
+            return 0, false, fmt.Errorf("CreateAccountIfNotExists: LastInsertId failed: %w", err)
+        }
+        return id, true, nil
     }
-    return id, nil
 }
 
-// GetAccountByID retrieves a single active account by its ID.
+// Queries run by the hot methods, prepared up front with WithPreparedStatements.
+const (
+	getAccountByIDQuery      = "SELECT " + accountColumns + " FROM accounts WHERE account_id = ? AND status <> 'CLOSED'"
+	adjustBalanceQuery       = "UPDATE accounts SET balance = balance + ? WHERE account_id = ? AND status <> 'CLOSED'"
+	adjustBalanceDebitQuery  = adjustBalanceQuery + " AND balance + ? >= -overdraft_limit AND (min_balance IS NULL OR balance + ? >= min_balance)"
+	adjustBalanceCreditQuery = adjustBalanceQuery + " AND (max_balance IS NULL OR balance + ? <= max_balance)"
+)
+
+// GetAccountByID retrieves a single account by its ID. CLOSED accounts are not returned;
+// FROZEN ones are. A missing or CLOSED account wraps both util.ErrAccountNotFound and
+// sql.ErrNoRows.
 func (r *mysqlAccountRepository) GetAccountByID(accountID int64) (models.Account, error) {
     var acc models.Account
-    query := "SELECT account_id, account_holder, balance, last_updated, is_deleted FROM accounts WHERE account_id = ? AND is_deleted = FALSE"
-    row := r.db.QueryRow(query, accountID)
-    err := row.Scan(&acc.AccountID, &acc.AccountHolder, &acc.Balance, &acc.LastUpdated, &acc.IsDeleted)
+    err := scanAccount(r.db.op("GetAccountByID").QueryRow(getAccountByIDQuery, accountID), &acc)
     if err != nil {
         if err == sql.ErrNoRows {
-            return acc, fmt.Errorf("GetAccountByID: no active account found with ID %d", accountID)
+            return acc, fmt.Errorf("GetAccountByID: %w: no active account with ID %d: %w", util.ErrAccountNotFound, accountID, err)
         }
         return acc, fmt.Errorf("GetAccountByID: %w", err)
     }
     return acc, nil
 }
 
-// GetAllAccounts retrieves all active accounts from the database.
-func (r *mysqlAccountRepository) GetAllAccounts() ([]models.Account, error) {
-    query := "SELECT account_id, account_holder, balance, last_updated, is_deleted FROM accounts WHERE is_deleted = FALSE"
-    rows, err := r.db.Query(query)
+// GetAccountByNumber retrieves a single active account by its account number. A missing or
+// CLOSED account wraps both util.ErrAccountNotFound and sql.ErrNoRows.
+func (r *mysqlAccountRepository) GetAccountByNumber(accountNumber string) (models.Account, error) {
+    var acc models.Account
+    query := "SELECT " + accountColumns + " FROM accounts WHERE account_number = ? AND status <> 'CLOSED'"
+    err := scanAccount(r.db.op("GetAccountByNumber").QueryRow(query, accountNumber), &acc)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return acc, fmt.Errorf("GetAccountByNumber: %w: no active account with number %s: %w", util.ErrAccountNotFound, accountNumber, err)
+        }
+        return acc, fmt.Errorf("GetAccountByNumber: %w", err)
+    }
+    return acc, nil
+}
+
+// GetAccountByIDIncludingDeleted retrieves a single account whatever its status, so CLOSED
+// accounts can be shown in admin views or resolved from historical transactions. A missing
+// account wraps both util.ErrAccountNotFound and sql.ErrNoRows.
+func (r *mysqlAccountRepository) GetAccountByIDIncludingDeleted(accountID int64) (models.Account, error) {
+    var acc models.Account
+    query := "SELECT " + accountColumns + " FROM accounts WHERE account_id = ?"
+    err := scanAccount(r.db.op("GetAccountByIDIncludingDeleted").QueryRow(query, accountID), &acc)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return acc, fmt.Errorf("GetAccountByIDIncludingDeleted: %w: ID %d: %w", util.ErrAccountNotFound, accountID, err)
+        }
+        return acc, fmt.Errorf("GetAccountByIDIncludingDeleted: %w", err)
+    }
+    return acc, nil
+}
+
+// GetAccountByIDForUpdate retrieves a single active account and locks its row until the
+// surrounding transaction ends. It only makes sense on a repository bound to a *sql.Tx.
+// A missing or CLOSED account wraps both util.ErrAccountNotFound and sql.ErrNoRows.
+func (r *mysqlAccountRepository) GetAccountByIDForUpdate(accountID int64) (models.Account, error) {
+    var acc models.Account
+    query := "SELECT " + accountColumns + " FROM accounts WHERE account_id = ? AND status <> 'CLOSED' FOR UPDATE"
+    err := scanAccount(r.db.op("GetAccountByIDForUpdate").QueryRow(query, accountID), &acc)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return acc, fmt.Errorf("GetAccountByIDForUpdate: %w: no active account with ID %d: %w", util.ErrAccountNotFound, accountID, err)
+        }
+        return acc, fmt.Errorf("GetAccountByIDForUpdate: %w", err)
+    }
+    return acc, nil
+}
+
+// accountSortColumns is the allowlist of columns GetAllAccounts may order by.
+var accountSortColumns = map[string]string{
+	"account_id":     "account_id",
+	"account_holder": "account_holder",
+	"balance":        "balance",
+	"last_updated":   "last_updated",
+}
+
+// accountListPage validates opts and returns the ORDER BY and LIMIT clauses for an account
+// listing, with the LIMIT arguments. op prefixes errors.
+func accountListPage(op string, opts AccountListOptions) (string, []interface{}, error) {
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = "account_id"
+	}
+	column, ok := accountSortColumns[sortBy]
+	if !ok {
+		return "", nil, fmt.Errorf("%s: unsupported sort field %q", op, opts.SortBy)
+	}
+	if opts.Offset < 0 {
+		return "", nil, fmt.Errorf("%s: offset must not be negative, got %d", op, opts.Offset)
+	}
+	direction := "ASC"
+	if opts.Descending {
+		direction = "DESC"
+	}
+
+	// The ORDER BY column comes from the allowlist above, never from caller input directly.
+	// account_id is appended as a tie-breaker so pages are stable.
+	clause := " ORDER BY " + column + " " + direction
+	if column != "account_id" {
+		clause += ", account_id " + direction
+	}
+	var args []interface{}
+	if opts.Limit > 0 {
+		clause += " LIMIT ? OFFSET ?"
+		args = append(args, opts.Limit, opts.Offset)
+	}
+	return clause, args, nil
+}
+
+// GetAllAccounts retrieves a page of accounts that are not CLOSED along with their total number.
+func (r *mysqlAccountRepository) GetAllAccounts(opts AccountListOptions) ([]models.Account, int64, error) {
+    page, args, err := accountListPage("GetAllAccounts", opts)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    var total int64
+    if err := r.db.op("GetAllAccounts").QueryRow("SELECT COUNT(*) FROM accounts WHERE status <> 'CLOSED'").Scan(&total); err != nil {
+        return nil, 0, fmt.Errorf("GetAllAccounts: count failed: %w", err)
+    }
+
+    query := "SELECT " + accountColumns + " FROM accounts WHERE status <> 'CLOSED'" + page
+    rows, err := r.db.op("GetAllAccounts").Query(query, args...)
     if err != nil {
-        return nil, fmt.Errorf("GetAllAccounts: %w", err)
+        return nil, 0, fmt.Errorf("GetAllAccounts: %w", err)
     }
     defer rows.Close()
 
     var accounts []models.Account
     for rows.Next() {
         var acc models.Account
-        if err := rows.Scan(&acc.AccountID, &acc.AccountHolder, &acc.Balance, &acc.LastUpdated, &acc.IsDeleted); err != nil {
-            return nil, fmt.Errorf("GetAllAccounts: scan error: %w", err)
+        if err := scanAccount(rows, &acc); err != nil {
+            return nil, 0, fmt.Errorf("GetAllAccounts: scan error: %w", err)
+        }
+        accounts = append(accounts, acc)
+    }
+    if err = rows.Err(); err != nil {
+        return nil, 0, fmt.Errorf("GetAllAccounts: rows iteration error: %w", err)
+    }
+    return accounts, total, nil
+}
+
+// accountActivityQuery counts the transactions of every account that has any, with the time of
+// the latest. Soft-deleted transactions are left out, as in listings, and a transfer from an
+// account to itself counts once.
+const accountActivityQuery = `SELECT account_id, COUNT(*) AS tx_count, MAX(transaction_ts) AS last_activity FROM (
+    SELECT from_account_id AS account_id, transaction_ts FROM transactions
+    WHERE from_account_id IS NOT NULL AND is_deleted = FALSE
+    UNION ALL
+    SELECT to_account_id, transaction_ts FROM transactions
+    WHERE to_account_id IS NOT NULL AND is_deleted = FALSE AND NOT (from_account_id <=> to_account_id)
+) AS account_transactions GROUP BY account_id`
+
+// GetAccountsWithActivity retrieves a page of accounts that are not CLOSED, each with its number
+// of transactions and the time of its latest one, along with the total number of accounts.
+// Accounts without transactions have a TxCount of 0 and a NULL LastActivity.
+func (r *mysqlAccountRepository) GetAccountsWithActivity(opts AccountListOptions) ([]models.AccountWithActivity, int64, error) {
+	page, args, err := accountListPage("GetAccountsWithActivity", opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := r.db.op("GetAccountsWithActivity").QueryRow("SELECT COUNT(*) FROM accounts WHERE status <> 'CLOSED'").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("GetAccountsWithActivity: count failed: %w", err)
+	}
+
+	// USING (account_id) leaves the account columns unambiguous, so accountColumns and the
+	// ORDER BY clause work unqualified.
+	query := "SELECT " + accountColumns + ", COALESCE(activity.tx_count, 0), activity.last_activity" +
+		" FROM accounts LEFT JOIN (" + accountActivityQuery + ") AS activity USING (account_id)" +
+		" WHERE status <> 'CLOSED'" + page
+	rows, err := r.db.op("GetAccountsWithActivity").Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetAccountsWithActivity: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []models.AccountWithActivity
+	for rows.Next() {
+		var acc models.AccountWithActivity
+		var lastActivity utcTime
+		err := rows.Scan(&acc.AccountID, &acc.AccountNumber, &acc.AccountHolder, (*decimal)(&acc.Balance), &acc.Currency,
+			(*nullDecimal)(&acc.MinBalance), (*nullDecimal)(&acc.MaxBalance), (*decimal)(&acc.OverdraftLimit),
+			(*utcTime)(&acc.LastUpdated), &acc.Status, &acc.Metadata, &acc.TxCount, &lastActivity)
+		if err != nil {
+			return nil, 0, fmt.Errorf("GetAccountsWithActivity: scan error: %w", err)
+		}
+		if acc.TxCount > 0 {
+			acc.LastActivity = sql.NullTime{Time: time.Time(lastActivity), Valid: true}
+		}
+		accounts = append(accounts, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("GetAccountsWithActivity: rows iteration error: %w", err)
+	}
+	return accounts, total, nil
+}
+
+// GetTopAccountsByBalance returns the n active accounts with the largest balances, largest first.
+// Ties are broken by account_id so the ranking is stable.
+func (r *mysqlAccountRepository) GetTopAccountsByBalance(n int) ([]models.Account, error) {
+    if n <= 0 {
+        return nil, fmt.Errorf("GetTopAccountsByBalance: n must be positive, got %d", n)
+    }
+    query := "SELECT " + accountColumns + " FROM accounts WHERE status <> 'CLOSED' ORDER BY balance DESC, account_id ASC LIMIT ?"
+    rows, err := r.db.op("GetTopAccountsByBalance").Query(query, n)
+    if err != nil {
+        return nil, fmt.Errorf("GetTopAccountsByBalance: %w", err)
+    }
+    defer rows.Close()
+
+    accounts := make([]models.Account, 0, n)
+    for rows.Next() {
+        var acc models.Account
+        if err := scanAccount(rows, &acc); err != nil {
+            return nil, fmt.Errorf("GetTopAccountsByBalance: scan error: %w", err)
         }
         accounts = append(accounts, acc)
     }
     if err = rows.Err(); err != nil {
-        return nil, fmt.Errorf("GetAllAccounts: rows iteration error: %w", err)
+        return nil, fmt.Errorf("GetTopAccountsByBalance: rows iteration error: %w", err)
     }
     return accounts, nil
 }
 
-// UpdateAccountHolderName updates the name of an existing account.
+// UpdateAccountHolderName updates the name of an existing account. The name is validated as in
+// CreateAccount. It returns 1 when the name changed and 0 when the account already had that
+// name. A missing account fails with util.ErrAccountNotFound and a CLOSED one with
+// util.ErrAccountInactive.
 func (r *mysqlAccountRepository) UpdateAccountHolderName(accountID int64, newHolderName string) (int64, error) {
-    query := "UPDATE accounts SET account_holder = ? WHERE account_id = ?"
-    result, err := r.db.Exec(query, newHolderName, accountID)
+    newHolderName, err := normalizeHolderName(newHolderName)
+    if err != nil {
+        return 0, fmt.Errorf("UpdateAccountHolderName: %w", err)
+    }
+    query := "UPDATE accounts SET account_holder = ? WHERE account_id = ? AND status <> 'CLOSED'"
+    result, err := r.db.op("UpdateAccountHolderName").Exec(query, newHolderName, accountID)
     if err != nil {
         return 0, fmt.Errorf("UpdateAccountHolderName: %w", err)
     }
@@ -80,27 +438,101 @@ func (r *mysqlAccountRepository) UpdateAccountHolderName(accountID int64, newHol
     if err != nil {
         return 0, fmt.Errorf("UpdateAccountHolderName: RowsAffected failed: %w", err)
     }
-    return rowsAffected, nil
+    if rowsAffected > 0 {
+        return rowsAffected, nil
+    }
+
+    // MySQL reports 0 affected rows both when no row matched and when the name was unchanged,
+    // so look the account up to tell them apart.
+    var status string
+    err = r.db.op("UpdateAccountHolderName").QueryRow("SELECT status FROM accounts WHERE account_id = ?", accountID).Scan(&status)
+    if err == sql.ErrNoRows {
+        return 0, fmt.Errorf("UpdateAccountHolderName: %w: ID %d", util.ErrAccountNotFound, accountID)
+    }
+    if err != nil {
+        return 0, fmt.Errorf("UpdateAccountHolderName: status lookup failed: %w", err)
+    }
+    if status == models.AccountStatusClosed {
+        return 0, fmt.Errorf("UpdateAccountHolderName: %w: ID %d is closed", util.ErrAccountInactive, accountID)
+    }
+    return 0, nil
 }
 
 // AdjustAccountBalance adds a specified amount to an account's balance.
+// A debit that would take the balance below -overdraft_limit fails with util.ErrInsufficientFunds,
+// one that would take it below min_balance fails with util.ErrBelowMinimumBalance, and a credit
+// that would take it above max_balance fails with util.ErrAboveMaximumBalance.
+// The limits are checked in the UPDATE itself, so concurrent adjustments cannot race past them.
+// A non-zero change to a missing or CLOSED account fails with util.ErrAccountNotFound.
 func (r *mysqlAccountRepository) AdjustAccountBalance(accountID int64, amountChange float64) (int64, error) {
-    query := "UPDATE accounts SET balance = balance + ? WHERE account_id = ?"
-    result, err := r.db.Exec(query, amountChange, accountID)
+    query := adjustBalanceQuery
+    args := []interface{}{amountChange, accountID}
+    switch {
+    case amountChange < 0:
+        query = adjustBalanceDebitQuery
+        args = append(args, amountChange, amountChange)
+    case amountChange > 0:
+        query = adjustBalanceCreditQuery
+        args = append(args, amountChange)
+    }
+
+    result, err := r.db.op("AdjustAccountBalance").Exec(query, args...)
     if err != nil {
-        return 0, fmt.Errorf("AdjustAccountBalance: %w", err)
+        return 0, fmt.Errorf("AdjustAccountBalance: %w", translateMySQLError(err))
     }
     rowsAffected, err := result.RowsAffected()
     if err != nil {
         return 0, fmt.Errorf("AdjustAccountBalance: RowsAffected failed: %w", err)
     }
+    if rowsAffected == 0 && amountChange != 0 {
+        // Zero rows means either no active account or a limit check rejected the update.
+        var balance, overdraftLimit float64
+        err := r.db.op("AdjustAccountBalance").QueryRow("SELECT balance, overdraft_limit FROM accounts WHERE account_id = ? AND status <> 'CLOSED'", accountID).Scan((*decimal)(&balance), (*decimal)(&overdraftLimit))
+        if errors.Is(err, sql.ErrNoRows) {
+            return 0, fmt.Errorf("AdjustAccountBalance: %w: no active account with ID %d", util.ErrAccountNotFound, accountID)
+        }
+        if err != nil {
+            return 0, fmt.Errorf("AdjustAccountBalance: failed to check account %d: %w", accountID, err)
+        }
+        limitErr := balanceLimitError(balance, overdraftLimit, amountChange)
+        return 0, fmt.Errorf("AdjustAccountBalance: account %d, change %.2f: %w", accountID, amountChange, limitErr)
+    }
+    return rowsAffected, nil
+}
+
+// balanceLimitError names the limit that rejected amountChange on an account with the given
+// balance and overdraft limit.
+func balanceLimitError(balance, overdraftLimit, amountChange float64) error {
+    switch {
+    case amountChange > 0:
+        return util.ErrAboveMaximumBalance
+    case balance+amountChange < -overdraftLimit:
+        return util.ErrInsufficientFunds
+    default:
+        return util.ErrBelowMinimumBalance
+    }
+}
+
+// SetAccountBalance overwrites an account's balance.
+func (r *mysqlAccountRepository) SetAccountBalance(accountID int64, balance float64) (int64, error) {
+    query := "UPDATE accounts SET balance = ? WHERE account_id = ?"
+    result, err := r.db.op("SetAccountBalance").Exec(query, balance, accountID)
+    if err != nil {
+        return 0, fmt.Errorf("SetAccountBalance: %w", translateMySQLError(err))
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("SetAccountBalance: RowsAffected failed: %w", err)
+    }
     return rowsAffected, nil
 }
 
-// SoftDeleteAccount marks an account as deleted instead of removing it from the database.
+// SoftDeleteAccount marks an account CLOSED instead of removing it from the database, keeping
+// its current status in previous_status for UndeleteAccount.
 func (r *mysqlAccountRepository) SoftDeleteAccount(accountID int64) (int64, error) {
-    query := "UPDATE accounts SET is_deleted = TRUE WHERE account_id = ? AND is_deleted = FALSE"
-    result, err := r.db.Exec(query, accountID)
+    // MySQL assigns left to right, so previous_status gets the status from before the update.
+    query := "UPDATE accounts SET previous_status = status, status = 'CLOSED' WHERE account_id = ? AND status <> 'CLOSED'"
+    result, err := r.db.op("SoftDeleteAccount").Exec(query, accountID)
     if err != nil {
         return 0, fmt.Errorf("SoftDeleteAccount: %w", err)
     }
@@ -114,10 +546,130 @@ func (r *mysqlAccountRepository) SoftDeleteAccount(accountID int64) (int64, erro
     return rowsAffected, nil
 }
 
-// UndeleteAccount reactivates a soft-deleted account.
+// inactiveAccountsQuery selects the IDs of ACTIVE accounts that have been neither updated nor
+// party to any transaction, including soft-deleted ones, since a time given twice more as
+// arguments. The last_updated check spares accounts opened since then.
+const inactiveAccountsQuery = `SELECT account_id FROM accounts
+    WHERE status = 'ACTIVE' AND last_updated < ?
+    AND NOT EXISTS (SELECT 1 FROM transactions WHERE from_account_id = accounts.account_id AND transaction_ts >= ?)
+    AND NOT EXISTS (SELECT 1 FROM transactions WHERE to_account_id = accounts.account_id AND transaction_ts >= ?)`
+
+// inactiveAccounts returns inactiveAccountsQuery narrowed by opts, with its arguments.
+func inactiveAccounts(inactiveSince time.Time, opts InactiveAccountOptions) (string, []interface{}) {
+	since := inactiveSince.UTC()
+	query := inactiveAccountsQuery
+	if opts.ZeroBalanceOnly {
+		query += " AND balance = 0"
+	}
+	return query, []interface{}{since, since, since}
+}
+
+// closeInactiveBatchSize caps the IDs closed by one UPDATE, keeping the statement well below
+// MySQL's limit on placeholders.
+const closeInactiveBatchSize = 1000
+
+// SoftDeleteInactiveAccounts closes every ACTIVE account with no transactions and no updates
+// since inactiveSince, as SoftDeleteAccount would one by one. FROZEN accounts are left alone.
+// It returns the number of accounts closed; FindInactiveAccounts lists them without closing
+// anything. The accounts are selected and locked first, then closed by ID, in one database
+// transaction: the repository's own, or a new one when it runs on a *sql.DB.
+func (r *mysqlAccountRepository) SoftDeleteInactiveAccounts(inactiveSince time.Time) (int64, error) {
+	return r.softDeleteInactiveAccounts("SoftDeleteInactiveAccounts", inactiveSince, InactiveAccountOptions{})
+}
+
+// SoftDeleteInactiveZeroBalanceAccounts is SoftDeleteInactiveAccounts limited to accounts whose
+// balance is exactly zero.
+func (r *mysqlAccountRepository) SoftDeleteInactiveZeroBalanceAccounts(inactiveSince time.Time) (int64, error) {
+	return r.softDeleteInactiveAccounts("SoftDeleteInactiveZeroBalanceAccounts", inactiveSince, InactiveAccountOptions{ZeroBalanceOnly: true})
+}
+
+// softDeleteInactiveAccounts runs closeInactiveAccounts inside a database transaction.
+func (r *mysqlAccountRepository) softDeleteInactiveAccounts(op string, inactiveSince time.Time, opts InactiveAccountOptions) (int64, error) {
+	db, ok := r.db.db.(*sql.DB)
+	if !ok {
+		return r.closeInactiveAccounts(op, inactiveSince, opts)
+	}
+	var closed int64
+	err := WithTransaction(db, func(tx *sql.Tx) error {
+		var err error
+		closed, err = (&mysqlAccountRepository{db: r.db.withDB(tx)}).closeInactiveAccounts(op, inactiveSince, opts)
+		return err
+	})
+	return closed, err
+}
+
+// closeInactiveAccounts locks the inactive accounts with SELECT ... FOR UPDATE and closes them by
+// ID. Selecting the IDs first avoids an UPDATE with a subquery on accounts itself, which MySQL
+// rejects or must materialize. It must run inside a transaction for the locks to hold.
+func (r *mysqlAccountRepository) closeInactiveAccounts(op string, inactiveSince time.Time, opts InactiveAccountOptions) (int64, error) {
+	inactive, args := inactiveAccounts(inactiveSince, opts)
+	rows, err := r.db.op(op).Query(inactive+" FOR UPDATE", args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	var ids []interface{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("%s: scan error: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: rows iteration error: %w", op, err)
+	}
+
+	var closed int64
+	for len(ids) > 0 {
+		batch := ids[:min(len(ids), closeInactiveBatchSize)]
+		ids = ids[len(batch):]
+		query := "UPDATE accounts SET previous_status = status, status = 'CLOSED' WHERE status = 'ACTIVE' AND account_id IN (" + placeholders(len(batch)) + ")"
+		result, err := r.db.op(op).Exec(query, batch...)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("%s: RowsAffected failed: %w", op, err)
+		}
+		closed += rowsAffected
+	}
+	return closed, nil
+}
+
+// FindInactiveAccounts returns the accounts SoftDeleteInactiveAccounts would close, in ID order.
+func (r *mysqlAccountRepository) FindInactiveAccounts(inactiveSince time.Time, opts InactiveAccountOptions) ([]models.Account, error) {
+	inactive, args := inactiveAccounts(inactiveSince, opts)
+	query := "SELECT " + accountColumns + " FROM accounts WHERE account_id IN (" + inactive + ") ORDER BY account_id"
+	rows, err := r.db.op("FindInactiveAccounts").Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("FindInactiveAccounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []models.Account
+	for rows.Next() {
+		var acc models.Account
+		if err := scanAccount(rows, &acc); err != nil {
+			return nil, fmt.Errorf("FindInactiveAccounts: scan error: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("FindInactiveAccounts: rows iteration error: %w", err)
+	}
+	return accounts, nil
+}
+
+// UndeleteAccount reopens a CLOSED account with the status it had when it was closed, so an
+// account frozen before closing comes back FROZEN. Accounts closed before previous_status was
+// recorded reopen as ACTIVE. Without a CLOSED account to reopen, e.g. when a retried call
+// already did, the error wraps ErrAccountNotClosed and nothing changes.
 func (r *mysqlAccountRepository) UndeleteAccount(accountID int64) (int64, error) {
-    query := "UPDATE accounts SET is_deleted = FALSE WHERE account_id = ? AND is_deleted = TRUE"
-    result, err := r.db.Exec(query, accountID)
+    query := "UPDATE accounts SET status = COALESCE(previous_status, 'ACTIVE'), previous_status = NULL WHERE account_id = ? AND status = 'CLOSED'"
+    result, err := r.db.op("UndeleteAccount").Exec(query, accountID)
     if err != nil {
         return 0, fmt.Errorf("UndeleteAccount: %w", err)
     }
@@ -126,18 +678,54 @@ func (r *mysqlAccountRepository) UndeleteAccount(accountID int64) (int64, error)
         return 0, fmt.Errorf("UndeleteAccount: RowsAffected failed: %w", err)
     }
     if rowsAffected == 0 {
-        return 0, fmt.Errorf("UndeleteAccount: no soft-deleted account found with ID %d to undelete, or already active", accountID)
+        return 0, fmt.Errorf("UndeleteAccount: %w: ID %d", ErrAccountNotClosed, accountID)
+    }
+    return rowsAffected, nil
+}
+
+// FreezeAccount moves an ACTIVE account to FROZEN. A frozen account stays visible but
+// cannot send or receive transfers.
+func (r *mysqlAccountRepository) FreezeAccount(accountID int64) (int64, error) {
+    query := "UPDATE accounts SET status = 'FROZEN' WHERE account_id = ? AND status = 'ACTIVE'"
+    result, err := r.db.op("FreezeAccount").Exec(query, accountID)
+    if err != nil {
+        return 0, fmt.Errorf("FreezeAccount: %w", err)
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("FreezeAccount: RowsAffected failed: %w", err)
+    }
+    if rowsAffected == 0 {
+        return 0, fmt.Errorf("FreezeAccount: no active account found with ID %d to freeze", accountID)
+    }
+    return rowsAffected, nil
+}
+
+// UnfreezeAccount moves a FROZEN account back to ACTIVE.
+func (r *mysqlAccountRepository) UnfreezeAccount(accountID int64) (int64, error) {
+    query := "UPDATE accounts SET status = 'ACTIVE' WHERE account_id = ? AND status = 'FROZEN'"
+    result, err := r.db.op("UnfreezeAccount").Exec(query, accountID)
+    if err != nil {
+        return 0, fmt.Errorf("UnfreezeAccount: %w", err)
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("UnfreezeAccount: RowsAffected failed: %w", err)
+    }
+    if rowsAffected == 0 {
+        return 0, fmt.Errorf("UnfreezeAccount: no frozen account found with ID %d to unfreeze", accountID)
     }
     return rowsAffected, nil
 }
 
 // CalculateTotalBalanceOfActiveAccounts computes the sum of balances for all non-deleted accounts.
+// The sum is computed by MySQL and parsed from its exact text, not summed as floats.
 func (r *mysqlAccountRepository) CalculateTotalBalanceOfActiveAccounts() (float64, error) {
     var totalBalance sql.NullFloat64
 
-    query := "SELECT SUM(balance) FROM accounts WHERE is_deleted = FALSE"
-    row := r.db.QueryRow(query)
-    err := row.Scan(&totalBalance)
+    query := "SELECT SUM(balance) FROM accounts WHERE status <> 'CLOSED'"
+    row := r.db.op("CalculateTotalBalanceOfActiveAccounts").QueryRow(query)
+    err := row.Scan((*nullDecimal)(&totalBalance))
     if err != nil {
         return 0, fmt.Errorf("CalculateTotalBalanceOfActiveAccounts: Scan failed: %w", err)
     }
@@ -147,3 +735,222 @@ func (r *mysqlAccountRepository) CalculateTotalBalanceOfActiveAccounts() (float6
     }
     return 0, nil
 }
+
+// GetBalancesByIDs returns the current balance of each active account in accountIDs,
+// keyed by account ID, using a single query. Missing or soft-deleted accounts are absent from the map.
+func (r *mysqlAccountRepository) GetBalancesByIDs(accountIDs []int64) (map[int64]float64, error) {
+	balances := make(map[int64]float64)
+	if len(accountIDs) == 0 {
+		return balances, nil
+	}
+
+	args := make([]interface{}, len(accountIDs))
+	for i, id := range accountIDs {
+		args[i] = id
+	}
+	query := "SELECT account_id, balance FROM accounts WHERE status <> 'CLOSED' AND account_id IN (" + placeholders(len(accountIDs)) + ")"
+	rows, err := r.db.op("GetBalancesByIDs").Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetBalancesByIDs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var balance float64
+		if err := rows.Scan(&id, (*decimal)(&balance)); err != nil {
+			return nil, fmt.Errorf("GetBalancesByIDs: scan error: %w", err)
+		}
+		balances[id] = balance
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetBalancesByIDs: rows iteration error: %w", err)
+	}
+	return balances, nil
+}
+
+// GetAccountsByIDs returns the accounts in accountIDs keyed by account ID, using a single query.
+// CLOSED accounts are included only when includeClosed is set, e.g. to label transactions that
+// reference an account closed since. Missing accounts are absent from the map.
+func (r *mysqlAccountRepository) GetAccountsByIDs(accountIDs []int64, includeClosed bool) (map[int64]models.Account, error) {
+	accounts := make(map[int64]models.Account)
+	if len(accountIDs) == 0 {
+		return accounts, nil
+	}
+
+	args := make([]interface{}, len(accountIDs))
+	for i, id := range accountIDs {
+		args[i] = id
+	}
+	query := "SELECT " + accountColumns + " FROM accounts WHERE account_id IN (" + placeholders(len(accountIDs)) + ")"
+	if !includeClosed {
+		query += " AND status <> 'CLOSED'"
+	}
+	rows, err := r.db.op("GetAccountsByIDs").Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetAccountsByIDs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var acc models.Account
+		if err := scanAccount(rows, &acc); err != nil {
+			return nil, fmt.Errorf("GetAccountsByIDs: scan error: %w", err)
+		}
+		accounts[acc.AccountID] = acc
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetAccountsByIDs: rows iteration error: %w", err)
+	}
+	return accounts, nil
+}
+
+// GetBalancesForTransactions returns the current balance of every active account referenced
+// by the given transactions, keyed by account ID, using a single query.
+// Accounts that are missing or soft-deleted are absent from the map.
+func (r *mysqlAccountRepository) GetBalancesForTransactions(transactions []models.Transaction) (map[int64]float64, error) {
+	balances, err := r.GetBalancesByIDs(accountIDsFromTransactions(transactions))
+	if err != nil {
+		return nil, fmt.Errorf("GetBalancesForTransactions: %w", err)
+	}
+	return balances, nil
+}
+
+// IsValidTransferTarget reports whether accountID can receive a transfer from sourceAccountID.
+// When it cannot, the second return value is one of the TransferTarget* reasons. The target and
+// the source's currency are read in a single query; a missing source skips the currency check.
+func (r *mysqlAccountRepository) IsValidTransferTarget(sourceAccountID, accountID int64) (bool, string, error) {
+	if sourceAccountID == accountID {
+		return false, TransferTargetSameAsSource, nil
+	}
+
+	var status, currency string
+	var system bool
+	var sourceCurrency sql.NullString
+	query := `SELECT t.status, t.currency, COALESCE(JSON_UNQUOTE(JSON_EXTRACT(t.metadata, '$.` + models.MetadataSystemAccount + `')) = 'true', FALSE), s.currency
+		FROM accounts t LEFT JOIN accounts s ON s.account_id = ?
+		WHERE t.account_id = ?`
+	err := r.db.op("IsValidTransferTarget").QueryRow(query, sourceAccountID, accountID).Scan(&status, &currency, &system, &sourceCurrency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, TransferTargetNotFound, nil
+		}
+		return false, "", fmt.Errorf("IsValidTransferTarget: %w", err)
+	}
+	return transferTargetVerdict(status, currency, system, sourceCurrency)
+}
+
+// transferTargetVerdict applies the IsValidTransferTarget checks to an existing target account.
+func transferTargetVerdict(status, currency string, system bool, sourceCurrency sql.NullString) (bool, string, error) {
+	switch status {
+	case models.AccountStatusClosed:
+		return false, TransferTargetInactive, nil
+	case models.AccountStatusFrozen:
+		return false, TransferTargetFrozen, nil
+	}
+	if system {
+		return false, TransferTargetSystem, nil
+	}
+	if sourceCurrency.Valid && sourceCurrency.String != currency {
+		return false, TransferTargetCurrencyMismatch, nil
+	}
+	return true, "", nil
+}
+
+// HasTransactions reports whether any transaction, on either side, references accountID.
+func (r *mysqlAccountRepository) HasTransactions(accountID int64) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM transactions WHERE from_account_id = ?) OR EXISTS(SELECT 1 FROM transactions WHERE to_account_id = ?)"
+	if err := r.db.op("HasTransactions").QueryRow(query, accountID, accountID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("HasTransactions: %w", err)
+	}
+	return exists, nil
+}
+
+// SetAccountMetadata merges updates into an account's metadata; keys not in updates are kept.
+// The merge runs in a single UPDATE, so concurrent updates to different keys do not clobber each other.
+func (r *mysqlAccountRepository) SetAccountMetadata(accountID int64, updates map[string]string) (int64, error) {
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	patch, err := models.AccountMetadata(updates).Value()
+	if err != nil {
+		return 0, fmt.Errorf("SetAccountMetadata: %w", err)
+	}
+	query := "UPDATE accounts SET metadata = JSON_MERGE_PATCH(COALESCE(metadata, JSON_OBJECT()), ?) WHERE account_id = ?"
+	result, err := r.db.op("SetAccountMetadata").Exec(query, patch, accountID)
+	if err != nil {
+		return 0, fmt.Errorf("SetAccountMetadata: %w", translateMySQLError(err))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("SetAccountMetadata: RowsAffected failed: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+// GetAccountMetadata returns an account's metadata, or an empty map when it has none.
+// A missing account wraps both util.ErrAccountNotFound and sql.ErrNoRows.
+func (r *mysqlAccountRepository) GetAccountMetadata(accountID int64) (models.AccountMetadata, error) {
+	var metadata models.AccountMetadata
+	err := r.db.op("GetAccountMetadata").QueryRow("SELECT metadata FROM accounts WHERE account_id = ?", accountID).Scan(&metadata)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("GetAccountMetadata: %w: no account found with ID %d: %w", util.ErrAccountNotFound, accountID, err)
+		}
+		return nil, fmt.Errorf("GetAccountMetadata: %w", err)
+	}
+	if metadata == nil {
+		metadata = models.AccountMetadata{}
+	}
+	return metadata, nil
+}
+
+// metadataKeyPath returns the JSON path selecting key in the metadata column. The key is quoted,
+// so dots and spaces are allowed; an empty key or one containing control characters is rejected.
+func metadataKeyPath(key string) (string, error) {
+	if err := validateMetadataKey(key); err != nil {
+		return "", err
+	}
+	return `$."` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key) + `"`, nil
+}
+
+// validateMetadataKey rejects keys GetAccountsByMetadata cannot look up.
+func validateMetadataKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+	for _, r := range key {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("key %q contains a control character", key)
+		}
+	}
+	return nil
+}
+
+// GetAccountsByMetadata returns the active accounts whose metadata has key set to value.
+func (r *mysqlAccountRepository) GetAccountsByMetadata(key, value string) ([]models.Account, error) {
+	path, err := metadataKeyPath(key)
+	if err != nil {
+		return nil, fmt.Errorf("GetAccountsByMetadata: %w", err)
+	}
+	query := "SELECT " + accountColumns + " FROM accounts WHERE status <> 'CLOSED' AND JSON_UNQUOTE(JSON_EXTRACT(metadata, ?)) = ? ORDER BY account_id"
+	rows, err := r.db.op("GetAccountsByMetadata").Query(query, path, value)
+	if err != nil {
+		return nil, fmt.Errorf("GetAccountsByMetadata: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []models.Account
+	for rows.Next() {
+		var acc models.Account
+		if err := scanAccount(rows, &acc); err != nil {
+			return nil, fmt.Errorf("GetAccountsByMetadata: scan error: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetAccountsByMetadata: rows iteration error: %w", err)
+	}
+	return accounts, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// mysqlLocker implements Locker with MySQL named locks (GET_LOCK / RELEASE_LOCK).
+type mysqlLocker struct {
+	db *sql.DB
+}
+
+// NewMySQLLocker creates a Locker backed by MySQL advisory locks.
+func NewMySQLLocker(db *sql.DB) Locker {
+	return &mysqlLocker{db: db}
+}
+
+// TryLock attempts to take the named lock without waiting. MySQL named locks belong to a
+// session, so a dedicated connection is held until release is called.
+func (l *mysqlLocker) TryLock(name string) (func() error, bool, error) {
+	ctx := context.Background()
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("TryLock: failed to get connection: %w", err)
+	}
+
+	var result sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&result); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("TryLock: GET_LOCK failed: %w", err)
+	}
+	if !result.Valid || result.Int64 != 1 {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	release := func() error {
+		defer conn.Close()
+		if _, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name); err != nil {
+			return fmt.Errorf("TryLock: RELEASE_LOCK failed: %w", err)
+		}
+		return nil
+	}
+	return release, true, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"fmt"
+	"sql-golang-playground/models"
+)
+
+// mysqlAttachmentRepository implements AttachmentRepository for MySQL.
+type mysqlAttachmentRepository struct {
+	db dbHandle
+}
+
+// NewMySQLAttachmentRepository creates a new MySQL attachment repository backed by a *sql.DB or *sql.Tx.
+func NewMySQLAttachmentRepository(db DBTX, opts ...RepositoryOption) AttachmentRepository {
+	return &mysqlAttachmentRepository{db: newDBHandle(db, opts)}
+}
+
+// AddAttachment records a file reference against a transaction and returns the attachment ID.
+// An unknown transaction ID fails with ErrForeignKeyViolation.
+func (r *mysqlAttachmentRepository) AddAttachment(transactionID int64, url, filename string) (int64, error) {
+	if url == "" || filename == "" {
+		return 0, fmt.Errorf("AddAttachment: url and filename are required")
+	}
+	query := "INSERT INTO transaction_attachments (transaction_id, url, filename, uploaded_at) VALUES (?, ?, ?, NOW())"
+	result, err := r.db.op("AddAttachment").Exec(query, transactionID, url, filename)
+	if err != nil {
+		return 0, fmt.Errorf("AddAttachment: %w", translateMySQLError(err))
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("AddAttachment: LastInsertId failed: %w", err)
+	}
+	return id, nil
+}
+
+// ListAttachments returns the attachments of a transaction, oldest first.
+func (r *mysqlAttachmentRepository) ListAttachments(transactionID int64) ([]models.Attachment, error) {
+	query := "SELECT attachment_id, transaction_id, url, filename, uploaded_at FROM transaction_attachments WHERE transaction_id = ? ORDER BY attachment_id"
+	rows, err := r.db.op("ListAttachments").Query(query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("ListAttachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		var a models.Attachment
+		if err := rows.Scan(&a.AttachmentID, &a.TransactionID, &a.URL, &a.Filename, &a.UploadedAt); err != nil {
+			return nil, fmt.Errorf("ListAttachments: scan error: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListAttachments: rows iteration error: %w", err)
+	}
+	return attachments, nil
+}
+
+// RemoveAttachment deletes an attachment reference. The referenced file is not touched.
+func (r *mysqlAttachmentRepository) RemoveAttachment(attachmentID int64) (int64, error) {
+	query := "DELETE FROM transaction_attachments WHERE attachment_id = ?"
+	result, err := r.db.op("RemoveAttachment").Exec(query, attachmentID)
+	if err != nil {
+		return 0, fmt.Errorf("RemoveAttachment: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("RemoveAttachment: RowsAffected failed: %w", err)
+	}
+	return rowsAffected, nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "testing"
+
+func TestAddAttachmentRequiresURLAndFilename(t *testing.T) {
+	// Validation happens before the database is touched.
+	attachments := NewMySQLAttachmentRepository(nil)
+	for _, tt := range []struct{ url, filename string }{
+		{"", "receipt.pdf"},
+		{"https://files.example.com/r/1", ""},
+	} {
+		if _, err := attachments.AddAttachment(1, tt.url, tt.filename); err == nil {
+			t.Errorf("AddAttachment(%q, %q) succeeded, want an error", tt.url, tt.filename)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"sql-golang-playground/models"
+)
+
+// mysqlBalanceSnapshotRepository implements BalanceSnapshotRepository for MySQL.
+type mysqlBalanceSnapshotRepository struct {
+	db dbHandle
+}
+
+// NewMySQLBalanceSnapshotRepository creates a new MySQL balance snapshot repository backed by a *sql.DB or *sql.Tx.
+func NewMySQLBalanceSnapshotRepository(db DBTX, opts ...RepositoryOption) BalanceSnapshotRepository {
+	return &mysqlBalanceSnapshotRepository{db: newDBHandle(db, opts)}
+}
+
+// CreateBalanceSnapshots records the balance as of asOf of every account that is not CLOSED and
+// returns the number of snapshots written. The stored balance is rolled back to asOf by undoing
+// the completed transactions after it, the same way GetLedgerBalance counts them, all in one
+// statement so concurrent transfers cannot skew it. Snapshots are immutable: if any of the
+// accounts already has one for asOf the error wraps ErrDuplicateEntry and nothing is written.
+func (r *mysqlBalanceSnapshotRepository) CreateBalanceSnapshots(asOf time.Time) (int64, error) {
+	query := `
+		INSERT INTO balance_snapshots (account_id, as_of, balance)
+		SELECT a.account_id, ?, a.balance - COALESCE(l.delta, 0)
+		FROM accounts a
+		LEFT JOIN (
+			SELECT account_id, SUM(delta) AS delta
+			FROM (
+				SELECT to_account_id AS account_id, COALESCE(credited_amount, ABS(amount)) AS delta
+				FROM transactions
+				WHERE to_account_id IS NOT NULL AND status = ? AND transaction_ts > ?
+				UNION ALL
+				SELECT from_account_id, -ABS(amount)
+				FROM transactions
+				WHERE from_account_id IS NOT NULL AND status = ? AND transaction_ts > ?
+				  AND (to_account_id IS NULL OR to_account_id <> from_account_id)
+			) entries
+			GROUP BY account_id
+		) l ON l.account_id = a.account_id
+		WHERE a.status <> ?`
+	asOf = asOf.UTC()
+	result, err := r.db.op("CreateBalanceSnapshots").Exec(query, asOf,
+		models.TransactionStatusCompleted, asOf, models.TransactionStatusCompleted, asOf, models.AccountStatusClosed)
+	if err != nil {
+		return 0, fmt.Errorf("CreateBalanceSnapshots: %w", translateMySQLError(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("CreateBalanceSnapshots: RowsAffected failed: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+// GetLatestBalanceSnapshot returns the account's most recent snapshot taken at or before asOf.
+// With no such snapshot the error wraps sql.ErrNoRows.
+func (r *mysqlBalanceSnapshotRepository) GetLatestBalanceSnapshot(accountID int64, asOf time.Time) (models.BalanceSnapshot, error) {
+	query := "SELECT account_id, as_of, balance FROM balance_snapshots WHERE account_id = ? AND as_of <= ? ORDER BY as_of DESC LIMIT 1"
+	var snapshot models.BalanceSnapshot
+	err := r.db.op("GetLatestBalanceSnapshot").QueryRow(query, accountID, asOf.UTC()).
+		Scan(&snapshot.AccountID, (*utcTime)(&snapshot.AsOf), (*decimal)(&snapshot.Balance))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return snapshot, fmt.Errorf("GetLatestBalanceSnapshot: no snapshot of account %d at or before %s: %w", accountID, asOf.UTC().Format(time.RFC3339), err)
+		}
+		return snapshot, fmt.Errorf("GetLatestBalanceSnapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// SumLedgerChanges returns the net effect on the account's balance of its completed transactions
+// with after < transaction_ts <= through, counted as GetLedgerBalance does. A zero after starts
+// from the first transaction.
+func (r *mysqlBalanceSnapshotRepository) SumLedgerChanges(accountID int64, after, through time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(CASE
+			WHEN to_account_id = ? THEN COALESCE(credited_amount, ABS(amount))
+			WHEN from_account_id = ? THEN -ABS(amount)
+			ELSE 0 END), 0)
+		FROM transactions
+		WHERE (from_account_id = ? OR to_account_id = ?) AND status = ? AND transaction_ts <= ?`
+	args := []interface{}{accountID, accountID, accountID, accountID, models.TransactionStatusCompleted, through.UTC()}
+	if !after.IsZero() {
+		query += " AND transaction_ts > ?"
+		args = append(args, after.UTC())
+	}
+
+	var change float64
+	if err := r.db.op("SumLedgerChanges").QueryRow(query, args...).Scan((*decimal)(&change)); err != nil {
+		return 0, fmt.Errorf("SumLedgerChanges: %w", err)
+	}
+	return change, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Typed errors for common MySQL failures. Errors returned by the repositories wrap both the
+// sentinel and the original *mysql.MySQLError, so callers can use errors.Is or errors.As.
+var (
+	ErrDuplicateEntry      = errors.New("duplicate entry")
+	ErrForeignKeyViolation = errors.New("foreign key constraint violation")
+	ErrDeadlock            = errors.New("deadlock detected")
+	ErrUnknownColumn       = errors.New("unknown column; the schema is missing a migration")
+)
+
+// ErrBalanceAffectingTransaction is returned by DeleteTransaction for rows that changed a balance;
+// those must be undone with TransactionService.ReverseTransaction instead.
+var ErrBalanceAffectingTransaction = errors.New("transaction affected account balances; reverse it instead of deleting")
+
+// ErrTransactionNotFound is returned by GetTransactionDetailByID when no transaction has the ID.
+// The error also wraps sql.ErrNoRows.
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// ErrCategoryNotFound is returned when a category filter names a category that does not exist.
+var ErrCategoryNotFound = errors.New("category not found")
+
+// ErrAccountNotClosed is returned by UndeleteAccount when there is no CLOSED account with the ID,
+// including when an earlier call already reopened it.
+var ErrAccountNotClosed = errors.New("no closed account to undelete")
+
+// MySQL server error numbers.
+const (
+	mysqlErrDuplicateEntry  uint16 = 1062
+	mysqlErrRowIsReferenced uint16 = 1451 // cannot delete or update a parent row
+	mysqlErrNoReferencedRow uint16 = 1452 // cannot add or update a child row
+	mysqlErrDeadlock        uint16 = 1213
+	mysqlErrBadField        uint16 = 1054 // unknown column
+)
+
+// notesColumnFix is the DDL that adds transactions.notes to databases created before it existed.
+const notesColumnFix = "ALTER TABLE transactions ADD COLUMN notes TEXT NULL"
+
+// translateMySQLError maps well-known MySQL error numbers to the typed errors above,
+// keeping the original error in the chain. Other errors are returned unchanged.
+func translateMySQLError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+	switch mysqlErr.Number {
+	case mysqlErrDuplicateEntry:
+		return fmt.Errorf("%w: %w", ErrDuplicateEntry, err)
+	case mysqlErrRowIsReferenced, mysqlErrNoReferencedRow:
+		return fmt.Errorf("%w: %w", ErrForeignKeyViolation, err)
+	case mysqlErrDeadlock:
+		return fmt.Errorf("%w: %w", ErrDeadlock, err)
+	case mysqlErrBadField:
+		return fmt.Errorf("%w: %w", ErrUnknownColumn, err)
+	}
+	return err
+}
+
+// translateNotesError is translateMySQLError for queries that use transactions.notes.
+// When that column is missing, the error names the column and the statement that adds it.
+func translateNotesError(err error) error {
+	err = translateMySQLError(err)
+	if errors.Is(err, ErrUnknownColumn) && strings.Contains(err.Error(), "'notes'") {
+		return fmt.Errorf("transactions.notes column is missing, add it with %q: %w", notesColumnFix, err)
+	}
+	return err
+}
+
+// isDuplicateEntryError reports whether err is a MySQL unique index violation.
+func isDuplicateEntryError(err error) bool {
+	return errors.Is(translateMySQLError(err), ErrDuplicateEntry)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestTranslateMySQLError(t *testing.T) {
+	tests := []struct {
+		name   string
+		number uint16
+		want   error
+	}{
+		{"duplicate entry", mysqlErrDuplicateEntry, ErrDuplicateEntry},
+		{"row is referenced", mysqlErrRowIsReferenced, ErrForeignKeyViolation},
+		{"no referenced row", mysqlErrNoReferencedRow, ErrForeignKeyViolation},
+		{"deadlock", mysqlErrDeadlock, ErrDeadlock},
+		{"unknown column", mysqlErrBadField, ErrUnknownColumn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driverErr := &mysql.MySQLError{Number: tt.number, Message: tt.name}
+			err := translateMySQLError(fmt.Errorf("exec: %w", driverErr))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("translateMySQLError = %v, want it to wrap %v", err, tt.want)
+			}
+			var got *mysql.MySQLError
+			if !errors.As(err, &got) || got != driverErr {
+				t.Errorf("translateMySQLError = %v, want it to keep the driver error", err)
+			}
+		})
+	}
+
+	other := &mysql.MySQLError{Number: 1064, Message: "syntax error"}
+	if err := translateMySQLError(other); err != other {
+		t.Errorf("translateMySQLError(%v) = %v, want it unchanged", other, err)
+	}
+	plain := errors.New("connection refused")
+	if err := translateMySQLError(plain); err != plain {
+		t.Errorf("translateMySQLError(%v) = %v, want it unchanged", plain, err)
+	}
+}
+
+func TestTranslateNotesError(t *testing.T) {
+	// The error MySQL returns when a query selects a column the table lacks.
+	missingNotes := &mysql.MySQLError{Number: mysqlErrBadField, Message: "Unknown column 'notes' in 'field list'"}
+	err := translateNotesError(missingNotes)
+	if !errors.Is(err, ErrUnknownColumn) || !strings.Contains(err.Error(), notesColumnFix) {
+		t.Errorf("translateNotesError = %v, want ErrUnknownColumn naming %q", err, notesColumnFix)
+	}
+	var driverErr *mysql.MySQLError
+	if !errors.As(err, &driverErr) {
+		t.Errorf("translateNotesError = %v, want it to keep the driver error", err)
+	}
+
+	// Other missing columns get no notes advice.
+	missingOther := &mysql.MySQLError{Number: mysqlErrBadField, Message: "Unknown column 'status' in 'where clause'"}
+	if err := translateNotesError(missingOther); !errors.Is(err, ErrUnknownColumn) || strings.Contains(err.Error(), "notes") {
+		t.Errorf("translateNotesError = %v, want a plain ErrUnknownColumn", err)
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"sql-golang-playground/models"
+)
+
+// mysqlFixtureRepository implements FixtureRepository for MySQL.
+type mysqlFixtureRepository struct {
+	db dbHandle
+}
+
+// NewMySQLFixtureRepository creates a new MySQL fixture repository backed by a *sql.DB or *sql.Tx.
+// Imports should use a *sql.Tx so a failed import leaves nothing behind.
+func NewMySQLFixtureRepository(db DBTX, opts ...RepositoryOption) FixtureRepository {
+	return &mysqlFixtureRepository{db: newDBHandle(db, opts)}
+}
+
+// GetAllAccountsForFixtures returns every account, CLOSED ones included, in ID order.
+func (r *mysqlFixtureRepository) GetAllAccountsForFixtures() ([]models.Account, error) {
+	rows, err := r.db.op("GetAllAccountsForFixtures").Query("SELECT " + accountColumns + " FROM accounts ORDER BY account_id")
+	if err != nil {
+		return nil, fmt.Errorf("GetAllAccountsForFixtures: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []models.Account
+	for rows.Next() {
+		var acc models.Account
+		if err := scanAccount(rows, &acc); err != nil {
+			return nil, fmt.Errorf("GetAllAccountsForFixtures: scan error: %w", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetAllAccountsForFixtures: rows iteration error: %w", err)
+	}
+	return accounts, nil
+}
+
+// GetAllTransactionsForFixtures returns every transaction, soft-deleted and held ones included,
+// in ID order, so a reversal always comes after the transaction it reverses.
+func (r *mysqlFixtureRepository) GetAllTransactionsForFixtures() ([]models.Transaction, error) {
+	rows, err := r.db.op("GetAllTransactionsForFixtures").Query("SELECT " + transactionColumns + " FROM transactions ORDER BY transaction_id")
+	if err != nil {
+		return nil, fmt.Errorf("GetAllTransactionsForFixtures: %w", translateNotesError(err))
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		var tx models.Transaction
+		if err := scanTransaction(rows, &tx); err != nil {
+			return nil, fmt.Errorf("GetAllTransactionsForFixtures: scan error: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetAllTransactionsForFixtures: rows iteration error: %w", err)
+	}
+	return transactions, nil
+}
+
+// InsertAccountFixture inserts acc as it is, balance and status included, and returns its ID.
+// acc.AccountID is kept when no account has it yet; otherwise the database assigns a new one.
+// An account number that is already taken fails with ErrDuplicateEntry.
+func (r *mysqlFixtureRepository) InsertAccountFixture(acc models.Account) (int64, error) {
+	id, err := r.freeID("InsertAccountFixture", "SELECT EXISTS(SELECT 1 FROM accounts WHERE account_id = ?)", acc.AccountID)
+	if err != nil {
+		return 0, err
+	}
+	query := "INSERT INTO accounts (account_id, account_number, account_holder, balance, currency, min_balance, max_balance, overdraft_limit, last_updated, status, metadata) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	result, err := r.db.op("InsertAccountFixture").Exec(query, id, acc.AccountNumber, acc.AccountHolder, acc.Balance, acc.Currency,
+		acc.MinBalance, acc.MaxBalance, acc.OverdraftLimit, acc.LastUpdated.UTC(), acc.Status, acc.Metadata)
+	if err != nil {
+		return 0, fmt.Errorf("InsertAccountFixture: account %d: %w", acc.AccountID, translateMySQLError(err))
+	}
+	return insertedID("InsertAccountFixture", id, result)
+}
+
+// InsertTransactionFixture inserts tx as it is and returns its ID, keeping tx.TransactionID when
+// it is free like InsertAccountFixture. The accounts and reversed transaction it refers to must
+// already exist under the IDs in tx.
+func (r *mysqlFixtureRepository) InsertTransactionFixture(tx models.Transaction) (int64, error) {
+	id, err := r.freeID("InsertTransactionFixture", "SELECT EXISTS(SELECT 1 FROM transactions WHERE transaction_id = ?)", tx.TransactionID)
+	if err != nil {
+		return 0, err
+	}
+	query := "INSERT INTO transactions (" + transactionColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	result, err := r.db.op("InsertTransactionFixture").Exec(query, id, tx.FromAccountID, tx.ToAccountID, tx.TransactionType, tx.Amount,
+		tx.CreditedAmount, tx.ExchangeRate, tx.TransactionTs.UTC(), tx.Description, tx.Notes, tx.ReversalOf, tx.Status, tx.IsDeleted)
+	if err != nil {
+		return 0, fmt.Errorf("InsertTransactionFixture: transaction %d: %w", tx.TransactionID, translateNotesError(err))
+	}
+	return insertedID("InsertTransactionFixture", id, result)
+}
+
+// freeID returns id when existsQuery reports no row with it, and NULL, meaning "assign one",
+// otherwise or when id is not positive.
+func (r *mysqlFixtureRepository) freeID(op, existsQuery string, id int64) (sql.NullInt64, error) {
+	if id <= 0 {
+		return sql.NullInt64{}, nil
+	}
+	var taken bool
+	if err := r.db.op(op).QueryRow(existsQuery, id).Scan(&taken); err != nil {
+		return sql.NullInt64{}, fmt.Errorf("%s: ID lookup failed: %w", op, err)
+	}
+	return sql.NullInt64{Int64: id, Valid: !taken}, nil
+}
+
+// insertedID returns the explicit id if one was used, or the one the database assigned.
+func insertedID(op string, id sql.NullInt64, result sql.Result) (int64, error) {
+	if id.Valid {
+		return id.Int64, nil
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: LastInsertId failed: %w", op, err)
+	}
+	return newID, nil
+}
@@ -0,0 +1,469 @@
+//go:build integration
+
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sql-golang-playground/internal/testdb"
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+)
+
+func TestMain(m *testing.M) {
+	testdb.Main(m)
+}
+
+func TestIntegrationAccountCRUD(t *testing.T) {
+	accounts := NewMySQLAccountRepository(testdb.New(t))
+
+	id, number, err := accounts.CreateAccount("  Alice  ", 100.10)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	acc, err := accounts.GetAccountByID(id)
+	if err != nil {
+		t.Fatalf("GetAccountByID: %v", err)
+	}
+	if acc.AccountHolder != "Alice" || acc.AccountNumber != number || acc.Balance != 100.10 ||
+		acc.Status != models.AccountStatusActive || acc.Currency != models.DefaultCurrency || acc.LastUpdated.IsZero() {
+		t.Errorf("GetAccountByID = %+v, want an ACTIVE account for Alice with 100.10", acc)
+	}
+	if byNumber, err := accounts.GetAccountByNumber(number); err != nil || byNumber.AccountID != id {
+		t.Errorf("GetAccountByNumber(%s) = %d, %v; want %d", number, byNumber.AccountID, err, id)
+	}
+
+	if n, err := accounts.UpdateAccountHolderName(id, "Alice Smith"); err != nil || n != 1 {
+		t.Errorf("UpdateAccountHolderName = %d, %v; want 1, nil", n, err)
+	}
+	if n, err := accounts.UpdateAccountHolderName(id, "Alice Smith"); err != nil || n != 0 {
+		t.Errorf("UpdateAccountHolderName with the same name = %d, %v; want 0, nil", n, err)
+	}
+
+	// DECIMAL arithmetic keeps cents exact where float64 would drift.
+	for _, change := range []float64{0.1, 0.2, -0.3} {
+		if _, err := accounts.AdjustAccountBalance(id, change); err != nil {
+			t.Fatalf("AdjustAccountBalance(%v): %v", change, err)
+		}
+	}
+	if acc, _ := accounts.GetAccountByID(id); acc.Balance != 100.10 {
+		t.Errorf("balance after +0.1 +0.2 -0.3 = %v, want 100.10", acc.Balance)
+	}
+	if _, err := accounts.AdjustAccountBalance(id, -1000); !errors.Is(err, util.ErrInsufficientFunds) {
+		t.Errorf("overdrawing AdjustAccountBalance error = %v, want %v", err, util.ErrInsufficientFunds)
+	}
+
+	if _, err := accounts.SoftDeleteAccount(id); err != nil {
+		t.Fatalf("SoftDeleteAccount: %v", err)
+	}
+	_, err = accounts.GetAccountByID(id)
+	if !errors.Is(err, util.ErrAccountNotFound) || !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetAccountByID of a closed account error = %v, want ErrAccountNotFound wrapping sql.ErrNoRows", err)
+	}
+	if _, total, err := accounts.GetAllAccounts(AccountListOptions{}); err != nil || total != 0 {
+		t.Errorf("GetAllAccounts total = %d, %v; want the closed account excluded", total, err)
+	}
+	if _, err := accounts.AdjustAccountBalance(id, 5); !errors.Is(err, util.ErrAccountNotFound) {
+		t.Errorf("AdjustAccountBalance of a closed account error = %v, want %v", err, util.ErrAccountNotFound)
+	}
+	if closed, err := accounts.GetAccountByIDIncludingDeleted(id); err != nil || closed.Status != models.AccountStatusClosed {
+		t.Errorf("GetAccountByIDIncludingDeleted = %+v, %v; want the CLOSED account", closed, err)
+	}
+
+	if _, err := accounts.UndeleteAccount(id); err != nil {
+		t.Fatalf("UndeleteAccount: %v", err)
+	}
+	if _, err := accounts.GetAccountByID(id); err != nil {
+		t.Errorf("GetAccountByID after UndeleteAccount: %v", err)
+	}
+	if _, err := accounts.GetAccountByID(id + 1000); !errors.Is(err, util.ErrAccountNotFound) {
+		t.Errorf("GetAccountByID of a missing account error = %v, want %v", err, util.ErrAccountNotFound)
+	}
+}
+
+func TestIntegrationSoftDeletedTransactionsAreHidden(t *testing.T) {
+	conn := testdb.New(t)
+	accounts := NewMySQLAccountRepository(conn)
+	transactions := NewMySQLTransactionRepository(conn)
+
+	id, _, err := accounts.CreateAccount("Alice", 0)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	to := sql.NullInt64{Int64: id, Valid: true}
+	keep, err := transactions.CreateTransaction(sql.NullInt64{}, to, "DEPOSIT", 10, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	hide, err := transactions.CreateTransaction(sql.NullInt64{}, to, "DEPOSIT", 20, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	if _, err := transactions.SoftDeleteTransaction(hide); err != nil {
+		t.Fatalf("SoftDeleteTransaction: %v", err)
+	}
+
+	history, err := transactions.GetTransactionsForAccount(id, nil)
+	if err != nil {
+		t.Fatalf("GetTransactionsForAccount: %v", err)
+	}
+	if len(history) != 1 || history[0].TransactionID != keep {
+		t.Errorf("GetTransactionsForAccount = %+v, want only transaction %d", history, keep)
+	}
+	if all, err := transactions.GetAllTransactionsForReconciliation(); err != nil || len(all) != 1 {
+		t.Errorf("GetAllTransactionsForReconciliation = %d transactions, %v; want 1", len(all), err)
+	}
+	if _, total, err := transactions.ListTransactions(TransactionFilter{IncludeDeleted: true}); err != nil || total != 2 {
+		t.Errorf("ListTransactions with IncludeDeleted total = %d, %v; want 2", total, err)
+	}
+	// Hidden rows still moved money, so they count toward the ledger.
+	if ledger, err := transactions.GetLedgerBalance(id); err != nil || ledger != 30 {
+		t.Errorf("GetLedgerBalance = %v, %v; want 30", ledger, err)
+	}
+}
+
+func TestIntegrationSoftDeleteInactiveAccounts(t *testing.T) {
+	conn := testdb.New(t)
+	accounts := NewMySQLAccountRepository(conn)
+	transactions := NewMySQLTransactionRepository(conn)
+	create := func(holder string, balance float64) int64 {
+		t.Helper()
+		id, _, err := accounts.CreateAccount(holder, balance)
+		if err != nil {
+			t.Fatalf("CreateAccount(%q): %v", holder, err)
+		}
+		return id
+	}
+	dormant := create("Dormant", 40)
+	empty := create("Empty", 0)
+	busy := create("Busy", 10)
+	frozen := create("Frozen", 0)
+	if _, err := accounts.FreezeAccount(frozen); err != nil {
+		t.Fatalf("FreezeAccount: %v", err)
+	}
+
+	// Every account was opened before since; only busy has a transaction after it.
+	since := time.Now().Add(time.Hour)
+	if _, err := transactions.CreateTransactionAt(sql.NullInt64{}, sql.NullInt64{Int64: busy, Valid: true}, "DEPOSIT", 5, sql.NullString{}, sql.NullString{}, since.Add(time.Minute)); err != nil {
+		t.Fatalf("CreateTransactionAt: %v", err)
+	}
+
+	// The accounts are selected from the table being updated, which MySQL rejects in a
+	// single UPDATE (error 1093); the SQL path must not.
+	closed, err := accounts.SoftDeleteInactiveZeroBalanceAccounts(since)
+	if err != nil || closed != 1 {
+		t.Fatalf("SoftDeleteInactiveZeroBalanceAccounts = %d, %v; want 1, nil", closed, err)
+	}
+	if _, err := accounts.GetAccountByID(empty); !errors.Is(err, util.ErrAccountNotFound) {
+		t.Errorf("zero-balance dormant account still open: %v", err)
+	}
+
+	closed, err = accounts.SoftDeleteInactiveAccounts(since)
+	if err != nil || closed != 1 {
+		t.Fatalf("SoftDeleteInactiveAccounts = %d, %v; want 1, nil", closed, err)
+	}
+	if _, err := accounts.GetAccountByID(dormant); !errors.Is(err, util.ErrAccountNotFound) {
+		t.Errorf("dormant account still open: %v", err)
+	}
+	for _, id := range []int64{busy, frozen} {
+		if _, err := accounts.GetAccountByID(id); err != nil {
+			t.Errorf("account %d was closed: %v", id, err)
+		}
+	}
+
+	// Closing records the previous status, so the accounts reopen as ACTIVE.
+	if _, err := accounts.UndeleteAccount(dormant); err != nil {
+		t.Fatalf("UndeleteAccount: %v", err)
+	}
+	if acc, err := accounts.GetAccountByID(dormant); err != nil || acc.Status != models.AccountStatusActive {
+		t.Errorf("reopened account = %+v, %v; want it ACTIVE", acc, err)
+	}
+}
+
+func TestIntegrationPreparedStatementsJoinTransaction(t *testing.T) {
+	conn := testdb.New(t)
+	accounts := NewMySQLAccountRepository(conn, WithPreparedStatements())
+	defer accounts.Close()
+	id, _, err := accounts.CreateAccount("Alice", 100)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+
+	// AdjustAccountBalance is prepared; bound to the transaction, it must run inside it and be
+	// undone by the rollback.
+	rollback := errors.New("roll back")
+	err = WithTransaction(conn, func(tx *sql.Tx) error {
+		if _, err := accounts.WithDBTX(tx).AdjustAccountBalance(id, -40); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("WithTransaction error = %v, want %v", err, rollback)
+	}
+	if acc, _ := accounts.GetAccountByID(id); acc.Balance != 100 {
+		t.Errorf("balance after rollback = %v, want 100", acc.Balance)
+	}
+}
+
+// BenchmarkAdjustAccountBalance compares balance adjustments with and without
+// WithPreparedStatements, on the database and inside transactions. Each iteration adjusts 1000
+// balances. Run with:
+//
+//	go test -tags integration -run '^$' -bench AdjustAccountBalance ./repository/
+func BenchmarkAdjustAccountBalance(b *testing.B) {
+	const adjustments = 1000
+	for _, bm := range []struct {
+		name string
+		opts []RepositoryOption
+	}{
+		{"unprepared", nil},
+		{"prepared", []RepositoryOption{WithPreparedStatements()}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			conn := testdb.New(b)
+			accounts := NewMySQLAccountRepository(conn, bm.opts...)
+			defer accounts.Close()
+			id, _, err := accounts.CreateAccount("Bench", 0)
+			if err != nil {
+				b.Fatalf("CreateAccount: %v", err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < adjustments; j++ {
+					if _, err := accounts.AdjustAccountBalance(id, 1); err != nil {
+						b.Fatalf("AdjustAccountBalance: %v", err)
+					}
+				}
+			}
+		})
+		b.Run(bm.name+" in transaction", func(b *testing.B) {
+			conn := testdb.New(b)
+			accounts := NewMySQLAccountRepository(conn, bm.opts...)
+			defer accounts.Close()
+			id, _, err := accounts.CreateAccount("Bench", 0)
+			if err != nil {
+				b.Fatalf("CreateAccount: %v", err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := WithTransaction(conn, func(tx *sql.Tx) error {
+					inTx := accounts.WithDBTX(tx)
+					for j := 0; j < adjustments; j++ {
+						if _, err := inTx.AdjustAccountBalance(id, 1); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					b.Fatalf("AdjustAccountBalance: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// countingObserver counts the queries it observes, by repository method.
+type countingObserver struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (o *countingObserver) ObserveQuery(name string, _ time.Duration, _ error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.counts == nil {
+		o.counts = make(map[string]int)
+	}
+	o.counts[name]++
+}
+
+func TestIntegrationGetBalancesForTransactions(t *testing.T) {
+	conn := testdb.New(t)
+	accounts := NewMySQLAccountRepository(conn)
+	var ids []int64
+	for i, balance := range []float64{100, 20.5, 7, 0.1} {
+		id, _, err := accounts.CreateAccount(fmt.Sprintf("Holder %d", i), balance)
+		if err != nil {
+			t.Fatalf("CreateAccount: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := accounts.SoftDeleteAccount(ids[2]); err != nil {
+		t.Fatalf("SoftDeleteAccount: %v", err)
+	}
+
+	id := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+	feed := []models.Transaction{
+		{FromAccountID: id(ids[0]), ToAccountID: id(ids[1])},
+		{FromAccountID: id(ids[1]), ToAccountID: id(ids[2])},
+		{ToAccountID: id(ids[3])},
+		{FromAccountID: id(ids[0]), ToAccountID: id(ids[3])},
+	}
+	observer := &countingObserver{}
+	observed := NewMySQLAccountRepository(conn, WithQueryObserver(observer))
+	balances, err := observed.GetBalancesForTransactions(feed)
+	if err != nil {
+		t.Fatalf("GetBalancesForTransactions: %v", err)
+	}
+	want := map[int64]float64{ids[0]: 100, ids[1]: 20.5, ids[3]: 0.1}
+	if !reflect.DeepEqual(balances, want) {
+		t.Errorf("GetBalancesForTransactions = %v, want %v", balances, want)
+	}
+	if n := len(observer.counts); n != 1 || observer.counts["GetBalancesByIDs"] != 1 {
+		t.Errorf("queries = %v, want a single GetBalancesByIDs", observer.counts)
+	}
+}
+
+func TestIntegrationIsValidTransferTarget(t *testing.T) {
+	db := testdb.New(t)
+	testIsValidTransferTarget(t, NewMySQLAccountRepository(db), NewMySQLFixtureRepository(db))
+}
+
+func TestIntegrationAccountMetadata(t *testing.T) {
+	testAccountMetadata(t, NewMySQLAccountRepository(testdb.New(t)))
+}
+
+func TestIntegrationCreateTransactionIdempotent(t *testing.T) {
+	conn := testdb.New(t)
+	testCreateTransactionIdempotent(t, NewMySQLAccountRepository(conn), NewMySQLTransactionRepository(conn))
+}
+
+func TestIntegrationFindTransactionIDGaps(t *testing.T) {
+	conn := testdb.New(t)
+	testFindTransactionIDGaps(t, NewMySQLAccountRepository(conn), NewMySQLTransactionRepository(conn))
+}
+
+func TestIntegrationGetTransactionsWithCategoryByAmount(t *testing.T) {
+	conn := testdb.New(t)
+	testGetTransactionsWithCategoryByAmount(t, NewMySQLAccountRepository(conn), NewMySQLTransactionRepository(conn))
+}
+
+func TestIntegrationGetFeesForPeriod(t *testing.T) {
+	conn := testdb.New(t)
+	testGetFeesForPeriod(t, NewMySQLAccountRepository(conn), NewMySQLTransactionRepository(conn))
+}
+
+func TestIntegrationHasTransactions(t *testing.T) {
+	conn := testdb.New(t)
+	testHasTransactions(t, NewMySQLAccountRepository(conn), NewMySQLTransactionRepository(conn))
+}
+
+func TestIntegrationAttachments(t *testing.T) {
+	conn := testdb.New(t)
+	accounts := NewMySQLAccountRepository(conn)
+	transactions := NewMySQLTransactionRepository(conn)
+	attachments := NewMySQLAttachmentRepository(conn)
+
+	accountID, _, err := accounts.CreateAccount("Alice", 0)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	to := sql.NullInt64{Int64: accountID, Valid: true}
+	txID, err := transactions.CreateTransaction(sql.NullInt64{}, to, "DEPOSIT", 10, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+
+	receipt, err := attachments.AddAttachment(txID, "https://files.example.com/r/1", "receipt.pdf")
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+	invoice, err := attachments.AddAttachment(txID, "https://files.example.com/r/2", "invoice.pdf")
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+	if _, err := attachments.AddAttachment(txID+1000, "https://files.example.com/r/3", "orphan.pdf"); !errors.Is(err, ErrForeignKeyViolation) {
+		t.Errorf("AddAttachment to a missing transaction error = %v, want %v", err, ErrForeignKeyViolation)
+	}
+
+	list, err := attachments.ListAttachments(txID)
+	if err != nil {
+		t.Fatalf("ListAttachments: %v", err)
+	}
+	if len(list) != 2 || list[0].AttachmentID != receipt || list[1].AttachmentID != invoice {
+		t.Fatalf("ListAttachments = %+v, want attachments %d and %d, oldest first", list, receipt, invoice)
+	}
+	if a := list[0]; a.TransactionID != txID || a.URL != "https://files.example.com/r/1" || a.Filename != "receipt.pdf" || a.UploadedAt.IsZero() {
+		t.Errorf("ListAttachments[0] = %+v, want the receipt", a)
+	}
+
+	if n, err := attachments.RemoveAttachment(receipt); err != nil || n != 1 {
+		t.Errorf("RemoveAttachment = %d, %v; want 1, nil", n, err)
+	}
+	if n, err := attachments.RemoveAttachment(receipt); err != nil || n != 0 {
+		t.Errorf("RemoveAttachment again = %d, %v; want 0, nil", n, err)
+	}
+	if list, err := attachments.ListAttachments(txID); err != nil || len(list) != 1 || list[0].AttachmentID != invoice {
+		t.Errorf("ListAttachments after removing = %+v, %v; want only the invoice", list, err)
+	}
+}
+
+func TestIntegrationMissingNotesColumn(t *testing.T) {
+	conn := testdb.New(t)
+	// A database created before transactions.notes existed.
+	if _, err := conn.Exec("ALTER TABLE transactions DROP COLUMN notes"); err != nil {
+		t.Fatalf("drop notes: %v", err)
+	}
+	_, err := NewMySQLTransactionRepository(conn).GetAllTransactionsForReconciliation()
+	if !errors.Is(err, ErrUnknownColumn) || !strings.Contains(err.Error(), notesColumnFix) {
+		t.Errorf("GetAllTransactionsForReconciliation error = %v, want ErrUnknownColumn naming %q", err, notesColumnFix)
+	}
+}
+
+func TestIntegrationTimestampsAreUTC(t *testing.T) {
+	conn := testdb.New(t)
+	testTimestampsAreUTC(t, NewMySQLAccountRepository(conn), NewMySQLTransactionRepository(conn))
+}
+
+func TestIntegrationHolderNameValidation(t *testing.T) {
+	testHolderNameValidation(t, NewMySQLAccountRepository(testdb.New(t)))
+}
+
+func TestIntegrationMySQLLocker(t *testing.T) {
+	conn := testdb.New(t)
+	locker := NewMySQLLocker(conn)
+	const name = "integration_test_lock"
+
+	release, acquired, err := locker.TryLock(name)
+	if err != nil || !acquired {
+		t.Fatalf("TryLock = %v, %v; want the lock", acquired, err)
+	}
+	// GET_LOCK is per session, so a second TryLock on another connection of the same pool must
+	// not get it while the first holds it.
+	if _, acquired, err := locker.TryLock(name); err != nil || acquired {
+		t.Fatalf("TryLock of a held lock = %v, %v; want it refused", acquired, err)
+	}
+	if _, acquired, err := NewMySQLLocker(conn).TryLock(name); err != nil || acquired {
+		t.Fatalf("TryLock of a held lock from another locker = %v, %v; want it refused", acquired, err)
+	}
+	if err := release(); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+
+	release, acquired, err = locker.TryLock(name)
+	if err != nil || !acquired {
+		t.Fatalf("TryLock after release = %v, %v; want the lock", acquired, err)
+	}
+	if err := release(); err != nil {
+		t.Errorf("release: %v", err)
+	}
+}
+
+func TestIntegrationGetDistinctTransactionTypes(t *testing.T) {
+	conn := testdb.New(t)
+	testGetDistinctTransactionTypes(t, NewMySQLAccountRepository(conn), NewMySQLTransactionRepository(conn))
+}
+
+func TestIntegrationPendingTransfersHidden(t *testing.T) {
+	conn := testdb.New(t)
+	testPendingTransfersHidden(t, NewMySQLAccountRepository(conn), NewMySQLTransactionRepository(conn))
+}
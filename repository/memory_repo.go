@@ -0,0 +1,1536 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+)
+
+// MemoryStore is an in-memory stand-in for the accounts and transactions tables, for tests that
+// should not need MySQL. Its repositories mirror the MySQL implementations' semantics: soft
+// deletes hide accounts, balance limits are enforced, and the same sentinel errors are returned.
+// All access is guarded by one mutex, so it is safe for concurrent use. MemoryStore is also a
+// Transactor: units of work run one at a time and are rolled back as a whole when they fail.
+type MemoryStore struct {
+	txMu            sync.Mutex // held for the whole of a WithTransaction unit of work
+	mu              sync.Mutex
+	accounts        map[int64]*models.Account
+	transactions    map[int64]*models.Transaction
+	idempotencyKeys map[string]int64
+	externalIDs     map[string]int64 // external customer ID -> account ID
+	categories      map[string]int64 // category name -> ID
+	categoryOf      map[int64]int64  // transaction ID -> category ID
+	previousStatus  map[int64]string // closed account ID -> status before closing
+	outboxEvents    []models.OutboxEvent
+	nextAccountID   int64
+	nextTxID        int64
+	nextCategoryID  int64
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		accounts:        make(map[int64]*models.Account),
+		transactions:    make(map[int64]*models.Transaction),
+		idempotencyKeys: make(map[string]int64),
+		externalIDs:     make(map[string]int64),
+		categories:      make(map[string]int64),
+		categoryOf:      make(map[int64]int64),
+		previousStatus:  make(map[int64]string),
+	}
+}
+
+// Accounts returns an AccountRepository backed by the store.
+func (m *MemoryStore) Accounts() AccountRepository {
+	return &memoryAccountRepository{store: m}
+}
+
+// Transactions returns a TransactionRepository backed by the store.
+func (m *MemoryStore) Transactions() TransactionRepository {
+	return &memoryTransactionRepository{store: m}
+}
+
+// Outbox returns an OutboxRepository backed by the store.
+func (m *MemoryStore) Outbox() OutboxRepository {
+	return &memoryOutboxRepository{store: m}
+}
+
+// Fixtures returns a FixtureRepository backed by the store. It is also the way to seed accounts
+// with a currency, balance limits or an overdraft, which CreateAccount does not set.
+func (m *MemoryStore) Fixtures() FixtureRepository {
+	return &memoryFixtureRepository{store: m}
+}
+
+// memoryState is a copy of a MemoryStore's data that WithTransaction rolls back to.
+type memoryState struct {
+	accounts        map[int64]*models.Account
+	transactions    map[int64]*models.Transaction
+	idempotencyKeys map[string]int64
+	externalIDs     map[string]int64
+	categories      map[string]int64
+	categoryOf      map[int64]int64
+	previousStatus  map[int64]string
+	outboxEvents    []models.OutboxEvent
+	nextAccountID   int64
+	nextTxID        int64
+	nextCategoryID  int64
+}
+
+// copyMap returns a shallow copy of src.
+func copyMap[K comparable, V any](src map[K]V) map[K]V {
+	out := make(map[K]V, len(src))
+	for k, v := range src {
+		out[k] = v
+	}
+	return out
+}
+
+// snapshot copies the store's data deeply enough that later changes do not reach the copy.
+func (m *MemoryStore) snapshot() memoryState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state := memoryState{
+		accounts:        make(map[int64]*models.Account, len(m.accounts)),
+		transactions:    make(map[int64]*models.Transaction, len(m.transactions)),
+		idempotencyKeys: copyMap(m.idempotencyKeys),
+		externalIDs:     copyMap(m.externalIDs),
+		categories:      copyMap(m.categories),
+		categoryOf:      copyMap(m.categoryOf),
+		previousStatus:  copyMap(m.previousStatus),
+		outboxEvents:    append([]models.OutboxEvent(nil), m.outboxEvents...),
+		nextAccountID:   m.nextAccountID,
+		nextTxID:        m.nextTxID,
+		nextCategoryID:  m.nextCategoryID,
+	}
+	for id, acc := range m.accounts {
+		acc := copyAccount(acc)
+		state.accounts[id] = &acc
+	}
+	for id, tx := range m.transactions {
+		tx := *tx
+		state.transactions[id] = &tx
+	}
+	return state
+}
+
+// restore replaces the store's data with state.
+func (m *MemoryStore) restore(state memoryState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accounts, m.transactions = state.accounts, state.transactions
+	m.idempotencyKeys, m.externalIDs = state.idempotencyKeys, state.externalIDs
+	m.categories, m.categoryOf, m.previousStatus = state.categories, state.categoryOf, state.previousStatus
+	m.outboxEvents = state.outboxEvents
+	m.nextAccountID, m.nextTxID, m.nextCategoryID = state.nextAccountID, state.nextTxID, state.nextCategoryID
+}
+
+// WithTransaction implements Transactor. Units of work run one at a time; when fn returns an
+// error or panics, every change it made through the store's repositories is undone and the
+// error or panic is passed on. tx is nil: the store's repositories ignore WithDBTX. Calls made
+// outside a unit of work are not serialized with it.
+func (m *MemoryStore) WithTransaction(fn func(tx DBTX) error) (err error) {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	state := m.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			m.restore(state)
+			panic(p)
+		}
+	}()
+	if err := fn(nil); err != nil {
+		m.restore(state)
+		return err
+	}
+	return nil
+}
+
+// activeAccount returns the account with id unless it is missing or CLOSED. The caller must hold m.mu.
+func (m *MemoryStore) activeAccount(id int64) (*models.Account, bool) {
+	acc, ok := m.accounts[id]
+	if !ok || acc.Status == models.AccountStatusClosed {
+		return nil, false
+	}
+	return acc, true
+}
+
+// copyAccount returns a copy of acc that shares no maps with the store.
+func copyAccount(acc *models.Account) models.Account {
+	out := *acc
+	if acc.Metadata != nil {
+		out.Metadata = make(models.AccountMetadata, len(acc.Metadata))
+		for k, v := range acc.Metadata {
+			out.Metadata[k] = v
+		}
+	}
+	return out
+}
+
+// memoryAccountRepository implements AccountRepository on a MemoryStore.
+type memoryAccountRepository struct {
+	store *MemoryStore
+}
+
+// CreateAccount adds an active account and returns its ID and generated account number.
+// holderName is validated as by the MySQL implementation.
+func (r *memoryAccountRepository) CreateAccount(holderName string, initialBalance float64) (int64, string, error) {
+	holderName, err := normalizeHolderName(holderName)
+	if err != nil {
+		return 0, "", fmt.Errorf("CreateAccount: %w", err)
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.insertAccount(holderName, initialBalance)
+}
+
+// insertAccount adds an account with a fresh account number. The caller holds the store lock
+// and has normalized holderName.
+func (r *memoryAccountRepository) insertAccount(holderName string, initialBalance float64) (int64, string, error) {
+	for attempt := 1; ; attempt++ {
+		accountNumber, err := generateAccountNumber()
+		if err != nil {
+			return 0, "", fmt.Errorf("CreateAccount: failed to generate account number: %w", err)
+		}
+		duplicate := false
+		for _, acc := range r.store.accounts {
+			if acc.AccountNumber == accountNumber {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			if attempt < accountNumberAttempts {
+				continue
+			}
+			return 0, "", fmt.Errorf("CreateAccount: %w: account number %s", ErrDuplicateEntry, accountNumber)
+		}
+
+		r.store.nextAccountID++
+		id := r.store.nextAccountID
+		r.store.accounts[id] = &models.Account{
+			AccountID:     id,
+			AccountNumber: accountNumber,
+			AccountHolder: holderName,
+			Balance:       initialBalance,
+			Currency:      models.DefaultCurrency,
+			LastUpdated:   time.Now().UTC(),
+			Status:        models.AccountStatusActive,
+		}
+		return id, accountNumber, nil
+	}
+}
+
+// CreateAccountIfNotExists creates an account for externalID unless one already exists, in which
+// case the existing account's ID is returned with created set to false.
+func (r *memoryAccountRepository) CreateAccountIfNotExists(externalID, holderName string, initialBalance float64) (int64, bool, error) {
+	if err := validateExternalCustomerID(externalID); err != nil {
+		return 0, false, fmt.Errorf("CreateAccountIfNotExists: %w", err)
+	}
+	holderName, err := normalizeHolderName(holderName)
+	if err != nil {
+		return 0, false, fmt.Errorf("CreateAccountIfNotExists: %w", err)
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if id, ok := r.store.externalIDs[externalID]; ok {
+		return id, false, nil
+	}
+	id, _, err := r.insertAccount(holderName, initialBalance)
+	if err != nil {
+		return 0, false, fmt.Errorf("CreateAccountIfNotExists: %w", err)
+	}
+	r.store.externalIDs[externalID] = id
+	return id, true, nil
+}
+
+// GetAccountByID retrieves a single active account by its ID.
+func (r *memoryAccountRepository) GetAccountByID(accountID int64) (models.Account, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.activeAccount(accountID)
+	if !ok {
+		return models.Account{}, fmt.Errorf("GetAccountByID: %w: no active account with ID %d: %w", util.ErrAccountNotFound, accountID, sql.ErrNoRows)
+	}
+	return copyAccount(acc), nil
+}
+
+// GetAccountByIDIncludingDeleted retrieves a single account whatever its status.
+func (r *memoryAccountRepository) GetAccountByIDIncludingDeleted(accountID int64) (models.Account, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.accounts[accountID]
+	if !ok {
+		return models.Account{}, fmt.Errorf("GetAccountByIDIncludingDeleted: %w: ID %d: %w", util.ErrAccountNotFound, accountID, sql.ErrNoRows)
+	}
+	return copyAccount(acc), nil
+}
+
+// GetAccountByNumber retrieves a single active account by its account number.
+func (r *memoryAccountRepository) GetAccountByNumber(accountNumber string) (models.Account, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, acc := range r.store.accounts {
+		if acc.AccountNumber == accountNumber && acc.Status != models.AccountStatusClosed {
+			return copyAccount(acc), nil
+		}
+	}
+	return models.Account{}, fmt.Errorf("GetAccountByNumber: %w: no active account with number %s: %w", util.ErrAccountNotFound, accountNumber, sql.ErrNoRows)
+}
+
+// GetAccountByIDForUpdate behaves like GetAccountByID; the store's mutex already serializes access.
+func (r *memoryAccountRepository) GetAccountByIDForUpdate(accountID int64) (models.Account, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.activeAccount(accountID)
+	if !ok {
+		return models.Account{}, fmt.Errorf("GetAccountByIDForUpdate: %w: no active account with ID %d: %w", util.ErrAccountNotFound, accountID, sql.ErrNoRows)
+	}
+	return copyAccount(acc), nil
+}
+
+// activeAccounts returns copies of all active accounts in ID order. The caller must hold the lock.
+func (r *memoryAccountRepository) activeAccounts() []models.Account {
+	var accounts []models.Account
+	for _, acc := range r.store.accounts {
+		if acc.Status != models.AccountStatusClosed {
+			accounts = append(accounts, copyAccount(acc))
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].AccountID < accounts[j].AccountID })
+	return accounts
+}
+
+// GetAllAccounts retrieves a page of active accounts along with the total number of active accounts.
+func (r *memoryAccountRepository) GetAllAccounts(opts AccountListOptions) ([]models.Account, int64, error) {
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = "account_id"
+	}
+	if _, ok := accountSortColumns[sortBy]; !ok {
+		return nil, 0, fmt.Errorf("GetAllAccounts: unsupported sort field %q", opts.SortBy)
+	}
+	if opts.Offset < 0 {
+		return nil, 0, fmt.Errorf("GetAllAccounts: offset must not be negative, got %d", opts.Offset)
+	}
+
+	r.store.mu.Lock()
+	accounts := r.activeAccounts()
+	r.store.mu.Unlock()
+
+	less := map[string]func(a, b models.Account) bool{
+		"account_id":     func(a, b models.Account) bool { return false },
+		"account_holder": func(a, b models.Account) bool { return a.AccountHolder < b.AccountHolder },
+		"balance":        func(a, b models.Account) bool { return a.Balance < b.Balance },
+		"last_updated":   func(a, b models.Account) bool { return a.LastUpdated.Before(b.LastUpdated) },
+	}[sortBy]
+	// accounts is already in ID order, so a stable sort keeps account_id as the tie-breaker.
+	sort.SliceStable(accounts, func(i, j int) bool {
+		a, b := accounts[i], accounts[j]
+		if opts.Descending {
+			a, b = b, a
+		}
+		return less(a, b)
+	})
+	if sortBy == "account_id" && opts.Descending {
+		for i, j := 0, len(accounts)-1; i < j; i, j = i+1, j-1 {
+			accounts[i], accounts[j] = accounts[j], accounts[i]
+		}
+	}
+
+	total := int64(len(accounts))
+	if opts.Limit > 0 {
+		if opts.Offset >= len(accounts) {
+			return nil, total, nil
+		}
+		end := opts.Offset + opts.Limit
+		if end > len(accounts) {
+			end = len(accounts)
+		}
+		accounts = accounts[opts.Offset:end]
+	}
+	return accounts, total, nil
+}
+
+// GetAccountsWithActivity retrieves a page of active accounts with their transaction counts and
+// latest transaction times, along with the total number of active accounts.
+func (r *memoryAccountRepository) GetAccountsWithActivity(opts AccountListOptions) ([]models.AccountWithActivity, int64, error) {
+	accounts, total, err := r.GetAllAccounts(opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetAccountsWithActivity: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	result := make([]models.AccountWithActivity, len(accounts))
+	for i, acc := range accounts {
+		result[i].Account = acc
+		for _, tx := range r.store.transactions {
+			if tx.IsDeleted || !touchesAccount(tx, acc.AccountID) {
+				continue
+			}
+			result[i].TxCount++
+			if !result[i].LastActivity.Valid || tx.TransactionTs.After(result[i].LastActivity.Time) {
+				result[i].LastActivity = sql.NullTime{Time: tx.TransactionTs, Valid: true}
+			}
+		}
+	}
+	return result, total, nil
+}
+
+// GetTopAccountsByBalance returns the n active accounts with the largest balances, largest first.
+func (r *memoryAccountRepository) GetTopAccountsByBalance(n int) ([]models.Account, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("GetTopAccountsByBalance: n must be positive, got %d", n)
+	}
+	r.store.mu.Lock()
+	accounts := r.activeAccounts()
+	r.store.mu.Unlock()
+
+	sort.SliceStable(accounts, func(i, j int) bool { return accounts[i].Balance > accounts[j].Balance })
+	if len(accounts) > n {
+		accounts = accounts[:n]
+	}
+	return accounts, nil
+}
+
+// UpdateAccountHolderName updates the holder name of an account, validated as in CreateAccount.
+// Like the MySQL implementation it returns 0 for an unchanged name and fails for a missing or
+// CLOSED account.
+func (r *memoryAccountRepository) UpdateAccountHolderName(accountID int64, newHolderName string) (int64, error) {
+	newHolderName, err := normalizeHolderName(newHolderName)
+	if err != nil {
+		return 0, fmt.Errorf("UpdateAccountHolderName: %w", err)
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.accounts[accountID]
+	if !ok {
+		return 0, fmt.Errorf("UpdateAccountHolderName: %w: ID %d", util.ErrAccountNotFound, accountID)
+	}
+	if acc.Status == models.AccountStatusClosed {
+		return 0, fmt.Errorf("UpdateAccountHolderName: %w: ID %d is closed", util.ErrAccountInactive, accountID)
+	}
+	if acc.AccountHolder == newHolderName {
+		return 0, nil
+	}
+	acc.AccountHolder = newHolderName
+	acc.LastUpdated = time.Now().UTC()
+	return 1, nil
+}
+
+// AdjustAccountBalance adds amountChange to an account's balance, enforcing the overdraft and
+// min/max balance limits like the MySQL implementation. A non-zero change to a missing or
+// CLOSED account fails with util.ErrAccountNotFound.
+func (r *memoryAccountRepository) AdjustAccountBalance(accountID int64, amountChange float64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if amountChange == 0 {
+		return 0, nil
+	}
+	acc, ok := r.store.activeAccount(accountID)
+	if !ok {
+		return 0, fmt.Errorf("AdjustAccountBalance: %w: no active account with ID %d", util.ErrAccountNotFound, accountID)
+	}
+	newBalance := acc.Balance + amountChange
+	rejected := false
+	if amountChange < 0 {
+		rejected = newBalance < -acc.OverdraftLimit || (acc.MinBalance.Valid && newBalance < acc.MinBalance.Float64)
+	} else {
+		rejected = acc.MaxBalance.Valid && newBalance > acc.MaxBalance.Float64
+	}
+	if rejected {
+		limitErr := balanceLimitError(acc.Balance, acc.OverdraftLimit, amountChange)
+		return 0, fmt.Errorf("AdjustAccountBalance: account %d, change %.2f: %w", accountID, amountChange, limitErr)
+	}
+	acc.Balance = newBalance
+	acc.LastUpdated = time.Now().UTC()
+	return 1, nil
+}
+
+// SetAccountBalance overwrites an account's balance.
+func (r *memoryAccountRepository) SetAccountBalance(accountID int64, balance float64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.accounts[accountID]
+	if !ok || acc.Balance == balance {
+		return 0, nil
+	}
+	acc.Balance = balance
+	acc.LastUpdated = time.Now().UTC()
+	return 1, nil
+}
+
+// SoftDeleteAccount marks an account CLOSED, remembering its status for UndeleteAccount.
+func (r *memoryAccountRepository) SoftDeleteAccount(accountID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.accounts[accountID]
+	if !ok || acc.Status == models.AccountStatusClosed {
+		return 0, fmt.Errorf("SoftDeleteAccount: no active account found with ID %d to soft delete, or already soft-deleted", accountID)
+	}
+	r.store.previousStatus[accountID] = acc.Status
+	acc.Status = models.AccountStatusClosed
+	acc.LastUpdated = time.Now().UTC()
+	return 1, nil
+}
+
+// SoftDeleteInactiveAccounts closes every ACTIVE account with no transactions and no updates
+// since inactiveSince, like the MySQL implementation.
+func (r *memoryAccountRepository) SoftDeleteInactiveAccounts(inactiveSince time.Time) (int64, error) {
+	return r.softDeleteInactiveAccounts(inactiveSince, InactiveAccountOptions{})
+}
+
+// SoftDeleteInactiveZeroBalanceAccounts is SoftDeleteInactiveAccounts limited to accounts whose
+// balance is exactly zero.
+func (r *memoryAccountRepository) SoftDeleteInactiveZeroBalanceAccounts(inactiveSince time.Time) (int64, error) {
+	return r.softDeleteInactiveAccounts(inactiveSince, InactiveAccountOptions{ZeroBalanceOnly: true})
+}
+
+// softDeleteInactiveAccounts closes the accounts matching inactiveSince and opts.
+func (r *memoryAccountRepository) softDeleteInactiveAccounts(inactiveSince time.Time, opts InactiveAccountOptions) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var closed int64
+	now := time.Now().UTC()
+	for _, acc := range r.inactiveAccounts(inactiveSince, opts) {
+		r.store.previousStatus[acc.AccountID] = acc.Status
+		acc.Status = models.AccountStatusClosed
+		acc.LastUpdated = now
+		closed++
+	}
+	return closed, nil
+}
+
+// FindInactiveAccounts returns the accounts SoftDeleteInactiveAccounts would close, in ID order.
+func (r *memoryAccountRepository) FindInactiveAccounts(inactiveSince time.Time, opts InactiveAccountOptions) ([]models.Account, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var accounts []models.Account
+	for _, acc := range r.inactiveAccounts(inactiveSince, opts) {
+		accounts = append(accounts, copyAccount(acc))
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].AccountID < accounts[j].AccountID })
+	return accounts, nil
+}
+
+// inactiveAccounts returns the accounts matching inactiveSince and opts. The caller must hold
+// r.store.mu.
+func (r *memoryAccountRepository) inactiveAccounts(inactiveSince time.Time, opts InactiveAccountOptions) []*models.Account {
+	var inactive []*models.Account
+	for _, acc := range r.store.accounts {
+		if acc.Status != models.AccountStatusActive || !acc.LastUpdated.Before(inactiveSince) {
+			continue
+		}
+		if opts.ZeroBalanceOnly && acc.Balance != 0 {
+			continue
+		}
+		active := false
+		for _, tx := range r.store.transactions {
+			if touchesAccount(tx, acc.AccountID) && !tx.TransactionTs.Before(inactiveSince) {
+				active = true
+				break
+			}
+		}
+		if !active {
+			inactive = append(inactive, acc)
+		}
+	}
+	return inactive
+}
+
+// UndeleteAccount reopens a CLOSED account with the status it had when it was closed.
+func (r *memoryAccountRepository) UndeleteAccount(accountID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.accounts[accountID]
+	if !ok || acc.Status != models.AccountStatusClosed {
+		return 0, fmt.Errorf("UndeleteAccount: %w: ID %d", ErrAccountNotClosed, accountID)
+	}
+	acc.Status = models.AccountStatusActive
+	if previous, ok := r.store.previousStatus[accountID]; ok {
+		acc.Status = previous
+		delete(r.store.previousStatus, accountID)
+	}
+	acc.LastUpdated = time.Now().UTC()
+	return 1, nil
+}
+
+// FreezeAccount moves an ACTIVE account to FROZEN.
+func (r *memoryAccountRepository) FreezeAccount(accountID int64) (int64, error) {
+	if r.setStatus(accountID, models.AccountStatusFrozen, models.AccountStatusActive) == 0 {
+		return 0, fmt.Errorf("FreezeAccount: no active account found with ID %d to freeze", accountID)
+	}
+	return 1, nil
+}
+
+// UnfreezeAccount moves a FROZEN account back to ACTIVE.
+func (r *memoryAccountRepository) UnfreezeAccount(accountID int64) (int64, error) {
+	if r.setStatus(accountID, models.AccountStatusActive, models.AccountStatusFrozen) == 0 {
+		return 0, fmt.Errorf("UnfreezeAccount: no frozen account found with ID %d to unfreeze", accountID)
+	}
+	return 1, nil
+}
+
+// setStatus moves an account whose status is one of from to status, and returns the number of
+// accounts changed.
+func (r *memoryAccountRepository) setStatus(accountID int64, status string, from ...string) int64 {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.accounts[accountID]
+	if !ok {
+		return 0
+	}
+	for _, f := range from {
+		if acc.Status == f {
+			acc.Status = status
+			acc.LastUpdated = time.Now().UTC()
+			return 1
+		}
+	}
+	return 0
+}
+
+// CalculateTotalBalanceOfActiveAccounts computes the sum of balances for all non-deleted accounts.
+func (r *memoryAccountRepository) CalculateTotalBalanceOfActiveAccounts() (float64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var total float64
+	for _, acc := range r.store.accounts {
+		if acc.Status != models.AccountStatusClosed {
+			total += acc.Balance
+		}
+	}
+	return total, nil
+}
+
+// GetBalancesByIDs returns the current balance of each active account in accountIDs.
+func (r *memoryAccountRepository) GetBalancesByIDs(accountIDs []int64) (map[int64]float64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	balances := make(map[int64]float64)
+	for _, id := range accountIDs {
+		if acc, ok := r.store.activeAccount(id); ok {
+			balances[id] = acc.Balance
+		}
+	}
+	return balances, nil
+}
+
+// GetAccountsByIDs returns the accounts in accountIDs keyed by ID; CLOSED ones only when
+// includeClosed is set.
+func (r *memoryAccountRepository) GetAccountsByIDs(accountIDs []int64, includeClosed bool) (map[int64]models.Account, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	accounts := make(map[int64]models.Account)
+	for _, id := range accountIDs {
+		acc, ok := r.store.accounts[id]
+		if !ok || (!includeClosed && acc.Status == models.AccountStatusClosed) {
+			continue
+		}
+		accounts[id] = copyAccount(acc)
+	}
+	return accounts, nil
+}
+
+// GetBalancesForTransactions returns the current balance of every active account referenced
+// by the given transactions.
+func (r *memoryAccountRepository) GetBalancesForTransactions(transactions []models.Transaction) (map[int64]float64, error) {
+	return r.GetBalancesByIDs(accountIDsFromTransactions(transactions))
+}
+
+// IsValidTransferTarget reports whether accountID can receive a transfer from sourceAccountID.
+func (r *memoryAccountRepository) IsValidTransferTarget(sourceAccountID, accountID int64) (bool, string, error) {
+	if sourceAccountID == accountID {
+		return false, TransferTargetSameAsSource, nil
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.accounts[accountID]
+	if !ok {
+		return false, TransferTargetNotFound, nil
+	}
+	var sourceCurrency sql.NullString
+	if source, ok := r.store.accounts[sourceAccountID]; ok {
+		sourceCurrency = sql.NullString{String: source.Currency, Valid: true}
+	}
+	return transferTargetVerdict(acc.Status, acc.Currency, acc.Metadata.IsSystemAccount(), sourceCurrency)
+}
+
+// HasTransactions reports whether any transaction, on either side, references accountID.
+func (r *memoryAccountRepository) HasTransactions(accountID int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, tx := range r.store.transactions {
+		if touchesAccount(tx, accountID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SetAccountMetadata merges updates into an account's metadata.
+func (r *memoryAccountRepository) SetAccountMetadata(accountID int64, updates map[string]string) (int64, error) {
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.accounts[accountID]
+	if !ok {
+		return 0, nil
+	}
+	if acc.Metadata == nil {
+		acc.Metadata = make(models.AccountMetadata, len(updates))
+	}
+	for k, v := range updates {
+		acc.Metadata[k] = v
+	}
+	return 1, nil
+}
+
+// GetAccountMetadata returns an account's metadata, or an empty map when it has none.
+func (r *memoryAccountRepository) GetAccountMetadata(accountID int64) (models.AccountMetadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	acc, ok := r.store.accounts[accountID]
+	if !ok {
+		return nil, fmt.Errorf("GetAccountMetadata: %w: no account found with ID %d: %w", util.ErrAccountNotFound, accountID, sql.ErrNoRows)
+	}
+	metadata := copyAccount(acc).Metadata
+	if metadata == nil {
+		metadata = models.AccountMetadata{}
+	}
+	return metadata, nil
+}
+
+// GetAccountsByMetadata returns the active accounts whose metadata has key set to value.
+func (r *memoryAccountRepository) GetAccountsByMetadata(key, value string) ([]models.Account, error) {
+	if err := validateMetadataKey(key); err != nil {
+		return nil, fmt.Errorf("GetAccountsByMetadata: %w", err)
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var accounts []models.Account
+	for _, acc := range r.activeAccounts() {
+		if v, ok := acc.Metadata[key]; ok && v == value {
+			accounts = append(accounts, acc)
+		}
+	}
+	return accounts, nil
+}
+
+// WithContext returns the repository itself; in-memory calls never block.
+func (r *memoryAccountRepository) WithContext(ctx context.Context) AccountRepository {
+	return r
+}
+
+// WithDBTX returns the repository itself; calls made inside MemoryStore.WithTransaction are
+// already part of its unit of work.
+func (r *memoryAccountRepository) WithDBTX(db DBTX) AccountRepository {
+	return r
+}
+
+// Close is a no-op; the store holds no statements.
+func (r *memoryAccountRepository) Close() error {
+	return nil
+}
+
+// touchesAccount reports whether tx has accountID on either side.
+func touchesAccount(tx *models.Transaction, accountID int64) bool {
+	return (tx.FromAccountID.Valid && tx.FromAccountID.Int64 == accountID) ||
+		(tx.ToAccountID.Valid && tx.ToAccountID.Int64 == accountID)
+}
+
+// memoryTransactionRepository implements TransactionRepository on a MemoryStore.
+type memoryTransactionRepository struct {
+	store *MemoryStore
+}
+
+// insert stores a new transaction and returns its ID. The caller must hold the lock.
+func (r *memoryTransactionRepository) insert(tx models.Transaction) int64 {
+	r.store.nextTxID++
+	tx.TransactionID = r.store.nextTxID
+	if tx.TransactionTs.IsZero() {
+		tx.TransactionTs = time.Now().UTC()
+	}
+	tx.TransactionTs = tx.TransactionTs.UTC()
+	if tx.Status == "" {
+		tx.Status = models.TransactionStatusCompleted
+	}
+	r.store.transactions[tx.TransactionID] = &tx
+	return tx.TransactionID
+}
+
+// create validates and inserts a transaction on behalf of op.
+func (r *memoryTransactionRepository) create(op string, tx models.Transaction) (int64, error) {
+	if err := validateAmount(tx.Amount); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.insert(tx), nil
+}
+
+// CreateTransaction inserts a new transaction and returns its ID.
+func (r *memoryTransactionRepository) CreateTransaction(fromID, toID sql.NullInt64, txType string, amount float64, description sql.NullString) (int64, error) {
+	return r.create("CreateTransaction", models.Transaction{FromAccountID: fromID, ToAccountID: toID, TransactionType: txType, Amount: amount, Description: description})
+}
+
+// CreateTransactionWithNotes inserts a new transaction with notes and returns its ID.
+func (r *memoryTransactionRepository) CreateTransactionWithNotes(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString) (int64, error) {
+	return r.create("CreateTransactionWithNotes", models.Transaction{FromAccountID: fromID, ToAccountID: toID, TransactionType: txType, Amount: amount, Description: description, Notes: notes})
+}
+
+// CreateTransactionAt inserts a new transaction stamped with ts (NOW() when zero) and returns its ID.
+func (r *memoryTransactionRepository) CreateTransactionAt(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString, ts time.Time) (int64, error) {
+	return r.create("CreateTransactionAt", models.Transaction{FromAccountID: fromID, ToAccountID: toID, TransactionType: txType, Amount: amount, Description: description, Notes: notes, TransactionTs: ts})
+}
+
+// CreateTransactionWithCategory inserts a new transaction in the named category, creating the
+// category if needed, and returns its ID.
+func (r *memoryTransactionRepository) CreateTransactionWithCategory(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString, categoryName string) (int64, error) {
+	if err := validateAmount(amount); err != nil {
+		return 0, fmt.Errorf("CreateTransactionWithCategory: %w", err)
+	}
+	categoryName = strings.TrimSpace(categoryName)
+	if categoryName == "" {
+		return 0, fmt.Errorf("CreateTransactionWithCategory: category name must not be empty")
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	categoryID, ok := r.store.categories[categoryName]
+	if !ok {
+		r.store.nextCategoryID++
+		categoryID = r.store.nextCategoryID
+		r.store.categories[categoryName] = categoryID
+	}
+	id := r.insert(models.Transaction{FromAccountID: fromID, ToAccountID: toID, TransactionType: txType, Amount: amount, Description: description, Notes: notes})
+	r.store.categoryOf[id] = categoryID
+	return id, nil
+}
+
+// CreatePendingReviewTransaction records a transaction held for review and returns its ID.
+func (r *memoryTransactionRepository) CreatePendingReviewTransaction(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString) (int64, error) {
+	return r.create("CreatePendingReviewTransaction", models.Transaction{FromAccountID: fromID, ToAccountID: toID, TransactionType: txType, Amount: amount, Description: description, Notes: notes, Status: models.TransactionStatusPendingReview})
+}
+
+// MarkTransactionCompleted moves a PENDING_REVIEW transaction to COMPLETED.
+func (r *memoryTransactionRepository) MarkTransactionCompleted(transactionID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tx, ok := r.store.transactions[transactionID]
+	if !ok || tx.Status != models.TransactionStatusPendingReview {
+		return 0, nil
+	}
+	tx.Status = models.TransactionStatusCompleted
+	tx.TransactionTs = time.Now().UTC()
+	return 1, nil
+}
+
+// CreateTransactionIdempotent inserts a transaction tagged with key, or returns the existing
+// transaction's ID with created set to false if the key was used before.
+func (r *memoryTransactionRepository) CreateTransactionIdempotent(key string, fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString) (int64, bool, error) {
+	if key == "" {
+		return 0, false, fmt.Errorf("CreateTransactionIdempotent: idempotency key must not be empty")
+	}
+	if err := validateAmount(amount); err != nil {
+		return 0, false, fmt.Errorf("CreateTransactionIdempotent: %w", err)
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if id, ok := r.store.idempotencyKeys[key]; ok {
+		return id, false, nil
+	}
+	id := r.insert(models.Transaction{FromAccountID: fromID, ToAccountID: toID, TransactionType: txType, Amount: amount, Description: description, Notes: notes})
+	r.store.idempotencyKeys[key] = id
+	return id, true, nil
+}
+
+// GetTransactionByID retrieves a single transaction by its ID. A missing ID wraps sql.ErrNoRows.
+func (r *memoryTransactionRepository) GetTransactionByID(transactionID int64) (models.Transaction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tx, ok := r.store.transactions[transactionID]
+	if !ok {
+		return models.Transaction{}, fmt.Errorf("GetTransactionByID: no transaction with ID %d: %w", transactionID, sql.ErrNoRows)
+	}
+	return *tx, nil
+}
+
+// GetTransactionByIDForUpdate is GetTransactionByID; the store's mutex already serializes access.
+func (r *memoryTransactionRepository) GetTransactionByIDForUpdate(transactionID int64) (models.Transaction, error) {
+	tx, err := r.GetTransactionByID(transactionID)
+	if err != nil {
+		return tx, fmt.Errorf("GetTransactionByIDForUpdate: %w", err)
+	}
+	return tx, nil
+}
+
+// GetTransactionDetailByID retrieves a single transaction with its category name. A missing ID
+// fails with ErrTransactionNotFound.
+func (r *memoryTransactionRepository) GetTransactionDetailByID(transactionID int64) (models.TransactionWithCategory, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tx, ok := r.store.transactions[transactionID]
+	if !ok {
+		return models.TransactionWithCategory{}, fmt.Errorf("GetTransactionDetailByID: %w: ID %d: %w", ErrTransactionNotFound, transactionID, sql.ErrNoRows)
+	}
+	twc := models.TransactionWithCategory{Transaction: *tx}
+	if categoryID, ok := r.store.categoryOf[transactionID]; ok {
+		for name, id := range r.store.categories {
+			if id == categoryID {
+				twc.CategoryName = sql.NullString{String: name, Valid: true}
+			}
+		}
+	}
+	return twc, nil
+}
+
+// selectTransactions returns copies of the transactions keep accepts, ordered by less.
+func (r *memoryTransactionRepository) selectTransactions(keep func(*models.Transaction) bool, less func(a, b models.Transaction) bool) []models.Transaction {
+	r.store.mu.Lock()
+	var out []models.Transaction
+	for _, tx := range r.store.transactions {
+		if keep(tx) {
+			out = append(out, *tx)
+		}
+	}
+	r.store.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return less(out[i], out[j]) })
+	return out
+}
+
+// newestFirst orders transactions by timestamp, then ID, descending.
+func newestFirst(a, b models.Transaction) bool {
+	if !a.TransactionTs.Equal(b.TransactionTs) {
+		return a.TransactionTs.After(b.TransactionTs)
+	}
+	return a.TransactionID > b.TransactionID
+}
+
+// typeSet validates types and returns them as a set; nil means no type filter.
+func typeSet(types []string) (map[string]bool, error) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+	args, err := transactionTypeArgs(types)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(args))
+	for _, a := range args {
+		set[a.(string)] = true
+	}
+	return set, nil
+}
+
+// GetTransactionsForAccount retrieves all visible, completed transactions involving accountID,
+// newest first, optionally limited to types, as seen by accountID.
+func (r *memoryTransactionRepository) GetTransactionsForAccount(accountID int64, types []string) ([]models.AccountTransaction, error) {
+	set, err := typeSet(types)
+	if err != nil {
+		return nil, fmt.Errorf("GetTransactionsForAccount: %w", err)
+	}
+	transactions := r.selectTransactions(func(tx *models.Transaction) bool {
+		return touchesAccount(tx, accountID) && !tx.IsDeleted && tx.Status == models.TransactionStatusCompleted &&
+			(set == nil || set[tx.TransactionType])
+	}, newestFirst)
+	var result []models.AccountTransaction
+	for _, tx := range transactions {
+		result = append(result, tx.ForAccount(accountID))
+	}
+	return result, nil
+}
+
+// GetTransactionsSince returns up to limit visible transactions involving accountID with an ID
+// above sinceTxID, in ascending ID order, as seen by accountID.
+func (r *memoryTransactionRepository) GetTransactionsSince(accountID int64, sinceTxID int64, limit int) ([]models.AccountTransaction, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("GetTransactionsSince: limit must be positive, got %d", limit)
+	}
+	transactions := r.selectTransactions(func(tx *models.Transaction) bool {
+		return touchesAccount(tx, accountID) && tx.TransactionID > sinceTxID && !tx.IsDeleted
+	}, func(a, b models.Transaction) bool { return a.TransactionID < b.TransactionID })
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+	}
+	var result []models.AccountTransaction
+	for _, tx := range transactions {
+		result = append(result, tx.ForAccount(accountID))
+	}
+	return result, nil
+}
+
+// GetTransactionsWithCategory retrieves an account's transactions along with their category names.
+func (r *memoryTransactionRepository) GetTransactionsWithCategory(accountID int64, opts CategoryListOptions) ([]models.TransactionWithCategory, error) {
+	if opts.CategoryID.Valid && opts.Uncategorized {
+		return nil, fmt.Errorf("GetTransactionsWithCategory: CategoryID and Uncategorized are mutually exclusive")
+	}
+	less := newestFirst
+	if opts.OrderByAmount {
+		less = func(a, b models.Transaction) bool {
+			if a.Amount != b.Amount {
+				return a.Amount > b.Amount
+			}
+			return a.TransactionID > b.TransactionID
+		}
+	}
+	transactions := r.selectTransactions(func(tx *models.Transaction) bool {
+		return touchesAccount(tx, accountID) && (opts.IncludeDeleted || !tx.IsDeleted) &&
+			(opts.IncludePending || tx.Status == models.TransactionStatusCompleted) &&
+			(!opts.MinAmount.Valid || tx.Amount >= opts.MinAmount.Float64)
+	}, less)
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	names := make(map[int64]string, len(r.store.categories))
+	for name, id := range r.store.categories {
+		names[id] = name
+	}
+	if _, ok := names[opts.CategoryID.Int64]; opts.CategoryID.Valid && !ok {
+		return nil, fmt.Errorf("GetTransactionsWithCategory: %w (ID: %d)", ErrCategoryNotFound, opts.CategoryID.Int64)
+	}
+	results := make([]models.TransactionWithCategory, 0, len(transactions))
+	for _, tx := range transactions {
+		twc := models.TransactionWithCategory{Transaction: tx}
+		categoryID, categorized := r.store.categoryOf[tx.TransactionID]
+		if (opts.CategoryID.Valid && categoryID != opts.CategoryID.Int64) || (opts.Uncategorized && categorized) {
+			continue
+		}
+		if categorized {
+			twc.CategoryName = sql.NullString{String: names[categoryID], Valid: true}
+		}
+		results = append(results, twc)
+	}
+	return results, nil
+}
+
+// UpdateTransactionDescription updates the description of an existing transaction.
+func (r *memoryTransactionRepository) UpdateTransactionDescription(transactionID int64, newDescription sql.NullString) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tx, ok := r.store.transactions[transactionID]
+	if !ok || tx.Description == newDescription {
+		return 0, nil
+	}
+	tx.Description = newDescription
+	return 1, nil
+}
+
+// DeleteTransaction removes a transaction that never affected a balance; completed
+// balance-affecting rows are refused with ErrBalanceAffectingTransaction.
+func (r *memoryTransactionRepository) DeleteTransaction(transactionID int64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tx, ok := r.store.transactions[transactionID]
+	if !ok {
+		return 0, nil
+	}
+	affecting := false
+	for _, t := range models.BalanceAffectingTransactionTypes {
+		if tx.TransactionType == t {
+			affecting = true
+		}
+	}
+	if affecting && tx.Amount != 0 && tx.Status == models.TransactionStatusCompleted {
+		return 0, fmt.Errorf("DeleteTransaction: transaction %d: %w", transactionID, ErrBalanceAffectingTransaction)
+	}
+	delete(r.store.transactions, transactionID)
+	delete(r.store.categoryOf, transactionID)
+	return 1, nil
+}
+
+// SetTransactionExchange records the credited amount and exchange rate of a transfer.
+func (r *memoryTransactionRepository) SetTransactionExchange(transactionID int64, creditedAmount, rate float64) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tx, ok := r.store.transactions[transactionID]
+	if !ok {
+		return 0, nil
+	}
+	tx.CreditedAmount = sql.NullFloat64{Float64: creditedAmount, Valid: true}
+	tx.ExchangeRate = sql.NullFloat64{Float64: rate, Valid: true}
+	return 1, nil
+}
+
+// SoftDeleteTransaction hides a visible transaction.
+func (r *memoryTransactionRepository) SoftDeleteTransaction(transactionID int64) (int64, error) {
+	if r.setDeleted(transactionID, true) == 0 {
+		return 0, fmt.Errorf("SoftDeleteTransaction: no visible transaction found with ID %d to soft delete, or already soft-deleted", transactionID)
+	}
+	return 1, nil
+}
+
+// UndeleteTransaction makes a soft-deleted transaction visible again.
+func (r *memoryTransactionRepository) UndeleteTransaction(transactionID int64) (int64, error) {
+	if r.setDeleted(transactionID, false) == 0 {
+		return 0, fmt.Errorf("UndeleteTransaction: no soft-deleted transaction found with ID %d to undelete, or already visible", transactionID)
+	}
+	return 1, nil
+}
+
+// setDeleted flips is_deleted and returns the number of transactions changed.
+func (r *memoryTransactionRepository) setDeleted(transactionID int64, deleted bool) int64 {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tx, ok := r.store.transactions[transactionID]
+	if !ok || tx.IsDeleted == deleted {
+		return 0
+	}
+	tx.IsDeleted = deleted
+	return 1
+}
+
+// GetAllTransactionsForReconciliation retrieves all visible, completed transactions in ID order.
+func (r *memoryTransactionRepository) GetAllTransactionsForReconciliation() ([]models.Transaction, error) {
+	return r.selectTransactions(func(tx *models.Transaction) bool {
+		return !tx.IsDeleted && tx.Status == models.TransactionStatusCompleted
+	}, func(a, b models.Transaction) bool {
+		return a.TransactionID < b.TransactionID
+	}), nil
+}
+
+// ForEachTransactionForReconciliation calls fn with every visible, completed transaction in ID
+// order, stopping at the first error. fn runs without the store's lock held.
+func (r *memoryTransactionRepository) ForEachTransactionForReconciliation(fn func(models.Transaction) error) error {
+	transactions, _ := r.GetAllTransactionsForReconciliation()
+	for _, tx := range transactions {
+		if err := fn(tx); err != nil {
+			return fmt.Errorf("ForEachTransactionForReconciliation: transaction %d: %w", tx.TransactionID, err)
+		}
+	}
+	return nil
+}
+
+// GetAllTransactionsForReconciliationSkippingBadRows is GetAllTransactionsForReconciliation;
+// in-memory rows always scan, so there are never bad rows.
+func (r *memoryTransactionRepository) GetAllTransactionsForReconciliationSkippingBadRows() ([]models.Transaction, []RowError, error) {
+	transactions, err := r.GetAllTransactionsForReconciliation()
+	return transactions, nil, err
+}
+
+// CreateReversalTransaction records the reversal of original. A second reversal of the same
+// transaction fails with ErrDuplicateEntry, like the unique index on reversal_of.
+func (r *memoryTransactionRepository) CreateReversalTransaction(original models.Transaction, description, notes sql.NullString) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, tx := range r.store.transactions {
+		if tx.ReversalOf.Valid && tx.ReversalOf.Int64 == original.TransactionID {
+			return 0, fmt.Errorf("CreateReversalTransaction: %w: transaction %d is already reversed", ErrDuplicateEntry, original.TransactionID)
+		}
+	}
+	return r.insert(models.Transaction{
+		FromAccountID:   original.ToAccountID,
+		ToAccountID:     original.FromAccountID,
+		TransactionType: original.TransactionType,
+		Amount:          original.Amount,
+		Description:     description,
+		Notes:           notes,
+		ReversalOf:      sql.NullInt64{Int64: original.TransactionID, Valid: true},
+	}), nil
+}
+
+// IsTransactionReversed reports whether a reversal of transactionID exists.
+func (r *memoryTransactionRepository) IsTransactionReversed(transactionID int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, tx := range r.store.transactions {
+		if tx.ReversalOf.Valid && tx.ReversalOf.Int64 == transactionID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FindTransactionIDGaps returns the ranges of transaction IDs missing between the lowest and
+// highest existing IDs.
+func (r *memoryTransactionRepository) FindTransactionIDGaps() ([]models.IDGap, error) {
+	r.store.mu.Lock()
+	ids := make([]int64, 0, len(r.store.transactions))
+	for id := range r.store.transactions {
+		ids = append(ids, id)
+	}
+	r.store.mu.Unlock()
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var gaps []models.IDGap
+	for i := 1; i < len(ids); i++ {
+		if ids[i] > ids[i-1]+1 {
+			gaps = append(gaps, models.IDGap{Start: ids[i-1] + 1, End: ids[i] - 1})
+		}
+	}
+	return gaps, nil
+}
+
+// GetLedgerBalance returns the balance implied by an account's completed transactions.
+func (r *memoryTransactionRepository) GetLedgerBalance(accountID int64) (float64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.ledgerBalances()[accountID], nil
+}
+
+// ledgerBalances returns the ledger balance of every account with completed transactions, with
+// the same rules as the MySQL GetLedgerBalance. The caller must hold mu.
+func (s *MemoryStore) ledgerBalances() map[int64]float64 {
+	balances := make(map[int64]float64)
+	for _, tx := range s.transactions {
+		if tx.Status != models.TransactionStatusCompleted {
+			continue
+		}
+		amount := tx.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+		if tx.ToAccountID.Valid {
+			credit := amount
+			if tx.CreditedAmount.Valid {
+				credit = tx.CreditedAmount.Float64
+			}
+			balances[tx.ToAccountID.Int64] += credit
+		}
+		if tx.FromAccountID.Valid && !(tx.ToAccountID.Valid && tx.ToAccountID.Int64 == tx.FromAccountID.Int64) {
+			balances[tx.FromAccountID.Int64] -= amount
+		}
+	}
+	return balances
+}
+
+// VerifyLedgerIntegrity returns every account whose stored balance differs by a cent or more
+// from its ledger balance, ordered by account ID.
+func (r *memoryTransactionRepository) VerifyLedgerIntegrity() ([]models.LedgerDiscrepancy, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	ledger := r.store.ledgerBalances()
+	var discrepancies []models.LedgerDiscrepancy
+	for _, acc := range r.store.accounts {
+		diff := acc.Balance - ledger[acc.AccountID]
+		if math.Abs(diff) < 0.01 {
+			continue
+		}
+		discrepancies = append(discrepancies, models.LedgerDiscrepancy{
+			AccountID:     acc.AccountID,
+			AccountNumber: acc.AccountNumber,
+			StoredBalance: acc.Balance,
+			LedgerBalance: ledger[acc.AccountID],
+			Difference:    diff,
+		})
+	}
+	sort.Slice(discrepancies, func(i, j int) bool { return discrepancies[i].AccountID < discrepancies[j].AccountID })
+	return discrepancies, nil
+}
+
+// NormalizeNegativeAmounts rewrites negative amounts as positive and returns the number changed.
+func (r *memoryTransactionRepository) NormalizeNegativeAmounts() (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var changed int64
+	for _, tx := range r.store.transactions {
+		if tx.Amount < 0 {
+			tx.Amount = -tx.Amount
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// GetFeesForPeriod returns the completed FEE transactions charged to an account with
+// from <= ts < to, oldest first, together with their total.
+func (r *memoryTransactionRepository) GetFeesForPeriod(accountID int64, from, to time.Time) (float64, []models.Transaction, error) {
+	fees := r.selectTransactions(func(tx *models.Transaction) bool {
+		return tx.FromAccountID.Valid && tx.FromAccountID.Int64 == accountID &&
+			tx.TransactionType == models.TransactionTypeFee && tx.Status == models.TransactionStatusCompleted &&
+			!tx.TransactionTs.Before(from) && tx.TransactionTs.Before(to)
+	}, func(a, b models.Transaction) bool { return newestFirst(b, a) })
+
+	var total float64
+	for _, tx := range fees {
+		total += tx.Amount
+	}
+	return total, fees, nil
+}
+
+// GetDistinctTransactionTypes returns every transaction type present, sorted.
+func (r *memoryTransactionRepository) GetDistinctTransactionTypes() ([]string, error) {
+	r.store.mu.Lock()
+	seen := make(map[string]bool)
+	for _, tx := range r.store.transactions {
+		seen[tx.TransactionType] = true
+	}
+	r.store.mu.Unlock()
+
+	types := make([]string, 0, len(seen))
+	for t := range seen {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// ListTransactions returns a page of transactions matching filter, newest first, with the total.
+func (r *memoryTransactionRepository) ListTransactions(filter TransactionFilter) ([]models.Transaction, int64, error) {
+	if filter.Offset < 0 {
+		return nil, 0, fmt.Errorf("ListTransactions: offset must not be negative, got %d", filter.Offset)
+	}
+	match, err := transactionFilterMatcher(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ListTransactions: %w", err)
+	}
+	transactions := r.selectTransactions(match, newestFirst)
+
+	total := int64(len(transactions))
+	if filter.Limit > 0 {
+		if filter.Offset >= len(transactions) {
+			return nil, total, nil
+		}
+		end := filter.Offset + filter.Limit
+		if end > len(transactions) {
+			end = len(transactions)
+		}
+		transactions = transactions[filter.Offset:end]
+	}
+	return transactions, total, nil
+}
+
+// transactionFilterMatcher returns a predicate for every filter in filter except Limit and Offset.
+func transactionFilterMatcher(filter TransactionFilter) (func(tx *models.Transaction) bool, error) {
+	set, err := typeSet(filter.Types)
+	if err != nil {
+		return nil, err
+	}
+	return func(tx *models.Transaction) bool {
+		return (filter.IncludeDeleted || !tx.IsDeleted) &&
+			(filter.IncludePending || tx.Status == models.TransactionStatusCompleted) &&
+			(!filter.AccountID.Valid || touchesAccount(tx, filter.AccountID.Int64)) &&
+			(set == nil || set[tx.TransactionType]) &&
+			(!filter.From.Valid || !tx.TransactionTs.Before(filter.From.Time)) &&
+			(!filter.To.Valid || tx.TransactionTs.Before(filter.To.Time)) &&
+			(!filter.MinAmount.Valid || tx.Amount >= filter.MinAmount.Float64) &&
+			(!filter.MaxAmount.Valid || tx.Amount <= filter.MaxAmount.Float64)
+	}, nil
+}
+
+// AggregateTransactions returns the count and summed amount of the transactions matching
+// filter, keyed by transaction type.
+func (r *memoryTransactionRepository) AggregateTransactions(filter TransactionFilter) (map[string]models.TransactionAggregate, error) {
+	match, err := transactionFilterMatcher(filter)
+	if err != nil {
+		return nil, fmt.Errorf("AggregateTransactions: %w", err)
+	}
+	aggregates := make(map[string]models.TransactionAggregate)
+	for _, tx := range r.selectTransactions(match, newestFirst) {
+		agg := aggregates[tx.TransactionType]
+		agg.Count++
+		agg.Total = math.Round((agg.Total+tx.Amount)*100) / 100
+		aggregates[tx.TransactionType] = agg
+	}
+	return aggregates, nil
+}
+
+// SearchTransactionsByNotes returns up to limit transactions, newest first, whose notes (and
+// optionally description) contain query as a case-insensitive substring. Transfers pending
+// review are left out.
+func (r *memoryTransactionRepository) SearchTransactionsByNotes(query string, limit int, includeDescription bool) ([]models.Transaction, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("SearchTransactionsByNotes: query must not be empty")
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("SearchTransactionsByNotes: limit must be positive, got %d", limit)
+	}
+	// MySQL's default collations compare case-insensitively.
+	needle := strings.ToLower(query)
+	contains := func(s sql.NullString) bool {
+		return s.Valid && strings.Contains(strings.ToLower(s.String), needle)
+	}
+	transactions := r.selectTransactions(func(tx *models.Transaction) bool {
+		return tx.Status == models.TransactionStatusCompleted &&
+			(contains(tx.Notes) || (includeDescription && contains(tx.Description)))
+	}, newestFirst)
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+	}
+	return transactions, nil
+}
+
+// WithContext returns the repository itself; in-memory calls never block.
+func (r *memoryTransactionRepository) WithContext(ctx context.Context) TransactionRepository {
+	return r
+}
+
+// WithDBTX returns the repository itself; calls made inside MemoryStore.WithTransaction are
+// already part of its unit of work.
+func (r *memoryTransactionRepository) WithDBTX(db DBTX) TransactionRepository {
+	return r
+}
+
+// Close is a no-op; the store holds no statements.
+func (r *memoryTransactionRepository) Close() error {
+	return nil
+}
+
+// memoryOutboxRepository implements OutboxRepository on a MemoryStore.
+type memoryOutboxRepository struct {
+	store *MemoryStore
+}
+
+// CreateEvent stores an unpublished event and returns its ID.
+func (r *memoryOutboxRepository) CreateEvent(eventType string, payload []byte) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := int64(len(s.outboxEvents) + 1)
+	s.outboxEvents = append(s.outboxEvents, models.OutboxEvent{
+		EventID:   id,
+		EventType: eventType,
+		Payload:   append([]byte(nil), payload...),
+		CreatedAt: time.Now(),
+	})
+	return id, nil
+}
+
+// GetUnpublishedEvents returns up to limit unpublished events, oldest first.
+func (r *memoryOutboxRepository) GetUnpublishedEvents(limit int) ([]models.OutboxEvent, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []models.OutboxEvent
+	for _, ev := range s.outboxEvents {
+		if len(events) >= limit {
+			break
+		}
+		if !ev.PublishedAt.Valid {
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+// MarkEventPublished records that an event has been delivered.
+func (r *memoryOutboxRepository) MarkEventPublished(eventID int64) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if eventID < 1 || eventID > int64(len(s.outboxEvents)) || s.outboxEvents[eventID-1].PublishedAt.Valid {
+		return 0, nil
+	}
+	s.outboxEvents[eventID-1].PublishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return 1, nil
+}
+
+// WithDBTX returns the repository itself; calls made inside MemoryStore.WithTransaction are
+// already part of its unit of work.
+func (r *memoryOutboxRepository) WithDBTX(db DBTX) OutboxRepository {
+	return r
+}
+
+// memoryFixtureRepository implements FixtureRepository on a MemoryStore.
+type memoryFixtureRepository struct {
+	store *MemoryStore
+}
+
+// GetAllAccountsForFixtures returns every account, CLOSED ones included, in ID order.
+func (r *memoryFixtureRepository) GetAllAccountsForFixtures() ([]models.Account, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accounts := make([]models.Account, 0, len(s.accounts))
+	for _, acc := range s.accounts {
+		accounts = append(accounts, copyAccount(acc))
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].AccountID < accounts[j].AccountID })
+	return accounts, nil
+}
+
+// GetAllTransactionsForFixtures returns every transaction, soft-deleted and held ones included,
+// in ID order.
+func (r *memoryFixtureRepository) GetAllTransactionsForFixtures() ([]models.Transaction, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transactions := make([]models.Transaction, 0, len(s.transactions))
+	for _, tx := range s.transactions {
+		transactions = append(transactions, *tx)
+	}
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].TransactionID < transactions[j].TransactionID })
+	return transactions, nil
+}
+
+// InsertAccountFixture inserts acc as it is, balance and status included, and returns its ID.
+// acc.AccountID is kept when no account has it yet; otherwise a new one is assigned. An account
+// number that is already taken fails with ErrDuplicateEntry.
+func (r *memoryFixtureRepository) InsertAccountFixture(acc models.Account) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.accounts {
+		if existing.AccountNumber == acc.AccountNumber {
+			return 0, fmt.Errorf("InsertAccountFixture: account %d: %w: account number %s", acc.AccountID, ErrDuplicateEntry, acc.AccountNumber)
+		}
+	}
+	if _, taken := s.accounts[acc.AccountID]; taken || acc.AccountID <= 0 {
+		acc.AccountID = s.nextAccountID + 1
+	}
+	s.nextAccountID = max(s.nextAccountID, acc.AccountID)
+	acc = copyAccount(&acc)
+	acc.LastUpdated = acc.LastUpdated.UTC()
+	s.accounts[acc.AccountID] = &acc
+	return acc.AccountID, nil
+}
+
+// InsertTransactionFixture inserts tx as it is and returns its ID, keeping tx.TransactionID when
+// it is free like InsertAccountFixture.
+func (r *memoryFixtureRepository) InsertTransactionFixture(tx models.Transaction) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, taken := s.transactions[tx.TransactionID]; taken || tx.TransactionID <= 0 {
+		tx.TransactionID = s.nextTxID + 1
+	}
+	s.nextTxID = max(s.nextTxID, tx.TransactionID)
+	tx.TransactionTs = tx.TransactionTs.UTC()
+	s.transactions[tx.TransactionID] = &tx
+	return tx.TransactionID, nil
+}
@@ -0,0 +1,763 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+)
+
+func TestMemoryAdjustAccountBalance(t *testing.T) {
+	store := NewMemoryStore()
+	accounts := store.Accounts()
+	open, _, err := accounts.CreateAccount("Alice", 100)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	closed, _, err := accounts.CreateAccount("Bob", 50)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	if _, err := accounts.SoftDeleteAccount(closed); err != nil {
+		t.Fatalf("SoftDeleteAccount: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		accountID int64
+		change    float64
+		wantRows  int64
+		wantErr   error
+	}{
+		{"credit", open, 25, 1, nil},
+		{"debit", open, -25, 1, nil},
+		{"overdraw", open, -101, 0, util.ErrInsufficientFunds},
+		{"missing account", 999, 10, 0, util.ErrAccountNotFound},
+		{"closed account", closed, 10, 0, util.ErrAccountNotFound},
+		{"zero change", 999, 0, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := accounts.AdjustAccountBalance(tt.accountID, tt.change)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("AdjustAccountBalance error = %v, want %v", err, tt.wantErr)
+			}
+			if rows != tt.wantRows {
+				t.Errorf("AdjustAccountBalance rows = %d, want %d", rows, tt.wantRows)
+			}
+		})
+	}
+
+	if acc, _ := accounts.GetAccountByIDIncludingDeleted(closed); acc.Balance != 50 {
+		t.Errorf("closed account balance = %.2f, want it left at 50.00", acc.Balance)
+	}
+	if acc, _ := accounts.GetAccountByID(open); acc.Balance != 100 {
+		t.Errorf("open account balance = %.2f, want 100.00", acc.Balance)
+	}
+}
+
+func TestMemorySoftDeleteInactiveAccounts(t *testing.T) {
+	store := NewMemoryStore()
+	accounts := store.Accounts()
+	create := func(holder string, balance float64) int64 {
+		t.Helper()
+		id, _, err := accounts.CreateAccount(holder, balance)
+		if err != nil {
+			t.Fatalf("CreateAccount(%q): %v", holder, err)
+		}
+		return id
+	}
+	dormant := create("Dormant", 40)
+	empty := create("Empty", 0)
+	busy := create("Busy", 10)
+	frozen := create("Frozen", 0)
+	if _, err := accounts.FreezeAccount(frozen); err != nil {
+		t.Fatalf("FreezeAccount: %v", err)
+	}
+
+	// Every account was opened before since; only busy has a transaction after it.
+	since := time.Now().Add(time.Hour)
+	if _, err := store.Transactions().CreateTransactionAt(sql.NullInt64{}, sql.NullInt64{Int64: busy, Valid: true}, "DEPOSIT", 5, sql.NullString{}, sql.NullString{}, since.Add(time.Minute)); err != nil {
+		t.Fatalf("CreateTransactionAt: %v", err)
+	}
+
+	found, err := accounts.FindInactiveAccounts(since, InactiveAccountOptions{})
+	if err != nil {
+		t.Fatalf("FindInactiveAccounts: %v", err)
+	}
+	if len(found) != 2 || found[0].AccountID != dormant || found[1].AccountID != empty {
+		t.Fatalf("FindInactiveAccounts = %+v, want accounts %d and %d", found, dormant, empty)
+	}
+
+	closed, err := accounts.SoftDeleteInactiveZeroBalanceAccounts(since)
+	if err != nil || closed != 1 {
+		t.Fatalf("SoftDeleteInactiveZeroBalanceAccounts = %d, %v; want 1, nil", closed, err)
+	}
+	if _, err := accounts.GetAccountByID(empty); !errors.Is(err, util.ErrAccountNotFound) {
+		t.Errorf("zero-balance dormant account still open: %v", err)
+	}
+
+	closed, err = accounts.SoftDeleteInactiveAccounts(since)
+	if err != nil || closed != 1 {
+		t.Fatalf("SoftDeleteInactiveAccounts = %d, %v; want 1, nil", closed, err)
+	}
+	if _, err := accounts.GetAccountByID(dormant); !errors.Is(err, util.ErrAccountNotFound) {
+		t.Errorf("dormant account still open: %v", err)
+	}
+	for _, id := range []int64{busy, frozen} {
+		if _, err := accounts.GetAccountByID(id); err != nil {
+			t.Errorf("account %d was closed: %v", id, err)
+		}
+	}
+
+	// Closed accounts can be reopened like ones closed one at a time.
+	if _, err := accounts.UndeleteAccount(dormant); err != nil {
+		t.Errorf("UndeleteAccount: %v", err)
+	}
+}
+
+func TestMemoryGetBalancesForTransactions(t *testing.T) {
+	store := NewMemoryStore()
+	accounts := store.Accounts()
+	alice, _, _ := accounts.CreateAccount("Alice", 100)
+	bob, _, _ := accounts.CreateAccount("Bob", 20.5)
+	carol, _, _ := accounts.CreateAccount("Carol", 7)
+	if _, err := accounts.SoftDeleteAccount(carol); err != nil {
+		t.Fatalf("SoftDeleteAccount: %v", err)
+	}
+
+	id := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+	feed := []models.Transaction{
+		{FromAccountID: id(alice), ToAccountID: id(bob)},
+		{FromAccountID: id(bob), ToAccountID: id(carol)},
+		{ToAccountID: id(999)},
+	}
+	balances, err := accounts.GetBalancesForTransactions(feed)
+	if err != nil {
+		t.Fatalf("GetBalancesForTransactions: %v", err)
+	}
+	// Closed and missing accounts are left out.
+	want := map[int64]float64{alice: 100, bob: 20.5}
+	if !reflect.DeepEqual(balances, want) {
+		t.Errorf("GetBalancesForTransactions = %v, want %v", balances, want)
+	}
+	if balances, err := accounts.GetBalancesForTransactions(nil); err != nil || len(balances) != 0 {
+		t.Errorf("GetBalancesForTransactions(nil) = %v, %v; want an empty map", balances, err)
+	}
+}
+
+// testIsValidTransferTarget checks every IsValidTransferTarget outcome on an empty repository.
+// fixtures seeds the account held in another currency.
+func testIsValidTransferTarget(t *testing.T, accounts AccountRepository, fixtures FixtureRepository) {
+	t.Helper()
+	create := func(holder string) int64 {
+		t.Helper()
+		id, _, err := accounts.CreateAccount(holder, 10)
+		if err != nil {
+			t.Fatalf("CreateAccount(%q): %v", holder, err)
+		}
+		return id
+	}
+	source := create("Source")
+	active := create("Active")
+	frozen := create("Frozen")
+	closed := create("Closed")
+	if _, err := accounts.FreezeAccount(frozen); err != nil {
+		t.Fatalf("FreezeAccount: %v", err)
+	}
+	if _, err := accounts.SoftDeleteAccount(closed); err != nil {
+		t.Fatalf("SoftDeleteAccount: %v", err)
+	}
+	system := create("Fee revenue")
+	if _, err := accounts.SetAccountMetadata(system, map[string]string{models.MetadataSystemAccount: "true"}); err != nil {
+		t.Fatalf("SetAccountMetadata: %v", err)
+	}
+	euro, err := fixtures.InsertAccountFixture(models.Account{AccountNumber: "EUR0000001", AccountHolder: "Euro", Balance: 10,
+		Currency: "EUR", LastUpdated: time.Now(), Status: models.AccountStatusActive})
+	if err != nil {
+		t.Fatalf("InsertAccountFixture: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		target     int64
+		wantValid  bool
+		wantReason string
+	}{
+		{"active", active, true, ""},
+		{"same as source", source, false, TransferTargetSameAsSource},
+		{"missing", closed + 1000, false, TransferTargetNotFound},
+		{"frozen", frozen, false, TransferTargetFrozen},
+		{"closed", closed, false, TransferTargetInactive},
+		{"system account", system, false, TransferTargetSystem},
+		{"other currency", euro, false, TransferTargetCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, reason, err := accounts.IsValidTransferTarget(source, tt.target)
+			if err != nil {
+				t.Fatalf("IsValidTransferTarget: %v", err)
+			}
+			if valid != tt.wantValid || reason != tt.wantReason {
+				t.Errorf("IsValidTransferTarget = %v, %q; want %v, %q", valid, reason, tt.wantValid, tt.wantReason)
+			}
+		})
+	}
+
+	// Without a source account there is no currency to compare against.
+	if valid, reason, err := accounts.IsValidTransferTarget(0, euro); err != nil || !valid {
+		t.Errorf("IsValidTransferTarget(0, euro) = %v, %q, %v; want valid", valid, reason, err)
+	}
+}
+
+func TestMemoryIsValidTransferTarget(t *testing.T) {
+	store := NewMemoryStore()
+	testIsValidTransferTarget(t, store.Accounts(), store.Fixtures())
+}
+
+// testAccountMetadata checks setting, reading and searching account metadata, including keys
+// that need quoting in a JSON path.
+func testAccountMetadata(t *testing.T, accounts AccountRepository) {
+	t.Helper()
+	alice, _, err := accounts.CreateAccount("Alice", 0)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	bob, _, err := accounts.CreateAccount("Bob", 0)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+
+	if got, err := accounts.GetAccountMetadata(alice); err != nil || len(got) != 0 {
+		t.Fatalf("GetAccountMetadata without metadata = %v, %v; want an empty map", got, err)
+	}
+	keys := map[string]string{"branch": "north", "a.b": "dot", `say "hi"`: "quote", `back\slash`: "backslash", "é key": "unicode"}
+	if _, err := accounts.SetAccountMetadata(alice, keys); err != nil {
+		t.Fatalf("SetAccountMetadata: %v", err)
+	}
+	if _, err := accounts.SetAccountMetadata(alice, map[string]string{"segment": "retail"}); err != nil {
+		t.Fatalf("SetAccountMetadata merge: %v", err)
+	}
+	if _, err := accounts.SetAccountMetadata(bob, map[string]string{"branch": "south"}); err != nil {
+		t.Fatalf("SetAccountMetadata: %v", err)
+	}
+
+	want := models.AccountMetadata{"segment": "retail"}
+	for k, v := range keys {
+		want[k] = v
+	}
+	if got, err := accounts.GetAccountMetadata(alice); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAccountMetadata = %v, %v; want %v", got, err, want)
+	}
+
+	for key, value := range keys {
+		found, err := accounts.GetAccountsByMetadata(key, value)
+		if err != nil {
+			t.Errorf("GetAccountsByMetadata(%q): %v", key, err)
+			continue
+		}
+		if len(found) != 1 || found[0].AccountID != alice {
+			t.Errorf("GetAccountsByMetadata(%q, %q) = %d accounts, want only Alice", key, value, len(found))
+		}
+	}
+	if found, err := accounts.GetAccountsByMetadata("branch", "south"); err != nil || len(found) != 1 || found[0].AccountID != bob {
+		t.Errorf("GetAccountsByMetadata(branch, south) = %v, %v; want only Bob", found, err)
+	}
+	for _, key := range []string{"", "tab\tkey", "nul\x00"} {
+		if _, err := accounts.GetAccountsByMetadata(key, "x"); err == nil {
+			t.Errorf("GetAccountsByMetadata(%q) succeeded, want an error", key)
+		}
+	}
+
+	_, err = accounts.GetAccountMetadata(bob + 1000)
+	if !errors.Is(err, util.ErrAccountNotFound) || !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetAccountMetadata of a missing account error = %v, want %v and %v", err, util.ErrAccountNotFound, sql.ErrNoRows)
+	}
+}
+
+func TestMemoryAccountMetadata(t *testing.T) {
+	testAccountMetadata(t, NewMemoryStore().Accounts())
+}
+
+// testCreateTransactionIdempotent fires the same idempotency key several times, concurrently,
+// and checks that one transaction is recorded and every call returns its ID.
+func testCreateTransactionIdempotent(t *testing.T, accounts AccountRepository, transactions TransactionRepository) {
+	t.Helper()
+	id, _, err := accounts.CreateAccount("Alice", 0)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	to := sql.NullInt64{Int64: id, Valid: true}
+	create := func(key string) (int64, bool, error) {
+		return transactions.CreateTransactionIdempotent(key, sql.NullInt64{}, to, "DEPOSIT", 25, sql.NullString{}, sql.NullString{})
+	}
+
+	const calls = 8
+	type result struct {
+		id      int64
+		created bool
+		err     error
+	}
+	results := make(chan result, calls)
+	for i := 0; i < calls; i++ {
+		go func() {
+			id, created, err := create("payment-1")
+			results <- result{id, created, err}
+		}()
+	}
+	var first int64
+	created := 0
+	for i := 0; i < calls; i++ {
+		r := <-results
+		if r.err != nil {
+			t.Fatalf("CreateTransactionIdempotent: %v", r.err)
+		}
+		if first == 0 {
+			first = r.id
+		}
+		if r.id != first {
+			t.Errorf("CreateTransactionIdempotent returned IDs %d and %d for the same key", first, r.id)
+		}
+		if r.created {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d calls reported creating the transaction, want 1", created)
+	}
+
+	if other, created, err := create("payment-2"); err != nil || !created || other == first {
+		t.Errorf("CreateTransactionIdempotent with a new key = %d, %v, %v; want a new transaction", other, created, err)
+	}
+	if _, total, err := transactions.ListTransactions(TransactionFilter{}); err != nil || total != 2 {
+		t.Errorf("ListTransactions total = %d, %v; want 2", total, err)
+	}
+	if _, _, err := create(""); err == nil {
+		t.Error("CreateTransactionIdempotent with an empty key succeeded")
+	}
+}
+
+func TestMemoryCreateTransactionIdempotent(t *testing.T) {
+	store := NewMemoryStore()
+	testCreateTransactionIdempotent(t, store.Accounts(), store.Transactions())
+}
+
+// testFindTransactionIDGaps deletes rows from the middle of the transactions table and checks
+// the gaps they leave are reported.
+func testFindTransactionIDGaps(t *testing.T, accounts AccountRepository, transactions TransactionRepository) {
+	t.Helper()
+	accountID, _, err := accounts.CreateAccount("Alice", 0)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	to := sql.NullInt64{Int64: accountID, Valid: true}
+	// Transfers held for review have moved no money, so they may be deleted.
+	ids := make([]int64, 7)
+	for i := range ids {
+		if ids[i], err = transactions.CreatePendingReviewTransaction(sql.NullInt64{}, to, "DEPOSIT", 10, sql.NullString{}, sql.NullString{}); err != nil {
+			t.Fatalf("CreatePendingReviewTransaction: %v", err)
+		}
+	}
+	if gaps, err := transactions.FindTransactionIDGaps(); err != nil || len(gaps) != 0 {
+		t.Fatalf("FindTransactionIDGaps before deleting = %v, %v; want none", gaps, err)
+	}
+
+	// Delete a single middle row, two adjacent ones and the last one, and soft-delete another.
+	for _, i := range []int{1, 3, 4, 6} {
+		if _, err := transactions.DeleteTransaction(ids[i]); err != nil {
+			t.Fatalf("DeleteTransaction(%d): %v", ids[i], err)
+		}
+	}
+	if _, err := transactions.SoftDeleteTransaction(ids[5]); err != nil {
+		t.Fatalf("SoftDeleteTransaction: %v", err)
+	}
+
+	gaps, err := transactions.FindTransactionIDGaps()
+	if err != nil {
+		t.Fatalf("FindTransactionIDGaps: %v", err)
+	}
+	// Nothing above the highest remaining ID counts as a gap, and soft-deleted rows are still there.
+	want := []models.IDGap{{Start: ids[1], End: ids[1]}, {Start: ids[3], End: ids[4]}}
+	if !reflect.DeepEqual(gaps, want) {
+		t.Errorf("FindTransactionIDGaps = %v, want %v", gaps, want)
+	}
+}
+
+func TestMemoryFindTransactionIDGaps(t *testing.T) {
+	store := NewMemoryStore()
+	testFindTransactionIDGaps(t, store.Accounts(), store.Transactions())
+}
+
+// testGetTransactionsWithCategoryByAmount checks amount ordering and the minimum amount filter
+// of GetTransactionsWithCategory.
+func testGetTransactionsWithCategoryByAmount(t *testing.T, accounts AccountRepository, transactions TransactionRepository) {
+	t.Helper()
+	accountID, _, err := accounts.CreateAccount("Alice", 0)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	to := sql.NullInt64{Int64: accountID, Valid: true}
+	var ids []int64
+	for _, amount := range []float64{50, 200, 10, 200} {
+		id, err := transactions.CreateTransactionWithCategory(sql.NullInt64{}, to, "DEPOSIT", amount, sql.NullString{}, sql.NullString{}, "Salary")
+		if err != nil {
+			t.Fatalf("CreateTransactionWithCategory: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	list := func(opts CategoryListOptions) []int64 {
+		t.Helper()
+		results, err := transactions.GetTransactionsWithCategory(accountID, opts)
+		if err != nil {
+			t.Fatalf("GetTransactionsWithCategory(%+v): %v", opts, err)
+		}
+		var got []int64
+		for _, r := range results {
+			if r.CategoryName.String != "Salary" {
+				t.Errorf("transaction %d category = %v, want Salary", r.TransactionID, r.CategoryName)
+			}
+			got = append(got, r.TransactionID)
+		}
+		return got
+	}
+
+	// Largest first; equal amounts newest first.
+	if got, want := list(CategoryListOptions{OrderByAmount: true}), []int64{ids[3], ids[1], ids[0], ids[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ordered by amount = %v, want %v", got, want)
+	}
+	if got, want := list(CategoryListOptions{OrderByAmount: true, MinAmount: sql.NullFloat64{Float64: 50, Valid: true}}), []int64{ids[3], ids[1], ids[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("at least 50, ordered by amount = %v, want %v", got, want)
+	}
+	if got := list(CategoryListOptions{MinAmount: sql.NullFloat64{Float64: 500, Valid: true}}); len(got) != 0 {
+		t.Errorf("at least 500 = %v, want none", got)
+	}
+}
+
+func TestMemoryGetTransactionsWithCategoryByAmount(t *testing.T) {
+	store := NewMemoryStore()
+	testGetTransactionsWithCategoryByAmount(t, store.Accounts(), store.Transactions())
+}
+
+// testGetFeesForPeriod checks that only the account's FEE transactions within [from, to) are
+// billed.
+func testGetFeesForPeriod(t *testing.T, accounts AccountRepository, transactions TransactionRepository) {
+	t.Helper()
+	alice, _, err := accounts.CreateAccount("Alice", 100)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	bob, _, err := accounts.CreateAccount("Bob", 100)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	from := time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	record := func(payer int64, txType string, amount float64, ts time.Time) int64 {
+		t.Helper()
+		id, err := transactions.CreateTransactionAt(sql.NullInt64{Int64: payer, Valid: true}, sql.NullInt64{}, txType, amount, sql.NullString{}, sql.NullString{}, ts)
+		if err != nil {
+			t.Fatalf("CreateTransactionAt: %v", err)
+		}
+		return id
+	}
+	first := record(alice, models.TransactionTypeFee, 1.25, from)
+	second := record(alice, models.TransactionTypeFee, 2.5, to.Add(-time.Second))
+	record(alice, models.TransactionTypeFee, 4, from.Add(-time.Second)) // the month before
+	record(alice, models.TransactionTypeFee, 8, to)                     // the month after
+	record(alice, models.TransactionTypeWithdrawal, 16, from.Add(time.Hour))
+	record(bob, models.TransactionTypeFee, 32, from.Add(time.Hour))
+
+	total, fees, err := transactions.GetFeesForPeriod(alice, from, to)
+	if err != nil {
+		t.Fatalf("GetFeesForPeriod: %v", err)
+	}
+	if total != 3.75 {
+		t.Errorf("GetFeesForPeriod total = %.2f, want 3.75", total)
+	}
+	if len(fees) != 2 || fees[0].TransactionID != first || fees[1].TransactionID != second {
+		t.Errorf("GetFeesForPeriod items = %+v, want transactions %d and %d, oldest first", fees, first, second)
+	}
+
+	if total, fees, err := transactions.GetFeesForPeriod(alice, to.AddDate(0, 1, 0), to.AddDate(0, 2, 0)); err != nil || total != 0 || len(fees) != 0 {
+		t.Errorf("GetFeesForPeriod with no fees = %.2f, %d items, %v; want 0, none", total, len(fees), err)
+	}
+}
+
+func TestMemoryGetFeesForPeriod(t *testing.T) {
+	store := NewMemoryStore()
+	testGetFeesForPeriod(t, store.Accounts(), store.Transactions())
+}
+
+// testHasTransactions checks HasTransactions for accounts that sent, received, never used, or
+// only have soft-deleted transactions.
+func testHasTransactions(t *testing.T, accounts AccountRepository, transactions TransactionRepository) {
+	t.Helper()
+	create := func(holder string) int64 {
+		t.Helper()
+		id, _, err := accounts.CreateAccount(holder, 100)
+		if err != nil {
+			t.Fatalf("CreateAccount(%q): %v", holder, err)
+		}
+		return id
+	}
+	sender, receiver, unused, hidden := create("Sender"), create("Receiver"), create("Unused"), create("Hidden")
+	id := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+	if _, err := transactions.CreateTransaction(id(sender), id(receiver), "TRANSFER", 10, sql.NullString{}); err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	deposit, err := transactions.CreateTransaction(sql.NullInt64{}, id(hidden), "DEPOSIT", 10, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	if _, err := transactions.SoftDeleteTransaction(deposit); err != nil {
+		t.Fatalf("SoftDeleteTransaction: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		accountID int64
+		want      bool
+	}{
+		{"sender", sender, true},
+		{"receiver", receiver, true},
+		{"unused", unused, false},
+		{"soft-deleted transactions only", hidden, true}, // the account was still used
+		{"missing account", hidden + 1000, false},
+	} {
+		if got, err := accounts.HasTransactions(tt.accountID); err != nil || got != tt.want {
+			t.Errorf("%s: HasTransactions = %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestMemoryHasTransactions(t *testing.T) {
+	store := NewMemoryStore()
+	testHasTransactions(t, store.Accounts(), store.Transactions())
+}
+
+// testTimestampsAreUTC checks that transaction and account timestamps come back in UTC,
+// whatever zone they were given in.
+func testTimestampsAreUTC(t *testing.T, accounts AccountRepository, transactions TransactionRepository) {
+	t.Helper()
+	accountID, _, err := accounts.CreateAccount("Alice", 0)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	to := sql.NullInt64{Int64: accountID, Valid: true}
+	instant := time.Date(2026, time.June, 30, 23, 30, 0, 0, time.UTC)
+	// 08:30 on July 1st in Tokyo is still June in UTC.
+	at, err := transactions.CreateTransactionAt(sql.NullInt64{}, to, "DEPOSIT", 10, sql.NullString{}, sql.NullString{}, instant.In(time.FixedZone("JST", 9*60*60)))
+	if err != nil {
+		t.Fatalf("CreateTransactionAt: %v", err)
+	}
+	now, err := transactions.CreateTransaction(sql.NullInt64{}, to, "DEPOSIT", 10, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+
+	for _, id := range []int64{at, now} {
+		tx, err := transactions.GetTransactionByID(id)
+		if err != nil {
+			t.Fatalf("GetTransactionByID(%d): %v", id, err)
+		}
+		if tx.TransactionTs.Location() != time.UTC {
+			t.Errorf("transaction %d timestamp %v is not in UTC", id, tx.TransactionTs)
+		}
+	}
+	if tx, _ := transactions.GetTransactionByID(at); !tx.TransactionTs.Equal(instant) {
+		t.Errorf("transaction timestamp = %v, want %v", tx.TransactionTs, instant)
+	}
+	if acc, err := accounts.GetAccountByID(accountID); err != nil || acc.LastUpdated.Location() != time.UTC {
+		t.Errorf("account last updated = %v, %v; want a UTC time", acc.LastUpdated, err)
+	}
+}
+
+func TestMemoryTimestampsAreUTC(t *testing.T) {
+	store := NewMemoryStore()
+	testTimestampsAreUTC(t, store.Accounts(), store.Transactions())
+}
+
+// testHolderNameValidation checks that CreateAccount and UpdateAccountHolderName reject blank
+// and over-length names and store valid ones trimmed.
+func testHolderNameValidation(t *testing.T, accounts AccountRepository) {
+	t.Helper()
+	tooLong := strings.Repeat("a", maxHolderNameLength+1)
+	for _, name := range []string{"", "   ", tooLong} {
+		if _, _, err := accounts.CreateAccount(name, 0); !errors.Is(err, util.ErrInvalidAccountHolder) {
+			t.Errorf("CreateAccount(%.10q) error = %v, want %v", name, err, util.ErrInvalidAccountHolder)
+		}
+	}
+
+	id, _, err := accounts.CreateAccount("  Alice  ", 0)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	for _, name := range []string{"", "   ", tooLong} {
+		if _, err := accounts.UpdateAccountHolderName(id, name); !errors.Is(err, util.ErrInvalidAccountHolder) {
+			t.Errorf("UpdateAccountHolderName(%.10q) error = %v, want %v", name, err, util.ErrInvalidAccountHolder)
+		}
+	}
+	if acc, err := accounts.GetAccountByID(id); err != nil || acc.AccountHolder != "Alice" {
+		t.Errorf("account holder = %q, %v; want Alice, trimmed and unchanged by the rejected renames", acc.AccountHolder, err)
+	}
+}
+
+func TestMemoryHolderNameValidation(t *testing.T) {
+	testHolderNameValidation(t, NewMemoryStore().Accounts())
+}
+
+// testGetDistinctTransactionTypes checks that every stored type is listed once, sorted, with
+// stray types kept so they can be spotted.
+func testGetDistinctTransactionTypes(t *testing.T, accounts AccountRepository, transactions TransactionRepository) {
+	t.Helper()
+	if types, err := transactions.GetDistinctTransactionTypes(); err != nil || len(types) != 0 {
+		t.Fatalf("GetDistinctTransactionTypes with no transactions = %q, %v; want none", types, err)
+	}
+
+	alice, _, err := accounts.CreateAccount("Alice", 100)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	to := sql.NullInt64{Int64: alice, Valid: true}
+	for _, txType := range []string{"WITHDRAWAL", "DEPOSIT", "DEPOSIT", "DEPOIST", "TRANSFER", "DEPOSIT"} {
+		if _, err := transactions.CreateTransaction(sql.NullInt64{}, to, txType, 1, sql.NullString{}); err != nil {
+			t.Fatalf("CreateTransaction(%s): %v", txType, err)
+		}
+	}
+
+	types, err := transactions.GetDistinctTransactionTypes()
+	if err != nil {
+		t.Fatalf("GetDistinctTransactionTypes: %v", err)
+	}
+	if want := []string{"DEPOIST", "DEPOSIT", "TRANSFER", "WITHDRAWAL"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("GetDistinctTransactionTypes = %q, want %q", types, want)
+	}
+	var stray []string
+	for _, txType := range types {
+		if !models.ValidTransactionTypes[txType] {
+			stray = append(stray, txType)
+		}
+	}
+	if !reflect.DeepEqual(stray, []string{"DEPOIST"}) {
+		t.Errorf("types missing from ValidTransactionTypes = %q, want [DEPOIST]", stray)
+	}
+}
+
+func TestMemoryGetDistinctTransactionTypes(t *testing.T) {
+	store := NewMemoryStore()
+	testGetDistinctTransactionTypes(t, store.Accounts(), store.Transactions())
+}
+
+func TestMemoryFixtures(t *testing.T) {
+	store := NewMemoryStore()
+	fixtures := store.Fixtures()
+	alice, _, err := store.Accounts().CreateAccount("Alice", 10)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+
+	seeded := models.Account{AccountID: alice, AccountNumber: "JP0001", AccountHolder: "Hiro", Balance: 1500, Currency: "JPY",
+		MaxBalance: sql.NullFloat64{Float64: 2000, Valid: true}, Status: models.AccountStatusActive}
+	id, err := fixtures.InsertAccountFixture(seeded)
+	if err != nil {
+		t.Fatalf("InsertAccountFixture: %v", err)
+	}
+	if id == alice {
+		t.Fatalf("InsertAccountFixture reused the taken ID %d", id)
+	}
+	acc, err := store.Accounts().GetAccountByID(id)
+	if err != nil || acc.Currency != "JPY" || acc.Balance != 1500 || acc.MaxBalance != seeded.MaxBalance {
+		t.Errorf("GetAccountByID = %+v, %v; want the seeded JPY account", acc, err)
+	}
+	if _, err := store.Accounts().AdjustAccountBalance(id, 600); !errors.Is(err, util.ErrAboveMaximumBalance) {
+		t.Errorf("AdjustAccountBalance past max_balance error = %v, want %v", err, util.ErrAboveMaximumBalance)
+	}
+	if _, err := fixtures.InsertAccountFixture(seeded); !errors.Is(err, ErrDuplicateEntry) {
+		t.Errorf("InsertAccountFixture with a taken account number error = %v, want %v", err, ErrDuplicateEntry)
+	}
+	// Accounts created afterwards do not collide with seeded IDs.
+	next, _, err := store.Accounts().CreateAccount("Bob", 0)
+	if err != nil || next <= id {
+		t.Errorf("CreateAccount after seeding = %d, %v; want an ID above %d", next, err, id)
+	}
+	if all, err := fixtures.GetAllAccountsForFixtures(); err != nil || len(all) != 3 {
+		t.Errorf("GetAllAccountsForFixtures = %d accounts, %v; want 3", len(all), err)
+	}
+}
+
+// testPendingTransfersHidden checks that transfers held for review, which have moved no funds,
+// are left out of the category listing, fee totals and notes search unless asked for.
+func testPendingTransfersHidden(t *testing.T, accounts AccountRepository, transactions TransactionRepository) {
+	t.Helper()
+	alice, _, err := accounts.CreateAccount("Alice", 100)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	bob, _, err := accounts.CreateAccount("Bob", 100)
+	if err != nil {
+		t.Fatalf("CreateAccount: %v", err)
+	}
+	from, to := sql.NullInt64{Int64: alice, Valid: true}, sql.NullInt64{Int64: bob, Valid: true}
+	notes := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+
+	completed, err := transactions.CreateTransactionWithNotes(from, to, "TRANSFER", 10, sql.NullString{}, notes("invoice 41"))
+	if err != nil {
+		t.Fatalf("CreateTransactionWithNotes: %v", err)
+	}
+	fee, err := transactions.CreateTransaction(from, to, models.TransactionTypeFee, 1, sql.NullString{})
+	if err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	held, err := transactions.CreatePendingReviewTransaction(from, to, "TRANSFER", 500, sql.NullString{}, notes("invoice 42"))
+	if err != nil {
+		t.Fatalf("CreatePendingReviewTransaction: %v", err)
+	}
+	if _, err := transactions.CreatePendingReviewTransaction(from, to, models.TransactionTypeFee, 5, sql.NullString{}, notes("invoice 42 fee")); err != nil {
+		t.Fatalf("CreatePendingReviewTransaction: %v", err)
+	}
+
+	ids := func(txs []models.TransactionWithCategory) []int64 {
+		var out []int64
+		for _, tx := range txs {
+			out = append(out, tx.TransactionID)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+		return out
+	}
+	listed, err := transactions.GetTransactionsWithCategory(bob, CategoryListOptions{})
+	if err != nil {
+		t.Fatalf("GetTransactionsWithCategory: %v", err)
+	}
+	if got, want := ids(listed), []int64{completed, fee}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetTransactionsWithCategory = %v, want %v without the held transfers", got, want)
+	}
+	listed, err = transactions.GetTransactionsWithCategory(bob, CategoryListOptions{IncludePending: true, MinAmount: sql.NullFloat64{Float64: 100, Valid: true}})
+	if err != nil {
+		t.Fatalf("GetTransactionsWithCategory with IncludePending: %v", err)
+	}
+	if got, want := ids(listed), []int64{held}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetTransactionsWithCategory with IncludePending = %v, want %v", got, want)
+	}
+
+	now := time.Now().UTC()
+	total, fees, err := transactions.GetFeesForPeriod(alice, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil || total != 1 || len(fees) != 1 || fees[0].TransactionID != fee {
+		t.Errorf("GetFeesForPeriod = %.2f, %+v, %v; want only the completed fee %d", total, fees, err, fee)
+	}
+
+	found, err := transactions.SearchTransactionsByNotes("invoice", 10, false)
+	if err != nil || len(found) != 1 || found[0].TransactionID != completed {
+		t.Errorf("SearchTransactionsByNotes = %+v, %v; want only the completed transfer %d", found, err, completed)
+	}
+}
+
+func TestMemoryPendingTransfersHidden(t *testing.T) {
+	store := NewMemoryStore()
+	testPendingTransfersHidden(t, store.Accounts(), store.Transactions())
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// QueryObserver receives one observation per database call made by a repository.
+// name is the repository method that issued the call, e.g. "GetAccountByID", so an
+// implementation can export per-method latency and error counts (for example to Prometheus)
+// without the repositories depending on a metrics library.
+type QueryObserver interface {
+	ObserveQuery(name string, dur time.Duration, err error)
+}
+
+// RepositoryOption configures optional repository behaviour.
+type RepositoryOption func(*dbHandle)
+
+// WithQueryObserver reports every database call of the repository to observer.
+func WithQueryObserver(observer QueryObserver) RepositoryOption {
+	return func(h *dbHandle) {
+		h.observer = observer
+	}
+}
+
+// dbHandle is the database a repository runs against, plus its optional observer, prepared
+// statements, timeouts and call context.
+type dbHandle struct {
+	db             DBTX
+	observer       QueryObserver
+	prepare        bool
+	stmts          map[string]*sql.Stmt // keyed by query text
+	timeout        time.Duration
+	methodTimeouts map[string]time.Duration // keyed by repository method name
+	ctx            context.Context          // nil means context.Background()
+}
+
+// newDBHandle applies opts to a handle for db.
+func newDBHandle(db DBTX, opts []RepositoryOption) dbHandle {
+	h := dbHandle{db: db}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	return h
+}
+
+// op returns the DBTX to use for calls made on behalf of the named repository method.
+// Without an observer, prepared statements, timeout or context it is the underlying database itself.
+func (h dbHandle) op(name string) DBTX {
+	db := h.db
+	if len(h.stmts) > 0 {
+		db = preparedDBTX{db: h.db, stmts: h.stmts}
+	}
+	if _, ok := h.db.(contextDBTX); ok {
+		if timeout := h.timeoutFor(name); timeout > 0 || h.ctx != nil {
+			ctx := h.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			db = timeoutDBTX{db: db, ctx: ctx, timeout: timeout}
+		}
+	}
+	if h.observer == nil {
+		return db
+	}
+	return observedDBTX{db: db, observer: h.observer, name: name}
+}
+
+// observedDBTX times each call and reports it under name.
+// For Query the observation covers executing the query, not iterating the rows;
+// for QueryRow the error is the one later returned by Scan, if the query itself failed.
+type observedDBTX struct {
+	db       DBTX
+	observer QueryObserver
+	name     string
+}
+
+// Exec runs query and observes it.
+func (o observedDBTX) Exec(query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := o.db.Exec(query, args...)
+	o.observer.ObserveQuery(o.name, time.Since(start), err)
+	return result, err
+}
+
+// QueryRow runs query and observes it.
+func (o observedDBTX) QueryRow(query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := o.db.QueryRow(query, args...)
+	o.observer.ObserveQuery(o.name, time.Since(start), row.Err())
+	return row
+}
+
+// Query runs query and observes it.
+func (o observedDBTX) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := o.db.Query(query, args...)
+	o.observer.ObserveQuery(o.name, time.Since(start), err)
+	return rows, err
+}
+
+// Prepare prepares query and observes it.
+func (o observedDBTX) Prepare(query string) (*sql.Stmt, error) {
+	start := time.Now()
+	stmt, err := o.db.Prepare(query)
+	o.observer.ObserveQuery(o.name, time.Since(start), err)
+	return stmt, err
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"fmt"
+	"sql-golang-playground/models"
+)
+
+// mysqlOutboxRepository implements OutboxRepository for MySQL.
+type mysqlOutboxRepository struct {
+	db dbHandle
+}
+
+// NewMySQLOutboxRepository creates a new MySQL outbox repository backed by a *sql.DB or *sql.Tx.
+func NewMySQLOutboxRepository(db DBTX, opts ...RepositoryOption) OutboxRepository {
+	return &mysqlOutboxRepository{db: newDBHandle(db, opts)}
+}
+
+// CreateEvent inserts an unpublished event and returns its ID.
+func (r *mysqlOutboxRepository) CreateEvent(eventType string, payload []byte) (int64, error) {
+	query := "INSERT INTO outbox_events (event_type, payload, created_at) VALUES (?, ?, NOW())"
+	result, err := r.db.op("CreateEvent").Exec(query, eventType, payload)
+	if err != nil {
+		return 0, fmt.Errorf("CreateEvent: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("CreateEvent: LastInsertId failed: %w", err)
+	}
+	return id, nil
+}
+
+// GetUnpublishedEvents retrieves up to limit unpublished events, oldest first.
+func (r *mysqlOutboxRepository) GetUnpublishedEvents(limit int) ([]models.OutboxEvent, error) {
+	query := "SELECT event_id, event_type, payload, created_at, published_at FROM outbox_events WHERE published_at IS NULL ORDER BY event_id LIMIT ?"
+	rows, err := r.db.op("GetUnpublishedEvents").Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetUnpublishedEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var ev models.OutboxEvent
+		if err := rows.Scan(&ev.EventID, &ev.EventType, &ev.Payload, &ev.CreatedAt, &ev.PublishedAt); err != nil {
+			return nil, fmt.Errorf("GetUnpublishedEvents: scan error: %w", err)
+		}
+		events = append(events, ev)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetUnpublishedEvents: rows iteration error: %w", err)
+	}
+	return events, nil
+}
+
+// MarkEventPublished records that an event has been delivered.
+func (r *mysqlOutboxRepository) MarkEventPublished(eventID int64) (int64, error) {
+	query := "UPDATE outbox_events SET published_at = NOW() WHERE event_id = ? AND published_at IS NULL"
+	result, err := r.db.op("MarkEventPublished").Exec(query, eventID)
+	if err != nil {
+		return 0, fmt.Errorf("MarkEventPublished: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("MarkEventPublished: RowsAffected failed: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+// WithDBTX returns a copy of the repository whose calls run on db, usually the *sql.Tx a
+// service is writing in, so events commit or roll back with the change they describe.
+func (r *mysqlOutboxRepository) WithDBTX(db DBTX) OutboxRepository {
+	return &mysqlOutboxRepository{db: r.db.withDB(db)}
+}
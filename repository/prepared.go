@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// WithPreparedStatements makes the repository prepare its hot queries once, when it is
+// constructed, and reuse the *sql.Stmt on every call instead of having the server re-parse the
+// SQL. Call the repository's Close when done with it to release the statements.
+// A query that fails to prepare is run unprepared, so its error surfaces on first use.
+func WithPreparedStatements() RepositoryOption {
+	return func(h *dbHandle) {
+		h.prepare = true
+	}
+}
+
+// prepareStatements prepares queries when WithPreparedStatements was given.
+func (h *dbHandle) prepareStatements(queries ...string) {
+	if !h.prepare {
+		return
+	}
+	h.stmts = make(map[string]*sql.Stmt, len(queries))
+	for _, query := range queries {
+		stmt, err := h.db.Prepare(query)
+		if err != nil {
+			continue
+		}
+		h.stmts[query] = stmt
+	}
+}
+
+// close releases the prepared statements.
+func (h dbHandle) close() error {
+	var errs []error
+	for _, stmt := range h.stmts {
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// preparedDBTX runs queries that have a prepared statement through it, and any other query
+// directly on db. The map is read-only after construction, so no locking is needed.
+type preparedDBTX struct {
+	db    DBTX
+	stmts map[string]*sql.Stmt
+}
+
+// Exec runs query, prepared if possible.
+func (p preparedDBTX) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if stmt, ok := p.stmts[query]; ok {
+		return stmt.Exec(args...)
+	}
+	return p.db.Exec(query, args...)
+}
+
+// QueryRow runs query, prepared if possible.
+func (p preparedDBTX) QueryRow(query string, args ...interface{}) *sql.Row {
+	if stmt, ok := p.stmts[query]; ok {
+		return stmt.QueryRow(args...)
+	}
+	return p.db.QueryRow(query, args...)
+}
+
+// Query runs query, prepared if possible.
+func (p preparedDBTX) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt, ok := p.stmts[query]; ok {
+		return stmt.Query(args...)
+	}
+	return p.db.Query(query, args...)
+}
+
+// ExecContext runs query under ctx, prepared if possible. p.db must implement contextDBTX.
+func (p preparedDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if stmt, ok := p.stmts[query]; ok {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return p.db.(contextDBTX).ExecContext(ctx, query, args...)
+}
+
+// QueryRowContext runs query under ctx, prepared if possible. p.db must implement contextDBTX.
+func (p preparedDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if stmt, ok := p.stmts[query]; ok {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return p.db.(contextDBTX).QueryRowContext(ctx, query, args...)
+}
+
+// QueryContext runs query under ctx, prepared if possible. p.db must implement contextDBTX.
+func (p preparedDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt, ok := p.stmts[query]; ok {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return p.db.(contextDBTX).QueryContext(ctx, query, args...)
+}
+
+// Prepare prepares query on the underlying database.
+func (p preparedDBTX) Prepare(query string) (*sql.Stmt, error) {
+	return p.db.Prepare(query)
+}
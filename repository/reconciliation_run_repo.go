@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"sql-golang-playground/models"
+)
+
+// reconciliationRunColumns is the column list scanned by scanReconciliationRun.
+const reconciliationRunColumns = "run_id, input, started_at, finished_at, matched, amount_mismatches, split_matches, partial_split_matches, ambiguous_splits, only_in_db, only_in_csv, file_errors, error"
+
+// scanReconciliationRun scans a row selected with reconciliationRunColumns.
+func scanReconciliationRun(row rowScanner, run *models.ReconciliationRun) error {
+	return row.Scan(&run.RunID, &run.Input, (*utcTime)(&run.StartedAt), (*utcTime)(&run.FinishedAt), &run.Matched, &run.AmountMismatches, &run.SplitMatches, &run.PartialSplitMatches, &run.AmbiguousSplits, &run.OnlyInDB, &run.OnlyInCSV, &run.FileErrors, &run.Error)
+}
+
+// mysqlReconciliationRunRepository implements ReconciliationRunRepository for MySQL.
+type mysqlReconciliationRunRepository struct {
+	db dbHandle
+}
+
+// NewMySQLReconciliationRunRepository creates a new MySQL reconciliation run repository backed by a *sql.DB or *sql.Tx.
+func NewMySQLReconciliationRunRepository(db DBTX, opts ...RepositoryOption) ReconciliationRunRepository {
+	return &mysqlReconciliationRunRepository{db: newDBHandle(db, opts)}
+}
+
+// CreateReconciliationRun stores a run summary and returns its ID. run.RunID is ignored.
+func (r *mysqlReconciliationRunRepository) CreateReconciliationRun(run models.ReconciliationRun) (int64, error) {
+	query := "INSERT INTO reconciliation_runs (input, started_at, finished_at, matched, amount_mismatches, split_matches, partial_split_matches, ambiguous_splits, only_in_db, only_in_csv, file_errors, error) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	result, err := r.db.op("CreateReconciliationRun").Exec(query, run.Input, run.StartedAt.UTC(), run.FinishedAt.UTC(), run.Matched, run.AmountMismatches, run.SplitMatches, run.PartialSplitMatches, run.AmbiguousSplits, run.OnlyInDB, run.OnlyInCSV, run.FileErrors, run.Error)
+	if err != nil {
+		return 0, fmt.Errorf("CreateReconciliationRun: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("CreateReconciliationRun: LastInsertId failed: %w", err)
+	}
+	return id, nil
+}
+
+// GetLastReconciliationRun returns the most recently started run for input, or for any input
+// when input is empty. With no runs the error wraps sql.ErrNoRows.
+func (r *mysqlReconciliationRunRepository) GetLastReconciliationRun(input string) (models.ReconciliationRun, error) {
+	query := "SELECT " + reconciliationRunColumns + " FROM reconciliation_runs"
+	var args []interface{}
+	if input != "" {
+		query += " WHERE input = ?"
+		args = append(args, input)
+	}
+	query += " ORDER BY started_at DESC, run_id DESC LIMIT 1"
+
+	var run models.ReconciliationRun
+	if err := scanReconciliationRun(r.db.op("GetLastReconciliationRun").QueryRow(query, args...), &run); err != nil {
+		if err == sql.ErrNoRows {
+			return run, fmt.Errorf("GetLastReconciliationRun: no runs recorded for %q: %w", input, err)
+		}
+		return run, fmt.Errorf("GetLastReconciliationRun: %w", err)
+	}
+	return run, nil
+}
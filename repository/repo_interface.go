@@ -1,7 +1,10 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"time"
+
 	"sql-golang-playground/models"
 )
 
@@ -13,26 +16,177 @@ type DBTX interface {
     Prepare(query string) (*sql.Stmt, error)
 }
 
+// AccountListOptions controls paging and ordering for GetAllAccounts and GetAccountsWithActivity.
+// A non-positive Limit returns every matching account. SortBy must be one of
+// account_id, account_holder, balance or last_updated; empty means account_id.
+type AccountListOptions struct {
+	Limit      int
+	Offset     int
+	SortBy     string
+	Descending bool
+}
+
+// InactiveAccountOptions narrows the accounts FindInactiveAccounts selects.
+type InactiveAccountOptions struct {
+	ZeroBalanceOnly bool // only accounts whose balance is exactly zero
+}
+
 // AccountRepository defines the interface for account-related database operations.
 type AccountRepository interface {
-	CreateAccount(holderName string, initialBalance float64) (int64, error)
+	CreateAccount(holderName string, initialBalance float64) (int64, string, error)
+	CreateAccountIfNotExists(externalID, holderName string, initialBalance float64) (int64, bool, error)
 	GetAccountByID(accountID int64) (models.Account, error)
-	GetAllAccounts() ([]models.Account, error)
+	GetAccountByIDIncludingDeleted(accountID int64) (models.Account, error)
+	GetAccountByNumber(accountNumber string) (models.Account, error)
+	GetAccountByIDForUpdate(accountID int64) (models.Account, error)
+	GetAllAccounts(opts AccountListOptions) ([]models.Account, int64, error)
+	GetAccountsWithActivity(opts AccountListOptions) ([]models.AccountWithActivity, int64, error)
+	GetTopAccountsByBalance(n int) ([]models.Account, error)
 	UpdateAccountHolderName(accountID int64, newHolderName string) (int64, error)
 	AdjustAccountBalance(accountID int64, amountChange float64) (int64, error)
+	SetAccountBalance(accountID int64, balance float64) (int64, error)
 	SoftDeleteAccount(accountID int64) (int64, error)
+	SoftDeleteInactiveAccounts(inactiveSince time.Time) (int64, error)
+	SoftDeleteInactiveZeroBalanceAccounts(inactiveSince time.Time) (int64, error)
+	FindInactiveAccounts(inactiveSince time.Time, opts InactiveAccountOptions) ([]models.Account, error)
     UndeleteAccount(accountID int64) (int64, error)
+	FreezeAccount(accountID int64) (int64, error)
+	UnfreezeAccount(accountID int64) (int64, error)
 	CalculateTotalBalanceOfActiveAccounts() (float64, error)
+	GetBalancesByIDs(accountIDs []int64) (map[int64]float64, error)
+	GetAccountsByIDs(accountIDs []int64, includeClosed bool) (map[int64]models.Account, error)
+	GetBalancesForTransactions(transactions []models.Transaction) (map[int64]float64, error)
+	IsValidTransferTarget(sourceAccountID, accountID int64) (bool, string, error)
+	HasTransactions(accountID int64) (bool, error)
+	SetAccountMetadata(accountID int64, updates map[string]string) (int64, error)
+	GetAccountMetadata(accountID int64) (models.AccountMetadata, error)
+	GetAccountsByMetadata(key, value string) ([]models.Account, error)
+	WithContext(ctx context.Context) AccountRepository
+	WithDBTX(db DBTX) AccountRepository
+	Close() error
+}
+
+// CategoryListOptions controls filtering and ordering for GetTransactionsWithCategory.
+// By default transactions are returned newest first with no amount or category filter.
+// CategoryID and Uncategorized are mutually exclusive.
+type CategoryListOptions struct {
+	MinAmount      sql.NullFloat64 // when valid, only transactions with amount >= MinAmount
+	OrderByAmount  bool            // order by amount, largest first, instead of by time
+	IncludeDeleted bool            // include soft-deleted transactions
+	IncludePending bool            // include transfers pending review, which have moved no funds
+	CategoryID     sql.NullInt64   // when valid, only transactions in this category, which must exist
+	Uncategorized  bool            // only transactions without a category
+}
+
+// RowError is a row that could not be scanned. TransactionID is 0 when the ID itself could
+// not be read.
+type RowError struct {
+	TransactionID int64
+	Err           error
+}
+
+// TransactionFilter selects transactions for ListTransactions and AggregateTransactions. Unset fields do not filter,
+// except that soft-deleted transactions are excluded unless IncludeDeleted is set.
+// From is inclusive and To exclusive. A non-positive Limit returns every matching transaction.
+type TransactionFilter struct {
+	AccountID sql.NullInt64 // either side of the transaction
+	Types     []string
+	From      sql.NullTime
+	To        sql.NullTime
+	MinAmount sql.NullFloat64
+	MaxAmount sql.NullFloat64
+	Limit     int
+	Offset    int
+
+	IncludeDeleted bool // include soft-deleted transactions
+	IncludePending bool // include transfers pending review, which have moved no funds
 }
 
 // TransactionRepository defines the interface for transaction-related database operations.
 type TransactionRepository interface {
 	CreateTransaction(fromID, toID sql.NullInt64, txType string, amount float64, description sql.NullString) (int64, error)
     CreateTransactionWithNotes(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString) (int64, error)
+	CreateTransactionAt(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString, ts time.Time) (int64, error)
+	CreateTransactionWithCategory(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString, categoryName string) (int64, error)
+	CreatePendingReviewTransaction(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString) (int64, error)
+	MarkTransactionCompleted(transactionID int64) (int64, error)
+	CreateTransactionIdempotent(key string, fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString) (int64, bool, error)
 	GetTransactionByID(transactionID int64) (models.Transaction, error)
-	GetTransactionsForAccount(accountID int64) ([]models.Transaction, error)
-	GetTransactionsWithCategory(accountID int64) ([]models.TransactionWithCategory, error)
+	GetTransactionByIDForUpdate(transactionID int64) (models.Transaction, error)
+	GetTransactionDetailByID(transactionID int64) (models.TransactionWithCategory, error)
+	GetTransactionsForAccount(accountID int64, types []string) ([]models.AccountTransaction, error)
+	GetTransactionsSince(accountID int64, sinceTxID int64, limit int) ([]models.AccountTransaction, error)
+	GetTransactionsWithCategory(accountID int64, opts CategoryListOptions) ([]models.TransactionWithCategory, error)
 	UpdateTransactionDescription(transactionID int64, newDescription sql.NullString) (int64, error)
 	DeleteTransaction(transactionID int64) (int64, error)
+	SoftDeleteTransaction(transactionID int64) (int64, error)
+	UndeleteTransaction(transactionID int64) (int64, error)
+	SetTransactionExchange(transactionID int64, creditedAmount, rate float64) (int64, error)
 	GetAllTransactionsForReconciliation() ([]models.Transaction, error)
-}
\ No newline at end of file
+	GetAllTransactionsForReconciliationSkippingBadRows() ([]models.Transaction, []RowError, error)
+	ForEachTransactionForReconciliation(fn func(models.Transaction) error) error
+	CreateReversalTransaction(original models.Transaction, description, notes sql.NullString) (int64, error)
+	IsTransactionReversed(transactionID int64) (bool, error)
+	FindTransactionIDGaps() ([]models.IDGap, error)
+	GetLedgerBalance(accountID int64) (float64, error)
+	VerifyLedgerIntegrity() ([]models.LedgerDiscrepancy, error)
+	NormalizeNegativeAmounts() (int64, error)
+	GetFeesForPeriod(accountID int64, from, to time.Time) (float64, []models.Transaction, error)
+	GetDistinctTransactionTypes() ([]string, error)
+	ListTransactions(filter TransactionFilter) ([]models.Transaction, int64, error)
+	AggregateTransactions(filter TransactionFilter) (map[string]models.TransactionAggregate, error)
+	SearchTransactionsByNotes(query string, limit int, includeDescription bool) ([]models.Transaction, error)
+	WithContext(ctx context.Context) TransactionRepository
+	WithDBTX(db DBTX) TransactionRepository
+	Close() error
+}
+// OutboxRepository defines the interface for transactional outbox operations.
+type OutboxRepository interface {
+	CreateEvent(eventType string, payload []byte) (int64, error)
+	GetUnpublishedEvents(limit int) ([]models.OutboxEvent, error)
+	MarkEventPublished(eventID int64) (int64, error)
+	WithDBTX(db DBTX) OutboxRepository
+}
+
+// AttachmentRepository defines the interface for transaction attachment references.
+type AttachmentRepository interface {
+	AddAttachment(transactionID int64, url, filename string) (int64, error)
+	ListAttachments(transactionID int64) ([]models.Attachment, error)
+	RemoveAttachment(attachmentID int64) (int64, error)
+}
+
+// ScheduledTransferRepository defines the interface for scheduled transfer storage.
+type ScheduledTransferRepository interface {
+	CreateScheduledTransfer(st models.ScheduledTransfer) (int64, error)
+	GetPendingScheduledTransfersForAccount(accountID int64, until time.Time) ([]models.ScheduledTransfer, error)
+	GetDueScheduledTransfers(now time.Time) ([]models.ScheduledTransfer, error)
+	ClaimScheduledTransfer(scheduledTransferID int64) (bool, error)
+	SetScheduledTransferStatus(scheduledTransferID int64, status string) (int64, error)
+}
+
+// BalanceSnapshotRepository defines the interface for point-in-time account balances.
+type BalanceSnapshotRepository interface {
+	CreateBalanceSnapshots(asOf time.Time) (int64, error)
+	GetLatestBalanceSnapshot(accountID int64, asOf time.Time) (models.BalanceSnapshot, error)
+	SumLedgerChanges(accountID int64, after, through time.Time) (float64, error)
+}
+
+// ReconciliationRunRepository defines the interface for stored reconciliation run summaries.
+type ReconciliationRunRepository interface {
+	CreateReconciliationRun(run models.ReconciliationRun) (int64, error)
+	GetLastReconciliationRun(input string) (models.ReconciliationRun, error)
+}
+
+// FixtureRepository reads and writes whole account and transaction rows, IDs included, to
+// snapshot and seed environments.
+type FixtureRepository interface {
+	GetAllAccountsForFixtures() ([]models.Account, error)
+	GetAllTransactionsForFixtures() ([]models.Transaction, error)
+	InsertAccountFixture(acc models.Account) (int64, error)
+	InsertTransactionFixture(tx models.Transaction) (int64, error)
+}
+
+// Locker acquires named locks that are exclusive across processes sharing the database.
+type Locker interface {
+	TryLock(name string) (release func() error, acquired bool, err error)
+}
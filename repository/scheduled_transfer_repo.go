@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"sql-golang-playground/models"
+)
+
+// scheduledTransferColumns is the column list scanned by scanScheduledTransfer.
+const scheduledTransferColumns = "scheduled_transfer_id, from_account_id, to_account_id, amount, execute_at, description, notes, status, created_at"
+
+// scanScheduledTransfer scans a row selected with scheduledTransferColumns.
+func scanScheduledTransfer(row rowScanner, st *models.ScheduledTransfer) error {
+	return row.Scan(&st.ScheduledTransferID, &st.FromAccountID, &st.ToAccountID, (*decimal)(&st.Amount), &st.ExecuteAt, &st.Description, &st.Notes, &st.Status, &st.CreatedAt)
+}
+
+// mysqlScheduledTransferRepository implements ScheduledTransferRepository for MySQL.
+type mysqlScheduledTransferRepository struct {
+	db dbHandle
+}
+
+// NewMySQLScheduledTransferRepository creates a new MySQL scheduled transfer repository backed by a *sql.DB or *sql.Tx.
+func NewMySQLScheduledTransferRepository(db DBTX, opts ...RepositoryOption) ScheduledTransferRepository {
+	return &mysqlScheduledTransferRepository{db: newDBHandle(db, opts)}
+}
+
+// CreateScheduledTransfer stores st as a pending scheduled transfer and returns its ID.
+// st.ScheduledTransferID, st.Status and st.CreatedAt are ignored.
+func (r *mysqlScheduledTransferRepository) CreateScheduledTransfer(st models.ScheduledTransfer) (int64, error) {
+	query := "INSERT INTO scheduled_transfers (from_account_id, to_account_id, amount, execute_at, description, notes, status) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	result, err := r.db.op("CreateScheduledTransfer").Exec(query, st.FromAccountID, st.ToAccountID, st.Amount, st.ExecuteAt.UTC(), st.Description, st.Notes, models.ScheduledTransferPending)
+	if err != nil {
+		return 0, fmt.Errorf("CreateScheduledTransfer: %w", translateMySQLError(err))
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("CreateScheduledTransfer: LastInsertId failed: %w", err)
+	}
+	return id, nil
+}
+
+// GetPendingScheduledTransfersForAccount returns the pending scheduled transfers on either side
+// of accountID that are due at or before until, in execution order.
+func (r *mysqlScheduledTransferRepository) GetPendingScheduledTransfersForAccount(accountID int64, until time.Time) ([]models.ScheduledTransfer, error) {
+	query := "SELECT " + scheduledTransferColumns + " FROM scheduled_transfers WHERE status = ? AND execute_at <= ? AND (from_account_id = ? OR to_account_id = ?) ORDER BY execute_at, scheduled_transfer_id"
+	rows, err := r.db.op("GetPendingScheduledTransfersForAccount").Query(query, models.ScheduledTransferPending, until, accountID, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("GetPendingScheduledTransfersForAccount: %w", err)
+	}
+	defer rows.Close()
+
+	var transfers []models.ScheduledTransfer
+	for rows.Next() {
+		var st models.ScheduledTransfer
+		if err := scanScheduledTransfer(rows, &st); err != nil {
+			return nil, fmt.Errorf("GetPendingScheduledTransfersForAccount: scan error: %w", err)
+		}
+		transfers = append(transfers, st)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetPendingScheduledTransfersForAccount: rows iteration error: %w", err)
+	}
+	return transfers, nil
+}
+
+// GetDueScheduledTransfers returns every pending scheduled transfer due at or before now, in
+// execution order.
+func (r *mysqlScheduledTransferRepository) GetDueScheduledTransfers(now time.Time) ([]models.ScheduledTransfer, error) {
+	query := "SELECT " + scheduledTransferColumns + " FROM scheduled_transfers WHERE status = ? AND execute_at <= ? ORDER BY execute_at, scheduled_transfer_id"
+	rows, err := r.db.op("GetDueScheduledTransfers").Query(query, models.ScheduledTransferPending, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("GetDueScheduledTransfers: %w", err)
+	}
+	defer rows.Close()
+
+	var transfers []models.ScheduledTransfer
+	for rows.Next() {
+		var st models.ScheduledTransfer
+		if err := scanScheduledTransfer(rows, &st); err != nil {
+			return nil, fmt.Errorf("GetDueScheduledTransfers: scan error: %w", err)
+		}
+		transfers = append(transfers, st)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetDueScheduledTransfers: rows iteration error: %w", err)
+	}
+	return transfers, nil
+}
+
+// ClaimScheduledTransfer moves a pending scheduled transfer to PROCESSING and reports whether
+// this call did so. The update is conditional on the row still being PENDING, so when several
+// processors race for the same transfer exactly one of them wins it.
+func (r *mysqlScheduledTransferRepository) ClaimScheduledTransfer(scheduledTransferID int64) (bool, error) {
+	query := "UPDATE scheduled_transfers SET status = ? WHERE scheduled_transfer_id = ? AND status = ?"
+	result, err := r.db.op("ClaimScheduledTransfer").Exec(query, models.ScheduledTransferProcessing, scheduledTransferID, models.ScheduledTransferPending)
+	if err != nil {
+		return false, fmt.Errorf("ClaimScheduledTransfer: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ClaimScheduledTransfer: RowsAffected failed: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// SetScheduledTransferStatus sets the status of a scheduled transfer and returns the number of
+// rows affected.
+func (r *mysqlScheduledTransferRepository) SetScheduledTransferStatus(scheduledTransferID int64, status string) (int64, error) {
+	query := "UPDATE scheduled_transfers SET status = ? WHERE scheduled_transfer_id = ?"
+	result, err := r.db.op("SetScheduledTransferStatus").Exec(query, status, scheduledTransferID)
+	if err != nil {
+		return 0, fmt.Errorf("SetScheduledTransferStatus: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("SetScheduledTransferStatus: RowsAffected failed: %w", err)
+	}
+	return rowsAffected, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSchemaMismatch is returned by CheckSchema when tables lack columns the repositories select.
+var ErrSchemaMismatch = errors.New("database schema is missing required columns; apply the pending migrations")
+
+// expectedColumns lists, per table, the columns the repositories read.
+var expectedColumns = map[string]string{
+	"accounts":     accountColumns,
+	"transactions": transactionColumns,
+}
+
+// CheckSchema verifies that every column the repositories select exists in the connected
+// database. Run it at startup so an unmigrated database fails fast with a list of what is
+// missing, instead of failing later on whichever query touches the column first.
+func CheckSchema(db DBTX) error {
+	var missing []string
+	for _, table := range []string{"accounts", "transactions"} {
+		present, err := tableColumns(db, table)
+		if err != nil {
+			return fmt.Errorf("CheckSchema: %w", err)
+		}
+		for _, col := range strings.Split(expectedColumns[table], ", ") {
+			if !present[col] {
+				missing = append(missing, table+"."+col)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("CheckSchema: %w: %s (see migrations/)", ErrSchemaMismatch, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// tableColumns returns the set of column names of table in the current database.
+func tableColumns(db DBTX, table string) (map[string]bool, error) {
+	query := "SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?"
+	rows, err := db.Query(query, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		columns[strings.ToLower(name)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return columns, nil
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+)
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// mysqlDatetimeLayout is how MySQL renders DATETIME values when parseTime is off.
+const mysqlDatetimeLayout = "2006-01-02 15:04:05.999999"
+
+// utcTime scans a DATETIME/TIMESTAMP column into a time.Time in UTC, whatever the DSN's loc.
+// Without parseTime the driver returns the raw text, which is read as UTC too.
+type utcTime time.Time
+
+// Scan implements sql.Scanner.
+func (t *utcTime) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case time.Time:
+		*t = utcTime(v.UTC())
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	case nil:
+		*t = utcTime(time.Time{})
+	default:
+		return fmt.Errorf("utcTime: cannot scan %T", src)
+	}
+	return nil
+}
+
+// parse reads a MySQL DATETIME string as UTC.
+func (t *utcTime) parse(s string) error {
+	parsed, err := time.ParseInLocation(mysqlDatetimeLayout, s, time.UTC)
+	if err != nil {
+		return fmt.Errorf("utcTime: %w", err)
+	}
+	*t = utcTime(parsed)
+	return nil
+}
+
+// maxExactDigits is how many significant decimal digits a float64 is guaranteed to round-trip.
+const maxExactDigits = 15
+
+// decimal scans a DECIMAL column into a float64 from the exact text the driver returns
+// (sql.RawBytes), rather than leaving the conversion to database/sql. The text is parsed with
+// strconv.ParseFloat, giving the float64 nearest the stored value, and a value with more
+// significant digits than a float64 can round-trip is an error instead of being silently rounded.
+type decimal float64
+
+// Scan implements sql.Scanner.
+func (d *decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return d.parse(string(v))
+	case string:
+		return d.parse(v)
+	case int64:
+		*d = decimal(v)
+	case float64:
+		*d = decimal(v) // a DOUBLE expression; there is no exact text to parse
+	default:
+		return fmt.Errorf("decimal: cannot scan %T", src)
+	}
+	return nil
+}
+
+// parse reads a DECIMAL's text form.
+func (d *decimal) parse(s string) error {
+	if n := significantDigits(s); n > maxExactDigits {
+		return fmt.Errorf("decimal: %s has %d significant digits, more than a float64 holds exactly", s, n)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("decimal: %w", err)
+	}
+	*d = decimal(f)
+	return nil
+}
+
+// significantDigits counts the significant digits of a plain decimal string such as "-0012.3400".
+func significantDigits(s string) int {
+	s = strings.TrimLeft(s, "+-")
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+	}
+	s = strings.Replace(s, ".", "", 1)
+	s = strings.TrimRight(strings.TrimLeft(s, "0"), "0")
+	return len(s)
+}
+
+// nullDecimal is the nullable counterpart of decimal, for scanning into a sql.NullFloat64.
+type nullDecimal sql.NullFloat64
+
+// Scan implements sql.Scanner.
+func (d *nullDecimal) Scan(src interface{}) error {
+	if src == nil {
+		*d = nullDecimal{}
+		return nil
+	}
+	var v decimal
+	if err := v.Scan(src); err != nil {
+		return err
+	}
+	*d = nullDecimal{Float64: float64(v), Valid: true}
+	return nil
+}
+
+// placeholders returns a comma-separated list of n "?" placeholders for an IN (...) clause.
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// qualifiedColumns prefixes every column of a comma-separated column list with alias.
+func qualifiedColumns(alias, columns string) string {
+	cols := strings.Split(columns, ", ")
+	for i, c := range cols {
+		cols[i] = alias + "." + c
+	}
+	return strings.Join(cols, ", ")
+}
+
+// accountIDsFromTransactions collects the distinct, non-NULL account IDs referenced by the transactions.
+func accountIDsFromTransactions(transactions []models.Transaction) []int64 {
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, tx := range transactions {
+		for _, id := range []sql.NullInt64{tx.FromAccountID, tx.ToAccountID} {
+			if id.Valid && !seen[id.Int64] {
+				seen[id.Int64] = true
+				ids = append(ids, id.Int64)
+			}
+		}
+	}
+	return ids
+}
+
+// validateAmount enforces the ledger sign convention: amounts are always stored as positive
+// values and direction is conveyed by transaction_type and the from/to account IDs.
+func validateAmount(amount float64) error {
+	if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return fmt.Errorf("%w: amount must be positive, got %v", util.ErrInvalidTransferAmount, amount)
+	}
+	return nil
+}
+
+// maxHolderNameLength is the length, in characters, of accounts.account_holder.
+const maxHolderNameLength = 255
+
+// normalizeHolderName trims surrounding whitespace from an account holder name and rejects it
+// with util.ErrInvalidAccountHolder if it is then empty, longer than the column, or contains
+// control characters such as newlines or tabs.
+func normalizeHolderName(name string) (string, error) {
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" {
+		return "", fmt.Errorf("%w: name is empty", util.ErrInvalidAccountHolder)
+	}
+	if n := utf8.RuneCountInString(trimmed); n > maxHolderNameLength {
+		return "", fmt.Errorf("%w: name is %d characters, the maximum is %d", util.ErrInvalidAccountHolder, n, maxHolderNameLength)
+	}
+	if !utf8.ValidString(trimmed) {
+		return "", fmt.Errorf("%w: name is not valid UTF-8", util.ErrInvalidAccountHolder)
+	}
+	if i := strings.IndexFunc(trimmed, unicode.IsControl); i >= 0 {
+		return "", fmt.Errorf("%w: name contains control character %U", util.ErrInvalidAccountHolder, []rune(trimmed[i:])[0])
+	}
+	return trimmed, nil
+}
+
+// transactionTypeArgs validates types against models.ValidTransactionTypes and returns them,
+// upper-cased, as query arguments for an IN (...) clause.
+func transactionTypeArgs(types []string) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(types))
+	for _, t := range types {
+		normalized := strings.ToUpper(strings.TrimSpace(t))
+		if !models.ValidTransactionTypes[normalized] {
+			return nil, fmt.Errorf("%w: %q", util.ErrInvalidTransactionType, t)
+		}
+		args = append(args, normalized)
+	}
+	return args, nil
+}
+
+// likeEscapeChar is the escape character declared in LIKE ... ESCAPE clauses built with likeContains.
+const likeEscapeChar = "!"
+
+// likeContains returns a LIKE pattern matching values that contain s literally:
+// %, _ and the escape character in s are escaped.
+func likeContains(s string) string {
+	r := strings.NewReplacer(likeEscapeChar, likeEscapeChar+likeEscapeChar, "%", likeEscapeChar+"%", "_", likeEscapeChar+"_")
+	return "%" + r.Replace(s) + "%"
+}
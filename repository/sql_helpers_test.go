@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-golang-playground/internal/util"
+	"sql-golang-playground/models"
+)
+
+func TestAccountIDsFromTransactions(t *testing.T) {
+	id := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+	transactions := []models.Transaction{
+		{FromAccountID: id(3), ToAccountID: id(1)},
+		{FromAccountID: sql.NullInt64{}, ToAccountID: id(3)}, // a deposit
+		{FromAccountID: id(2), ToAccountID: sql.NullInt64{}}, // a withdrawal
+		{FromAccountID: id(1), ToAccountID: id(2)},
+	}
+	want := []int64{3, 1, 2}
+	if got := accountIDsFromTransactions(transactions); !reflect.DeepEqual(got, want) {
+		t.Errorf("accountIDsFromTransactions = %v, want %v", got, want)
+	}
+	if got := accountIDsFromTransactions(nil); len(got) != 0 {
+		t.Errorf("accountIDsFromTransactions(nil) = %v, want none", got)
+	}
+}
+
+func TestUTCTimeScan(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	instant := time.Date(2026, time.June, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		src  interface{}
+		want time.Time
+	}{
+		{"time in another zone", instant.In(tokyo), instant},       // parseTime with a non-UTC loc
+		{"DATETIME bytes", []byte("2026-06-01 12:30:00"), instant}, // parseTime off
+		{"DATETIME string with fraction", "2026-06-01 12:30:00.25", instant.Add(250 * time.Millisecond)},
+		{"NULL", nil, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got utcTime
+			if err := got.Scan(tt.src); err != nil {
+				t.Fatalf("Scan(%v): %v", tt.src, err)
+			}
+			if ts := time.Time(got); !ts.Equal(tt.want) || (!ts.IsZero() && ts.Location() != time.UTC) {
+				t.Errorf("Scan(%v) = %v, want %v in UTC", tt.src, ts, tt.want)
+			}
+		})
+	}
+
+	var ts utcTime
+	if err := ts.Scan(int64(0)); err == nil {
+		t.Error("Scan(int64) succeeded, want an error")
+	}
+	if err := ts.Scan("June 1st"); err == nil {
+		t.Error("Scan of an unparseable string succeeded, want an error")
+	}
+}
+
+func TestNormalizeHolderName(t *testing.T) {
+	longest := strings.Repeat("é", maxHolderNameLength) // the limit counts characters, not bytes
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "Alice Smith", "Alice Smith", false},
+		{"trimmed", "  Alice\t", "Alice", false},
+		{"longest allowed", longest, longest, false},
+		{"empty", "", "", true},
+		{"only whitespace", " \t\n ", "", true},
+		{"over length", longest + "e", "", true},
+		{"newline inside", "Alice\nSmith", "", true},
+		{"invalid UTF-8", "Alice\xff", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeHolderName(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, util.ErrInvalidAccountHolder) {
+					t.Errorf("normalizeHolderName(%q) error = %v, want %v", tt.input, err, util.ErrInvalidAccountHolder)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("normalizeHolderName(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
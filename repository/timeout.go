@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// WithQueryTimeout bounds every database call of the repository to d, so a pathological query
+// cannot hold a connection indefinitely when the caller set no deadline. A context passed with
+// WithContext that already has a deadline takes precedence. For Query and QueryRow the timeout
+// covers running the query but not reading its rows.
+func WithQueryTimeout(d time.Duration) RepositoryOption {
+	return func(h *dbHandle) {
+		h.timeout = d
+	}
+}
+
+// WithMethodTimeout overrides the WithQueryTimeout default for the repository method named
+// method, e.g. WithMethodTimeout("GetAllTransactionsForReconciliation", 5*time.Minute).
+// A non-positive d disables the timeout for that method.
+func WithMethodTimeout(method string, d time.Duration) RepositoryOption {
+	return func(h *dbHandle) {
+		if h.methodTimeouts == nil {
+			h.methodTimeouts = make(map[string]time.Duration)
+		}
+		h.methodTimeouts[method] = d
+	}
+}
+
+// withContext returns a copy of h whose calls run under ctx.
+func (h dbHandle) withContext(ctx context.Context) dbHandle {
+	h.ctx = ctx
+	return h
+}
+
+// withDB returns a copy of h whose calls run on db, typically a *sql.Tx, keeping the other
+// options. Prepared statements are rebound to a *sql.Tx with Tx.Stmt, which the transaction
+// closes when it ends; for any other db they are dropped and the queries run unprepared.
+func (h dbHandle) withDB(db DBTX) dbHandle {
+	h.db = db
+	if len(h.stmts) == 0 {
+		return h
+	}
+	stmts := h.stmts
+	h.stmts = nil
+	if tx, ok := db.(*sql.Tx); ok {
+		h.stmts = make(map[string]*sql.Stmt, len(stmts))
+		for query, stmt := range stmts {
+			h.stmts[query] = tx.Stmt(stmt)
+		}
+	}
+	return h
+}
+
+// timeoutFor returns the timeout for the named method; zero means none.
+func (h dbHandle) timeoutFor(name string) time.Duration {
+	if d, ok := h.methodTimeouts[name]; ok {
+		return d
+	}
+	return h.timeout
+}
+
+// contextDBTX is the context-aware subset of *sql.DB and *sql.Tx used to apply timeouts.
+type contextDBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// timeoutDBTX runs each call under ctx, bounded by timeout when ctx has no deadline of its own.
+// db must implement contextDBTX.
+type timeoutDBTX struct {
+	db      DBTX
+	ctx     context.Context
+	timeout time.Duration
+}
+
+// context returns the context for one call and the function that releases it.
+func (t timeoutDBTX) context() (context.Context, context.CancelFunc) {
+	if _, ok := t.ctx.Deadline(); ok || t.timeout <= 0 {
+		return t.ctx, func() {}
+	}
+	return context.WithTimeout(t.ctx, t.timeout)
+}
+
+// Exec runs query under the call's context.
+func (t timeoutDBTX) Exec(query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := t.context()
+	defer cancel()
+	return t.db.(contextDBTX).ExecContext(ctx, query, args...)
+}
+
+// queryContext returns the context for one Query or QueryRow call and the function to call
+// with the query's error once it returns. The rows are read after the call returns, so the
+// context cannot be canceled then, and a timeout covering the reads would cut off a slow
+// stream mid-way. Instead the timeout bounds running the query: done stops the timer, and
+// cancels the context only when the query failed.
+func (t timeoutDBTX) queryContext() (ctx context.Context, done func(err error)) {
+	if _, ok := t.ctx.Deadline(); ok || t.timeout <= 0 {
+		return t.ctx, func(error) {}
+	}
+	ctx, cancel := context.WithCancel(t.ctx)
+	timer := time.AfterFunc(t.timeout, cancel)
+	return ctx, func(err error) {
+		timer.Stop()
+		if err != nil {
+			cancel()
+		}
+	}
+}
+
+// QueryRow runs query under the call's context. The timeout bounds running the query, not
+// scanning the row.
+func (t timeoutDBTX) QueryRow(query string, args ...interface{}) *sql.Row {
+	ctx, done := t.queryContext()
+	row := t.db.(contextDBTX).QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+// Query runs query under the call's context. The timeout bounds running the query, not
+// reading the rows, so a slow consumer of a large result is not cut off.
+func (t timeoutDBTX) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, done := t.queryContext()
+	rows, err := t.db.(contextDBTX).QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+// Prepare prepares query on the underlying database.
+func (t timeoutDBTX) Prepare(query string) (*sql.Stmt, error) {
+	return t.db.Prepare(query)
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// slowDriver is a database/sql driver for exercising timeouts. The query "SLOW" blocks until
+// its context is done; any other query returns the rows 1, 2 and 3.
+type slowDriver struct{}
+
+func (slowDriver) Open(string) (driver.Conn, error) { return slowConn{}, nil }
+
+type slowConn struct{}
+
+func (slowConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("slowConn: Prepare not supported")
+}
+func (slowConn) Close() error              { return nil }
+func (slowConn) Begin() (driver.Tx, error) { return nil, errors.New("slowConn: Begin not supported") }
+
+func (slowConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if query == "SLOW" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &countingRows{}, nil
+}
+
+type countingRows struct{ n int64 }
+
+func (r *countingRows) Columns() []string { return []string{"n"} }
+func (r *countingRows) Close() error      { return nil }
+func (r *countingRows) Next(dest []driver.Value) error {
+	if r.n == 3 {
+		return io.EOF
+	}
+	r.n++
+	dest[0] = r.n
+	return nil
+}
+
+func init() {
+	sql.Register("slow", slowDriver{})
+}
+
+func openSlowDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("slow", "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestTimeoutDBTXCancelsSlowQuery(t *testing.T) {
+	db := timeoutDBTX{db: openSlowDB(t), ctx: context.Background(), timeout: 20 * time.Millisecond}
+
+	start := time.Now()
+	if _, err := db.Query("SLOW"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Query error = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Query returned after %v, want about the 20ms timeout", elapsed)
+	}
+	if err := db.QueryRow("SLOW").Scan(new(int64)); !errors.Is(err, context.Canceled) {
+		t.Errorf("QueryRow error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestTimeoutDBTXRowsOutliveTimeout(t *testing.T) {
+	const timeout = 20 * time.Millisecond
+	db := timeoutDBTX{db: openSlowDB(t), ctx: context.Background(), timeout: timeout}
+
+	rows, err := db.Query("ROWS")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+	var read int
+	for rows.Next() {
+		read++
+		time.Sleep(2 * timeout) // a slow consumer must not be cut off by the query timeout
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err: %v", err)
+	}
+	if read != 3 {
+		t.Errorf("read %d rows, want 3", read)
+	}
+
+	row := db.QueryRow("ROWS")
+	time.Sleep(2 * timeout)
+	var n int64
+	if err := row.Scan(&n); err != nil || n != 1 {
+		t.Errorf("QueryRow Scan = %d, %v; want 1, nil", n, err)
+	}
+}
@@ -1,27 +1,73 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 	"sql-golang-playground/models"
 )
 
+// transactionColumns is the column list scanned by scanTransaction. Every query that returns
+// models.Transaction selects it, so a schema change is a single edit here.
+const transactionColumns = "transaction_id, from_account_id, to_account_id, transaction_type, amount, credited_amount, exchange_rate, transaction_ts, description, notes, reversal_of, status, is_deleted"
+
+// transactionScanDest returns the scan destinations for transactionColumns.
+func transactionScanDest(tx *models.Transaction) []interface{} {
+	return []interface{}{&tx.TransactionID, &tx.FromAccountID, &tx.ToAccountID, &tx.TransactionType, (*decimal)(&tx.Amount), (*nullDecimal)(&tx.CreditedAmount), &tx.ExchangeRate, (*utcTime)(&tx.TransactionTs), &tx.Description, &tx.Notes, &tx.ReversalOf, &tx.Status, &tx.IsDeleted}
+}
+
+// scanTransaction scans a row selected with transactionColumns.
+func scanTransaction(row rowScanner, tx *models.Transaction) error {
+	return row.Scan(transactionScanDest(tx)...)
+}
+
 // mysqlTransactionRepository implements TransactionRepository for MySQL.
 type mysqlTransactionRepository struct {
-	db *sql.DB
+	db dbHandle
+}
+
+// NewMySQLTransactionRepository creates a new MySQL transaction repository backed by a *sql.DB or *sql.Tx.
+func NewMySQLTransactionRepository(db DBTX, opts ...RepositoryOption) TransactionRepository {
+	h := newDBHandle(db, opts)
+	h.prepareStatements(createTransactionWithNotesQuery)
+	return &mysqlTransactionRepository{db: h}
+}
+
+// WithContext returns a copy of the repository whose calls run under ctx. A deadline on ctx
+// overrides WithQueryTimeout and WithMethodTimeout for those calls. The copy shares the prepared
+// statements, so Close the original rather than the copy.
+func (r *mysqlTransactionRepository) WithContext(ctx context.Context) TransactionRepository {
+	return &mysqlTransactionRepository{db: r.db.withContext(ctx)}
+}
+
+// WithDBTX returns a copy of the repository whose calls run on db, usually the *sql.Tx a
+// service is writing in, so its reads see that transaction's own changes and its locks. The copy
+// keeps the repository's options; Close the original rather than the copy.
+func (r *mysqlTransactionRepository) WithDBTX(db DBTX) TransactionRepository {
+	return &mysqlTransactionRepository{db: r.db.withDB(db)}
 }
 
-// NewMySQLTransactionRepository creates a new MySQL transaction repository.
-func NewMySQLTransactionRepository(db *sql.DB) TransactionRepository {
-	return &mysqlTransactionRepository{db: db}
+// Close releases the statements prepared by WithPreparedStatements. It does not close the
+// underlying database.
+func (r *mysqlTransactionRepository) Close() error {
+	return r.db.close()
 }
 
+// createTransactionWithNotesQuery is prepared up front with WithPreparedStatements.
+const createTransactionWithNotesQuery = "INSERT INTO transactions (from_account_id, to_account_id, transaction_type, amount, description, notes, transaction_ts) VALUES (?, ?, ?, ?, ?, ?, NOW())"
+
 // CreateTransaction inserts a new transaction and returns its ID.
+// amount must be positive; direction is conveyed by txType and the from/to account IDs.
 func (r *mysqlTransactionRepository) CreateTransaction(fromID, toID sql.NullInt64, txType string, amount float64, description sql.NullString) (int64, error) {
+    if err := validateAmount(amount); err != nil {
+        return 0, fmt.Errorf("CreateTransaction: %w", err)
+    }
     query := "INSERT INTO transactions (from_account_id, to_account_id, transaction_type, amount, description, transaction_ts) VALUES (?, ?, ?, ?, ?, NOW())"
-    result, err := r.db.Exec(query, fromID, toID, txType, amount, description)
+    result, err := r.db.op("CreateTransaction").Exec(query, fromID, toID, txType, amount, description)
     if err != nil {
-        return 0, fmt.Errorf("CreateTransaction: %w", err)
+        return 0, fmt.Errorf("CreateTransaction: %w", translateMySQLError(err))
     }
 
     id, err := result.LastInsertId()
@@ -32,12 +78,15 @@ func (r *mysqlTransactionRepository) CreateTransaction(fromID, toID sql.NullInt6
 }
 
 // CreateTransactionWithNotes inserts a new transaction with additional notes and returns its ID.
+// amount must be positive; direction is conveyed by txType and the from/to account IDs.
 func (r *mysqlTransactionRepository) CreateTransactionWithNotes(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString) (int64, error) {
-    query := "INSERT INTO transactions (from_account_id, to_account_id, transaction_type, amount, description, notes, transaction_ts) VALUES (?, ?, ?, ?, ?, ?, NOW())"
-    result, err := r.db.Exec(query, fromID, toID, txType, amount, description, notes)
-    if err != nil {
+    if err := validateAmount(amount); err != nil {
         return 0, fmt.Errorf("CreateTransactionWithNotes: %w", err)
     }
+    result, err := r.db.op("CreateTransactionWithNotes").Exec(createTransactionWithNotesQuery, fromID, toID, txType, amount, description, notes)
+    if err != nil {
+        return 0, fmt.Errorf("CreateTransactionWithNotes: %w", translateNotesError(err))
+    }
 
     id, err := result.LastInsertId()
     if err != nil {
@@ -46,37 +95,216 @@ func (r *mysqlTransactionRepository) CreateTransactionWithNotes(fromID, toID sql
     return id, nil
 }
 
+// CreateTransactionAt inserts a new transaction stamped with ts and returns its ID.
+// It is meant for imports of historical transactions; a zero ts means NOW().
+// amount must be positive; direction is conveyed by txType and the from/to account IDs.
+func (r *mysqlTransactionRepository) CreateTransactionAt(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString, ts time.Time) (int64, error) {
+    if err := validateAmount(amount); err != nil {
+        return 0, fmt.Errorf("CreateTransactionAt: %w", err)
+    }
+    timestamp := sql.NullTime{Time: ts.UTC(), Valid: !ts.IsZero()}
+    query := "INSERT INTO transactions (from_account_id, to_account_id, transaction_type, amount, description, notes, transaction_ts) VALUES (?, ?, ?, ?, ?, ?, COALESCE(?, NOW()))"
+    result, err := r.db.op("CreateTransactionAt").Exec(query, fromID, toID, txType, amount, description, notes, timestamp)
+    if err != nil {
+        return 0, fmt.Errorf("CreateTransactionAt: %w", translateNotesError(err))
+    }
+
+    id, err := result.LastInsertId()
+    if err != nil {
+        return 0, fmt.Errorf("CreateTransactionAt: LastInsertId failed: %w", err)
+    }
+    return id, nil
+}
+
+// CreateTransactionWithCategory inserts a new transaction in the named category and returns its ID.
+// A category that does not exist yet is created first. Run it on a *sql.Tx to make both inserts
+// atomic; the unique index on category_name keeps concurrent callers from creating duplicates.
+func (r *mysqlTransactionRepository) CreateTransactionWithCategory(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString, categoryName string) (int64, error) {
+    if err := validateAmount(amount); err != nil {
+        return 0, fmt.Errorf("CreateTransactionWithCategory: %w", err)
+    }
+    categoryName = strings.TrimSpace(categoryName)
+    if categoryName == "" {
+        return 0, fmt.Errorf("CreateTransactionWithCategory: category name must not be empty")
+    }
+
+    // On a duplicate name, LAST_INSERT_ID(category_id) makes LastInsertId return the existing row's ID.
+    upsert := "INSERT INTO transaction_categories (category_name) VALUES (?) ON DUPLICATE KEY UPDATE category_id = LAST_INSERT_ID(category_id)"
+    result, err := r.db.op("CreateTransactionWithCategory").Exec(upsert, categoryName)
+    if err != nil {
+        return 0, fmt.Errorf("CreateTransactionWithCategory: failed to resolve category %q: %w", categoryName, translateMySQLError(err))
+    }
+    categoryID, err := result.LastInsertId()
+    if err != nil {
+        return 0, fmt.Errorf("CreateTransactionWithCategory: LastInsertId failed for category %q: %w", categoryName, err)
+    }
+
+    query := "INSERT INTO transactions (from_account_id, to_account_id, transaction_type, amount, description, notes, category_id, transaction_ts) VALUES (?, ?, ?, ?, ?, ?, ?, NOW())"
+    result, err = r.db.op("CreateTransactionWithCategory").Exec(query, fromID, toID, txType, amount, description, notes, categoryID)
+    if err != nil {
+        return 0, fmt.Errorf("CreateTransactionWithCategory: %w", translateNotesError(err))
+    }
+
+    id, err := result.LastInsertId()
+    if err != nil {
+        return 0, fmt.Errorf("CreateTransactionWithCategory: LastInsertId failed: %w", err)
+    }
+    return id, nil
+}
+
+// CreatePendingReviewTransaction records a transaction held for review and returns its ID.
+// No balances are touched; MarkTransactionCompleted releases it once the funds have moved.
+func (r *mysqlTransactionRepository) CreatePendingReviewTransaction(fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString) (int64, error) {
+    if err := validateAmount(amount); err != nil {
+        return 0, fmt.Errorf("CreatePendingReviewTransaction: %w", err)
+    }
+    query := "INSERT INTO transactions (from_account_id, to_account_id, transaction_type, amount, description, notes, status, transaction_ts) VALUES (?, ?, ?, ?, ?, ?, ?, NOW())"
+    result, err := r.db.op("CreatePendingReviewTransaction").Exec(query, fromID, toID, txType, amount, description, notes, models.TransactionStatusPendingReview)
+    if err != nil {
+        return 0, fmt.Errorf("CreatePendingReviewTransaction: %w", translateNotesError(err))
+    }
+
+    id, err := result.LastInsertId()
+    if err != nil {
+        return 0, fmt.Errorf("CreatePendingReviewTransaction: LastInsertId failed: %w", err)
+    }
+    return id, nil
+}
+
+// MarkTransactionCompleted moves a PENDING_REVIEW transaction to COMPLETED, stamping it with
+// the current time. It affects zero rows if the transaction is not pending review, so
+// concurrent approvals cannot both succeed.
+func (r *mysqlTransactionRepository) MarkTransactionCompleted(transactionID int64) (int64, error) {
+    query := "UPDATE transactions SET status = ?, transaction_ts = NOW() WHERE transaction_id = ? AND status = ?"
+    result, err := r.db.op("MarkTransactionCompleted").Exec(query, models.TransactionStatusCompleted, transactionID, models.TransactionStatusPendingReview)
+    if err != nil {
+        return 0, fmt.Errorf("MarkTransactionCompleted: %w", translateMySQLError(err))
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("MarkTransactionCompleted: RowsAffected failed: %w", err)
+    }
+    return rowsAffected, nil
+}
+
+// CreateTransactionIdempotent inserts a transaction tagged with an idempotency key and returns its ID.
+// If a transaction with the same key already exists, nothing is inserted and the existing ID is
+// returned with created set to false. The unique index on idempotency_key makes this race-safe.
+func (r *mysqlTransactionRepository) CreateTransactionIdempotent(key string, fromID, toID sql.NullInt64, txType string, amount float64, description, notes sql.NullString) (int64, bool, error) {
+    if key == "" {
+        return 0, false, fmt.Errorf("CreateTransactionIdempotent: idempotency key must not be empty")
+    }
+    if err := validateAmount(amount); err != nil {
+        return 0, false, fmt.Errorf("CreateTransactionIdempotent: %w", err)
+    }
+    query := "INSERT INTO transactions (idempotency_key, from_account_id, to_account_id, transaction_type, amount, description, notes, transaction_ts) VALUES (?, ?, ?, ?, ?, ?, ?, NOW())"
+    result, err := r.db.op("CreateTransactionIdempotent").Exec(query, key, fromID, toID, txType, amount, description, notes)
+    if err != nil {
+        if !isDuplicateEntryError(err) {
+            return 0, false, fmt.Errorf("CreateTransactionIdempotent: %w", translateNotesError(err))
+        }
+        var existingID int64
+        if err := r.db.op("CreateTransactionIdempotent").QueryRow("SELECT transaction_id FROM transactions WHERE idempotency_key = ?", key).Scan(&existingID); err != nil {
+            return 0, false, fmt.Errorf("CreateTransactionIdempotent: failed to look up existing transaction for key %q: %w", key, err)
+        }
+        return existingID, false, nil
+    }
+
+    id, err := result.LastInsertId()
+    if err != nil {
+        return 0, false, fmt.Errorf("CreateTransactionIdempotent: LastInsertId failed: %w", err)
+    }
+    return id, true, nil
+}
+
 // GetTransactionByID retrieves a single transaction by its ID.
 func (r *mysqlTransactionRepository) GetTransactionByID(transactionID int64) (models.Transaction, error) {
     var tx models.Transaction
-    query := "SELECT transaction_id, from_account_id, to_account_id, transaction_type, amount, transaction_ts, description FROM transactions WHERE transaction_id = ?"
-    row := r.db.QueryRow(query, transactionID)
-    err := row.Scan(&tx.TransactionID, &tx.FromAccountID, &tx.ToAccountID, &tx.TransactionType, &tx.Amount, &tx.TransactionTs, &tx.Description)
+    query := "SELECT " + transactionColumns + " FROM transactions WHERE transaction_id = ?"
+    row := r.db.op("GetTransactionByID").QueryRow(query, transactionID)
+    err := scanTransaction(row, &tx)
     if err != nil {
         if err == sql.ErrNoRows {
-            return tx, fmt.Errorf("GetTransactionByID: no transaction with ID %d", transactionID)
+            return tx, fmt.Errorf("GetTransactionByID: no transaction with ID %d: %w", transactionID, err)
         }
-        return tx, fmt.Errorf("GetTransactionByID: %w", err)
+        return tx, fmt.Errorf("GetTransactionByID: %w", translateNotesError(err))
     }
     return tx, nil
 }
 
-// GetTransactionsForAccount retrieves all transactions involving a specific account ID.
-func (r *mysqlTransactionRepository) GetTransactionsForAccount(accountID int64) ([]models.Transaction, error) {
-    query := "SELECT transaction_id, from_account_id, to_account_id, transaction_type, amount, transaction_ts, description FROM transactions WHERE from_account_id = ? OR to_account_id = ? ORDER BY transaction_ts DESC"
-    rows, err := r.db.Query(query, accountID, accountID)
+// GetTransactionByIDForUpdate is GetTransactionByID with the row locked until the surrounding
+// transaction ends, so concurrent writers working on the same transaction, such as two
+// reversals of it, are serialized. It only makes sense on a repository bound to a *sql.Tx.
+func (r *mysqlTransactionRepository) GetTransactionByIDForUpdate(transactionID int64) (models.Transaction, error) {
+	var tx models.Transaction
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE transaction_id = ? FOR UPDATE"
+	err := scanTransaction(r.db.op("GetTransactionByIDForUpdate").QueryRow(query, transactionID), &tx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return tx, fmt.Errorf("GetTransactionByIDForUpdate: no transaction with ID %d: %w", transactionID, err)
+		}
+		return tx, fmt.Errorf("GetTransactionByIDForUpdate: %w", translateNotesError(err))
+	}
+	return tx, nil
+}
+
+// GetTransactionDetailByID retrieves a single transaction, including its notes and category
+// name, for a detail view. Notes and category are NULL-safe; a missing ID fails with
+// ErrTransactionNotFound.
+func (r *mysqlTransactionRepository) GetTransactionDetailByID(transactionID int64) (models.TransactionWithCategory, error) {
+    var twc models.TransactionWithCategory
+    query := `
+        SELECT
+            ` + qualifiedColumns("t", transactionColumns) + `,
+            tc.category_name
+        FROM
+            transactions t
+        LEFT JOIN
+            transaction_categories tc ON t.category_id = tc.category_id
+        WHERE
+            t.transaction_id = ?`
+    row := r.db.op("GetTransactionDetailByID").QueryRow(query, transactionID)
+    err := row.Scan(append(transactionScanDest(&twc.Transaction), &twc.CategoryName)...)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return twc, fmt.Errorf("GetTransactionDetailByID: %w: ID %d: %w", ErrTransactionNotFound, transactionID, err)
+        }
+        return twc, fmt.Errorf("GetTransactionDetailByID: %w", translateNotesError(err))
+    }
+    return twc, nil
+}
+
+// GetTransactionsForAccount retrieves all transactions involving a specific account ID, each
+// with its direction and signed amount from that account's side (see Transaction.ForAccount).
+// A non-empty types restricts the result to those transaction types; nil means all types.
+// Unknown types are rejected with util.ErrInvalidTransactionType. Soft-deleted transactions and
+// transfers pending review, which have moved no funds, are excluded; use ListTransactions with
+// IncludeDeleted and IncludePending to see them.
+func (r *mysqlTransactionRepository) GetTransactionsForAccount(accountID int64, types []string) ([]models.AccountTransaction, error) {
+    query := "SELECT " + transactionColumns + " FROM transactions WHERE (from_account_id = ? OR to_account_id = ?) AND is_deleted = FALSE AND status = ?"
+    args := []interface{}{accountID, accountID, models.TransactionStatusCompleted}
+    if len(types) > 0 {
+        typeArgs, err := transactionTypeArgs(types)
+        if err != nil {
+            return nil, fmt.Errorf("GetTransactionsForAccount: %w", err)
+        }
+        query += " AND transaction_type IN (" + placeholders(len(typeArgs)) + ")"
+        args = append(args, typeArgs...)
+    }
+    query += " ORDER BY transaction_ts DESC"
+    rows, err := r.db.op("GetTransactionsForAccount").Query(query, args...)
     if err != nil {
-        return nil, fmt.Errorf("GetTransactionsForAccount: %w", err)
+        return nil, fmt.Errorf("GetTransactionsForAccount: %w", translateNotesError(err))
     }
     defer rows.Close()
 
-    var transactions []models.Transaction
+    var transactions []models.AccountTransaction
     for rows.Next() {
         var tx models.Transaction
-        if err := rows.Scan(&tx.TransactionID, &tx.FromAccountID, &tx.ToAccountID, &tx.TransactionType, &tx.Amount, &tx.TransactionTs, &tx.Description); err != nil {
+        if err := scanTransaction(rows, &tx); err != nil {
             return nil, fmt.Errorf("GetTransactionsForAccount: scan error: %w", err)
         }
-        transactions = append(transactions, tx)
+        transactions = append(transactions, tx.ForAccount(accountID))
     }
     if err = rows.Err(); err != nil {
         return nil, fmt.Errorf("GetTransactionsForAccount: rows iteration error: %w", err)
@@ -84,37 +312,112 @@ func (r *mysqlTransactionRepository) GetTransactionsForAccount(accountID int64)
     return transactions, nil
 }
 
+// GetTransactionsSince returns up to limit transactions involving accountID whose ID is greater
+// than sinceTxID, in ascending ID order and as seen by accountID, for incremental sync: a poller
+// passes the last ID it received as the next sinceTxID, starting from 0. Soft-deleted
+// transactions are excluded. IDs are assigned at insert, so a transaction committed after a
+// higher ID can be passed over by a poller that is right at the head of the table. Transfers
+// pending review are included, with their Status: approving one keeps its ID, so a poller that
+// skipped it would never see it.
+func (r *mysqlTransactionRepository) GetTransactionsSince(accountID int64, sinceTxID int64, limit int) ([]models.AccountTransaction, error) {
+    if limit <= 0 {
+        return nil, fmt.Errorf("GetTransactionsSince: limit must be positive, got %d", limit)
+    }
+    query := "SELECT " + transactionColumns + " FROM transactions WHERE (from_account_id = ? OR to_account_id = ?) AND transaction_id > ? AND is_deleted = FALSE ORDER BY transaction_id ASC LIMIT ?"
+    rows, err := r.db.op("GetTransactionsSince").Query(query, accountID, accountID, sinceTxID, limit)
+    if err != nil {
+        return nil, fmt.Errorf("GetTransactionsSince: %w", translateNotesError(err))
+    }
+    defer rows.Close()
+
+    var transactions []models.AccountTransaction
+    for rows.Next() {
+        var tx models.Transaction
+        if err := scanTransaction(rows, &tx); err != nil {
+            return nil, fmt.Errorf("GetTransactionsSince: scan error: %w", err)
+        }
+        transactions = append(transactions, tx.ForAccount(accountID))
+    }
+    if err = rows.Err(); err != nil {
+        return nil, fmt.Errorf("GetTransactionsSince: rows iteration error: %w", err)
+    }
+    return transactions, nil
+}
+
 // GetTransactionsWithCategory retrieves transactions along with their category names.
-func (r *mysqlTransactionRepository) GetTransactionsWithCategory(accountID int64) ([]models.TransactionWithCategory, error) {
+// Soft-deleted transactions and transfers pending review are excluded unless
+// opts.IncludeDeleted or opts.IncludePending is set.
+// Filtering on a category that does not exist returns ErrCategoryNotFound.
+func (r *mysqlTransactionRepository) GetTransactionsWithCategory(accountID int64, opts CategoryListOptions) ([]models.TransactionWithCategory, error) {
+    if opts.CategoryID.Valid && opts.Uncategorized {
+        return nil, fmt.Errorf("GetTransactionsWithCategory: CategoryID and Uncategorized are mutually exclusive")
+    }
+    if opts.CategoryID.Valid {
+        var exists bool
+        err := r.db.op("GetTransactionsWithCategory").QueryRow("SELECT EXISTS(SELECT 1 FROM transaction_categories WHERE category_id = ?)", opts.CategoryID.Int64).Scan(&exists)
+        if err != nil {
+            return nil, fmt.Errorf("GetTransactionsWithCategory: failed to check category: %w", err)
+        }
+        if !exists {
+            return nil, fmt.Errorf("GetTransactionsWithCategory: %w (ID: %d)", ErrCategoryNotFound, opts.CategoryID.Int64)
+        }
+    }
+
     query := `
         SELECT
-            t.transaction_id, t.from_account_id, t.to_account_id,
-            t.transaction_type, t.amount, t.transaction_ts, t.description,
+            ` + qualifiedColumns("t", transactionColumns) + `,
             tc.category_name
         FROM
             transactions t
         LEFT JOIN
             transaction_categories tc ON t.category_id = tc.category_id
         WHERE
-            (t.from_account_id = ? OR t.to_account_id = ?)
+            (t.from_account_id = ? OR t.to_account_id = ?)`
+    args := []interface{}{accountID, accountID}
+
+    if !opts.IncludeDeleted {
+        query += `
+            AND t.is_deleted = FALSE`
+    }
+    if !opts.IncludePending {
+        query += `
+            AND t.status = ?`
+        args = append(args, models.TransactionStatusCompleted)
+    }
+    if opts.MinAmount.Valid {
+        query += `
+            AND t.amount >= ?`
+        args = append(args, opts.MinAmount.Float64)
+    }
+    if opts.CategoryID.Valid {
+        query += `
+            AND tc.category_id = ?`
+        args = append(args, opts.CategoryID.Int64)
+    }
+    if opts.Uncategorized {
+        query += `
+            AND tc.category_id IS NULL`
+    }
+    if opts.OrderByAmount {
+        query += `
+        ORDER BY
+            t.amount DESC, t.transaction_id DESC;`
+    } else {
+        query += `
         ORDER BY
             t.transaction_ts DESC;`
+    }
 
-    rows, err := r.db.Query(query, accountID, accountID)
+    rows, err := r.db.op("GetTransactionsWithCategory").Query(query, args...)
     if err != nil {
-        return nil, fmt.Errorf("GetTransactionsWithCategory: db.Query failed: %w", err)
+        return nil, fmt.Errorf("GetTransactionsWithCategory: db.Query failed: %w", translateNotesError(err))
     }
     defer rows.Close()
 
     var results []models.TransactionWithCategory
     for rows.Next() {
         var twc models.TransactionWithCategory
-        err := rows.Scan(
-            &twc.Transaction.TransactionID, &twc.Transaction.FromAccountID, &twc.Transaction.ToAccountID,
-            &twc.Transaction.TransactionType, &twc.Transaction.Amount, &twc.Transaction.TransactionTs,
-            &twc.Transaction.Description,
-            &twc.CategoryName,
-        )
+        err := rows.Scan(append(transactionScanDest(&twc.Transaction), &twc.CategoryName)...)
         if err != nil {
             return nil, fmt.Errorf("GetTransactionsWithCategory: rows.Scan failed: %w", err)
         }
@@ -129,7 +432,7 @@ func (r *mysqlTransactionRepository) GetTransactionsWithCategory(accountID int64
 // UpdateTransactionDescription updates the description of an existing transaction.
 func (r *mysqlTransactionRepository) UpdateTransactionDescription(transactionID int64, newDescription sql.NullString) (int64, error) {
     query := "UPDATE transactions SET description = ? WHERE transaction_id = ?"
-    result, err := r.db.Exec(query, newDescription, transactionID)
+    result, err := r.db.op("UpdateTransactionDescription").Exec(query, newDescription, transactionID)
     if err != nil {
         return 0, fmt.Errorf("UpdateTransactionDescription: %w", err)
     }
@@ -140,39 +443,500 @@ func (r *mysqlTransactionRepository) UpdateTransactionDescription(transactionID
     return rowsAffected, nil
 }
 
-// DeleteTransaction removes a transaction from the database.
+// DeleteTransaction removes a transaction that never affected a balance from the database.
+// Completed rows of a balance-affecting type with a non-zero amount are refused with
+// ErrBalanceAffectingTransaction. Deleting a missing row affects zero rows.
 func (r *mysqlTransactionRepository) DeleteTransaction(transactionID int64) (int64, error) {
-    query := "DELETE FROM transactions WHERE transaction_id = ?"
-    result, err := r.db.Exec(query, transactionID)
+    types := models.BalanceAffectingTransactionTypes
+    query := "DELETE FROM transactions WHERE transaction_id = ? AND (amount = 0 OR status <> ? OR transaction_type NOT IN (" + placeholders(len(types)) + "))"
+    args := []interface{}{transactionID, models.TransactionStatusCompleted}
+    for _, t := range types {
+        args = append(args, t)
+    }
+    result, err := r.db.op("DeleteTransaction").Exec(query, args...)
     if err != nil {
-        return 0, fmt.Errorf("DeleteTransaction: %w", err)
+        return 0, fmt.Errorf("DeleteTransaction: %w", translateMySQLError(err))
     }
     rowsAffected, err := result.RowsAffected()
     if err != nil {
         return 0, fmt.Errorf("DeleteTransaction: RowsAffected failed: %w", err)
     }
+    if rowsAffected == 0 {
+        var exists bool
+        err := r.db.op("DeleteTransaction").QueryRow("SELECT EXISTS(SELECT 1 FROM transactions WHERE transaction_id = ?)", transactionID).Scan(&exists)
+        if err != nil {
+            return 0, fmt.Errorf("DeleteTransaction: failed to check transaction %d: %w", transactionID, err)
+        }
+        if exists {
+            return 0, fmt.Errorf("DeleteTransaction: transaction %d: %w", transactionID, ErrBalanceAffectingTransaction)
+        }
+    }
+    return rowsAffected, nil
+}
+
+// SoftDeleteTransaction hides a transaction from listings and reconciliation. Unlike
+// DeleteTransaction it keeps the row, so the ledger and audit history are unchanged.
+func (r *mysqlTransactionRepository) SoftDeleteTransaction(transactionID int64) (int64, error) {
+    query := "UPDATE transactions SET is_deleted = TRUE WHERE transaction_id = ? AND is_deleted = FALSE"
+    result, err := r.db.op("SoftDeleteTransaction").Exec(query, transactionID)
+    if err != nil {
+        return 0, fmt.Errorf("SoftDeleteTransaction: %w", err)
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("SoftDeleteTransaction: RowsAffected failed: %w", err)
+    }
+    if rowsAffected == 0 {
+        return 0, fmt.Errorf("SoftDeleteTransaction: no visible transaction found with ID %d to soft delete, or already soft-deleted", transactionID)
+    }
+    return rowsAffected, nil
+}
+
+// UndeleteTransaction makes a soft-deleted transaction visible again.
+func (r *mysqlTransactionRepository) UndeleteTransaction(transactionID int64) (int64, error) {
+    query := "UPDATE transactions SET is_deleted = FALSE WHERE transaction_id = ? AND is_deleted = TRUE"
+    result, err := r.db.op("UndeleteTransaction").Exec(query, transactionID)
+    if err != nil {
+        return 0, fmt.Errorf("UndeleteTransaction: %w", err)
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("UndeleteTransaction: RowsAffected failed: %w", err)
+    }
+    if rowsAffected == 0 {
+        return 0, fmt.Errorf("UndeleteTransaction: no soft-deleted transaction found with ID %d to undelete, or already visible", transactionID)
+    }
+    return rowsAffected, nil
+}
+
+// SetTransactionExchange records that a transfer credited creditedAmount, in the receiver's
+// currency, at the given exchange rate.
+func (r *mysqlTransactionRepository) SetTransactionExchange(transactionID int64, creditedAmount, rate float64) (int64, error) {
+    query := "UPDATE transactions SET credited_amount = ?, exchange_rate = ? WHERE transaction_id = ?"
+    result, err := r.db.op("SetTransactionExchange").Exec(query, creditedAmount, rate, transactionID)
+    if err != nil {
+        return 0, fmt.Errorf("SetTransactionExchange: %w", translateMySQLError(err))
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("SetTransactionExchange: RowsAffected failed: %w", err)
+    }
     return rowsAffected, nil
 }
 
-// GetAllTransactionsForReconciliation retrieves all transactions from the database for reconciliation.
+// GetAllTransactionsForReconciliation retrieves all completed transactions that are not
+// soft-deleted from the database for reconciliation. Transfers pending review have moved no
+// funds, so they cannot appear on a statement and are left out.
 func (r *mysqlTransactionRepository) GetAllTransactionsForReconciliation() ([]models.Transaction, error) {
-    query := "SELECT transaction_id, from_account_id, to_account_id, transaction_type, amount, description, notes, transaction_ts FROM transactions ORDER BY transaction_id"
-    rows, err := r.db.Query(query)
+    transactions, _, err := r.reconciliationTransactions("GetAllTransactionsForReconciliation", false)
+    return transactions, err
+}
+
+// GetAllTransactionsForReconciliationSkippingBadRows is GetAllTransactionsForReconciliation for
+// tables with bad data: a row that fails to scan, e.g. on an unexpected NULL, is skipped and
+// returned as a RowError instead of aborting the whole read.
+func (r *mysqlTransactionRepository) GetAllTransactionsForReconciliationSkippingBadRows() ([]models.Transaction, []RowError, error) {
+    return r.reconciliationTransactions("GetAllTransactionsForReconciliationSkippingBadRows", true)
+}
+
+// ForEachTransactionForReconciliation calls fn with every completed transaction that is not
+// soft-deleted, in ID order, one row at a time, so exports of large tables do not hold them all in memory.
+// Iteration stops at the first error from fn, which is returned wrapped. The query holds a
+// connection until iteration ends, so fn should not block for long.
+func (r *mysqlTransactionRepository) ForEachTransactionForReconciliation(fn func(models.Transaction) error) error {
+    _, err := r.eachReconciliationTransaction("ForEachTransactionForReconciliation", false, fn)
+    return err
+}
+
+// reconciliationTransactions reads every completed transaction that is not soft-deleted, in
+// ID order.
+// With skipBadRows, rows that fail to scan are collected instead of failing the read.
+func (r *mysqlTransactionRepository) reconciliationTransactions(op string, skipBadRows bool) ([]models.Transaction, []RowError, error) {
+    var transactions []models.Transaction
+    badRows, err := r.eachReconciliationTransaction(op, skipBadRows, func(tx models.Transaction) error {
+        transactions = append(transactions, tx)
+        return nil
+    })
     if err != nil {
-        return nil, fmt.Errorf("GetAllTransactionsForReconciliation: %w", err)
+        return nil, nil, err
+    }
+    return transactions, badRows, nil
+}
+
+// eachReconciliationTransaction streams every completed transaction that is not soft-deleted,
+// in ID order, to fn. With skipBadRows, rows that fail to scan are returned instead of failing.
+func (r *mysqlTransactionRepository) eachReconciliationTransaction(op string, skipBadRows bool, fn func(models.Transaction) error) ([]RowError, error) {
+    query := "SELECT " + transactionColumns + " FROM transactions WHERE is_deleted = FALSE AND status = ? ORDER BY transaction_id"
+    rows, err := r.db.op(op).Query(query, models.TransactionStatusCompleted)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", op, translateNotesError(err))
     }
     defer rows.Close()
 
-    var transactions []models.Transaction
+    var badRows []RowError
     for rows.Next() {
         var tx models.Transaction
-        if err := rows.Scan(&tx.TransactionID, &tx.FromAccountID, &tx.ToAccountID, &tx.TransactionType, &tx.Amount, &tx.Description, &tx.Notes, &tx.TransactionTs); err != nil {
-            return nil, fmt.Errorf("GetAllTransactionsForReconciliation: scan error: %w", err)
+        if err := scanTransaction(rows, &tx); err != nil {
+            if !skipBadRows {
+                return nil, fmt.Errorf("%s: scan error: %w", op, err)
+            }
+            // Columns are assigned in order, so the ID is set unless it was the bad column.
+            badRows = append(badRows, RowError{TransactionID: tx.TransactionID, Err: err})
+            continue
+        }
+        if err := fn(tx); err != nil {
+            return nil, fmt.Errorf("%s: transaction %d: %w", op, tx.TransactionID, err)
         }
-        transactions = append(transactions, tx)
     }
     if err = rows.Err(); err != nil {
-        return nil, fmt.Errorf("GetAllTransactionsForReconciliation: rows iteration error: %w", err)
+        return nil, fmt.Errorf("%s: rows iteration error: %w", op, err)
     }
-    return transactions, nil
+    return badRows, nil
+}
+
+// CreateReversalTransaction records a TRANSFER that moves original's amount back from its
+// receiver to its sender, linked to original via reversal_of, and returns the new ID.
+func (r *mysqlTransactionRepository) CreateReversalTransaction(original models.Transaction, description, notes sql.NullString) (int64, error) {
+    query := "INSERT INTO transactions (from_account_id, to_account_id, transaction_type, amount, description, notes, reversal_of, transaction_ts) VALUES (?, ?, ?, ?, ?, ?, ?, NOW())"
+    result, err := r.db.op("CreateReversalTransaction").Exec(query, original.ToAccountID, original.FromAccountID, original.TransactionType, original.Amount, description, notes, original.TransactionID)
+    if err != nil {
+        return 0, fmt.Errorf("CreateReversalTransaction: %w", translateNotesError(err))
+    }
+
+    id, err := result.LastInsertId()
+    if err != nil {
+        return 0, fmt.Errorf("CreateReversalTransaction: LastInsertId failed: %w", err)
+    }
+    return id, nil
+}
+
+// IsTransactionReversed reports whether a reversal has already been recorded for the transaction.
+func (r *mysqlTransactionRepository) IsTransactionReversed(transactionID int64) (bool, error) {
+    var reversed bool
+    query := "SELECT EXISTS(SELECT 1 FROM transactions WHERE reversal_of = ?)"
+    if err := r.db.op("IsTransactionReversed").QueryRow(query, transactionID).Scan(&reversed); err != nil {
+        return false, fmt.Errorf("IsTransactionReversed: %w", err)
+    }
+    return reversed, nil
+}
+
+// FindTransactionIDGaps returns the ranges of transaction IDs missing between the lowest and
+// highest existing IDs, e.g. from hard deletes or failed inserts that consumed an auto-increment value.
+func (r *mysqlTransactionRepository) FindTransactionIDGaps() ([]models.IDGap, error) {
+    query := `
+        SELECT transaction_id + 1 AS gap_start, next_id - 1 AS gap_end
+        FROM (
+            SELECT transaction_id, LEAD(transaction_id) OVER (ORDER BY transaction_id) AS next_id
+            FROM transactions
+        ) ordered
+        WHERE next_id > transaction_id + 1
+        ORDER BY gap_start`
+    rows, err := r.db.op("FindTransactionIDGaps").Query(query)
+    if err != nil {
+        return nil, fmt.Errorf("FindTransactionIDGaps: %w", err)
+    }
+    defer rows.Close()
+
+    var gaps []models.IDGap
+    for rows.Next() {
+        var gap models.IDGap
+        if err := rows.Scan(&gap.Start, &gap.End); err != nil {
+            return nil, fmt.Errorf("FindTransactionIDGaps: scan error: %w", err)
+        }
+        gaps = append(gaps, gap)
+    }
+    if err = rows.Err(); err != nil {
+        return nil, fmt.Errorf("FindTransactionIDGaps: rows iteration error: %w", err)
+    }
+    return gaps, nil
+}
+
+// GetLedgerBalance returns the balance implied by an account's completed transactions: amounts
+// received (to_account_id) count as credits and amounts sent (from_account_id) as debits. ABS() is
+// used because legacy rows store some withdrawals as negative amounts. The receiver of a
+// cross-currency transfer is credited credited_amount.
+func (r *mysqlTransactionRepository) GetLedgerBalance(accountID int64) (float64, error) {
+    var balance float64
+    query := `
+        SELECT COALESCE(SUM(CASE
+            WHEN to_account_id = ? THEN COALESCE(credited_amount, ABS(amount))
+            WHEN from_account_id = ? THEN -ABS(amount)
+            ELSE 0 END), 0)
+        FROM transactions
+        WHERE (from_account_id = ? OR to_account_id = ?) AND status = ?`
+    if err := r.db.op("GetLedgerBalance").QueryRow(query, accountID, accountID, accountID, accountID, models.TransactionStatusCompleted).Scan((*decimal)(&balance)); err != nil {
+        return 0, fmt.Errorf("GetLedgerBalance: %w", err)
+    }
+    return balance, nil
+}
+
+// VerifyLedgerIntegrity returns every account, closed ones included, whose stored balance differs
+// by a cent or more from its ledger balance as computed by GetLedgerBalance, ordered by account
+// ID. It runs a single aggregate query and changes nothing; RecomputeBalance fixes an account.
+func (r *mysqlTransactionRepository) VerifyLedgerIntegrity() ([]models.LedgerDiscrepancy, error) {
+    query := `
+        SELECT a.account_id, a.account_number, a.balance, COALESCE(l.ledger, 0)
+        FROM accounts a
+        LEFT JOIN (
+            SELECT account_id, SUM(delta) AS ledger
+            FROM (
+                SELECT to_account_id AS account_id, COALESCE(credited_amount, ABS(amount)) AS delta
+                FROM transactions
+                WHERE to_account_id IS NOT NULL AND status = ?
+                UNION ALL
+                SELECT from_account_id, -ABS(amount)
+                FROM transactions
+                WHERE from_account_id IS NOT NULL AND status = ?
+                  AND (to_account_id IS NULL OR to_account_id <> from_account_id)
+            ) entries
+            GROUP BY account_id
+        ) l ON l.account_id = a.account_id
+        WHERE ABS(a.balance - COALESCE(l.ledger, 0)) >= 0.01
+        ORDER BY a.account_id`
+    rows, err := r.db.op("VerifyLedgerIntegrity").Query(query, models.TransactionStatusCompleted, models.TransactionStatusCompleted)
+    if err != nil {
+        return nil, fmt.Errorf("VerifyLedgerIntegrity: %w", err)
+    }
+    defer rows.Close()
+
+    var discrepancies []models.LedgerDiscrepancy
+    for rows.Next() {
+        var d models.LedgerDiscrepancy
+        if err := rows.Scan(&d.AccountID, &d.AccountNumber, (*decimal)(&d.StoredBalance), (*decimal)(&d.LedgerBalance)); err != nil {
+            return nil, fmt.Errorf("VerifyLedgerIntegrity: scan error: %w", err)
+        }
+        d.Difference = d.StoredBalance - d.LedgerBalance
+        discrepancies = append(discrepancies, d)
+    }
+    if err = rows.Err(); err != nil {
+        return nil, fmt.Errorf("VerifyLedgerIntegrity: rows iteration error: %w", err)
+    }
+    return discrepancies, nil
+}
+
+// NormalizeNegativeAmounts rewrites legacy rows that stored a negative amount (e.g. withdrawals)
+// to the positive-amount convention and returns the number of rows changed.
+func (r *mysqlTransactionRepository) NormalizeNegativeAmounts() (int64, error) {
+    query := "UPDATE transactions SET amount = ABS(amount) WHERE amount < 0"
+    result, err := r.db.op("NormalizeNegativeAmounts").Exec(query)
+    if err != nil {
+        return 0, fmt.Errorf("NormalizeNegativeAmounts: %w", err)
+    }
+    rowsAffected, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("NormalizeNegativeAmounts: RowsAffected failed: %w", err)
+    }
+    return rowsAffected, nil
+}
+
+// GetFeesForPeriod returns the completed FEE transactions charged to an account with
+// from <= transaction_ts < to, oldest first, together with their total.
+func (r *mysqlTransactionRepository) GetFeesForPeriod(accountID int64, from, to time.Time) (float64, []models.Transaction, error) {
+    query := "SELECT " + transactionColumns + " FROM transactions WHERE from_account_id = ? AND transaction_type = ? AND status = ? AND transaction_ts >= ? AND transaction_ts < ? ORDER BY transaction_ts, transaction_id"
+    rows, err := r.db.op("GetFeesForPeriod").Query(query, accountID, models.TransactionTypeFee, models.TransactionStatusCompleted, from, to)
+    if err != nil {
+        return 0, nil, fmt.Errorf("GetFeesForPeriod: %w", translateNotesError(err))
+    }
+    defer rows.Close()
+
+    var total float64
+    var fees []models.Transaction
+    for rows.Next() {
+        var tx models.Transaction
+        if err := scanTransaction(rows, &tx); err != nil {
+            return 0, nil, fmt.Errorf("GetFeesForPeriod: scan error: %w", err)
+        }
+        total += tx.Amount
+        fees = append(fees, tx)
+    }
+    if err = rows.Err(); err != nil {
+        return 0, nil, fmt.Errorf("GetFeesForPeriod: rows iteration error: %w", err)
+    }
+    return total, fees, nil
+}
+
+// GetDistinctTransactionTypes returns every transaction type present in the data, sorted.
+// Types missing from models.ValidTransactionTypes point at stray or mistyped rows.
+func (r *mysqlTransactionRepository) GetDistinctTransactionTypes() ([]string, error) {
+	rows, err := r.db.op("GetDistinctTransactionTypes").Query("SELECT DISTINCT transaction_type FROM transactions ORDER BY transaction_type")
+	if err != nil {
+		return nil, fmt.Errorf("GetDistinctTransactionTypes: %w", err)
+	}
+	defer rows.Close()
+
+	var types []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("GetDistinctTransactionTypes: scan error: %w", err)
+		}
+		types = append(types, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetDistinctTransactionTypes: rows iteration error: %w", err)
+	}
+	return types, nil
+}
+
+// ListTransactions returns a page of transactions matching filter, newest first, together with
+// the total number of matching transactions. Transfers pending review are only included with
+// filter.IncludePending.
+func (r *mysqlTransactionRepository) ListTransactions(filter TransactionFilter) ([]models.Transaction, int64, error) {
+	if filter.Offset < 0 {
+		return nil, 0, fmt.Errorf("ListTransactions: offset must not be negative, got %d", filter.Offset)
+	}
+	where, args, err := transactionFilterWhere(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ListTransactions: %w", err)
+	}
+
+	var total int64
+	if err := r.db.op("ListTransactions").QueryRow("SELECT COUNT(*) FROM transactions"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("ListTransactions: count failed: %w", err)
+	}
+
+	query := "SELECT " + transactionColumns + " FROM transactions" + where +
+		" ORDER BY transaction_ts DESC, transaction_id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	}
+	rows, err := r.db.op("ListTransactions").Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ListTransactions: %w", translateNotesError(err))
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		var tx models.Transaction
+		if err := scanTransaction(rows, &tx); err != nil {
+			return nil, 0, fmt.Errorf("ListTransactions: scan error: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("ListTransactions: rows iteration error: %w", err)
+	}
+	return transactions, total, nil
+}
+
+// transactionFilterWhere returns the WHERE clause, with a leading space, and its arguments
+// for every filter in filter except Limit and Offset. It is empty when nothing filters.
+func transactionFilterWhere(filter TransactionFilter) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.AccountID.Valid {
+		conditions = append(conditions, "(from_account_id = ? OR to_account_id = ?)")
+		args = append(args, filter.AccountID.Int64, filter.AccountID.Int64)
+	}
+	if len(filter.Types) > 0 {
+		typeArgs, err := transactionTypeArgs(filter.Types)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, "transaction_type IN ("+placeholders(len(typeArgs))+")")
+		args = append(args, typeArgs...)
+	}
+	if filter.From.Valid {
+		conditions = append(conditions, "transaction_ts >= ?")
+		args = append(args, filter.From.Time)
+	}
+	if filter.To.Valid {
+		conditions = append(conditions, "transaction_ts < ?")
+		args = append(args, filter.To.Time)
+	}
+	if filter.MinAmount.Valid {
+		conditions = append(conditions, "amount >= ?")
+		args = append(args, filter.MinAmount.Float64)
+	}
+	if filter.MaxAmount.Valid {
+		conditions = append(conditions, "amount <= ?")
+		args = append(args, filter.MaxAmount.Float64)
+	}
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "is_deleted = FALSE")
+	}
+	if !filter.IncludePending {
+		conditions = append(conditions, "status = ?")
+		args = append(args, models.TransactionStatusCompleted)
+	}
+	if len(conditions) == 0 {
+		return "", args, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// AggregateTransactions returns the number and summed amount of the transactions matching
+// filter, keyed by transaction type, computed with a single GROUP BY query. Limit and Offset
+// are ignored. Like ListTransactions it counts transfers still pending review only with
+// filter.IncludePending; types with no matching transactions are absent from the map.
+func (r *mysqlTransactionRepository) AggregateTransactions(filter TransactionFilter) (map[string]models.TransactionAggregate, error) {
+	where, args, err := transactionFilterWhere(filter)
+	if err != nil {
+		return nil, fmt.Errorf("AggregateTransactions: %w", err)
+	}
+	query := "SELECT transaction_type, COUNT(*), COALESCE(SUM(amount), 0) FROM transactions" + where + " GROUP BY transaction_type"
+	rows, err := r.db.op("AggregateTransactions").Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("AggregateTransactions: %w", err)
+	}
+	defer rows.Close()
+
+	aggregates := make(map[string]models.TransactionAggregate)
+	for rows.Next() {
+		var txType string
+		var agg models.TransactionAggregate
+		if err := rows.Scan(&txType, &agg.Count, (*decimal)(&agg.Total)); err != nil {
+			return nil, fmt.Errorf("AggregateTransactions: scan error: %w", err)
+		}
+		aggregates[txType] = agg
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("AggregateTransactions: rows iteration error: %w", err)
+	}
+	return aggregates, nil
+}
+
+// SearchTransactionsByNotes returns up to limit transactions, newest first, whose notes contain
+// query as a literal substring; LIKE wildcards in query match only themselves. When
+// includeDescription is set, a match in the description also counts. Transfers pending review
+// are left out.
+func (r *mysqlTransactionRepository) SearchTransactionsByNotes(query string, limit int, includeDescription bool) ([]models.Transaction, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("SearchTransactionsByNotes: query must not be empty")
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("SearchTransactionsByNotes: limit must be positive, got %d", limit)
+	}
+	pattern := likeContains(query)
+	where := "notes LIKE ? ESCAPE '" + likeEscapeChar + "'"
+	args := []interface{}{pattern}
+	if includeDescription {
+		where = "(" + where + " OR description LIKE ? ESCAPE '" + likeEscapeChar + "')"
+		args = append(args, pattern)
+	}
+	where += " AND status = ?"
+	args = append(args, models.TransactionStatusCompleted, limit)
+
+	sqlQuery := "SELECT " + transactionColumns + " FROM transactions WHERE " + where +
+		" ORDER BY transaction_ts DESC, transaction_id DESC LIMIT ?"
+	rows, err := r.db.op("SearchTransactionsByNotes").Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SearchTransactionsByNotes: %w", translateNotesError(err))
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		var tx models.Transaction
+		if err := scanTransaction(rows, &tx); err != nil {
+			return nil, fmt.Errorf("SearchTransactionsByNotes: scan error: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("SearchTransactionsByNotes: rows iteration error: %w", err)
+	}
+	return transactions, nil
 }
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// WithTransaction runs fn inside a database transaction. The transaction is committed
+// when fn returns nil and rolled back when fn returns an error or panics; a panic is
+// re-raised after the rollback. fn's error is returned unchanged so callers can still
+// match it with errors.Is.
+//
+// Repositories bound to the transaction are created by passing tx to their constructors:
+//
+//	err := repository.WithTransaction(db, func(tx *sql.Tx) error {
+//		accounts := repository.NewMySQLAccountRepository(tx)
+//		transactions := repository.NewMySQLTransactionRepository(tx)
+//		...
+//	})
+func WithTransaction(db *sql.DB, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("WithTransaction: begin failed: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback also failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("WithTransaction: commit failed: %w", err)
+	}
+	return nil
+}
+
+// Transactor runs units of work that must commit or roll back as a whole. Services take a
+// Transactor instead of a *sql.DB so they can run against MemoryStore in tests; inside fn they
+// bind their repositories to tx with WithDBTX.
+type Transactor interface {
+	WithTransaction(fn func(tx DBTX) error) error
+}
+
+// sqlTransactor implements Transactor with database transactions.
+type sqlTransactor struct {
+	db *sql.DB
+}
+
+// NewSQLTransactor creates a Transactor that runs each unit of work in a transaction on db,
+// with the commit and rollback behaviour of WithTransaction. tx is a *sql.Tx.
+func NewSQLTransactor(db *sql.DB) Transactor {
+	return sqlTransactor{db: db}
+}
+
+// WithTransaction runs fn inside a new database transaction.
+func (t sqlTransactor) WithTransaction(fn func(tx DBTX) error) error {
+	return WithTransaction(t.db, func(tx *sql.Tx) error {
+		return fn(tx)
+	})
+}